@@ -0,0 +1,288 @@
+// The PromptSentinel gRPC API. It offers the same validation as the HTTP API
+// and the CLI, behind the same API keys, sent as "authorization: Bearer
+// <key>" metadata.
+//
+// Regenerate the Go code with `make proto` after changing this file.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.27.1
+// source: promptsentinel/v1/promptsentinel.proto
+
+package promptsentinelv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	PromptSentinel_Check_FullMethodName          = "/promptsentinel.v1.PromptSentinel/Check"
+	PromptSentinel_Validate_FullMethodName       = "/promptsentinel.v1.PromptSentinel/Validate"
+	PromptSentinel_Batch_FullMethodName          = "/promptsentinel.v1.PromptSentinel/Batch"
+	PromptSentinel_Redact_FullMethodName         = "/promptsentinel.v1.PromptSentinel/Redact"
+	PromptSentinel_ValidateStream_FullMethodName = "/promptsentinel.v1.PromptSentinel/ValidateStream"
+)
+
+// PromptSentinelClient is the client API for PromptSentinel service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PromptSentinelClient interface {
+	// Check runs the fast checks of `promptsentinel check`.
+	Check(ctx context.Context, in *PromptRequest, opts ...grpc.CallOption) (*CheckResponse, error)
+	// Validate runs the comprehensive analysis of `promptsentinel validate`.
+	Validate(ctx context.Context, in *PromptRequest, opts ...grpc.CallOption) (*ComprehensiveValidationResult, error)
+	// Batch checks up to 100 prompts in one call. Results are in request
+	// order.
+	Batch(ctx context.Context, in *BatchRequest, opts ...grpc.CallOption) (*BatchResponse, error)
+	// Redact replaces blocked patterns and custom rule matches with
+	// [REDACTED] and checks the redacted prompt.
+	Redact(ctx context.Context, in *RedactRequest, opts ...grpc.CallOption) (*RedactResponse, error)
+	// ValidateStream checks each prompt as it arrives and responds in order.
+	ValidateStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[PromptRequest, CheckResponse], error)
+}
+
+type promptSentinelClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPromptSentinelClient(cc grpc.ClientConnInterface) PromptSentinelClient {
+	return &promptSentinelClient{cc}
+}
+
+func (c *promptSentinelClient) Check(ctx context.Context, in *PromptRequest, opts ...grpc.CallOption) (*CheckResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CheckResponse)
+	err := c.cc.Invoke(ctx, PromptSentinel_Check_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *promptSentinelClient) Validate(ctx context.Context, in *PromptRequest, opts ...grpc.CallOption) (*ComprehensiveValidationResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ComprehensiveValidationResult)
+	err := c.cc.Invoke(ctx, PromptSentinel_Validate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *promptSentinelClient) Batch(ctx context.Context, in *BatchRequest, opts ...grpc.CallOption) (*BatchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchResponse)
+	err := c.cc.Invoke(ctx, PromptSentinel_Batch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *promptSentinelClient) Redact(ctx context.Context, in *RedactRequest, opts ...grpc.CallOption) (*RedactResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RedactResponse)
+	err := c.cc.Invoke(ctx, PromptSentinel_Redact_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *promptSentinelClient) ValidateStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[PromptRequest, CheckResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PromptSentinel_ServiceDesc.Streams[0], PromptSentinel_ValidateStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[PromptRequest, CheckResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PromptSentinel_ValidateStreamClient = grpc.BidiStreamingClient[PromptRequest, CheckResponse]
+
+// PromptSentinelServer is the server API for PromptSentinel service.
+// All implementations must embed UnimplementedPromptSentinelServer
+// for forward compatibility.
+type PromptSentinelServer interface {
+	// Check runs the fast checks of `promptsentinel check`.
+	Check(context.Context, *PromptRequest) (*CheckResponse, error)
+	// Validate runs the comprehensive analysis of `promptsentinel validate`.
+	Validate(context.Context, *PromptRequest) (*ComprehensiveValidationResult, error)
+	// Batch checks up to 100 prompts in one call. Results are in request
+	// order.
+	Batch(context.Context, *BatchRequest) (*BatchResponse, error)
+	// Redact replaces blocked patterns and custom rule matches with
+	// [REDACTED] and checks the redacted prompt.
+	Redact(context.Context, *RedactRequest) (*RedactResponse, error)
+	// ValidateStream checks each prompt as it arrives and responds in order.
+	ValidateStream(grpc.BidiStreamingServer[PromptRequest, CheckResponse]) error
+	mustEmbedUnimplementedPromptSentinelServer()
+}
+
+// UnimplementedPromptSentinelServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPromptSentinelServer struct{}
+
+func (UnimplementedPromptSentinelServer) Check(context.Context, *PromptRequest) (*CheckResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Check not implemented")
+}
+func (UnimplementedPromptSentinelServer) Validate(context.Context, *PromptRequest) (*ComprehensiveValidationResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Validate not implemented")
+}
+func (UnimplementedPromptSentinelServer) Batch(context.Context, *BatchRequest) (*BatchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Batch not implemented")
+}
+func (UnimplementedPromptSentinelServer) Redact(context.Context, *RedactRequest) (*RedactResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Redact not implemented")
+}
+func (UnimplementedPromptSentinelServer) ValidateStream(grpc.BidiStreamingServer[PromptRequest, CheckResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method ValidateStream not implemented")
+}
+func (UnimplementedPromptSentinelServer) mustEmbedUnimplementedPromptSentinelServer() {}
+func (UnimplementedPromptSentinelServer) testEmbeddedByValue()                        {}
+
+// UnsafePromptSentinelServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PromptSentinelServer will
+// result in compilation errors.
+type UnsafePromptSentinelServer interface {
+	mustEmbedUnimplementedPromptSentinelServer()
+}
+
+func RegisterPromptSentinelServer(s grpc.ServiceRegistrar, srv PromptSentinelServer) {
+	// If the following call pancis, it indicates UnimplementedPromptSentinelServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&PromptSentinel_ServiceDesc, srv)
+}
+
+func _PromptSentinel_Check_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PromptRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PromptSentinelServer).Check(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PromptSentinel_Check_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PromptSentinelServer).Check(ctx, req.(*PromptRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PromptSentinel_Validate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PromptRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PromptSentinelServer).Validate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PromptSentinel_Validate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PromptSentinelServer).Validate(ctx, req.(*PromptRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PromptSentinel_Batch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PromptSentinelServer).Batch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PromptSentinel_Batch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PromptSentinelServer).Batch(ctx, req.(*BatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PromptSentinel_Redact_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RedactRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PromptSentinelServer).Redact(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PromptSentinel_Redact_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PromptSentinelServer).Redact(ctx, req.(*RedactRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PromptSentinel_ValidateStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(PromptSentinelServer).ValidateStream(&grpc.GenericServerStream[PromptRequest, CheckResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PromptSentinel_ValidateStreamServer = grpc.BidiStreamingServer[PromptRequest, CheckResponse]
+
+// PromptSentinel_ServiceDesc is the grpc.ServiceDesc for PromptSentinel service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PromptSentinel_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "promptsentinel.v1.PromptSentinel",
+	HandlerType: (*PromptSentinelServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Check",
+			Handler:    _PromptSentinel_Check_Handler,
+		},
+		{
+			MethodName: "Validate",
+			Handler:    _PromptSentinel_Validate_Handler,
+		},
+		{
+			MethodName: "Batch",
+			Handler:    _PromptSentinel_Batch_Handler,
+		},
+		{
+			MethodName: "Redact",
+			Handler:    _PromptSentinel_Redact_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ValidateStream",
+			Handler:       _PromptSentinel_ValidateStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "promptsentinel/v1/promptsentinel.proto",
+}
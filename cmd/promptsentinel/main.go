@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"promptsentinel/internal/cli"
+	"promptsentinel/sentinel"
 
 	"github.com/spf13/cobra"
 )
@@ -16,13 +17,25 @@ func main() {
 		Long: `PromptSentinel is a command-line tool for validating AI prompts for safety and security.
 It helps you check if your prompts are safe for your specific use case and manage
 configuration settings for prompt validation.`,
-		Version: "1.0.0",
+		Version: sentinel.Version,
 	}
 
 	// Add subcommands
 	rootCmd.AddCommand(cli.NewCheckCommand())
 	rootCmd.AddCommand(cli.NewConfigCommand())
 	rootCmd.AddCommand(cli.NewValidateCommand())
+	rootCmd.AddCommand(cli.NewValidateOutputCommand())
+	rootCmd.AddCommand(cli.NewScanDocumentCommand())
+	rootCmd.AddCommand(cli.NewValidateToolCallCommand())
+	rootCmd.AddCommand(cli.NewDBCommand())
+	rootCmd.AddCommand(cli.NewHistoryCommand())
+	rootCmd.AddCommand(cli.NewKeysCommand())
+	rootCmd.AddCommand(cli.NewAuditCommand())
+	rootCmd.AddCommand(cli.NewApprovalsCommand())
+	rootCmd.AddCommand(cli.NewCanaryCommand())
+	rootCmd.AddCommand(cli.NewServeCommand())
+	rootCmd.AddCommand(cli.NewProxyCommand())
+	cli.AddLogFlags(rootCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -3,7 +3,11 @@ package auth
 // Package auth provides helpers for working with PromptSentinel API keys.
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
 	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"strings"
@@ -13,6 +17,17 @@ import (
 // space for entropy while remaining easy to document.
 const MinimumAPIKeyLength = 16
 
+// GeneratedKeyPrefix marks keys created by GenerateAPIKey so that leaked keys
+// are easy to recognize in logs and secret scanners.
+const GeneratedKeyPrefix = "ps_"
+
+// LookupPrefixLength is how many leading characters of a key are stored in
+// clear text to find its record. The rest of the key is only kept as a hash.
+const LookupPrefixLength = 12
+
+// generatedKeyBytes is the amount of randomness in a generated key.
+const generatedKeyBytes = 32
+
 // APIKey represents a sanitized API key string. It can safely be shared with
 // functions that need to compare keys without exposing the raw, user-provided
 // value.
@@ -37,6 +52,17 @@ func NewAPIKey(raw string) (APIKey, error) {
 	return APIKey{value: trimmed}, nil
 }
 
+// GenerateAPIKey creates a new random API key with GeneratedKeyPrefix. The
+// raw value should be shown to the user once and then only stored as Hash.
+func GenerateAPIKey() (APIKey, error) {
+	buf := make([]byte, generatedKeyBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return APIKey{}, fmt.Errorf("generate api key: %w", err)
+	}
+
+	return NewAPIKey(GeneratedKeyPrefix + base64.RawURLEncoding.EncodeToString(buf))
+}
+
 // Value exposes the normalized API key. Code interacting with external systems
 // (such as the database) can use this accessor instead of storing the key
 // manually.
@@ -68,3 +94,10 @@ func (k APIKey) Matches(candidate string) bool {
 
 	return subtle.ConstantTimeCompare([]byte(k.value), []byte(trimmed)) == 1
 }
+
+// Hash returns the hex-encoded SHA-256 of the key. Generated keys carry enough
+// entropy that a fast hash is sufficient, and the hash is what gets stored.
+func (k APIKey) Hash() string {
+	sum := sha256.Sum256([]byte(k.value))
+	return hex.EncodeToString(sum[:])
+}
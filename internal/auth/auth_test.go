@@ -1,6 +1,9 @@
 package auth
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestNewAPIKeyTrimAndValidate(t *testing.T) {
 	key, err := NewAPIKey("   sk-1234567890abcdef   ")
@@ -49,3 +52,43 @@ func TestAPIKeyPrefix(t *testing.T) {
 		t.Fatalf("expected full value when requested prefix is longer, got %q", prefix)
 	}
 }
+
+func TestGenerateAPIKey(t *testing.T) {
+	first, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.HasPrefix(first.Value(), GeneratedKeyPrefix) {
+		t.Fatalf("expected generated key to start with %q, got %q", GeneratedKeyPrefix, first.Value())
+	}
+	if len(first.Value()) < MinimumAPIKeyLength {
+		t.Fatalf("expected generated key to satisfy MinimumAPIKeyLength, got %d", len(first.Value()))
+	}
+	if first.Matches(second.Value()) {
+		t.Fatal("expected generated keys to differ")
+	}
+}
+
+func TestAPIKeyHash(t *testing.T) {
+	key, err := NewAPIKey("sk-1234567890abcdef")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(key.Hash()) != 64 {
+		t.Fatalf("expected 64 character hex hash, got %q", key.Hash())
+	}
+	if strings.Contains(key.Hash(), key.Value()) {
+		t.Fatal("expected hash to hide the key")
+	}
+
+	other, _ := NewAPIKey(" sk-1234567890abcdef ")
+	if key.Hash() != other.Hash() {
+		t.Fatal("expected normalized keys to hash identically")
+	}
+}
@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"promptsentinel/internal/promptdb"
+
+	"github.com/spf13/cobra"
+)
+
+// NewApprovalsCommand creates the approvals command for reviewing flagged prompts
+func NewApprovalsCommand() *cobra.Command {
+	var databaseURL string
+	var actor string
+
+	cmd := &cobra.Command{
+		Use:   "approvals",
+		Short: "Review prompts waiting for approval",
+		Long: `When require_approval is enabled, prompts flagged by check are queued
+for human review. Tickets are pending until approved, rejected, or expired.
+
+Examples:
+  promptsentinel approvals list
+  promptsentinel approvals show <id>
+  promptsentinel approvals approve <id> --reason "reviewed with legal"
+  promptsentinel approvals reject <id>`,
+	}
+
+	cmd.PersistentFlags().StringVar(&databaseURL, "database-url", "", "Database connection string (defaults to $PROMPTSENTINEL_DATABASE_URL)")
+	cmd.PersistentFlags().StringVar(&actor, "actor", "", "Who is making the decision (defaults to $PROMPTSENTINEL_OWNER or the current user)")
+
+	cmd.AddCommand(newApprovalsListCommand(&databaseURL))
+	cmd.AddCommand(newApprovalsShowCommand(&databaseURL))
+	cmd.AddCommand(newApprovalsDecideCommand(&databaseURL, &actor, true))
+	cmd.AddCommand(newApprovalsDecideCommand(&databaseURL, &actor, false))
+
+	return cmd
+}
+
+func newApprovalsListCommand(databaseURL *string) *cobra.Command {
+	var status string
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List approval tickets",
+		Long:  "List approval tickets, oldest first.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if status == "all" {
+				status = ""
+			}
+
+			ctx := context.Background()
+			store, err := openMigratedDatabase(ctx, *databaseURL)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			approvals, err := store.ListApprovals(ctx, status, limit)
+			if err != nil {
+				return fmt.Errorf("failed to list approvals: %w", err)
+			}
+
+			displayApprovals(approvals)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&status, "status", promptdb.ApprovalPending, "Filter by status (pending, approved, rejected, expired, all)")
+	cmd.Flags().IntVarP(&limit, "limit", "n", 50, "Maximum number of tickets to list (0 for all)")
+
+	return cmd
+}
+
+func newApprovalsShowCommand(databaseURL *string) *cobra.Command {
+	var showPrompt bool
+
+	cmd := &cobra.Command{
+		Use:   "show <id>",
+		Short: "Show an approval ticket",
+		Long:  "Show the status and details of an approval ticket.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			promptCipher, err := getPromptCipher(showPrompt)
+			if err != nil {
+				return err
+			}
+
+			ctx := context.Background()
+			store, err := openMigratedDatabase(ctx, *databaseURL)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			approval, err := store.GetApproval(ctx, args[0])
+			if err != nil {
+				return fmt.Errorf("failed to load approval: %w", err)
+			}
+
+			displayApproval(approval, promptCipher)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&showPrompt, "show-prompt", false, "Decrypt the stored prompt with $PROMPTSENTINEL_PROMPT_KEY")
+
+	return cmd
+}
+
+func newApprovalsDecideCommand(databaseURL, actor *string, approve bool) *cobra.Command {
+	var reason string
+
+	use, short := "reject <id>", "Reject a pending prompt"
+	if approve {
+		use, short = "approve <id>", "Approve a pending prompt"
+	}
+
+	cmd := &cobra.Command{
+		Use:   use,
+		Short: short,
+		Long:  short + ". The decision is recorded in the audit log.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			store, err := openMigratedDatabase(ctx, *databaseURL)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			approval, err := store.DecideApproval(ctx, args[0], approve, getOwner(*actor), reason)
+			if err != nil {
+				return fmt.Errorf("failed to decide approval: %w", err)
+			}
+
+			fmt.Printf("Ticket %s is now %s\n", approval.ID, approval.Status)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&reason, "reason", "", "Reason recorded with the decision")
+
+	return cmd
+}
@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"promptsentinel/internal/promptdb"
+
+	"github.com/spf13/cobra"
+)
+
+// NewAuditCommand creates the audit command for inspecting the audit log
+func NewAuditCommand() *cobra.Command {
+	var databaseURL string
+
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Inspect and verify the audit log",
+		Long: `The audit log records every validation (with the policy version that
+judged it), API key creation and revocation, and configuration change.
+Entries are hash-chained, so modifying or deleting any entry is detectable.
+
+Examples:
+  promptsentinel audit list --action config.set
+  promptsentinel audit verify
+  promptsentinel audit verify --expect-head <hash from a previous run>`,
+	}
+
+	cmd.PersistentFlags().StringVar(&databaseURL, "database-url", "", "Database connection string (defaults to $PROMPTSENTINEL_DATABASE_URL)")
+
+	cmd.AddCommand(newAuditListCommand(&databaseURL))
+	cmd.AddCommand(newAuditVerifyCommand(&databaseURL))
+
+	return cmd
+}
+
+func newAuditListCommand(databaseURL *string) *cobra.Command {
+	var action string
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List recent audit entries",
+		Long:  "List the most recent audit log entries, newest first.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			store, err := openMigratedDatabase(ctx, *databaseURL)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			entries, err := store.ListAuditEntries(ctx, action, limit)
+			if err != nil {
+				return fmt.Errorf("failed to list audit entries: %w", err)
+			}
+
+			displayAuditEntries(entries)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&action, "action", "", "Only show entries with this action (validation, api_key.created, api_key.revoked, config.set)")
+	cmd.Flags().IntVarP(&limit, "limit", "n", 50, "Maximum number of entries to show (0 for all)")
+
+	return cmd
+}
+
+func newAuditVerifyCommand(databaseURL *string) *cobra.Command {
+	var expectHead string
+
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Verify the audit log hash chain",
+		Long: `Verify recomputes every entry's hash and checks the chain links and
+sequence numbers. Deleting entries from the end of the log leaves a valid,
+shorter chain; pass the head hash printed by an earlier run with
+--expect-head to detect that as well.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			// Verification only reads, so it never migrates the log it
+			// is checking.
+			store, err := openDatabase(ctx, *databaseURL)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+			if err := store.CheckSchema(ctx); err != nil {
+				return fmt.Errorf("cannot verify audit log: %w; run 'promptsentinel db migrate' first", err)
+			}
+
+			verification, err := store.VerifyAuditLog(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to verify audit log: %w", err)
+			}
+
+			displayAuditVerification(verification)
+			if !verification.Valid() {
+				return fmt.Errorf("audit log verification failed with %d problem(s)", len(verification.Problems))
+			}
+
+			if expectHead != "" {
+				entries, err := store.ListAuditEntries(ctx, "", 0)
+				if err != nil {
+					return fmt.Errorf("failed to read audit log: %w", err)
+				}
+				if !containsAuditHash(entries, expectHead) {
+					return fmt.Errorf("audit log does not contain expected head %s: entries were deleted or replaced", expectHead)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&expectHead, "expect-head", "", "Head hash from a previous verification that must still be present")
+
+	return cmd
+}
+
+// containsAuditHash reports whether any entry has the given hash
+func containsAuditHash(entries []promptdb.AuditEntry, hash string) bool {
+	for _, entry := range entries {
+		if entry.Hash == hash {
+			return true
+		}
+	}
+	return false
+}
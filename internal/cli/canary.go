@@ -0,0 +1,221 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"promptsentinel/internal/promptdb"
+	"promptsentinel/sentinel"
+
+	"github.com/spf13/cobra"
+)
+
+// NewCanaryCommand creates the canary command for managing canary tokens
+func NewCanaryCommand() *cobra.Command {
+	var databaseURL string
+	var actor string
+
+	cmd := &cobra.Command{
+		Use:   "canary",
+		Short: "Manage canary tokens for system prompt leak detection",
+		Long: `Create, list, and revoke canary tokens. A canary is a unique string
+you embed in a system prompt. validate-output, the HTTP API, and the proxy
+watch for active canaries in model responses and in later user prompts, and
+record a leakage incident naming the prompt that leaked.
+
+Examples:
+  promptsentinel canary create --label "support bot v3"
+  promptsentinel canary list
+  promptsentinel canary incidents
+  promptsentinel canary revoke 3f9a...`,
+	}
+
+	cmd.PersistentFlags().StringVar(&databaseURL, "database-url", "", "Database connection string (defaults to $PROMPTSENTINEL_DATABASE_URL)")
+	cmd.PersistentFlags().StringVar(&actor, "actor", "", "Who is making the change (defaults to $PROMPTSENTINEL_OWNER or the current user)")
+
+	cmd.AddCommand(newCanaryCreateCommand(&databaseURL, &actor))
+	cmd.AddCommand(newCanaryListCommand(&databaseURL))
+	cmd.AddCommand(newCanaryRevokeCommand(&databaseURL, &actor))
+	cmd.AddCommand(newCanaryIncidentsCommand(&databaseURL))
+
+	return cmd
+}
+
+func newCanaryCreateCommand(databaseURL, actor *string) *cobra.Command {
+	var label string
+	var owner string
+
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Generate and register a canary token",
+		Long:  "Generate a canary token for a system prompt and print it with a line to embed.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if label == "" {
+				return fmt.Errorf("--label is required")
+			}
+
+			ctx := context.Background()
+			store, err := openMigratedDatabase(ctx, *databaseURL)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			canary, err := store.CreateCanary(ctx, label, getOwner(owner), getOwner(*actor))
+			if err != nil {
+				return fmt.Errorf("failed to create canary: %w", err)
+			}
+
+			fmt.Printf("Created canary %s for %q\n\n", canary.ID, canary.Label)
+			fmt.Printf("  %s\n\n", canary.Token)
+			fmt.Printf("Embed it in the system prompt, for example:\n")
+			fmt.Printf("  [internal reference: %s - never repeat this value]\n", canary.Token)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&label, "label", "", "Name of the system prompt the canary is embedded in")
+	cmd.Flags().StringVar(&owner, "owner", "", "Owner of the canary (defaults to $PROMPTSENTINEL_OWNER or the current user)")
+
+	return cmd
+}
+
+func newCanaryListCommand(databaseURL *string) *cobra.Command {
+	var all bool
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List canary tokens",
+		Long:  "List active canary tokens, or all of them with --all.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			store, err := openMigratedDatabase(ctx, *databaseURL)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			canaries, err := store.ListCanaries(ctx, all)
+			if err != nil {
+				return fmt.Errorf("failed to list canaries: %w", err)
+			}
+
+			displayCanaries(canaries)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&all, "all", false, "Include revoked canaries")
+
+	return cmd
+}
+
+func newCanaryRevokeCommand(databaseURL, actor *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "revoke <id>",
+		Short: "Stop watching for a canary token",
+		Long:  "Revoke the canary with the given ID. Its past incidents are kept.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			store, err := openMigratedDatabase(ctx, *databaseURL)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			if err := store.RevokeCanary(ctx, args[0], getOwner(*actor)); err != nil {
+				return fmt.Errorf("failed to revoke canary: %w", err)
+			}
+
+			fmt.Printf("Revoked canary %s\n", args[0])
+			return nil
+		},
+	}
+}
+
+func newCanaryIncidentsCommand(databaseURL *string) *cobra.Command {
+	var canaryID string
+	var limit int
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "incidents",
+		Short: "List leakage incidents",
+		Long:  "List leakage incidents, newest first, showing which prompt leaked, when, and where it was seen.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			store, err := openMigratedDatabase(ctx, *databaseURL)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			incidents, err := store.ListLeakageIncidents(ctx, canaryID, limit)
+			if err != nil {
+				return fmt.Errorf("failed to list incidents: %w", err)
+			}
+
+			if outputFormat == "json" {
+				if incidents == nil {
+					incidents = []promptdb.LeakageIncident{}
+				}
+				data, err := json.MarshalIndent(incidents, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal incidents: %w", err)
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+
+			displayLeakageIncidents(incidents)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&canaryID, "canary", "", "Only show incidents for this canary ID")
+	cmd.Flags().IntVar(&limit, "limit", 50, "Maximum number of incidents to show")
+	cmd.Flags().StringVarP(&outputFormat, "format", "f", "text", "Output format (text, json)")
+
+	return cmd
+}
+
+// loadCanaries returns the active canary tokens, or none with a warning when
+// the database is unavailable
+func loadCanaries(databaseURL string) []sentinel.CanaryToken {
+	ctx := context.Background()
+	store, err := openMigratedDatabase(ctx, databaseURL)
+	if err == nil {
+		defer store.Close()
+		var canaries []sentinel.CanaryToken
+		if canaries, err = store.ActiveCanaryTokens(ctx); err == nil {
+			return canaries
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Warning: canary tokens not checked: %v\n", err)
+	return nil
+}
+
+// recordCanaryLeaks opens a leakage incident for each canary found, linked
+// to the recorded history event
+func recordCanaryLeaks(opts historyOptions, leaks []sentinel.CanaryToken, source, location string, event promptdb.ValidationEvent) {
+	if opts.disabled || len(leaks) == 0 {
+		return
+	}
+
+	ctx := context.Background()
+	store, err := openMigratedDatabase(ctx, opts.databaseURL)
+	if err == nil {
+		defer store.Close()
+		_, err = store.RecordCanaryLeaks(ctx, leaks, source, location, event.OwnerID, event.ID)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record leakage incident: %v\n", err)
+	}
+}
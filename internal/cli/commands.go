@@ -4,9 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 
-	"promptsentinel/internal/validator"
+	"promptsentinel/sentinel"
 
 	"github.com/spf13/cobra"
 )
@@ -15,6 +14,8 @@ import (
 func NewCheckCommand() *cobra.Command {
 	var configFile string
 	var useCase string
+	var messagesMode bool
+	var history historyOptions
 
 	cmd := &cobra.Command{
 		Use:   "check [prompt]",
@@ -22,47 +23,64 @@ func NewCheckCommand() *cobra.Command {
 		Long: `Check validates a prompt against safety and security criteria.
 You can provide the prompt as an argument or via stdin.
 
+With --messages, the input is a JSON conversation in OpenAI or Anthropic
+format, read from the file named by the argument or from stdin. Each message
+is checked under its role's policy and issues name the message they came from.
+
+When require_approval is enabled, flagged prompts are placed in the approval
+queue and reported as pending approval with a ticket ID.
+
 Examples:
   promptsentinel check "Write a story about a cat"
   echo "Your prompt here" | promptsentinel check
-  promptsentinel check "Your prompt" --config ./config.json`,
+  promptsentinel check "Your prompt" --config ./config.json
+  promptsentinel check --messages ./conversation.json`,
 		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			// Override use case if provided
+			var opts []sentinel.Option
+			if useCase != "" {
+				opts = append(opts, sentinel.WithUseCase(useCase))
+			}
+
+			engine, err := newEngine(configFile, opts...)
+			if err != nil {
+				return err
+			}
+
 			var prompt string
-			var err error
+			var result *sentinel.Result
 
-			if len(args) > 0 {
-				prompt = args[0]
-			} else {
-				// Read from stdin
-				prompt, err = readFromStdin()
+			if messagesMode {
+				messages, err := readMessages(args)
 				if err != nil {
-					return fmt.Errorf("failed to read from stdin: %w", err)
+					return err
 				}
-			}
-
-			if strings.TrimSpace(prompt) == "" {
-				return fmt.Errorf("prompt cannot be empty")
-			}
 
-			// Load configuration
-			config, err := loadConfig(configFile)
-			if err != nil {
-				return fmt.Errorf("failed to load config: %w", err)
-			}
+				conversation, err := engine.CheckConversation(cmd.Context(), messages)
+				if err != nil {
+					return fmt.Errorf("validation failed: %w", err)
+				}
+				prompt, result = sentinel.ConversationText(messages), &conversation.ValidationResult
+			} else {
+				if prompt, err = readPrompt(args); err != nil {
+					return err
+				}
 
-			// Override use case if provided
-			if useCase != "" {
-				config.UseCase = useCase
+				// Validate the prompt
+				if result, err = engine.Check(cmd.Context(), prompt); err != nil {
+					return fmt.Errorf("validation failed: %w", err)
+				}
 			}
 
-			// Validate the prompt
-			result, err := validator.ValidatePrompt(prompt, config)
-			if err != nil {
-				return fmt.Errorf("validation failed: %w", err)
+			// Record results and queue flagged prompts for approval
+			event := recordHistory(history, "check", prompt, engine.Config(), result)
+			if engine.RequiresApproval(result) {
+				if err := submitForApproval(history, event, result); err != nil {
+					return fmt.Errorf("failed to queue prompt for approval: %w", err)
+				}
 			}
 
-			// Display results
 			displayResults(result)
 			return nil
 		},
@@ -70,6 +88,8 @@ Examples:
 
 	cmd.Flags().StringVarP(&configFile, "config", "c", "", "Path to configuration file")
 	cmd.Flags().StringVarP(&useCase, "use-case", "u", "", "Override the use case for validation")
+	cmd.Flags().BoolVar(&messagesMode, "messages", false, "Read a JSON message array (OpenAI or Anthropic format) instead of a prompt")
+	addHistoryFlags(cmd, &history)
 
 	return cmd
 }
@@ -93,6 +113,9 @@ func NewConfigCommand() *cobra.Command {
 func NewValidateCommand() *cobra.Command {
 	var configFile string
 	var outputFormat string
+	var messagesMode bool
+	var profile bool
+	var history historyOptions
 
 	cmd := &cobra.Command{
 		Use:   "validate [prompt]",
@@ -100,38 +123,51 @@ func NewValidateCommand() *cobra.Command {
 		Long: `Validate performs a comprehensive analysis of a prompt including
 safety checks, security analysis, and compliance validation.
 
+With --messages, the input is a JSON conversation in OpenAI or Anthropic
+format, read from the file named by the argument or from stdin.
+
+With --profile, the time spent in each stage and the slowest detectors are
+printed to stderr.
+
 Examples:
   promptsentinel validate "Your prompt here"
-  promptsentinel validate "Your prompt" --format json`,
+  promptsentinel validate "Your prompt" --format json
+  promptsentinel validate "Your prompt" --profile
+  promptsentinel validate --messages ./conversation.json`,
 		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			engine, err := newEngine(configFile)
+			if err != nil {
+				return err
+			}
+
 			var prompt string
-			var err error
+			var result *sentinel.ComprehensiveResult
 
-			if len(args) > 0 {
-				prompt = args[0]
-			} else {
-				prompt, err = readFromStdin()
+			// Perform comprehensive validation
+			if messagesMode {
+				messages, err := readMessages(args)
 				if err != nil {
-					return fmt.Errorf("failed to read from stdin: %w", err)
+					return err
 				}
-			}
 
-			if strings.TrimSpace(prompt) == "" {
-				return fmt.Errorf("prompt cannot be empty")
-			}
+				prompt = sentinel.ConversationText(messages)
+				result, err = engine.ValidateConversation(cmd.Context(), messages)
+				if err != nil {
+					return fmt.Errorf("validation failed: %w", err)
+				}
+			} else {
+				if prompt, err = readPrompt(args); err != nil {
+					return err
+				}
 
-			// Load configuration
-			config, err := loadConfig(configFile)
-			if err != nil {
-				return fmt.Errorf("failed to load config: %w", err)
+				result, err = engine.Validate(cmd.Context(), prompt)
+				if err != nil {
+					return fmt.Errorf("validation failed: %w", err)
+				}
 			}
 
-			// Perform comprehensive validation
-			result, err := validator.ValidatePromptComprehensive(prompt, config)
-			if err != nil {
-				return fmt.Errorf("validation failed: %w", err)
-			}
+			recordHistory(history, "validate", prompt, engine.Config(), &result.ValidationResult)
 
 			// Display results in requested format
 			if outputFormat == "json" {
@@ -139,6 +175,9 @@ Examples:
 			} else {
 				displayDetailedResults(result)
 			}
+			if profile {
+				displayProfile(os.Stderr, result.PerformanceMetrics, 10)
+			}
 
 			return nil
 		},
@@ -146,6 +185,9 @@ Examples:
 
 	cmd.Flags().StringVarP(&configFile, "config", "c", "", "Path to configuration file")
 	cmd.Flags().StringVarP(&outputFormat, "format", "f", "text", "Output format (text, json)")
+	cmd.Flags().BoolVar(&messagesMode, "messages", false, "Read a JSON message array (OpenAI or Anthropic format) instead of a prompt")
+	cmd.Flags().BoolVar(&profile, "profile", false, "Print stage timings and the slowest detectors to stderr")
+	addHistoryFlags(cmd, &history)
 
 	return cmd
 }
@@ -164,7 +206,7 @@ func newConfigInitCommand() *cobra.Command {
 			}
 
 			// Create default configuration
-			config := validator.DefaultConfig()
+			config := sentinel.DefaultConfig()
 
 			// Ensure config directory exists
 			if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
@@ -207,6 +249,8 @@ func newConfigShowCommand() *cobra.Command {
 
 func newConfigSetCommand() *cobra.Command {
 	var configFile string
+	var databaseURL string
+	var actor string
 	var key string
 	var value string
 
@@ -225,6 +269,8 @@ func newConfigSetCommand() *cobra.Command {
 			}
 
 			// Update configuration
+			oldValue := getConfigValue(config, key)
+			oldVersion := sentinel.PolicyVersion(config)
 			if err := setConfigValue(config, key, value); err != nil {
 				return fmt.Errorf("failed to set config value: %w", err)
 			}
@@ -235,12 +281,16 @@ func newConfigSetCommand() *cobra.Command {
 				return fmt.Errorf("failed to save config: %w", err)
 			}
 
+			recordConfigChange(databaseURL, getOwner(actor), configPath, key, oldValue, getConfigValue(config, key), oldVersion, sentinel.PolicyVersion(config))
+
 			fmt.Printf("Set %s = %s\n", key, value)
 			return nil
 		},
 	}
 
 	cmd.Flags().StringVarP(&configFile, "config", "c", "", "Path to configuration file")
+	cmd.Flags().StringVar(&databaseURL, "database-url", "", "Database for the audit log (defaults to $PROMPTSENTINEL_DATABASE_URL)")
+	cmd.Flags().StringVar(&actor, "actor", "", "Who is making the change (defaults to $PROMPTSENTINEL_OWNER or the current user)")
 
 	return cmd
 }
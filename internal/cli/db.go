@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// NewDBCommand creates the db command for managing the database schema
+func NewDBCommand() *cobra.Command {
+	var databaseURL string
+
+	cmd := &cobra.Command{
+		Use:   "db",
+		Short: "Manage the PromptSentinel database schema",
+		Long: `Manage the database schema using the migrations embedded in the binary.
+
+The connection string is read from --database-url or the
+PROMPTSENTINEL_DATABASE_URL environment variable. Use sqlite://path for the
+embedded SQLite backend or postgres://... for PostgreSQL. Without either, an
+SQLite database next to the default configuration file is used.
+
+Examples:
+  promptsentinel db migrate
+  promptsentinel db status
+  promptsentinel db rollback --steps 2`,
+	}
+
+	cmd.PersistentFlags().StringVar(&databaseURL, "database-url", "", "Database connection string, e.g. sqlite://./prompts.db or postgres://... (defaults to $PROMPTSENTINEL_DATABASE_URL)")
+
+	cmd.AddCommand(newDBMigrateCommand(&databaseURL))
+	cmd.AddCommand(newDBStatusCommand(&databaseURL))
+	cmd.AddCommand(newDBRollbackCommand(&databaseURL))
+
+	return cmd
+}
+
+func newDBMigrateCommand(databaseURL *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply all pending migrations",
+		Long:  "Apply every embedded migration that has not yet been applied to the database.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+
+			store, err := openDatabase(ctx, *databaseURL)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			applied, err := store.Migrate(ctx)
+			for _, migration := range applied {
+				fmt.Printf("Applied %04d_%s\n", migration.Version, migration.Name)
+			}
+			if err != nil {
+				return fmt.Errorf("migration failed: %w", err)
+			}
+
+			if len(applied) == 0 {
+				fmt.Println("Database is up to date")
+			}
+			return nil
+		},
+	}
+}
+
+func newDBStatusCommand(databaseURL *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show applied and pending migrations",
+		Long:  "List every embedded migration and whether it has been applied.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+
+			store, err := openDatabase(ctx, *databaseURL)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			statuses, err := store.MigrationStatuses(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to read migration status: %w", err)
+			}
+
+			displayMigrationStatuses(statuses)
+			return nil
+		},
+	}
+}
+
+func newDBRollbackCommand(databaseURL *string) *cobra.Command {
+	var steps int
+
+	cmd := &cobra.Command{
+		Use:   "rollback",
+		Short: "Revert the most recently applied migrations",
+		Long:  "Run the down scripts for the most recently applied migrations, newest first.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+
+			store, err := openDatabase(ctx, *databaseURL)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			reverted, err := store.Rollback(ctx, steps)
+			for _, migration := range reverted {
+				fmt.Printf("Rolled back %04d_%s\n", migration.Version, migration.Name)
+			}
+			if err != nil {
+				return fmt.Errorf("rollback failed: %w", err)
+			}
+
+			if len(reverted) == 0 {
+				fmt.Println("No applied migrations to roll back")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&steps, "steps", 1, "Number of migrations to roll back")
+
+	return cmd
+}
@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"promptsentinel/sentinel"
+
+	"github.com/spf13/cobra"
+)
+
+// NewScanDocumentCommand creates the scan-document command for checking
+// documents retrieved for RAG
+func NewScanDocumentCommand() *cobra.Command {
+	var configFile string
+	var outputFormat string
+	var documentType string
+	var chunkSize int
+
+	cmd := &cobra.Command{
+		Use:     "scan-document [file]",
+		Aliases: []string{"scan-doc"},
+		Short:   "Scan a retrieved document for indirect prompt injection",
+		Long: `Scan-document checks a document before it is added to a model's context,
+for example a web page or file retrieved for RAG. Provide a file, or the
+document via stdin.
+
+Hidden content a human reader would not see, such as HTML comments, elements
+styled display:none, white-on-white text, image alt text, and invisible
+Unicode, is removed from the visible text and checked on its own. Instructions
+aimed at the model are flagged, more severely when hidden. The document is
+split into chunks that are scored separately so that poisoned chunks can be
+dropped and the rest kept.
+
+Examples:
+  promptsentinel scan-document page.html
+  promptsentinel scan-document reviews.json --format json
+  curl -s https://example.com | promptsentinel scan-document --type html --chunk-size 500`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			engine, err := newEngine(configFile)
+			if err != nil {
+				return err
+			}
+
+			name := ""
+			var content string
+			if len(args) > 0 {
+				name = args[0]
+				data, err := os.ReadFile(name)
+				if err != nil {
+					return fmt.Errorf("failed to read document: %w", err)
+				}
+				content = string(data)
+			} else if content, err = readFromStdin(); err != nil {
+				return fmt.Errorf("failed to read from stdin: %w", err)
+			}
+			if strings.TrimSpace(content) == "" {
+				return fmt.Errorf("document cannot be empty")
+			}
+
+			opts := sentinel.DocumentOptions{ChunkSize: chunkSize}
+			switch documentType {
+			case "auto":
+				opts.Format = sentinel.DetectDocumentFormat(name, content)
+			case sentinel.FormatText, sentinel.FormatMarkdown, sentinel.FormatHTML, sentinel.FormatJSON:
+				opts.Format = documentType
+			default:
+				return fmt.Errorf("unknown document type %q (expected auto, text, markdown, html, or json)", documentType)
+			}
+
+			result, err := engine.ScanDocument(cmd.Context(), content, opts)
+			if err != nil {
+				return fmt.Errorf("scan failed: %w", err)
+			}
+
+			if outputFormat == "json" {
+				data, err := json.MarshalIndent(result, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal results: %w", err)
+				}
+				fmt.Println(string(data))
+			} else {
+				displayDocumentResults(result)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&configFile, "config", "c", "", "Path to configuration file")
+	cmd.Flags().StringVarP(&outputFormat, "format", "f", "text", "Output format (text, json)")
+	cmd.Flags().StringVarP(&documentType, "type", "t", "auto", "Document type (auto, text, markdown, html, json)")
+	cmd.Flags().IntVar(&chunkSize, "chunk-size", sentinel.DefaultChunkSize, "Maximum characters of visible text per chunk")
+
+	return cmd
+}
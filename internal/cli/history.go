@@ -0,0 +1,198 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"promptsentinel/internal/promptdb"
+
+	"github.com/spf13/cobra"
+)
+
+// NewHistoryCommand creates the history command for querying past validations
+func NewHistoryCommand() *cobra.Command {
+	var databaseURL string
+	var since string
+	var until string
+	var owner string
+	var useCase string
+	var detector string
+	var outcome string
+	var limit int
+	var outputFormat string
+	var showPrompts bool
+
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Query recorded validation results",
+		Long: `History lists the results recorded by check and validate, newest first,
+together with a pass/fail summary of everything that matched.
+
+Time ranges accept durations (24h, 7d), dates (2025-01-31), or RFC 3339
+timestamps. --detector matches the issue type reported by a detector, such
+as pattern, length, use_case, or custom_rule.
+
+Examples:
+  promptsentinel history --since 7d
+  promptsentinel history --owner team-x --outcome failed --since 7d
+  promptsentinel history --detector pattern --format json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			now := time.Now()
+			filter := promptdb.HistoryFilter{
+				OwnerID:   owner,
+				UseCase:   useCase,
+				IssueType: detector,
+				Limit:     limit,
+			}
+
+			var err error
+			if filter.Since, err = parseTimeFlag(since, now); err != nil {
+				return fmt.Errorf("invalid --since: %w", err)
+			}
+			if filter.Until, err = parseTimeFlag(until, now); err != nil {
+				return fmt.Errorf("invalid --until: %w", err)
+			}
+
+			switch strings.ToLower(outcome) {
+			case "", "all":
+			case "passed", "valid":
+				valid := true
+				filter.Valid = &valid
+			case "failed", "blocked", "invalid":
+				valid := false
+				filter.Valid = &valid
+			default:
+				return fmt.Errorf("invalid --outcome %q (use passed, failed, or all)", outcome)
+			}
+
+			promptCipher, err := getPromptCipher(showPrompts)
+			if err != nil {
+				return err
+			}
+
+			ctx := context.Background()
+			store, err := openMigratedDatabase(ctx, databaseURL)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			events, err := store.QueryValidationEvents(ctx, filter)
+			if err != nil {
+				return fmt.Errorf("failed to query history: %w", err)
+			}
+
+			summary, err := store.SummarizeValidationEvents(ctx, filter)
+			if err != nil {
+				return fmt.Errorf("failed to summarize history: %w", err)
+			}
+
+			if outputFormat == "json" {
+				displayJSONHistory(events, summary)
+			} else {
+				displayHistory(events, summary, promptCipher)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&databaseURL, "database-url", "", "Database connection string (defaults to $PROMPTSENTINEL_DATABASE_URL)")
+	cmd.Flags().StringVar(&since, "since", "", "Only include results at or after this time")
+	cmd.Flags().StringVar(&until, "until", "", "Only include results before this time")
+	cmd.Flags().StringVar(&owner, "owner", "", "Only include results for this owner")
+	cmd.Flags().StringVarP(&useCase, "use-case", "u", "", "Only include results for this use case")
+	cmd.Flags().StringVar(&detector, "detector", "", "Only include results where this detector reported an issue")
+	cmd.Flags().StringVar(&outcome, "outcome", "all", "Filter by outcome (passed, failed, all)")
+	cmd.Flags().IntVarP(&limit, "limit", "n", 50, "Maximum number of results to list (0 for no limit)")
+	cmd.Flags().StringVarP(&outputFormat, "format", "f", "text", "Output format (text, json)")
+	cmd.Flags().BoolVar(&showPrompts, "show-prompts", false, "Decrypt stored prompts with $PROMPTSENTINEL_PROMPT_KEY")
+
+	return cmd
+}
+
+// parseTimeFlag parses a time flag relative to now. Durations are interpreted
+// as "that long ago", with a d suffix for whole days.
+func parseTimeFlag(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("%q is not a duration, date, or RFC 3339 timestamp", value)
+}
+
+// displayHistory displays validation history
+func displayHistory(events []promptdb.ValidationEvent, summary promptdb.HistorySummary, promptCipher *promptdb.PromptCipher) {
+	fmt.Printf("\n📜 Validation History\n")
+	fmt.Printf("=====================\n\n")
+	fmt.Printf("Matched: %d (passed %d, failed %d)\n\n", summary.Total, summary.Passed, summary.Failed)
+
+	for _, event := range events {
+		status := "❌ FAILED"
+		if event.IsValid {
+			status = "✅ PASSED"
+		}
+
+		fmt.Printf("%s  %s  %-8s owner=%s use_case=%s score=%d\n",
+			event.CreatedAt.Local().Format("2006-01-02 15:04:05"), status, event.Source,
+			event.OwnerID, event.UseCase, event.Score)
+		if len(event.IssueTypes) > 0 {
+			fmt.Printf("     detectors: %s\n", strings.Join(event.IssueTypes, ", "))
+		}
+		fmt.Printf("     fingerprint: %s\n", shortFingerprint(event.PromptFingerprint))
+
+		if promptCipher != nil && event.EncryptedPrompt != "" {
+			prompt, err := promptCipher.Open(event.EncryptedPrompt)
+			if err != nil {
+				prompt = fmt.Sprintf("<unable to decrypt: %v>", err)
+			}
+			fmt.Printf("     prompt: %s\n", prompt)
+		}
+	}
+}
+
+// shortFingerprint returns the first 16 characters of a fingerprint, or all
+// of it when it is shorter, as rows written by other tools may be
+func shortFingerprint(fingerprint string) string {
+	if len(fingerprint) <= 16 {
+		return fingerprint
+	}
+	return fingerprint[:16]
+}
+
+// displayJSONHistory displays validation history in JSON format
+func displayJSONHistory(events []promptdb.ValidationEvent, summary promptdb.HistorySummary) {
+	if events == nil {
+		events = []promptdb.ValidationEvent{}
+	}
+
+	data, err := json.MarshalIndent(map[string]interface{}{
+		"summary": summary,
+		"events":  events,
+	}, "", "  ")
+	if err != nil {
+		fmt.Printf("Error marshaling history: %v\n", err)
+		return
+	}
+	fmt.Println(string(data))
+}
@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"promptsentinel/internal/auth"
+	"promptsentinel/internal/promptdb"
+
+	"github.com/spf13/cobra"
+)
+
+// NewKeysCommand creates the keys command for managing API keys
+func NewKeysCommand() *cobra.Command {
+	var databaseURL string
+	var actor string
+
+	cmd := &cobra.Command{
+		Use:   "keys",
+		Short: "Manage API keys",
+		Long: `Create, list, and revoke API keys. Only a hash and a short lookup
+prefix of each key are stored; the full key is shown once at creation.
+Every creation and revocation is recorded in the audit log. Keys created
+with --reviewer may also list and decide every owner's approval tickets.
+
+Examples:
+  promptsentinel keys create --owner team-x
+  promptsentinel keys create --owner security --reviewer
+  promptsentinel keys list
+  promptsentinel keys revoke ps_AbCdEfGhI`,
+	}
+
+	cmd.PersistentFlags().StringVar(&databaseURL, "database-url", "", "Database connection string (defaults to $PROMPTSENTINEL_DATABASE_URL)")
+	cmd.PersistentFlags().StringVar(&actor, "actor", "", "Who is making the change (defaults to $PROMPTSENTINEL_OWNER or the current user)")
+
+	cmd.AddCommand(newKeysCreateCommand(&databaseURL, &actor))
+	cmd.AddCommand(newKeysListCommand(&databaseURL))
+	cmd.AddCommand(newKeysRevokeCommand(&databaseURL, &actor))
+
+	return cmd
+}
+
+func newKeysCreateCommand(databaseURL, actor *string) *cobra.Command {
+	var owner string
+	var reviewer bool
+
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create a new API key",
+		Long:  "Generate a new API key for an owner and print it once.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if owner == "" {
+				return fmt.Errorf("--owner is required")
+			}
+
+			key, err := auth.GenerateAPIKey()
+			if err != nil {
+				return err
+			}
+
+			ctx := context.Background()
+			store, err := openMigratedDatabase(ctx, *databaseURL)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			record := promptdb.APIKeyRecord{
+				Prefix:  key.Prefix(auth.LookupPrefixLength),
+				Hash:    key.Hash(),
+				OwnerID: owner,
+			}
+			if reviewer {
+				record.Scopes = []string{promptdb.ScopeReviewer}
+			}
+			if err := store.CreateAPIKey(ctx, record, getOwner(*actor)); err != nil {
+				return fmt.Errorf("failed to create api key: %w", err)
+			}
+
+			fmt.Printf("Created API key for %s\n\n", owner)
+			fmt.Printf("  %s\n\n", key.Value())
+			fmt.Printf("Store it now; it cannot be shown again. Revoke it with:\n")
+			fmt.Printf("  promptsentinel keys revoke %s\n", record.Prefix)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&owner, "owner", "", "Owner (user or team) the key belongs to")
+	cmd.Flags().BoolVar(&reviewer, "reviewer", false, "Let the key list and decide approval tickets")
+
+	return cmd
+}
+
+func newKeysListCommand(databaseURL *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List API keys",
+		Long:  "List every API key prefix with its owner and status.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			store, err := openMigratedDatabase(ctx, *databaseURL)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			keys, err := store.ListAPIKeys(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to list api keys: %w", err)
+			}
+
+			displayAPIKeys(keys)
+			return nil
+		},
+	}
+}
+
+func newKeysRevokeCommand(databaseURL, actor *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "revoke <prefix>",
+		Short: "Revoke an API key",
+		Long:  "Revoke the active API key with the given prefix.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			store, err := openMigratedDatabase(ctx, *databaseURL)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			if err := store.RevokeAPIKey(ctx, args[0], getOwner(*actor)); err != nil {
+				return fmt.Errorf("failed to revoke api key: %w", err)
+			}
+
+			fmt.Printf("Revoked API key %s\n", args[0])
+			return nil
+		},
+	}
+}
@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"log/slog"
+	"os"
+
+	"promptsentinel/internal/logging"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultLogLevelAnnotation names the cobra annotation a command sets to
+// change its default --log-level. Long-running commands log at info; the
+// rest stay quiet unless something goes wrong.
+const defaultLogLevelAnnotation = "promptsentinel/default-log-level"
+
+// AddLogFlags registers --log-level and --log-format on root and makes the
+// logger they describe the default before any command runs, so the
+// validator, server, and store all log through it to stderr, redacted.
+func AddLogFlags(root *cobra.Command) {
+	var level, format string
+	root.PersistentFlags().StringVar(&level, "log-level", "", "Minimum level to log: debug, info, warn, or error (defaults to info for serve and proxy, warn otherwise)")
+	root.PersistentFlags().StringVar(&format, "log-format", logging.FormatText, "Log format: text or json")
+
+	root.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if level == "" {
+			level = "warn"
+			if annotated, ok := cmd.Annotations[defaultLogLevelAnnotation]; ok {
+				level = annotated
+			}
+		}
+		parsed, err := logging.ParseLevel(level)
+		if err != nil {
+			return err
+		}
+		logger, err := logging.New(os.Stderr, format, parsed)
+		if err != nil {
+			return err
+		}
+		slog.SetDefault(logger)
+		return nil
+	}
+}
@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"time"
+
+	"promptsentinel/internal/notify"
+
+	"github.com/spf13/cobra"
+)
+
+// notifyOptions holds the notification flags shared by serve and proxy
+type notifyOptions struct {
+	webhooks  []string
+	slack     []string
+	smtpAddr  string
+	emailFrom string
+	emailTo   []string
+	dedupe    time.Duration
+}
+
+// addNotifyFlags registers the notification flags
+func addNotifyFlags(cmd *cobra.Command, opts *notifyOptions) {
+	cmd.Flags().StringArrayVar(&opts.webhooks, "notify-webhook", nil, "URL to post alerts to as JSON, signed with $PROMPTSENTINEL_WEBHOOK_SECRET (repeatable)")
+	cmd.Flags().StringArrayVar(&opts.slack, "notify-slack", nil, "Slack incoming webhook URL to post alerts to (repeatable)")
+	cmd.Flags().StringVar(&opts.smtpAddr, "notify-smtp", "", "SMTP server host:port to email alerts through, authenticating with $PROMPTSENTINEL_SMTP_USERNAME and $PROMPTSENTINEL_SMTP_PASSWORD when set")
+	cmd.Flags().StringVar(&opts.emailFrom, "notify-email-from", "", "Sender address for alert emails")
+	cmd.Flags().StringArrayVar(&opts.emailTo, "notify-email-to", nil, "Recipient of alert emails (repeatable)")
+	cmd.Flags().DurationVar(&opts.dedupe, "notify-dedupe", notify.DefaultDedupeWindow, "How long an alert suppresses repeats of itself (0 to send every alert)")
+}
+
+// newNotifier builds a Notifier from the flags, or returns nil when no sink
+// is configured
+func (o notifyOptions) newNotifier() (*notify.Notifier, error) {
+	var sinks []notify.Sink
+	for _, raw := range o.webhooks {
+		if err := checkWebhookURL(raw); err != nil {
+			return nil, fmt.Errorf("--notify-webhook: %w", err)
+		}
+		sinks = append(sinks, &notify.Webhook{URL: raw, Secret: os.Getenv("PROMPTSENTINEL_WEBHOOK_SECRET")})
+	}
+	for _, raw := range o.slack {
+		if err := checkWebhookURL(raw); err != nil {
+			return nil, fmt.Errorf("--notify-slack: %w", err)
+		}
+		sinks = append(sinks, &notify.Slack{URL: raw})
+	}
+	if o.smtpAddr != "" {
+		if o.emailFrom == "" || len(o.emailTo) == 0 {
+			return nil, errors.New("--notify-smtp needs --notify-email-from and at least one --notify-email-to")
+		}
+		sinks = append(sinks, &notify.Email{
+			Addr:     o.smtpAddr,
+			From:     o.emailFrom,
+			To:       o.emailTo,
+			Username: os.Getenv("PROMPTSENTINEL_SMTP_USERNAME"),
+			Password: os.Getenv("PROMPTSENTINEL_SMTP_PASSWORD"),
+		})
+	}
+	if len(sinks) == 0 {
+		return nil, nil
+	}
+
+	dedupe := o.dedupe
+	if dedupe == 0 {
+		dedupe = -1
+	}
+	return notify.New(notify.Options{Sinks: sinks, DedupeWindow: dedupe, Logger: slog.Default()}), nil
+}
+
+// closeNotifier gives alerts still being retried up to ten seconds to
+// deliver at shutdown
+func closeNotifier(notifier *notify.Notifier) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := notifier.Close(ctx); err != nil {
+		slog.Warn("abandoned undelivered alerts", "error", err)
+	}
+}
+
+// checkWebhookURL rejects URLs that are not absolute http or https. The URL
+// is left out of the error, since webhook URLs can hold tokens.
+func checkWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("must be an absolute http or https URL")
+	}
+	return nil
+}
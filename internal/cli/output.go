@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"promptsentinel/internal/promptdb"
+	"promptsentinel/sentinel"
+
+	"github.com/spf13/cobra"
+)
+
+// NewValidateOutputCommand creates the validate-output command for checking
+// model responses
+func NewValidateOutputCommand() *cobra.Command {
+	var configFile string
+	var outputFormat string
+	var systemPrompt string
+	var systemPromptFile string
+	var history historyOptions
+
+	cmd := &cobra.Command{
+		Use:   "validate-output [response]",
+		Short: "Check a model response for leaks and policy violations",
+		Long: `Validate-output checks a model response before it is shown to a user.
+You can provide the response as an argument or via stdin.
+
+It flags secrets and personal data in the response, markdown or HTML images
+and links that could send data to hosts outside allowed_domains, and the
+blocked patterns and custom rules from your configuration. When the system
+prompt is given, the response is also checked for repeating it verbatim or
+nearly so. Canary tokens registered with "promptsentinel canary create" are
+detected and recorded as leakage incidents.
+
+Examples:
+  promptsentinel validate-output "Here is your answer..."
+  cat response.txt | promptsentinel validate-output --system-prompt-file ./system.txt
+  promptsentinel validate-output "..." --system-prompt "You are a support agent" --format json`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			engine, err := newEngine(configFile)
+			if err != nil {
+				return err
+			}
+
+			if systemPromptFile != "" {
+				if systemPrompt != "" {
+					return fmt.Errorf("use either --system-prompt or --system-prompt-file, not both")
+				}
+				data, err := os.ReadFile(systemPromptFile)
+				if err != nil {
+					return fmt.Errorf("failed to read system prompt: %w", err)
+				}
+				systemPrompt = string(data)
+			}
+
+			output, err := readPrompt(args)
+			if err != nil {
+				return err
+			}
+
+			opts := sentinel.OutputOptions{
+				SystemPrompt: systemPrompt,
+				Canaries:     loadCanaries(history.databaseURL),
+			}
+			result, err := engine.ValidateOutput(cmd.Context(), output, opts)
+			if err != nil {
+				return fmt.Errorf("validation failed: %w", err)
+			}
+
+			event := recordHistory(history, "validate-output", output, engine.Config(), &result.ValidationResult)
+			recordCanaryLeaks(history, result.CanaryLeaks, "validate-output", promptdb.LeakLocationResponse, event)
+
+			if outputFormat == "json" {
+				data, err := json.MarshalIndent(result, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal results: %w", err)
+				}
+				fmt.Println(string(data))
+			} else {
+				displayOutputResults(result)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&configFile, "config", "c", "", "Path to configuration file")
+	cmd.Flags().StringVarP(&outputFormat, "format", "f", "text", "Output format (text, json)")
+	cmd.Flags().StringVar(&systemPrompt, "system-prompt", "", "System prompt to check the response for leaking")
+	cmd.Flags().StringVar(&systemPromptFile, "system-prompt-file", "", "File containing the system prompt to check for leaks")
+	addHistoryFlags(cmd, &history)
+
+	return cmd
+}
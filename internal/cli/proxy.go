@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"promptsentinel/internal/promptdb"
+	"promptsentinel/internal/proxy"
+	"promptsentinel/sentinel"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultUpstreamURL is the OpenAI API, used when no upstream is configured
+const defaultUpstreamURL = "https://api.openai.com"
+
+// NewProxyCommand creates the proxy command for enforcing policy on LLM traffic
+func NewProxyCommand() *cobra.Command {
+	var configFile string
+	var addr string
+	var upstream string
+	var mode string
+	var validationTimeout time.Duration
+	var history historyOptions
+	var notifications notifyOptions
+
+	cmd := &cobra.Command{
+		Use:   "proxy",
+		Short: "Enforce policy on OpenAI-compatible API traffic",
+		Long: `Proxy sits in front of an OpenAI-compatible API. Every chat/completions
+and completions request is validated before it is forwarded upstream, and
+the decision is returned in X-PromptSentinel-* response headers. Streaming
+responses are passed through as they arrive. GET requests to other
+endpoints, such as /v1/models, pass through unchecked; other requests the
+proxy cannot validate, such as POST /v1/embeddings, are refused with 403.
+
+Modes:
+  block     Reject flagged requests with 403 (default)
+  redact    Replace flagged content with [REDACTED] and forward
+  annotate  Forward everything and only report the decision
+
+Every client needs a PromptSentinel API key (see "promptsentinel keys"),
+sent in X-API-Key or, when the proxy holds the provider key, as the bearer
+token; it is never forwarded upstream. Validations are recorded under the
+key's owner, and canary tokens are watched for even with --no-history. Set PROMPTSENTINEL_UPSTREAM_API_KEY to replace client
+credentials with a provider key held by the proxy, so clients can use their
+PromptSentinel key as their SDK's API key:
+
+  OPENAI_BASE_URL=http://localhost:8081/v1 OPENAI_API_KEY=ps_... python app.py
+
+The proxy listens on localhost only unless --addr says otherwise.
+
+Examples:
+  promptsentinel proxy --upstream https://api.openai.com
+  promptsentinel proxy --upstream http://localhost:11434 --mode redact
+  promptsentinel proxy --mode annotate --no-history`,
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{defaultLogLevelAnnotation: "info"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			target, err := url.Parse(getUpstreamURL(upstream))
+			if err != nil {
+				return fmt.Errorf("invalid upstream URL: %w", err)
+			}
+
+			// Fail fast on an unreadable configuration file.
+			if _, err := loadConfig(configFile); err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			promptCipher, err := getPromptCipher(history.storePrompt)
+			if err != nil {
+				return err
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			// Keys are always checked and canaries always watched for;
+			// history is only recorded when enabled.
+			keys, err := openMigratedDatabase(ctx, history.databaseURL)
+			if err != nil {
+				return err
+			}
+			defer keys.Close()
+			var store *promptdb.Store
+			if !history.disabled {
+				store = keys
+			}
+
+			notifier, err := notifications.newNotifier()
+			if err != nil {
+				return err
+			}
+			defer closeNotifier(notifier)
+
+			p, err := proxy.New(proxy.Options{
+				Upstream: target,
+				LoadConfig: func() (*sentinel.Config, error) {
+					return loadConfig(configFile)
+				},
+				Mode:              mode,
+				UpstreamAPIKey:    os.Getenv("PROMPTSENTINEL_UPSTREAM_API_KEY"),
+				Keys:              keys,
+				Store:             store,
+				Canaries:          keys,
+				PromptCipher:      promptCipher,
+				ValidationTimeout: validationTimeout,
+				Notifier:          notifier,
+				Logger:            slog.Default(),
+			})
+			if err != nil {
+				return err
+			}
+
+			slog.Info("forwarding", "upstream", target.Redacted(), "mode", mode)
+			return listenAndServe(ctx, addr, p, "PromptSentinel proxy")
+		},
+	}
+
+	cmd.Flags().StringVarP(&configFile, "config", "c", "", "Path to configuration file")
+	cmd.Flags().StringVar(&addr, "addr", "127.0.0.1:8081", "Address to listen on")
+	cmd.Flags().StringVar(&upstream, "upstream", "", "Upstream API base URL (defaults to $PROMPTSENTINEL_UPSTREAM_URL or "+defaultUpstreamURL+")")
+	cmd.Flags().StringVar(&mode, "mode", proxy.ModeBlock, "What to do with flagged requests (block, redact, annotate)")
+	cmd.Flags().DurationVar(&validationTimeout, "validation-timeout", 0, "Longest a validation may take before fail_mode decides the result (0 for no limit)")
+	addHistoryFlags(cmd, &history)
+	_ = cmd.Flags().MarkDeprecated("owner", "validations are recorded under the owner of each client's API key")
+	addNotifyFlags(cmd, &notifications)
+
+	return cmd
+}
+
+// getUpstreamURL returns the upstream API base URL
+func getUpstreamURL(upstream string) string {
+	if upstream != "" {
+		return upstream
+	}
+	if env := os.Getenv("PROMPTSENTINEL_UPSTREAM_URL"); env != "" {
+		return env
+	}
+	return defaultUpstreamURL
+}
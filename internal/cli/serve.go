@@ -0,0 +1,373 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"promptsentinel/internal/jobs"
+	"promptsentinel/internal/metrics"
+	"promptsentinel/internal/promptdb"
+	"promptsentinel/internal/resultcache"
+	"promptsentinel/internal/server"
+	"promptsentinel/internal/tracing"
+	"promptsentinel/sentinel"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+)
+
+// NewServeCommand creates the serve command for running the HTTP API
+func NewServeCommand() *cobra.Command {
+	var configFile string
+	var databaseURL string
+	var addr string
+	var approvalTTL time.Duration
+	var storePrompts bool
+	var validationTimeout time.Duration
+	var grpcAddr string
+	var rateLimit float64
+	var rateBurst int
+	var metricsAddr string
+	var trace tracing.Options
+	var notifications notifyOptions
+	var jobsDir string
+	var jobsInputDir string
+	var jobWorkers int
+	var maxUploadBytes int64
+	var cacheBackend string
+	var cacheSize int
+	var cacheTTL time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Run the PromptSentinel HTTP and gRPC APIs",
+		Long: `Serve exposes check, validate, and the approval queue over HTTP.
+Requests must carry an API key from "promptsentinel keys create" as
+"Authorization: Bearer <key>".
+
+With --grpc-addr, the same process also serves the gRPC service defined in
+api/promptsentinel/v1/promptsentinel.proto, with the key sent as
+"authorization: Bearer <key>" metadata. --rate-limit applies to each key
+owner across both APIs.
+
+Prometheus metrics are served at /metrics on the API address, or on
+--metrics-addr to keep them off the public port.
+
+With --trace-exporter, each request is traced with spans for loading the
+config, normalizing messages, validating, and every stage and detector.
+Spans carry use cases, scores, and issue counts, never prompts. They go to
+an OTLP collector over gRPC (--trace-endpoint, or the standard
+OTEL_EXPORTER_OTLP_* variables), or to stdout or --trace-file as JSON.
+
+With --result-cache, checks and validations of a prompt or conversation
+seen before under the same policy reuse the earlier result. Entries are
+keyed by a hash of the prompt and the policy version, so a config change
+misses every older entry, and never hold prompt text. "memory" keeps up to
+--result-cache-size entries per process; "sql" shares them through the
+database. Every request is still recorded in history.
+
+Batch jobs validate JSONL datasets in the background with --job-workers
+workers, resuming from their last checkpoint after a restart. Uploads are
+kept in --jobs-dir until their job finishes; --jobs-input-dir lets clients
+submit datasets already on the server by path instead. Replicas sharing a
+database need to share --jobs-dir as well.
+
+Endpoints:
+  GET  /healthz
+  GET  /openapi.json                OpenAPI 3.1 description of these endpoints
+  POST /v1/check                    {"prompt": "...", "use_case": "..."}
+  POST /v1/check                    {"messages": [{"role": "user", "content": "..."}]}
+  POST /v1/validate                 {"prompt": "..."} or {"messages": [...]}
+  GET  /v1/approvals?status=pending reviewer keys only
+  GET  /v1/approvals/{id}           the ticket's owner or a reviewer
+  POST /v1/approvals/{id}/approve   {"reason": "..."}, reviewer keys only
+  POST /v1/approvals/{id}/reject    {"reason": "..."}, reviewer keys only
+  POST /v1/jobs/upload?name=...     JSONL body, one {"id": "...", "prompt": "..."} per line
+  POST /v1/jobs                     {"path": "logs/2024-06.jsonl", "mode": "validate"}
+  GET  /v1/jobs?status=running
+  GET  /v1/jobs/{id}
+  GET  /v1/jobs/{id}/results?after=0
+  POST /v1/jobs/{id}/cancel
+
+Examples:
+  promptsentinel serve --addr :8080
+  promptsentinel serve --grpc-addr :9090 --rate-limit 10
+  promptsentinel serve --metrics-addr 127.0.0.1:9100
+  promptsentinel serve --trace-exporter otlp --trace-endpoint collector:4317 --trace-insecure
+  promptsentinel serve --trace-exporter file --trace-file ./spans.jsonl
+  promptsentinel serve --log-format json --log-level debug
+  promptsentinel serve --result-cache memory --result-cache-ttl 30m
+  promptsentinel serve --jobs-input-dir /var/log/prompts --job-workers 4
+  promptsentinel serve --notify-webhook https://siem.example.com/hooks/promptsentinel
+  promptsentinel serve --database-url postgres://... --config ./config.json`,
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{defaultLogLevelAnnotation: "info"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			promptCipher, err := getPromptCipher(storePrompts)
+			if err != nil {
+				return err
+			}
+
+			// Fail fast on an unreadable configuration file.
+			if _, err := loadConfig(configFile); err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			trace.ServiceVersion = sentinel.Version
+			tracerProvider, shutdownTracing, err := tracing.Setup(ctx, trace)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				// Flush the spans of the last requests.
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				if err := shutdownTracing(shutdownCtx); err != nil {
+					fmt.Fprintf(os.Stderr, "Failed to flush traces: %v\n", err)
+				}
+			}()
+
+			store, err := openMigratedDatabase(ctx, databaseURL)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			notifier, err := notifications.newNotifier()
+			if err != nil {
+				return err
+			}
+			defer closeNotifier(notifier)
+
+			load := func() (*sentinel.Config, error) {
+				return loadConfig(configFile)
+			}
+			metricsRegistry := metrics.New(metrics.Options{
+				ApprovalQueueDepth: func(ctx context.Context) (int, error) {
+					return store.CountApprovals(ctx, promptdb.ApprovalPending)
+				},
+				LoadConfig: load,
+			})
+
+			cache, err := newResultCache(cacheBackend, store, cacheSize, cacheTTL, metricsRegistry)
+			if err != nil {
+				return err
+			}
+
+			var runner *jobs.Runner
+			if jobWorkers > 0 {
+				if jobsDir == "" {
+					jobsDir = getDefaultJobsDir()
+				}
+				runner, err = jobs.New(jobs.Options{
+					Store:             store,
+					LoadConfig:        load,
+					Dir:               jobsDir,
+					InputDir:          jobsInputDir,
+					Workers:           jobWorkers,
+					ValidationTimeout: validationTimeout,
+					Metrics:           metricsRegistry,
+					Logger:            slog.Default(),
+				})
+				if err != nil {
+					return err
+				}
+			}
+
+			srv := server.New(server.Options{
+				Store:             store,
+				LoadConfig:        load,
+				PromptCipher:      promptCipher,
+				ApprovalTTL:       approvalTTL,
+				ValidationTimeout: validationTimeout,
+				Metrics:           metricsRegistry,
+				RateLimit:         rateLimit,
+				RateBurst:         rateBurst,
+				TracerProvider:    tracerProvider,
+				Logger:            slog.Default(),
+				Notifier:          notifier,
+				Jobs:              runner,
+				MaxUploadBytes:    maxUploadBytes,
+				Cache:             cache,
+			})
+
+			handler := srv.Handler()
+			servers := []func(context.Context) error{}
+			if metricsAddr == "" {
+				mux := http.NewServeMux()
+				mux.Handle("/", handler)
+				mux.Handle("GET /metrics", metricsRegistry.Handler())
+				handler = mux
+			} else {
+				servers = append(servers, func(ctx context.Context) error {
+					return listenAndServe(ctx, metricsAddr, metricsRegistry.Handler(), "Metrics")
+				})
+			}
+			servers = append(servers, func(ctx context.Context) error {
+				return listenAndServe(ctx, addr, handler, "PromptSentinel API")
+			})
+			if grpcAddr != "" {
+				servers = append(servers, func(ctx context.Context) error {
+					return serveGRPC(ctx, grpcAddr, srv.GRPCServer(), "PromptSentinel gRPC API")
+				})
+			}
+
+			if runner != nil {
+				servers = append(servers, func(ctx context.Context) error {
+					runner.Run(ctx)
+					return nil
+				})
+			}
+
+			return serveAll(ctx, servers...)
+		},
+	}
+
+	cmd.Flags().StringVarP(&configFile, "config", "c", "", "Path to configuration file")
+	cmd.Flags().StringVar(&databaseURL, "database-url", "", "Database connection string (defaults to $PROMPTSENTINEL_DATABASE_URL)")
+	cmd.Flags().StringVar(&addr, "addr", ":8080", "Address to listen on")
+	cmd.Flags().DurationVar(&approvalTTL, "approval-ttl", 24*time.Hour, "How long approval tickets stay pending before expiring")
+	cmd.Flags().BoolVar(&storePrompts, "store-prompts", false, "Store prompts encrypted with $PROMPTSENTINEL_PROMPT_KEY")
+	cmd.Flags().DurationVar(&validationTimeout, "validation-timeout", 0, "Longest a validation may take before fail_mode decides the result (0 for no limit)")
+	cmd.Flags().StringVar(&grpcAddr, "grpc-addr", "", "Address to serve the gRPC API on (disabled when empty)")
+	cmd.Flags().Float64Var(&rateLimit, "rate-limit", 0, "Requests per second allowed per API key owner (0 for no limit)")
+	cmd.Flags().IntVar(&rateBurst, "rate-burst", 0, "Requests an owner may make at once before --rate-limit applies (defaults to the rate)")
+	cmd.Flags().StringVar(&metricsAddr, "metrics-addr", "", "Address to serve Prometheus /metrics on, instead of the API address")
+	cmd.Flags().StringVar(&trace.Exporter, "trace-exporter", tracing.ExporterNone, "Where to send traces: none, otlp, stdout, or file")
+	cmd.Flags().StringVar(&trace.Endpoint, "trace-endpoint", "", "OTLP collector host:port (defaults to $OTEL_EXPORTER_OTLP_ENDPOINT or localhost:4317)")
+	cmd.Flags().BoolVar(&trace.Insecure, "trace-insecure", false, "Send traces to the OTLP collector without TLS")
+	cmd.Flags().StringVar(&trace.File, "trace-file", "", "File to append spans to with --trace-exporter file")
+	cmd.Flags().Float64Var(&trace.SampleRatio, "trace-sample", 1, "Fraction of requests to trace, from 0 to 1")
+	cmd.Flags().StringVar(&cacheBackend, "result-cache", cacheNone, "Where to cache validation results: none, memory, or sql")
+	cmd.Flags().IntVar(&cacheSize, "result-cache-size", resultcache.DefaultSize, "Most results to keep cached")
+	cmd.Flags().DurationVar(&cacheTTL, "result-cache-ttl", resultcache.DefaultTTL, "How long a cached result is reused")
+	cmd.Flags().IntVar(&jobWorkers, "job-workers", jobs.DefaultWorkers, "Batch jobs to run at once (0 disables the jobs API)")
+	cmd.Flags().StringVar(&jobsDir, "jobs-dir", "", "Directory for uploaded job datasets (defaults to jobs/ next to the default config)")
+	cmd.Flags().StringVar(&jobsInputDir, "jobs-input-dir", "", "Directory clients may submit server-side datasets from (disabled when empty)")
+	cmd.Flags().Int64Var(&maxUploadBytes, "job-max-upload", server.DefaultMaxUploadBytes, "Largest dataset upload in bytes")
+	addNotifyFlags(cmd, &notifications)
+
+	return cmd
+}
+
+// Result cache backends accepted by --result-cache
+const (
+	cacheNone   = "none"
+	cacheMemory = "memory"
+	cacheSQL    = "sql"
+)
+
+// newResultCache returns the result cache named by backend, or nil for none
+func newResultCache(backend string, store *promptdb.Store, size int, ttl time.Duration, metricsRegistry *metrics.Metrics) (*resultcache.Cache, error) {
+	opts := resultcache.Options{Metrics: metricsRegistry, Logger: slog.Default()}
+	switch backend {
+	case cacheNone, "":
+		return nil, nil
+	case cacheMemory:
+		return resultcache.New(resultcache.NewMemory(size, ttl), opts), nil
+	case cacheSQL:
+		return resultcache.New(resultcache.NewSQL(store, size, ttl), opts), nil
+	default:
+		return nil, fmt.Errorf("unknown result cache %q: use none, memory, or sql", backend)
+	}
+}
+
+// serveAll runs servers until ctx is cancelled or one of them stops, then
+// stops the rest and returns the first error
+func serveAll(ctx context.Context, servers ...func(context.Context) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errCh := make(chan error, len(servers))
+	for _, serve := range servers {
+		go func(serve func(context.Context) error) {
+			err := serve(ctx)
+			cancel()
+			errCh <- err
+		}(serve)
+	}
+
+	var first error
+	for range servers {
+		if err := <-errCh; err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// serveGRPC runs server on addr until ctx is cancelled, then stops it
+// gracefully, cutting off calls still running after ten seconds
+func serveGRPC(ctx context.Context, addr string, server *grpc.Server, name string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		slog.Info("listening", "server", name, "addr", addr)
+		errCh <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			return fmt.Errorf("gRPC server failed: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(10 * time.Second):
+		server.Stop()
+	}
+	return nil
+}
+
+// listenAndServe runs handler on addr until ctx is cancelled, then shuts the
+// server down gracefully
+func listenAndServe(ctx context.Context, addr string, handler http.Handler, name string) error {
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		slog.Info("listening", "server", name, "addr", addr)
+		errCh <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("server failed: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return httpServer.Shutdown(shutdownCtx)
+}
@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"promptsentinel/sentinel"
+
+	"github.com/spf13/cobra"
+)
+
+// NewValidateToolCallCommand creates the validate-tool-call command for
+// checking the tool calls an agent wants to make
+func NewValidateToolCallCommand() *cobra.Command {
+	var configFile string
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "validate-tool-call [file]",
+		Short: "Check an agent's tool call against the tool policies",
+		Long: `Validate-tool-call checks a tool call before an agent runs it. Provide a
+JSON file, or the JSON via stdin, in the generic {"name", "arguments"} form,
+as an OpenAI tool call, or as an Anthropic tool_use block.
+
+When tool_policies is set in the configuration, only the tools it lists may
+be called, and each tool's arguments are checked against its JSON schema.
+URLs in the arguments must point to allowed_domains, or to the tool's own
+allowed_domains, when either is set. Every string argument is checked for
+forbidden shell patterns and for instructions injected for the model. Issues
+name the argument they concern with a JSON pointer.
+
+Examples:
+  promptsentinel validate-tool-call call.json
+  echo '{"name": "fetch_url", "arguments": {"url": "https://example.com"}}' | promptsentinel validate-tool-call
+  promptsentinel validate-tool-call call.json --format json`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			engine, err := newEngine(configFile)
+			if err != nil {
+				return err
+			}
+
+			var data []byte
+			if len(args) > 0 {
+				if data, err = os.ReadFile(args[0]); err != nil {
+					return fmt.Errorf("failed to read tool call: %w", err)
+				}
+			} else {
+				content, err := readFromStdin()
+				if err != nil {
+					return fmt.Errorf("failed to read from stdin: %w", err)
+				}
+				data = []byte(content)
+			}
+
+			var call sentinel.ToolCall
+			if err := json.Unmarshal(data, &call); err != nil {
+				return fmt.Errorf("invalid tool call: %w", err)
+			}
+
+			result, err := engine.ValidateToolCall(cmd.Context(), call)
+			if err != nil {
+				return fmt.Errorf("validation failed: %w", err)
+			}
+
+			if outputFormat == "json" {
+				data, err := json.MarshalIndent(result, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal results: %w", err)
+				}
+				fmt.Println(string(data))
+			} else {
+				displayResults(&result.ValidationResult)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&configFile, "config", "c", "", "Path to configuration file")
+	cmd.Flags().StringVarP(&outputFormat, "format", "f", "text", "Output format (text, json)")
+
+	return cmd
+}
@@ -2,13 +2,22 @@ package cli
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"os/user"
 	"path/filepath"
+	"sort"
 	"strings"
 
-	"promptsentinel/internal/validator"
+	"promptsentinel/internal/promptdb"
+	"promptsentinel/internal/tokenizer"
+	"promptsentinel/sentinel"
+
+	"github.com/spf13/cobra"
 )
 
 // readFromStdin reads input from stdin
@@ -27,6 +36,49 @@ func readFromStdin() (string, error) {
 	return strings.Join(lines, "\n"), nil
 }
 
+// readPrompt returns the prompt given as an argument or on stdin
+func readPrompt(args []string) (string, error) {
+	prompt := ""
+	if len(args) > 0 {
+		prompt = args[0]
+	} else {
+		var err error
+		if prompt, err = readFromStdin(); err != nil {
+			return "", fmt.Errorf("failed to read from stdin: %w", err)
+		}
+	}
+
+	if strings.TrimSpace(prompt) == "" {
+		return "", fmt.Errorf("prompt cannot be empty")
+	}
+	return prompt, nil
+}
+
+// readMessages parses a JSON conversation from the file named in args, or
+// from stdin when no file is given
+func readMessages(args []string) ([]sentinel.Message, error) {
+	var data []byte
+	if len(args) > 0 {
+		content, err := os.ReadFile(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read messages: %w", err)
+		}
+		data = content
+	} else {
+		content, err := readFromStdin()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read from stdin: %w", err)
+		}
+		data = []byte(content)
+	}
+
+	messages, err := sentinel.ParseMessages(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse messages: %w", err)
+	}
+	return messages, nil
+}
+
 // getDefaultConfigPath returns the default configuration file path
 func getDefaultConfigPath() string {
 	homeDir, err := os.UserHomeDir()
@@ -45,32 +97,187 @@ func getConfigPath(configFile string) string {
 	return getDefaultConfigPath()
 }
 
-// loadConfig loads configuration from file
-func loadConfig(configFile string) (*validator.Config, error) {
-	configPath := getConfigPath(configFile)
+// getDefaultDatabaseURL returns the embedded SQLite database stored next to
+// the default configuration file
+func getDefaultDatabaseURL() string {
+	return "sqlite://" + filepath.Join(filepath.Dir(getDefaultConfigPath()), "promptsentinel.db")
+}
+
+// getDefaultJobsDir returns the directory for uploaded job datasets, next to
+// the default configuration file
+func getDefaultJobsDir() string {
+	return filepath.Join(filepath.Dir(getDefaultConfigPath()), "jobs")
+}
+
+// getDatabaseURL returns the database connection string
+func getDatabaseURL(databaseURL string) string {
+	if databaseURL != "" {
+		return databaseURL
+	}
+	if env := os.Getenv("PROMPTSENTINEL_DATABASE_URL"); env != "" {
+		return env
+	}
+	return getDefaultDatabaseURL()
+}
+
+// openDatabase opens the database configured by flag or environment
+func openDatabase(ctx context.Context, databaseURL string) (*promptdb.Store, error) {
+	dsn := getDatabaseURL(databaseURL)
+
+	// The default SQLite file lives in the config directory, which may not
+	// exist yet on a fresh install.
+	if backend, path, err := promptdb.ParseDSN(dsn); err == nil && backend == promptdb.BackendSQLite {
+		if dir := filepath.Dir(strings.SplitN(path, "?", 2)[0]); dir != "." {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return nil, fmt.Errorf("failed to create database directory: %w", err)
+			}
+		}
+	}
+
+	store, err := promptdb.OpenStore(ctx, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	store.SetLogger(slog.Default())
+	return store, nil
+}
+
+// openMigratedDatabase opens the configured database and applies any pending
+// migrations so that a fresh install works without running db migrate first
+func openMigratedDatabase(ctx context.Context, databaseURL string) (*promptdb.Store, error) {
+	store, err := openDatabase(ctx, databaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := store.Migrate(ctx); err != nil {
+		_ = store.Close()
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+	return store, nil
+}
+
+// historyOptions controls how check and validate results are recorded
+type historyOptions struct {
+	databaseURL string
+	owner       string
+	disabled    bool
+	storePrompt bool
+}
+
+// addHistoryFlags registers the history flags shared by check and validate
+func addHistoryFlags(cmd *cobra.Command, opts *historyOptions) {
+	cmd.Flags().StringVar(&opts.databaseURL, "database-url", "", "Database for validation history (defaults to $PROMPTSENTINEL_DATABASE_URL)")
+	cmd.Flags().StringVar(&opts.owner, "owner", "", "Owner recorded with the result (defaults to $PROMPTSENTINEL_OWNER or the current user)")
+	cmd.Flags().BoolVar(&opts.disabled, "no-history", false, "Do not record the result in validation history")
+	cmd.Flags().BoolVar(&opts.storePrompt, "store-prompt", false, "Store the prompt encrypted with $PROMPTSENTINEL_PROMPT_KEY")
+}
+
+// getOwner returns the owner recorded with validation history
+func getOwner(owner string) string {
+	if owner != "" {
+		return owner
+	}
+	if env := os.Getenv("PROMPTSENTINEL_OWNER"); env != "" {
+		return env
+	}
+	if current, err := user.Current(); err == nil && current.Username != "" {
+		return current.Username
+	}
+	return "local"
+}
+
+// getPromptCipher returns the cipher for stored prompts, or nil when prompts
+// should not be stored
+func getPromptCipher(storePrompt bool) (*promptdb.PromptCipher, error) {
+	if !storePrompt {
+		return nil, nil
+	}
+
+	encoded := os.Getenv("PROMPTSENTINEL_PROMPT_KEY")
+	if encoded == "" {
+		return nil, fmt.Errorf("PROMPTSENTINEL_PROMPT_KEY must be set to store prompts")
+	}
+
+	key, err := promptdb.ParsePromptKey(encoded)
+	if err != nil {
+		return nil, err
+	}
+	return promptdb.NewPromptCipher(key)
+}
 
-	// Check if config file exists
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		// Return default config if file doesn't exist
-		return validator.DefaultConfig(), nil
+// recordHistory stores a validation result in the history database and
+// returns the recorded event, whose ID is empty when nothing was stored.
+// Failures are reported as warnings so that validation never depends on the
+// database.
+func recordHistory(opts historyOptions, source, prompt string, config *sentinel.Config, result *sentinel.Result) promptdb.ValidationEvent {
+	promptCipher, err := getPromptCipher(opts.storePrompt)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: prompt will not be stored: %v\n", err)
 	}
 
-	// Read and parse config file
-	data, err := os.ReadFile(configPath)
+	event, err := promptdb.NewValidationEvent(source, getOwner(opts.owner), prompt, config, result, promptCipher)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		fmt.Fprintf(os.Stderr, "Warning: prompt will not be stored: %v\n", err)
+		event, _ = promptdb.NewValidationEvent(source, getOwner(opts.owner), prompt, config, result, nil)
 	}
 
-	var config validator.Config
-	if err := json.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	if opts.disabled {
+		return event
 	}
 
-	return &config, nil
+	ctx := context.Background()
+	store, err := openMigratedDatabase(ctx, opts.databaseURL)
+	if err == nil {
+		defer store.Close()
+		event.ID, err = store.RecordValidationEvent(ctx, event)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record validation history: %v\n", err)
+	}
+
+	return event
+}
+
+// submitForApproval opens an approval ticket for a flagged result and marks
+// the result pending. Unlike history, this must succeed: a prompt that needs
+// approval cannot be reported as allowed.
+func submitForApproval(opts historyOptions, event promptdb.ValidationEvent, result *sentinel.Result) error {
+	ctx := context.Background()
+	store, err := openMigratedDatabase(ctx, opts.databaseURL)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	approval, err := store.CreateApproval(ctx, promptdb.NewApproval(event, promptdb.DefaultApprovalTTL))
+	if err != nil {
+		return err
+	}
+
+	result.Outcome = sentinel.OutcomePendingApproval
+	result.ApprovalID = approval.ID
+	return nil
+}
+
+// loadConfig loads configuration from file
+func loadConfig(configFile string) (*sentinel.Config, error) {
+	return sentinel.LoadConfigFile(getConfigPath(configFile))
+}
+
+// newEngine creates the engine the validation commands run on from the
+// configuration file and opts
+func newEngine(configFile string, opts ...sentinel.Option) (*sentinel.Engine, error) {
+	opts = append([]sentinel.Option{sentinel.WithConfigFile(getConfigPath(configFile))}, opts...)
+	engine, err := sentinel.New(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	return engine, nil
 }
 
 // saveConfig saves configuration to file
-func saveConfig(config *validator.Config, configPath string) error {
+func saveConfig(config *sentinel.Config, configPath string) error {
 	// Ensure directory exists
 	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
 		return fmt.Errorf("failed to create config directory: %w", err)
@@ -91,7 +298,7 @@ func saveConfig(config *validator.Config, configPath string) error {
 }
 
 // setConfigValue sets a configuration value
-func setConfigValue(config *validator.Config, key, value string) error {
+func setConfigValue(config *sentinel.Config, key, value string) error {
 	switch key {
 	case "use_case":
 		config.UseCase = value
@@ -114,6 +321,21 @@ func setConfigValue(config *validator.Config, key, value string) error {
 	case "require_approval":
 		// Parse boolean value
 		config.RequireApproval = strings.ToLower(value) == "true"
+	case "max_length_unit":
+		if value != "characters" && value != "tokens" {
+			return fmt.Errorf("invalid max_length_unit value: expected characters or tokens")
+		}
+		config.MaxLengthUnit = value
+	case "tokenizer":
+		if _, err := tokenizer.Get(value); err != nil {
+			return fmt.Errorf("invalid tokenizer value: %w", err)
+		}
+		config.Tokenizer = value
+	case "fail_mode":
+		if value != sentinel.FailClosed && value != sentinel.FailOpen {
+			return fmt.Errorf("invalid fail_mode value: expected closed or open")
+		}
+		config.FailMode = value
 	default:
 		return fmt.Errorf("unknown configuration key: %s", key)
 	}
@@ -121,8 +343,82 @@ func setConfigValue(config *validator.Config, key, value string) error {
 	return nil
 }
 
+// maxLengthUnit returns the unit of the length limits, applying the default
+func maxLengthUnit(config *sentinel.Config) string {
+	if config.MaxLengthUnit == "" {
+		return "characters"
+	}
+	return config.MaxLengthUnit
+}
+
+// tokenizerName returns the configured tokenizer, applying the default
+func tokenizerName(config *sentinel.Config) string {
+	if config.Tokenizer == "" {
+		return tokenizer.Default
+	}
+	return config.Tokenizer
+}
+
+// failMode returns what happens when validation runs out of time, applying
+// the default
+func failMode(config *sentinel.Config) string {
+	if config.FailMode == "" {
+		return sentinel.FailClosed
+	}
+	return config.FailMode
+}
+
+// getConfigValue returns the current value of a settable configuration key
+func getConfigValue(config *sentinel.Config, key string) string {
+	switch key {
+	case "use_case":
+		return config.UseCase
+	case "safety_level":
+		return config.SafetyLevel
+	case "max_length":
+		return fmt.Sprintf("%d", config.MaxLength)
+	case "min_length":
+		return fmt.Sprintf("%d", config.MinLength)
+	case "require_approval":
+		return fmt.Sprintf("%t", config.RequireApproval)
+	case "max_length_unit":
+		return maxLengthUnit(config)
+	case "tokenizer":
+		return tokenizerName(config)
+	case "fail_mode":
+		return failMode(config)
+	default:
+		return ""
+	}
+}
+
+// recordConfigChange appends a config.set entry to the audit log. Failures are
+// reported as warnings because the configuration file has already been saved.
+func recordConfigChange(databaseURL, actor, configPath, key, oldValue, newValue, oldVersion, newVersion string) {
+	ctx := context.Background()
+	store, err := openMigratedDatabase(ctx, databaseURL)
+	if err == nil {
+		defer store.Close()
+		_, err = store.AppendAudit(ctx, promptdb.AuditEntry{
+			Actor:   actor,
+			Action:  promptdb.AuditActionConfigChanged,
+			Subject: key,
+			Details: promptdb.NewAuditDetails(map[string]any{
+				"config_path":        configPath,
+				"old_value":          oldValue,
+				"new_value":          newValue,
+				"old_policy_version": oldVersion,
+				"new_policy_version": newVersion,
+			}),
+		})
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record configuration change in audit log: %v\n", err)
+	}
+}
+
 // displayResults displays validation results
-func displayResults(result *validator.ValidationResult) {
+func displayResults(result *sentinel.Result) {
 	fmt.Printf("\n🔍 Prompt Validation Results\n")
 	fmt.Printf("============================\n\n")
 
@@ -132,6 +428,10 @@ func displayResults(result *validator.ValidationResult) {
 		status = "✅ PASSED"
 	}
 	fmt.Printf("Status: %s\n", status)
+	if result.Outcome == sentinel.OutcomePendingApproval {
+		fmt.Printf("Outcome: ⏳ PENDING APPROVAL (ticket %s)\n", result.ApprovalID)
+		fmt.Printf("         Poll with: promptsentinel approvals show %s\n", result.ApprovalID)
+	}
 	fmt.Printf("Score: %d/100\n\n", result.Score)
 
 	// Issues
@@ -145,7 +445,18 @@ func displayResults(result *validator.ValidationResult) {
 				severityIcon = "ℹ️"
 			}
 
-			fmt.Printf("  %d. %s [%s] %s\n", i+1, severityIcon, strings.ToUpper(issue.Severity), issue.Message)
+			location := ""
+			if issue.MessageIndex != nil {
+				location = fmt.Sprintf("(message %d, %s) ", *issue.MessageIndex, issue.Role)
+			}
+			if issue.ChunkIndex != nil {
+				location = fmt.Sprintf("(chunk %d) ", *issue.ChunkIndex)
+			}
+			if issue.Path != "" {
+				location = fmt.Sprintf("(at %s) ", issue.Path)
+			}
+
+			fmt.Printf("  %d. %s [%s] %s%s\n", i+1, severityIcon, strings.ToUpper(issue.Severity), location, issue.Message)
 			if issue.Suggestion != "" {
 				fmt.Printf("     💡 Suggestion: %s\n", issue.Suggestion)
 			}
@@ -172,7 +483,7 @@ func displayResults(result *validator.ValidationResult) {
 }
 
 // displayDetailedResults displays comprehensive validation results
-func displayDetailedResults(result *validator.ComprehensiveValidationResult) {
+func displayDetailedResults(result *sentinel.ComprehensiveResult) {
 	// Display basic results first
 	displayResults(&result.ValidationResult)
 
@@ -207,13 +518,126 @@ func displayDetailedResults(result *validator.ComprehensiveValidationResult) {
 	// Performance Metrics
 	fmt.Printf("\n⚡ Performance Metrics\n")
 	fmt.Printf("=====================\n")
-	fmt.Printf("Estimated Tokens: %d\n", result.PerformanceMetrics.EstimatedTokens)
+	fmt.Printf("Tokens: %d (%s)\n", result.PerformanceMetrics.EstimatedTokens, result.PerformanceMetrics.Tokenizer)
 	fmt.Printf("Complexity Score: %.2f\n", result.PerformanceMetrics.ComplexityScore)
 	fmt.Printf("Resource Intensive: %t\n", result.PerformanceMetrics.ResourceIntensive)
+	fmt.Printf("Processing Time: %.3fms\n", result.PerformanceMetrics.ProcessingTime)
+	if len(result.PerformanceMetrics.ModelFit) > 0 {
+		fmt.Printf("Model Fit:\n")
+		for _, fit := range result.PerformanceMetrics.ModelFit {
+			status := "✅"
+			if !fit.Fits {
+				status = "❌"
+			}
+			fmt.Printf("  %s %-14s %d of %d tokens (%.1f%%), ~$%.6f\n", status, fit.Model, fit.Tokens, fit.ContextWindow, fit.ContextUsed*100, fit.EstimatedCostUSD)
+		}
+	}
+
+	if result.ConversationAnalysis != nil {
+		displayConversationAnalysis(result.ConversationAnalysis)
+	}
+}
+
+// displayConversationAnalysis displays multi-turn findings and the per-turn
+// risk timeline
+func displayConversationAnalysis(analysis *sentinel.ConversationAnalysis) {
+	fmt.Printf("\n💬 Conversation Analysis\n")
+	fmt.Printf("========================\n")
+	fmt.Printf("Risk Level: %s\n", strings.ToUpper(analysis.RiskLevel))
+	fmt.Printf("Cumulative Risk: %d (peak turn %d)\n", analysis.CumulativeRisk, analysis.PeakRisk)
+	fmt.Printf("Crescendo: %t\n", analysis.Crescendo)
+	fmt.Printf("Split Payload: %t\n", analysis.SplitPayload)
+	fmt.Printf("Coerced Persona: %t\n", analysis.CoercedPersona)
+
+	fmt.Printf("Risk Timeline:\n")
+	for _, turn := range analysis.Timeline {
+		bar := strings.Repeat("█", turn.CumulativeRisk/10)
+		fmt.Printf("  #%-3d %-9s risk %3d  cumulative %3d %s", turn.Index, turn.Role, turn.Risk, turn.CumulativeRisk, bar)
+		if len(turn.Signals) > 0 {
+			fmt.Printf("  (%s)", strings.Join(turn.Signals, ", "))
+		}
+		fmt.Println()
+	}
+}
+
+// displayOutputResults displays model response validation results
+func displayOutputResults(result *sentinel.OutputResult) {
+	displayResults(&result.ValidationResult)
+
+	if leakage := result.SystemPromptLeakage; leakage != nil {
+		fmt.Printf("\n🕵️  System Prompt Leakage\n")
+		fmt.Printf("========================\n")
+		fmt.Printf("Leaked: %t\n", leakage.Leaked)
+		fmt.Printf("Verbatim: %t\n", leakage.Verbatim)
+		fmt.Printf("Overlap: %.0f%% (%d of %d phrases)\n", leakage.Overlap*100, leakage.MatchedNGrams, leakage.TotalNGrams)
+		fmt.Printf("Longest Run: %d words\n", leakage.LongestRun)
+	}
+
+	fmt.Printf("\n🔒 Security Analysis\n")
+	fmt.Printf("===================\n")
+	fmt.Printf("Risk Level: %s\n", strings.ToUpper(result.SecurityAnalysis.RiskLevel))
+	fmt.Printf("Sensitive Data: %t\n", result.SecurityAnalysis.HasSensitiveData)
+	if len(result.ComplianceCheck.ComplianceIssues) > 0 {
+		fmt.Printf("Compliance Issues:\n")
+		for i, issue := range result.ComplianceCheck.ComplianceIssues {
+			fmt.Printf("  %d. %s\n", i+1, issue)
+		}
+	}
+}
+
+// displayDocumentResults displays a document scan with a line per chunk
+func displayDocumentResults(result *sentinel.DocumentResult) {
+	displayResults(&result.ValidationResult)
+
+	fmt.Printf("\n📄 Chunks (%s)\n", result.Format)
+	fmt.Printf("==========\n")
+	for _, chunk := range result.Chunks {
+		status := "✅"
+		if chunk.Poisoned {
+			status = "❌ POISONED"
+		}
+		location := ""
+		if chunk.Path != "" {
+			location = " " + chunk.Path
+		}
+		fmt.Printf("  %d.%s %s score %d, %d chars, %d hidden\n", chunk.Index, location, status, chunk.Score, len(chunk.Text), len(chunk.Hidden))
+	}
+
+	if poisoned := result.PoisonedChunks(); len(poisoned) > 0 {
+		fmt.Printf("\nDrop chunks %v before passing the document to the model.\n", poisoned)
+	}
+}
+
+// displayProfile writes the stage timings and the slowest detectors to w,
+// so that it can go to stderr without mixing into JSON output
+func displayProfile(w io.Writer, metrics sentinel.PerformanceMetrics, limit int) {
+	fmt.Fprintf(w, "\n⏱️  Profile (%.0fµs total)\n", metrics.ProcessingTimeMicros)
+	fmt.Fprintf(w, "==========================\n")
+	fmt.Fprintf(w, "Stages:\n")
+	for _, stage := range metrics.Stages {
+		fmt.Fprintf(w, "  %-22s %10.1fµs\n", stage.Name, stage.DurationMicros)
+	}
+
+	detectors := append([]sentinel.Timing(nil), metrics.Detectors...)
+	sort.SliceStable(detectors, func(i, j int) bool {
+		return detectors[i].DurationMicros > detectors[j].DurationMicros
+	})
+	if len(detectors) > limit {
+		detectors = detectors[:limit]
+	}
+
+	fmt.Fprintf(w, "Slowest Detectors:\n")
+	for i, detector := range detectors {
+		name := detector.Name
+		if detector.Detail != "" {
+			name += " " + detector.Detail
+		}
+		fmt.Fprintf(w, "  %2d. %10.1fµs  %dx  %s\n", i+1, detector.DurationMicros, detector.Calls, name)
+	}
 }
 
 // displayJSONResults displays results in JSON format
-func displayJSONResults(result *validator.ComprehensiveValidationResult) {
+func displayJSONResults(result *sentinel.ComprehensiveResult) {
 	data, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
 		fmt.Printf("Error marshaling results: %v\n", err)
@@ -223,15 +647,17 @@ func displayJSONResults(result *validator.ComprehensiveValidationResult) {
 }
 
 // displayConfig displays configuration
-func displayConfig(config *validator.Config) {
+func displayConfig(config *sentinel.Config) {
 	fmt.Printf("\n⚙️  PromptSentinel Configuration\n")
 	fmt.Printf("================================\n\n")
 
 	fmt.Printf("Use Case: %s\n", config.UseCase)
 	fmt.Printf("Safety Level: %s\n", config.SafetyLevel)
-	fmt.Printf("Max Length: %d\n", config.MaxLength)
-	fmt.Printf("Min Length: %d\n", config.MinLength)
+	fmt.Printf("Max Length: %d %s\n", config.MaxLength, maxLengthUnit(config))
+	fmt.Printf("Min Length: %d %s\n", config.MinLength, maxLengthUnit(config))
+	fmt.Printf("Tokenizer: %s\n", tokenizerName(config))
 	fmt.Printf("Require Approval: %t\n", config.RequireApproval)
+	fmt.Printf("Fail Mode: %s\n", failMode(config))
 
 	if len(config.AllowedDomains) > 0 {
 		fmt.Printf("Allowed Domains: %s\n", strings.Join(config.AllowedDomains, ", "))
@@ -247,3 +673,180 @@ func displayConfig(config *validator.Config) {
 
 	fmt.Printf("Last Updated: %s\n", config.LastUpdated.Format("2006-01-02 15:04:05"))
 }
+
+// displayMigrationStatuses displays the state of each schema migration
+func displayMigrationStatuses(statuses []promptdb.MigrationStatus) {
+	fmt.Printf("\n🗄️  Schema Migrations\n")
+	fmt.Printf("====================\n\n")
+
+	for _, status := range statuses {
+		state := "pending"
+		if status.Applied {
+			state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("  %04d_%s: %s\n", status.Version, status.Name, state)
+	}
+}
+
+// displayAPIKeys displays API keys without their secrets
+func displayAPIKeys(keys []promptdb.APIKeyInfo) {
+	fmt.Printf("\n🔑 API Keys\n")
+	fmt.Printf("===========\n\n")
+
+	if len(keys) == 0 {
+		fmt.Println("No API keys found")
+		return
+	}
+
+	for _, key := range keys {
+		status := "active"
+		if key.RevokedAt != nil {
+			status = "revoked " + key.RevokedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		scopes := ""
+		if len(key.Scopes) > 0 {
+			scopes = "  scopes=" + strings.Join(key.Scopes, ",")
+		}
+		fmt.Printf("  %s  owner=%s%s  created=%s  %s\n", key.Prefix, key.OwnerID, scopes,
+			key.CreatedAt.Local().Format("2006-01-02 15:04:05"), status)
+	}
+}
+
+// displayAuditEntries displays audit log entries
+func displayAuditEntries(entries []promptdb.AuditEntry) {
+	fmt.Printf("\n🧾 Audit Log\n")
+	fmt.Printf("============\n\n")
+
+	if len(entries) == 0 {
+		fmt.Println("No audit entries found")
+		return
+	}
+
+	for _, entry := range entries {
+		fmt.Printf("  #%d  %s  %-16s actor=%s subject=%s\n", entry.Seq,
+			entry.CreatedAt.Local().Format("2006-01-02 15:04:05"), entry.Action, entry.Actor, entry.Subject)
+		if entry.Details != "{}" {
+			fmt.Printf("       %s\n", entry.Details)
+		}
+	}
+}
+
+// displayAuditVerification displays the result of verifying the audit chain
+func displayAuditVerification(verification promptdb.AuditVerification) {
+	fmt.Printf("\n🧾 Audit Log Verification\n")
+	fmt.Printf("=========================\n\n")
+
+	status := "❌ TAMPERED"
+	if verification.Valid() {
+		status = "✅ INTACT"
+	}
+	fmt.Printf("Status: %s\n", status)
+	fmt.Printf("Entries: %d\n", verification.Entries)
+	fmt.Printf("Head: #%d %s\n", verification.HeadSeq, verification.HeadHash)
+
+	if len(verification.Problems) > 0 {
+		fmt.Printf("\nProblems Found:\n")
+		for i, problem := range verification.Problems {
+			fmt.Printf("  %d. entry #%d: %s\n", i+1, problem.Seq, problem.Message)
+		}
+	}
+}
+
+// displayCanaries displays canary tokens
+func displayCanaries(canaries []promptdb.Canary) {
+	fmt.Printf("\n🐤 Canary Tokens\n")
+	fmt.Printf("================\n\n")
+
+	if len(canaries) == 0 {
+		fmt.Println("No canary tokens found")
+		return
+	}
+
+	for _, canary := range canaries {
+		status := "active"
+		if canary.RevokedAt != nil {
+			status = "revoked " + canary.RevokedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("  %s  %s  label=%q owner=%s created=%s  %s\n", canary.ID, canary.Token, canary.Label,
+			canary.OwnerID, canary.CreatedAt.Local().Format("2006-01-02 15:04:05"), status)
+	}
+}
+
+// displayLeakageIncidents displays leaked canaries, naming the prompt each
+// one was embedded in
+func displayLeakageIncidents(incidents []promptdb.LeakageIncident) {
+	fmt.Printf("\n🚨 Leakage Incidents\n")
+	fmt.Printf("====================\n\n")
+
+	if len(incidents) == 0 {
+		fmt.Println("No leakage incidents found")
+		return
+	}
+
+	for _, incident := range incidents {
+		fmt.Printf("  %s  %q leaked in a %s via %s (owner=%s)\n",
+			incident.DetectedAt.Local().Format("2006-01-02 15:04:05"), incident.Label,
+			incident.Location, incident.Source, incident.OwnerID)
+		fmt.Printf("       canary=%s created=%s", incident.CanaryID, incident.CanaryCreatedAt.Local().Format("2006-01-02 15:04:05"))
+		if incident.ValidationEventID != "" {
+			fmt.Printf(" event=%s", incident.ValidationEventID)
+		}
+		fmt.Println()
+	}
+}
+
+// displayApprovals displays a list of approval tickets
+func displayApprovals(approvals []promptdb.Approval) {
+	fmt.Printf("\n⏳ Approval Queue\n")
+	fmt.Printf("=================\n\n")
+
+	if len(approvals) == 0 {
+		fmt.Println("No approval tickets found")
+		return
+	}
+
+	for _, approval := range approvals {
+		fmt.Printf("  %s  %-8s owner=%s use_case=%s score=%d created=%s\n",
+			approval.ID, approval.Status, approval.OwnerID, approval.UseCase, approval.Score,
+			approval.CreatedAt.Local().Format("2006-01-02 15:04:05"))
+		if len(approval.IssueTypes) > 0 {
+			fmt.Printf("     detectors: %s\n", strings.Join(approval.IssueTypes, ", "))
+		}
+	}
+}
+
+// displayApproval displays a single approval ticket
+func displayApproval(approval promptdb.Approval, promptCipher *promptdb.PromptCipher) {
+	fmt.Printf("\n⏳ Approval %s\n", approval.ID)
+	fmt.Printf("==========================================\n\n")
+
+	fmt.Printf("Status: %s\n", strings.ToUpper(approval.Status))
+	fmt.Printf("Owner: %s\n", approval.OwnerID)
+	fmt.Printf("Source: %s\n", approval.Source)
+	fmt.Printf("Use Case: %s\n", approval.UseCase)
+	fmt.Printf("Policy Version: %s\n", approval.PolicyVersion)
+	fmt.Printf("Score: %d/100\n", approval.Score)
+	if len(approval.IssueTypes) > 0 {
+		fmt.Printf("Detectors: %s\n", strings.Join(approval.IssueTypes, ", "))
+	}
+	fmt.Printf("Fingerprint: %s\n", approval.PromptFingerprint)
+	fmt.Printf("Created: %s\n", approval.CreatedAt.Local().Format("2006-01-02 15:04:05"))
+	fmt.Printf("Expires: %s\n", approval.ExpiresAt.Local().Format("2006-01-02 15:04:05"))
+
+	if approval.DecidedAt != nil {
+		fmt.Printf("Decided By: %s at %s\n", approval.DecidedBy, approval.DecidedAt.Local().Format("2006-01-02 15:04:05"))
+	}
+	if approval.Reason != "" {
+		fmt.Printf("Reason: %s\n", approval.Reason)
+	}
+
+	if promptCipher != nil {
+		if approval.EncryptedPrompt == "" {
+			fmt.Printf("Prompt: <not stored>\n")
+		} else if prompt, err := promptCipher.Open(approval.EncryptedPrompt); err != nil {
+			fmt.Printf("Prompt: <unable to decrypt: %v>\n", err)
+		} else {
+			fmt.Printf("Prompt: %s\n", prompt)
+		}
+	}
+}
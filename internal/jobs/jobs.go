@@ -0,0 +1,424 @@
+// Package jobs validates large JSONL datasets in the background. Jobs are
+// queued in promptdb and run by a bounded pool of workers that lease them,
+// save their results with a checkpoint as they go, and resume from the last
+// checkpoint when a job is released at shutdown or its worker stops without
+// releasing it.
+//
+// Each line of a dataset is a JSON object with a prompt or a messages
+// conversation, as accepted by the check endpoint, and optionally an id
+// echoed in its result and a use_case overriding the job's:
+//
+//	{"id": "log-1", "prompt": "Summarize this ticket"}
+//	{"id": "log-2", "messages": [{"role": "user", "content": "Hi"}], "use_case": "business"}
+//
+// Results hold fingerprints rather than prompts. Uploaded datasets are
+// deleted once their job finishes or is cancelled.
+package jobs
+
+import (
+	"context"
+	cryptorand "crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"promptsentinel/internal/logging"
+	"promptsentinel/internal/metrics"
+	"promptsentinel/internal/promptdb"
+	"promptsentinel/internal/validator"
+)
+
+// Defaults for the zero values of Options.
+const (
+	DefaultWorkers         = 2
+	DefaultPollInterval    = 5 * time.Second
+	DefaultLease           = time.Minute
+	DefaultCheckpointEvery = 100
+	DefaultMaxAttempts     = 3
+)
+
+// MaxLineBytes bounds a line of a dataset, like the API bounds a request
+// body. Longer lines are recorded as errors and skipped.
+const MaxLineBytes = 1 << 20
+
+var (
+	// ErrEmptyDataset is returned when submitting a dataset with no items.
+	ErrEmptyDataset = errors.New("dataset has no items")
+	// ErrPathsDisabled is returned when submitting a server-side path to a
+	// Runner without an InputDir.
+	ErrPathsDisabled = errors.New("server-side datasets are disabled")
+	// ErrPathNotAllowed is returned when a submitted path is absolute,
+	// leaves InputDir, or is not a regular file.
+	ErrPathNotAllowed = errors.New("path must name a file inside the input directory")
+)
+
+// Options configures a Runner.
+type Options struct {
+	// Store queues the jobs and holds their results. Required.
+	Store *promptdb.Store
+	// LoadConfig returns the validation policy. It is called at each
+	// checkpoint so configuration changes apply to running jobs. Required.
+	LoadConfig func() (*validator.Config, error)
+	// Dir is where uploaded datasets are kept until their job finishes.
+	// Required.
+	Dir string
+	// InputDir, when set, is the directory server-side datasets may be
+	// read from. Paths are resolved inside it.
+	InputDir string
+	// Workers is how many jobs run at once in this process. Zero uses
+	// DefaultWorkers.
+	Workers int
+	// PollInterval is how often idle workers look for jobs queued by other
+	// processes. Zero uses DefaultPollInterval.
+	PollInterval time.Duration
+	// Lease is how long a worker holds a job between checkpoints before
+	// another may take it over. Zero uses DefaultLease.
+	Lease time.Duration
+	// CheckpointEvery is how many items a worker validates between
+	// checkpoints. Workers also checkpoint when a third of the lease has
+	// passed. Zero uses DefaultCheckpointEvery.
+	CheckpointEvery int
+	// MaxAttempts is how many times a job is claimed without being released
+	// before it fails, so a dataset that crashes its worker is not retried
+	// forever. Zero uses DefaultMaxAttempts.
+	MaxAttempts int
+	// ValidationTimeout bounds the validation of each item. Zero means no
+	// limit.
+	ValidationTimeout time.Duration
+	// Metrics, when set, records every validation under the "job" source.
+	Metrics *metrics.Metrics
+	// Logger, when set, receives job progress and failures, through
+	// logging.Redact.
+	Logger *slog.Logger
+}
+
+// Runner submits jobs and runs them with a bounded pool of workers.
+type Runner struct {
+	opts   Options
+	logger *slog.Logger
+	// worker names this process in job leases.
+	worker string
+	wake   chan struct{}
+}
+
+// New creates a Runner, creating Dir if needed. Call Run to start its
+// workers.
+func New(opts Options) (*Runner, error) {
+	if opts.Store == nil || opts.LoadConfig == nil {
+		return nil, errors.New("jobs: a store and a config loader are required")
+	}
+	if opts.Dir == "" {
+		return nil, errors.New("jobs: a directory for uploads is required")
+	}
+	if err := os.MkdirAll(opts.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create jobs directory: %w", err)
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = DefaultWorkers
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultPollInterval
+	}
+	if opts.Lease <= 0 {
+		opts.Lease = DefaultLease
+	}
+	if opts.CheckpointEvery <= 0 {
+		opts.CheckpointEvery = DefaultCheckpointEvery
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = DefaultMaxAttempts
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		host = "worker"
+	}
+	suffix := make([]byte, 4)
+	if _, err := cryptorand.Read(suffix); err != nil {
+		return nil, fmt.Errorf("name worker: %w", err)
+	}
+
+	return &Runner{
+		opts:   opts,
+		logger: logging.Redact(opts.Logger),
+		worker: host + "-" + hex.EncodeToString(suffix),
+		wake:   make(chan struct{}, 1),
+	}, nil
+}
+
+// Spec holds the settings a job is submitted with.
+type Spec struct {
+	// Mode is promptdb.JobModeCheck, the default, or
+	// promptdb.JobModeValidate.
+	Mode string
+	// UseCase overrides the policy's use case for items that do not set
+	// their own.
+	UseCase string
+}
+
+// SubmitUpload saves the dataset read from body under Dir and queues a job
+// for it, named name. A body that fails to read, for example because the
+// caller limited its size, is discarded and its error returned.
+func (r *Runner) SubmitUpload(ctx context.Context, ownerID, name string, spec Spec, body io.Reader) (promptdb.Job, error) {
+	file, err := os.CreateTemp(r.opts.Dir, "upload-*.jsonl")
+	if err != nil {
+		return promptdb.Job{}, fmt.Errorf("save dataset: %w", err)
+	}
+	path := file.Name()
+
+	counter := &itemCounter{}
+	_, err = io.Copy(file, io.TeeReader(body, counter))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && counter.items() == 0 {
+		err = ErrEmptyDataset
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return promptdb.Job{}, err
+	}
+
+	total := counter.items()
+	job, err := r.opts.Store.CreateJob(ctx, promptdb.Job{
+		OwnerID:   ownerID,
+		Input:     promptdb.JobInputUpload,
+		InputName: name,
+		InputPath: path,
+		Mode:      spec.Mode,
+		UseCase:   spec.UseCase,
+		Total:     &total,
+	})
+	if err != nil {
+		_ = os.Remove(path)
+		return promptdb.Job{}, err
+	}
+
+	r.Wake()
+	return job, nil
+}
+
+// SubmitPath queues a job for the dataset at path, relative to InputDir. Its
+// items are counted when the job starts.
+func (r *Runner) SubmitPath(ctx context.Context, ownerID, path string, spec Spec) (promptdb.Job, error) {
+	resolved, err := r.resolvePath(path)
+	if err != nil {
+		return promptdb.Job{}, err
+	}
+
+	job, err := r.opts.Store.CreateJob(ctx, promptdb.Job{
+		OwnerID:   ownerID,
+		Input:     promptdb.JobInputPath,
+		InputName: path,
+		InputPath: resolved,
+		Mode:      spec.Mode,
+		UseCase:   spec.UseCase,
+	})
+	if err != nil {
+		return promptdb.Job{}, err
+	}
+
+	r.Wake()
+	return job, nil
+}
+
+// resolvePath returns the absolute path of a dataset named relative to
+// InputDir, refusing paths that lead outside it, including through
+// symbolic links.
+func (r *Runner) resolvePath(path string) (string, error) {
+	if r.opts.InputDir == "" {
+		return "", ErrPathsDisabled
+	}
+	if !filepath.IsLocal(path) {
+		return "", ErrPathNotAllowed
+	}
+
+	root, err := filepath.EvalSymlinks(r.opts.InputDir)
+	if err != nil {
+		return "", fmt.Errorf("resolve input directory: %w", err)
+	}
+	if root, err = filepath.Abs(root); err != nil {
+		return "", fmt.Errorf("resolve input directory: %w", err)
+	}
+	resolved, err := filepath.EvalSymlinks(filepath.Join(root, path))
+	if err != nil {
+		return "", ErrPathNotAllowed
+	}
+	if rel, err := filepath.Rel(root, resolved); err != nil || !filepath.IsLocal(rel) {
+		return "", ErrPathNotAllowed
+	}
+	if info, err := os.Stat(resolved); err != nil || !info.Mode().IsRegular() {
+		return "", ErrPathNotAllowed
+	}
+	return resolved, nil
+}
+
+// Cancel cancels a queued or running job, as promptdb.Store.CancelJob does,
+// and deletes its uploaded dataset. A running job's worker stops at its next
+// checkpoint.
+func (r *Runner) Cancel(ctx context.Context, id, actor string) (promptdb.Job, error) {
+	job, err := r.opts.Store.CancelJob(ctx, id, actor)
+	if err != nil {
+		return promptdb.Job{}, err
+	}
+	r.removeUpload(job)
+	return job, nil
+}
+
+// Wake tells an idle worker to look for jobs now rather than at its next
+// poll.
+func (r *Runner) Wake() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run starts the workers and blocks until ctx is done and they have stopped.
+// Workers release the jobs they hold as they stop, so the jobs resume from
+// their last checkpoint on the next start.
+func (r *Runner) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 1; i <= r.opts.Workers; i++ {
+		wg.Add(1)
+		go func(worker string) {
+			defer wg.Done()
+			r.work(ctx, worker)
+		}(fmt.Sprintf("%s-%d", r.worker, i))
+	}
+	wg.Wait()
+}
+
+// work claims and runs jobs until ctx is done.
+func (r *Runner) work(ctx context.Context, worker string) {
+	for {
+		job, err := r.opts.Store.ClaimJob(ctx, worker, r.opts.Lease)
+		if err == nil {
+			r.run(ctx, job)
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if !errors.Is(err, promptdb.ErrNoQueuedJobs) {
+			r.logger.ErrorContext(ctx, "job claim failed", "worker", worker, "error", err)
+		}
+
+		timer := time.NewTimer(r.opts.PollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-r.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// run processes a claimed job and records how it ended.
+func (r *Runner) run(ctx context.Context, job promptdb.Job) {
+	logger := r.logger.With("job_id", job.ID, "worker", job.LeaseOwner)
+
+	if job.Attempts > r.opts.MaxAttempts {
+		message := fmt.Sprintf("gave up after %d attempts", r.opts.MaxAttempts)
+		if err := r.opts.Store.FinishJob(ctx, job, promptdb.JobFailed, message); err != nil {
+			logger.ErrorContext(ctx, "job update failed", "error", err)
+			return
+		}
+		logger.WarnContext(ctx, "job failed", "error", message)
+		r.removeUpload(job)
+		return
+	}
+
+	logger.InfoContext(ctx, "job started", "attempt", job.Attempts, "resume_line", job.Line)
+	err := r.process(ctx, &job)
+	switch {
+	case errors.Is(err, promptdb.ErrJobLeaseLost):
+		// Cancelled, or taken over after the lease ran out.
+		logger.InfoContext(ctx, "job stopped", "processed", job.Processed)
+		if current, getErr := r.opts.Store.GetJob(context.WithoutCancel(ctx), job.ID); getErr == nil && current.Finished() {
+			r.removeUpload(current)
+		}
+		return
+	case ctx.Err() != nil:
+		// Shutting down: hand the job back to resume from its checkpoint.
+		releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		if releaseErr := r.opts.Store.ReleaseJob(releaseCtx, job); releaseErr != nil && !errors.Is(releaseErr, promptdb.ErrJobLeaseLost) {
+			logger.ErrorContext(releaseCtx, "job release failed", "error", releaseErr)
+			return
+		}
+		logger.InfoContext(releaseCtx, "job released", "processed", job.Processed)
+		return
+	case err != nil:
+		if finishErr := r.opts.Store.FinishJob(ctx, job, promptdb.JobFailed, err.Error()); finishErr != nil {
+			logger.ErrorContext(ctx, "job update failed", "error", finishErr)
+			return
+		}
+		logger.WarnContext(ctx, "job failed", "processed", job.Processed, "error", err)
+	default:
+		if finishErr := r.opts.Store.FinishJob(ctx, job, promptdb.JobSucceeded, ""); finishErr != nil {
+			logger.ErrorContext(ctx, "job update failed", "error", finishErr)
+			return
+		}
+		logger.InfoContext(ctx, "job finished", "processed", job.Processed, "flagged", job.Flagged, "errors", job.Errors)
+	}
+	r.removeUpload(job)
+}
+
+// removeUpload deletes the dataset of an upload job. Server-side datasets
+// belong to whoever put them there and are left alone.
+func (r *Runner) removeUpload(job promptdb.Job) {
+	if job.Input != promptdb.JobInputUpload {
+		return
+	}
+	if err := os.Remove(job.InputPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		r.logger.Warn("uploaded dataset not removed", "job_id", job.ID, "error", err)
+	}
+}
+
+// itemCounter counts the non-blank lines written to it.
+type itemCounter struct {
+	count int64
+	// blank is whether the current line has only whitespace so far.
+	blank bool
+	// started is whether anything of the current line has been written.
+	started bool
+}
+
+func (c *itemCounter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		switch {
+		case b == '\n':
+			if c.started && !c.blank {
+				c.count++
+			}
+			c.started, c.blank = false, false
+		case !c.started:
+			c.started, c.blank = true, isSpace(b)
+		case !isSpace(b):
+			c.blank = false
+		}
+	}
+	return len(p), nil
+}
+
+// items returns the count, including a final line without a newline.
+func (c *itemCounter) items() int64 {
+	if c.started && !c.blank {
+		return c.count + 1
+	}
+	return c.count
+}
+
+func isSpace(b byte) bool {
+	return strings.IndexByte(" \t\r", b) >= 0
+}
@@ -0,0 +1,250 @@
+package jobs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"promptsentinel/internal/promptdb"
+	"promptsentinel/internal/validator"
+)
+
+// Item is one line of a dataset.
+type Item struct {
+	ID       string          `json:"id,omitempty"`
+	Prompt   string          `json:"prompt,omitempty"`
+	Messages json.RawMessage `json:"messages,omitempty"`
+	UseCase  string          `json:"use_case,omitempty"`
+}
+
+// Errors recorded for lines that cannot be validated. They are fixed
+// strings because parse errors can quote the line.
+const (
+	errLineTooLong     = "line is longer than the 1 MiB limit"
+	errLineNotJSON     = "line is not a JSON object"
+	errPromptAndTurns  = "line has both prompt and messages"
+	errNoPrompt        = "line has no prompt or messages"
+	errBadConversation = "messages is not a valid conversation"
+	errValidation      = "validation failed"
+)
+
+// process validates the job's dataset from its checkpoint to the end,
+// checkpointing as it goes. job is updated with the progress saved.
+func (r *Runner) process(ctx context.Context, job *promptdb.Job) error {
+	file, err := os.Open(job.InputPath)
+	if err != nil {
+		return fmt.Errorf("open dataset: %w", err)
+	}
+	defer file.Close()
+
+	if job.Total == nil {
+		counter := &itemCounter{}
+		if _, err := io.Copy(counter, file); err != nil {
+			return fmt.Errorf("read dataset: %w", err)
+		}
+		total := counter.items()
+		job.Total = &total
+	}
+	if _, err := file.Seek(job.Offset, io.SeekStart); err != nil {
+		return fmt.Errorf("read dataset: %w", err)
+	}
+	reader := bufio.NewReader(file)
+
+	config, err := r.loadConfig(job)
+	if err != nil {
+		return err
+	}
+	results := make([]promptdb.JobResult, 0, r.opts.CheckpointEvery)
+	lastCheckpoint := time.Now()
+
+	checkpoint := func() error {
+		if err := r.opts.Store.CheckpointJob(ctx, *job, r.opts.Lease, results); err != nil {
+			return err
+		}
+		results = results[:0]
+		lastCheckpoint = time.Now()
+		return nil
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		line, size, tooLong, readErr := readLine(reader, MaxLineBytes)
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return fmt.Errorf("read dataset: %w", readErr)
+		}
+		if size == 0 {
+			break
+		}
+
+		result, counted := r.validateLine(ctx, job, config, line, tooLong)
+		job.Line++
+		job.Offset += size
+		if counted {
+			results = append(results, result)
+			job.Processed++
+			switch {
+			case result.Error != "":
+				job.Errors++
+			case !result.IsValid:
+				job.Flagged++
+			}
+		}
+
+		if len(results) >= r.opts.CheckpointEvery || time.Since(lastCheckpoint) >= r.opts.Lease/3 {
+			if err := checkpoint(); err != nil {
+				return err
+			}
+			// Pick up policy changes between checkpoints.
+			if config, err = r.loadConfig(job); err != nil {
+				return err
+			}
+		}
+		if readErr != nil {
+			break
+		}
+	}
+
+	return checkpoint()
+}
+
+// loadConfig loads the policy with the job's use case applied.
+func (r *Runner) loadConfig(job *promptdb.Job) (*validator.Config, error) {
+	config, err := r.opts.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	if job.UseCase != "" {
+		config.UseCase = job.UseCase
+	}
+	return config, nil
+}
+
+// validateLine validates the line of job's dataset after its checkpoint. It
+// reports false for blank lines, which are skipped.
+func (r *Runner) validateLine(ctx context.Context, job *promptdb.Job, config *validator.Config, line []byte, tooLong bool) (promptdb.JobResult, bool) {
+	lineNumber := job.Line + 1
+	failed := func(itemID, message string) (promptdb.JobResult, bool) {
+		return promptdb.JobResult{Line: lineNumber, ItemID: itemID, IssueTypes: []string{}, Error: message}, true
+	}
+	if tooLong {
+		return failed("", errLineTooLong)
+	}
+	if len(bytes.Trim(line, " \t\r")) == 0 {
+		return promptdb.JobResult{}, false
+	}
+
+	var item Item
+	if err := json.Unmarshal(line, &item); err != nil {
+		return failed("", errLineNotJSON)
+	}
+
+	var messages []validator.Message
+	switch {
+	case len(item.Messages) > 0 && item.Prompt != "":
+		return failed(item.ID, errPromptAndTurns)
+	case len(item.Messages) > 0:
+		var err error
+		if messages, err = validator.ParseMessages(item.Messages); err != nil {
+			return failed(item.ID, errBadConversation)
+		}
+	case strings.TrimSpace(item.Prompt) == "":
+		return failed(item.ID, errNoPrompt)
+	}
+
+	if item.UseCase != "" {
+		itemConfig := *config
+		itemConfig.UseCase = item.UseCase
+		config = &itemConfig
+	}
+
+	validateCtx, cancel := r.validationContext(ctx)
+	defer cancel()
+	start := time.Now()
+
+	result, riskLevel, err := validate(validateCtx, job.Mode, config, item.Prompt, messages)
+	if err != nil {
+		return failed(item.ID, errValidation)
+	}
+	r.opts.Metrics.ObserveValidation("job", config.UseCase, result, time.Since(start))
+
+	prompt := item.Prompt
+	if messages != nil {
+		prompt = validator.ConversationText(messages)
+	}
+	jobResult := promptdb.NewJobResult(lineNumber, item.ID, prompt, config, result)
+	jobResult.RiskLevel = riskLevel
+	return jobResult, true
+}
+
+// validate runs mode's kind of validation on a prompt or conversation,
+// returning the risk level of a comprehensive analysis with the result.
+func validate(ctx context.Context, mode string, config *validator.Config, prompt string, messages []validator.Message) (*validator.ValidationResult, string, error) {
+	if mode == promptdb.JobModeValidate {
+		var result *validator.ComprehensiveValidationResult
+		var err error
+		if messages != nil {
+			result, err = validator.ValidateConversationComprehensiveContext(ctx, messages, config)
+		} else {
+			result, err = validator.ValidatePromptComprehensiveContext(ctx, prompt, config)
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		riskLevel := result.SecurityAnalysis.RiskLevel
+		if result.ConversationAnalysis != nil && result.ConversationAnalysis.RiskLevel == "high" {
+			riskLevel = "high"
+		}
+		return &result.ValidationResult, riskLevel, nil
+	}
+
+	if messages != nil {
+		result, err := validator.ValidateConversationContext(ctx, messages, config)
+		if err != nil {
+			return nil, "", err
+		}
+		return &result.ValidationResult, "", nil
+	}
+	result, err := validator.ValidatePromptContext(ctx, prompt, config)
+	return result, "", err
+}
+
+// validationContext bounds the validation of an item by ValidationTimeout,
+// and sends the validator's warnings to the Runner's logger.
+func (r *Runner) validationContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx = validator.WithLogger(ctx, r.logger)
+	if r.opts.ValidationTimeout > 0 {
+		return context.WithTimeout(ctx, r.opts.ValidationTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// readLine reads the next line from reader, returning it without its line
+// ending along with the bytes it took up. A line longer than limit is
+// consumed but not returned, and reported as tooLong. At the end of the
+// input the error is io.EOF, with the last line when it had no newline.
+func readLine(reader *bufio.Reader, limit int) (line []byte, size int64, tooLong bool, err error) {
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		size += int64(len(chunk))
+		if !tooLong {
+			if len(line)+len(chunk) > limit+2 {
+				tooLong, line = true, nil
+			} else {
+				line = append(line, chunk...)
+			}
+		}
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			return bytes.TrimRight(line, "\r\n"), size, tooLong, err
+		}
+	}
+}
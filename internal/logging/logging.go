@@ -0,0 +1,170 @@
+// Package logging builds the structured loggers the CLI, server, and store
+// write with. Every logger it returns passes records through a redacting
+// handler, so prompts, API keys, and secrets stay out of the logs even when
+// a caller logs them by mistake:
+//
+//   - attributes named for text or credentials, such as "prompt",
+//     "content", "output", "api_key", or "authorization", are replaced
+//     whatever they hold;
+//   - every other string, error, and the message itself has the
+//     credentials ValidateOutput recognizes, and bearer tokens, replaced.
+//
+// Text logged under any other key cannot be recognized as a prompt, so
+// callers log counts, IDs, and rule names instead.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"promptsentinel/internal/validator"
+)
+
+// Formats for New
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// sensitiveKeys name attributes that carry request text or credentials.
+// Keys are compared without case.
+var sensitiveKeys = map[string]bool{
+	"prompt":        true,
+	"prompts":       true,
+	"messages":      true,
+	"content":       true,
+	"output":        true,
+	"system_prompt": true,
+	"text":          true,
+	"body":          true,
+	"api_key":       true,
+	"authorization": true,
+	"password":      true,
+	"secret":        true,
+	"token":         true,
+	"credential":    true,
+}
+
+// bearerPattern matches an Authorization header value
+var bearerPattern = regexp.MustCompile(`(?i)\bbearer\s+\S+`)
+
+// New returns a logger writing records at level or above to w as text or
+// JSON, with redaction.
+func New(w io.Writer, format string, level slog.Leveler) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch format {
+	case "", FormatText:
+		handler = slog.NewTextHandler(w, opts)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q (want %s or %s)", format, FormatText, FormatJSON)
+	}
+	return slog.New(&redactHandler{next: handler}), nil
+}
+
+// ParseLevel parses debug, info, warn, or error, as slog.Level does.
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q (want debug, info, warn, or error)", s)
+	}
+	return level, nil
+}
+
+// Redact returns logger with redaction, so a logger handed in by a caller
+// gets the same guarantee as one from New. A nil logger discards
+// everything.
+func Redact(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return Discard()
+	}
+	if _, ok := logger.Handler().(*redactHandler); ok {
+		return logger
+	}
+	return slog.New(&redactHandler{next: logger.Handler()})
+}
+
+// Discard returns a logger that writes nothing.
+func Discard() *slog.Logger {
+	return slog.New(discardHandler{})
+}
+
+// RedactString removes credentials from s, as the handler does for every
+// string it passes on.
+func RedactString(s string) string {
+	s, _ = validator.RedactSecrets(s)
+	return bearerPattern.ReplaceAllString(s, "Bearer "+validator.RedactionMarker)
+}
+
+// redactHandler redacts records before passing them to next
+type redactHandler struct {
+	next slog.Handler
+}
+
+func (h *redactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *redactHandler) Handle(ctx context.Context, record slog.Record) error {
+	out := slog.NewRecord(record.Time, record.Level, RedactString(record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		out.AddAttrs(redactAttr(attr))
+		return true
+	})
+	return h.next.Handle(ctx, out)
+}
+
+func (h *redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redacted[i] = redactAttr(attr)
+	}
+	return &redactHandler{next: h.next.WithAttrs(redacted)}
+}
+
+func (h *redactHandler) WithGroup(name string) slog.Handler {
+	return &redactHandler{next: h.next.WithGroup(name)}
+}
+
+func redactAttr(attr slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(attr.Key)] {
+		return slog.String(attr.Key, validator.RedactionMarker)
+	}
+
+	value := attr.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindString:
+		value = slog.StringValue(RedactString(value.String()))
+	case slog.KindGroup:
+		group := value.Group()
+		redacted := make([]slog.Attr, len(group))
+		for i, a := range group {
+			redacted[i] = redactAttr(a)
+		}
+		value = slog.GroupValue(redacted...)
+	case slog.KindAny:
+		// Errors and arbitrary values are logged by their text, so the
+		// text is what gets redacted.
+		if err, ok := value.Any().(error); ok {
+			value = slog.StringValue(RedactString(err.Error()))
+		} else {
+			value = slog.StringValue(RedactString(fmt.Sprint(value.Any())))
+		}
+	}
+	return slog.Attr{Key: attr.Key, Value: value}
+}
+
+// discardHandler drops every record. slog.DiscardHandler needs Go 1.24.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
@@ -0,0 +1,200 @@
+// Package metrics exposes validation outcomes in the Prometheus text format.
+// Every label takes values from a fixed set, or from a capped set for
+// detector names, so a stream of unusual requests cannot create unbounded
+// series.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"promptsentinel/internal/validator"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// otherLabel replaces label values outside a metric's known set.
+const otherLabel = "other"
+
+// maxDetectors caps the distinct detector label values. The validator's
+// issue types are fixed in code, so the cap only matters if that changes.
+const maxDetectors = 64
+
+// collectTimeout bounds the queries run for gauges at each scrape.
+const collectTimeout = 5 * time.Second
+
+// Results of a result cache lookup.
+const (
+	CacheHit   = "hit"
+	CacheMiss  = "miss"
+	CacheError = "error"
+)
+
+// Label values the metrics accept as they are
+var (
+	knownSources    = []string{"check", "validate", "validate-output", "scan-document", "validate-tool-call", "redact", "job"}
+	knownOutcomes   = []string{validator.OutcomeAllowed, validator.OutcomeBlocked, validator.OutcomePendingApproval}
+	knownUseCases   = []string{"general", "educational", "business", "creative"}
+	knownSeverities = []string{"error", "warning", "info"}
+	knownLookups    = []string{CacheHit, CacheMiss, CacheError}
+)
+
+// Options configures the gauges, which are read at each scrape. A nil
+// function leaves its gauge out.
+type Options struct {
+	// ApprovalQueueDepth returns the number of pending approval tickets.
+	ApprovalQueueDepth func(ctx context.Context) (int, error)
+	// LoadConfig returns the policy whose rules are counted.
+	LoadConfig func() (*validator.Config, error)
+}
+
+// Metrics records validations. A nil *Metrics records nothing, so callers
+// need not check whether metrics are enabled.
+type Metrics struct {
+	registry    *prometheus.Registry
+	validations *prometheus.CounterVec
+	detections  *prometheus.CounterVec
+	score       *prometheus.HistogramVec
+	duration    *prometheus.HistogramVec
+	cache       *prometheus.CounterVec
+
+	mu        sync.Mutex
+	detectors map[string]bool
+}
+
+// New creates Metrics with its own registry, including the Go runtime and
+// process collectors.
+func New(opts Options) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		validations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "promptsentinel_validations_total",
+			Help: "Validations by source, outcome, and use case.",
+		}, []string{"source", "outcome", "use_case"}),
+		detections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "promptsentinel_detections_total",
+			Help: "Issues reported by detector and severity.",
+		}, []string{"detector", "severity"}),
+		score: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "promptsentinel_validation_score",
+			Help:    "Validation scores, from 0 to 100.",
+			Buckets: prometheus.LinearBuckets(0, 10, 11),
+		}, []string{"source"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "promptsentinel_validation_duration_seconds",
+			Help:    "Time spent validating, excluding storage.",
+			Buckets: prometheus.ExponentialBuckets(0.0001, 2, 16),
+		}, []string{"source"}),
+		cache: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "promptsentinel_result_cache_lookups_total",
+			Help: "Result cache lookups by source and result: hit, miss, or error.",
+		}, []string{"source", "result"}),
+		detectors: make(map[string]bool),
+	}
+
+	m.registry.MustRegister(
+		m.validations, m.detections, m.score, m.duration, m.cache,
+		&stateCollector{opts: opts},
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveValidation records one validation of source under useCase that
+// produced result and took elapsed.
+func (m *Metrics) ObserveValidation(source, useCase string, result *validator.ValidationResult, elapsed time.Duration) {
+	if m == nil || result == nil {
+		return
+	}
+
+	source = known(source, knownSources)
+	m.validations.WithLabelValues(source, known(result.Outcome, knownOutcomes), known(useCase, knownUseCases)).Inc()
+	m.score.WithLabelValues(source).Observe(float64(result.Score))
+	m.duration.WithLabelValues(source).Observe(elapsed.Seconds())
+
+	for _, issue := range result.Issues {
+		m.detections.WithLabelValues(m.detector(issue.Type), known(issue.Severity, knownSeverities)).Inc()
+	}
+}
+
+// ObserveCacheLookup records a result cache lookup for source that was a
+// CacheHit, CacheMiss, or CacheError.
+func (m *Metrics) ObserveCacheLookup(source, result string) {
+	if m == nil {
+		return
+	}
+	m.cache.WithLabelValues(known(source, knownSources), known(result, knownLookups)).Inc()
+}
+
+// detector returns name as a label value while fewer than maxDetectors
+// names have been seen
+func (m *Metrics) detector(name string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.detectors[name] {
+		return name
+	}
+	if len(m.detectors) >= maxDetectors {
+		return otherLabel
+	}
+	m.detectors[name] = true
+	return name
+}
+
+func known(value string, values []string) string {
+	for _, v := range values {
+		if v == value {
+			return value
+		}
+	}
+	return otherLabel
+}
+
+// stateCollector reports the gauges read from the store and policy. A
+// gauge whose source fails is left out of that scrape rather than reported
+// as zero.
+type stateCollector struct {
+	opts Options
+}
+
+var (
+	approvalQueueDesc = prometheus.NewDesc("promptsentinel_approval_queue_depth",
+		"Approval tickets waiting for a decision.", nil, nil)
+	rulesDesc = prometheus.NewDesc("promptsentinel_rules",
+		"Rules in the loaded policy, by kind.", []string{"kind"}, nil)
+)
+
+func (c *stateCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- approvalQueueDesc
+	ch <- rulesDesc
+}
+
+func (c *stateCollector) Collect(ch chan<- prometheus.Metric) {
+	if c.opts.ApprovalQueueDepth != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+		depth, err := c.opts.ApprovalQueueDepth(ctx)
+		cancel()
+		if err == nil {
+			ch <- prometheus.MustNewConstMetric(approvalQueueDesc, prometheus.GaugeValue, float64(depth))
+		}
+	}
+
+	if c.opts.LoadConfig != nil {
+		if config, err := c.opts.LoadConfig(); err == nil {
+			ch <- prometheus.MustNewConstMetric(rulesDesc, prometheus.GaugeValue, float64(len(config.BlockedPatterns)), "blocked_pattern")
+			ch <- prometheus.MustNewConstMetric(rulesDesc, prometheus.GaugeValue, float64(len(config.CustomRules)), "custom_rule")
+			ch <- prometheus.MustNewConstMetric(rulesDesc, prometheus.GaugeValue, float64(len(config.ToolPolicies)), "tool_policy")
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"promptsentinel/internal/validator"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestObserveValidation(t *testing.T) {
+	m := New(Options{})
+
+	result := &validator.ValidationResult{
+		Outcome: validator.OutcomeBlocked,
+		Score:   65,
+		Issues: []validator.ValidationIssue{
+			{Type: "injection", Severity: "error"},
+			{Type: "pattern", Severity: "warning"},
+			{Type: "pattern", Severity: "critical"},
+		},
+	}
+	m.ObserveValidation("check", "business", result, 3*time.Millisecond)
+	m.ObserveValidation("check", "made-up-use-case", result, time.Millisecond)
+	m.ObserveValidation("unknown-source", "general", &validator.ValidationResult{Outcome: "weird"}, time.Millisecond)
+
+	tests := []struct {
+		name   string
+		labels []string
+		want   float64
+	}{
+		{"known labels", []string{"check", validator.OutcomeBlocked, "business"}, 1},
+		{"unknown use case", []string{"check", validator.OutcomeBlocked, otherLabel}, 1},
+		{"unknown source and outcome", []string{otherLabel, otherLabel, "general"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := testutil.ToFloat64(m.validations.WithLabelValues(tt.labels...)); got != tt.want {
+				t.Errorf("validations%v = %v, want %v", tt.labels, got, tt.want)
+			}
+		})
+	}
+
+	if got := testutil.ToFloat64(m.detections.WithLabelValues("pattern", otherLabel)); got != 2 {
+		t.Errorf("Expected unknown severities to be counted as other, got %v", got)
+	}
+	if got := testutil.CollectAndCount(m.score); got != 2 {
+		t.Errorf("Expected one score series per source, got %d", got)
+	}
+}
+
+func TestDetectorCardinalityIsBounded(t *testing.T) {
+	m := New(Options{})
+	for i := 0; i < maxDetectors+10; i++ {
+		issue := validator.ValidationIssue{Type: fmt.Sprintf("detector_%d", i), Severity: "info"}
+		m.ObserveValidation("check", "general", &validator.ValidationResult{Issues: []validator.ValidationIssue{issue}}, 0)
+	}
+
+	if got := testutil.CollectAndCount(m.detections); got != maxDetectors+1 {
+		t.Errorf("Expected %d detector series, got %d", maxDetectors+1, got)
+	}
+	if got := testutil.ToFloat64(m.detections.WithLabelValues(otherLabel, "info")); got != 10 {
+		t.Errorf("Expected the overflow counted as other, got %v", got)
+	}
+}
+
+func TestGauges(t *testing.T) {
+	config := validator.DefaultConfig()
+	config.CustomRules = map[string]string{"ticket": `TICKET-\d+`}
+
+	m := New(Options{
+		ApprovalQueueDepth: func(ctx context.Context) (int, error) { return 3, nil },
+		LoadConfig:         func() (*validator.Config, error) { return config, nil },
+	})
+	expected := fmt.Sprintf(`
+# HELP promptsentinel_approval_queue_depth Approval tickets waiting for a decision.
+# TYPE promptsentinel_approval_queue_depth gauge
+promptsentinel_approval_queue_depth 3
+# HELP promptsentinel_rules Rules in the loaded policy, by kind.
+# TYPE promptsentinel_rules gauge
+promptsentinel_rules{kind="blocked_pattern"} %d
+promptsentinel_rules{kind="custom_rule"} 1
+promptsentinel_rules{kind="tool_policy"} 0
+`, len(config.BlockedPatterns))
+	if err := testutil.GatherAndCompare(m.registry, strings.NewReader(expected), "promptsentinel_approval_queue_depth", "promptsentinel_rules"); err != nil {
+		t.Error(err)
+	}
+
+	failing := New(Options{
+		ApprovalQueueDepth: func(ctx context.Context) (int, error) { return 0, errors.New("database down") },
+		LoadConfig:         func() (*validator.Config, error) { return nil, errors.New("bad config") },
+	})
+	if got, err := testutil.GatherAndCount(failing.registry, "promptsentinel_approval_queue_depth", "promptsentinel_rules"); err != nil || got != 0 {
+		t.Errorf("Expected failing gauges to be left out, got %d (%v)", got, err)
+	}
+}
+
+func TestObserveCacheLookup(t *testing.T) {
+	m := New(Options{})
+	m.ObserveCacheLookup("check", CacheHit)
+	m.ObserveCacheLookup("check", CacheHit)
+	m.ObserveCacheLookup("validate", CacheMiss)
+	m.ObserveCacheLookup("unknown-source", "stale")
+
+	expected := `
+# HELP promptsentinel_result_cache_lookups_total Result cache lookups by source and result: hit, miss, or error.
+# TYPE promptsentinel_result_cache_lookups_total counter
+promptsentinel_result_cache_lookups_total{result="hit",source="check"} 2
+promptsentinel_result_cache_lookups_total{result="miss",source="validate"} 1
+promptsentinel_result_cache_lookups_total{result="other",source="other"} 1
+`
+	if err := testutil.GatherAndCompare(m.registry, strings.NewReader(expected), "promptsentinel_result_cache_lookups_total"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	m := New(Options{})
+	m.ObserveValidation("validate", "general", &validator.ValidationResult{Outcome: validator.OutcomeAllowed, Score: 100}, time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		`promptsentinel_validations_total{outcome="allowed",source="validate",use_case="general"} 1`,
+		`promptsentinel_validation_score_bucket{source="validate",le="100"} 1`,
+		`promptsentinel_validation_duration_seconds_count{source="validate"} 1`,
+		`go_goroutines`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %q in the exposition", want)
+		}
+	}
+}
+
+func TestNilMetrics(t *testing.T) {
+	var m *Metrics
+	m.ObserveValidation("check", "general", &validator.ValidationResult{}, time.Millisecond)
+	m.ObserveCacheLookup("check", CacheHit)
+}
@@ -0,0 +1,87 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// Email sends each alert as a plain-text message through an SMTP server.
+// The server's STARTTLS is used when it offers it.
+type Email struct {
+	// Addr is the server's host:port.
+	Addr string
+	From string
+	To   []string
+	// Username and Password, when Username is set, authenticate with
+	// PLAIN, which net/smtp only allows over TLS or to localhost.
+	Username string
+	Password string
+
+	// send delivers a message; nil uses smtp.SendMail.
+	send func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// Name returns the server's address
+func (e *Email) Name() string {
+	return "email " + e.Addr
+}
+
+// Send mails alert to every recipient. smtp.SendMail cannot be cancelled,
+// so ctx is only checked before sending.
+func (e *Email) Send(ctx context.Context, alert Alert) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(e.To) == 0 {
+		return Permanent(errors.New("no recipients"))
+	}
+
+	var auth smtp.Auth
+	if e.Username != "" {
+		host, _, err := net.SplitHostPort(e.Addr)
+		if err != nil {
+			return Permanent(fmt.Errorf("smtp address: %w", err))
+		}
+		auth = smtp.PlainAuth("", e.Username, e.Password, host)
+	}
+
+	send := e.send
+	if send == nil {
+		send = smtp.SendMail
+	}
+	err := send(e.Addr, auth, e.From, e.To, e.message(alert))
+
+	// 5xx replies reject the message or the login outright.
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code >= 500 {
+		return Permanent(err)
+	}
+	return err
+}
+
+// message formats alert as an RFC 5322 message
+func (e *Email) message(alert Alert) []byte {
+	headers := []string{
+		"From: " + e.From,
+		"To: " + strings.Join(e.To, ", "),
+		"Subject: [PromptSentinel] " + headerSafe(alert.Summary()),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"Message-ID: <" + alert.ID + "@promptsentinel>",
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=utf-8",
+	}
+	body := strings.ReplaceAll(alert.Text(), "\n", "\r\n")
+	return []byte(strings.Join(headers, "\r\n") + "\r\n\r\n" + body + "\r\n")
+}
+
+// headerSafe removes line breaks, which would let a canary label inject
+// headers
+func headerSafe(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+func TestEmail(t *testing.T) {
+	var gotAddr, gotFrom string
+	var gotTo []string
+	var gotAuth smtp.Auth
+	var gotMsg []byte
+
+	sink := &Email{
+		Addr:     "localhost:2525",
+		From:     "sentinel@example.com",
+		To:       []string{"security@example.com", "oncall@example.com"},
+		Username: "sentinel",
+		Password: "hunter2",
+		send: func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
+			gotAddr, gotAuth, gotFrom, gotTo, gotMsg = addr, auth, from, to, msg
+			return nil
+		},
+	}
+
+	alert := testLeak("canary-1")
+	alert.ID = "alert-1"
+	alert.Canary.Label = "bot\r\nBcc: attacker@example.com"
+	if err := sink.Send(context.Background(), alert); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if gotAddr != "localhost:2525" || gotFrom != "sentinel@example.com" || len(gotTo) != 2 || gotAuth == nil {
+		t.Errorf("unexpected envelope: %s %s %v %v", gotAddr, gotFrom, gotTo, gotAuth)
+	}
+
+	msg := string(gotMsg)
+	headers, body, ok := strings.Cut(msg, "\r\n\r\n")
+	if !ok {
+		t.Fatalf("expected headers and a body, got %q", msg)
+	}
+	for _, want := range []string{"To: security@example.com, oncall@example.com", "Subject: [PromptSentinel] Canary", "Message-ID: <alert-1@promptsentinel>"} {
+		if !strings.Contains(headers, want) {
+			t.Errorf("expected header %q in %q", want, headers)
+		}
+	}
+	if strings.Contains(headers, "\r\nBcc:") {
+		t.Errorf("expected the label not to inject headers, got %q", headers)
+	}
+	if !strings.Contains(body, "Incident: incident-1\r\n") {
+		t.Errorf("expected the details with CRLF line endings, got %q", body)
+	}
+}
+
+func TestEmailErrors(t *testing.T) {
+	rejected := &Email{Addr: "localhost:25", From: "a@example.com", To: []string{"b@example.com"}, send: func(string, smtp.Auth, string, []string, []byte) error {
+		return &textproto.Error{Code: 550, Msg: "mailbox unavailable"}
+	}}
+	var permanent *permanentError
+	if err := rejected.Send(context.Background(), testLeak("canary-1")); !errors.As(err, &permanent) {
+		t.Errorf("expected a 5xx reply to fail permanently, got %v", err)
+	}
+
+	busy := &Email{Addr: "localhost:25", From: "a@example.com", To: []string{"b@example.com"}, send: func(string, smtp.Auth, string, []string, []byte) error {
+		return &textproto.Error{Code: 421, Msg: "try again later"}
+	}}
+	if err := busy.Send(context.Background(), testLeak("canary-1")); err == nil || errors.As(err, &permanent) {
+		t.Errorf("expected a 4xx reply to be retried, got %v", err)
+	}
+
+	if err := (&Email{Addr: "localhost:25"}).Send(context.Background(), testLeak("canary-1")); !errors.As(err, &permanent) {
+		t.Errorf("expected no recipients to fail permanently, got %v", err)
+	}
+}
@@ -0,0 +1,473 @@
+// Package notify tells a security team about high-risk prompts and canary
+// leaks as they happen. A Notifier delivers each Alert to every configured
+// Sink — a signed webhook, a Slack-compatible webhook, or email — in the
+// background, retrying failures with exponential backoff and dropping
+// repeats of an alert seen within the dedupe window.
+//
+// Alerts describe how a request was judged and which canary leaked, never
+// the prompt or response text, so they can go to chat and email safely.
+package notify
+
+import (
+	"context"
+	cryptorand "crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"promptsentinel/internal/logging"
+	"promptsentinel/internal/promptdb"
+	"promptsentinel/internal/validator"
+)
+
+// Alert kinds
+const (
+	KindHighRisk   = "high_risk"
+	KindCanaryLeak = "canary_leak"
+)
+
+// riskHigh is the risk level that raises an alert
+const riskHigh = "high"
+
+// Defaults for Options
+const (
+	DefaultMaxAttempts  = 5
+	DefaultBackoff      = time.Second
+	DefaultMaxBackoff   = time.Minute
+	DefaultDedupeWindow = 10 * time.Minute
+	DefaultMaxPending   = 64
+)
+
+// Alert is one notification. Risk is set for high-risk alerts and Canary
+// for canary leaks.
+type Alert struct {
+	// ID is the same on every attempt to deliver the alert, so receivers
+	// can ignore retries they already handled.
+	ID                string         `json:"id"`
+	Kind              string         `json:"kind"`
+	Time              time.Time      `json:"time"`
+	Source            string         `json:"source"`
+	OwnerID           string         `json:"owner_id"`
+	ValidationEventID string         `json:"validation_event_id,omitempty"`
+	Risk              *RiskDetails   `json:"risk,omitempty"`
+	Canary            *CanaryDetails `json:"canary,omitempty"`
+}
+
+// RiskDetails describes the validation behind a high-risk alert
+type RiskDetails struct {
+	UseCase   string `json:"use_case"`
+	RiskLevel string `json:"risk_level"`
+	Score     int    `json:"score"`
+	Outcome   string `json:"outcome"`
+	// Threats are the security analysis's threat categories.
+	Threats    []string `json:"threats"`
+	IssueTypes []string `json:"issue_types"`
+	// PromptFingerprint identifies the prompt in validation history
+	// without revealing it.
+	PromptFingerprint string `json:"prompt_fingerprint"`
+}
+
+// CanaryDetails names the canary behind a leak alert. The token itself is
+// never included.
+type CanaryDetails struct {
+	ID         string `json:"id"`
+	Label      string `json:"label"`
+	Location   string `json:"location"`
+	IncidentID string `json:"incident_id"`
+}
+
+// HighRisk returns an alert for a comprehensive validation whose security
+// analysis, or conversation analysis, rates the risk high, and false for
+// any other. event is the validation's history record.
+func HighRisk(event promptdb.ValidationEvent, result *validator.ComprehensiveValidationResult) (Alert, bool) {
+	high := result.SecurityAnalysis.RiskLevel == riskHigh
+	if analysis := result.ConversationAnalysis; analysis != nil && analysis.RiskLevel == riskHigh {
+		high = true
+	}
+	if !high {
+		return Alert{}, false
+	}
+
+	// The analysis repeats a threat for each pattern that found it.
+	threats := []string{}
+	for _, threat := range result.SecurityAnalysis.Threats {
+		if !slices.Contains(threats, threat) {
+			threats = append(threats, threat)
+		}
+	}
+	return Alert{
+		Kind:              KindHighRisk,
+		Time:              event.CreatedAt,
+		Source:            event.Source,
+		OwnerID:           event.OwnerID,
+		ValidationEventID: event.ID,
+		Risk: &RiskDetails{
+			UseCase:           event.UseCase,
+			RiskLevel:         riskHigh,
+			Score:             result.Score,
+			Outcome:           result.Outcome,
+			Threats:           threats,
+			IssueTypes:        event.IssueTypes,
+			PromptFingerprint: event.PromptFingerprint,
+		},
+	}, true
+}
+
+// CanaryLeak returns an alert for a leakage incident
+func CanaryLeak(incident promptdb.LeakageIncident) Alert {
+	return Alert{
+		Kind:              KindCanaryLeak,
+		Time:              incident.DetectedAt,
+		Source:            incident.Source,
+		OwnerID:           incident.OwnerID,
+		ValidationEventID: incident.ValidationEventID,
+		Canary: &CanaryDetails{
+			ID:         incident.CanaryID,
+			Label:      incident.Label,
+			Location:   incident.Location,
+			IncidentID: incident.ID,
+		},
+	}
+}
+
+// Summary describes the alert in one line
+func (a Alert) Summary() string {
+	switch {
+	case a.Canary != nil:
+		return fmt.Sprintf("Canary %q leaked in a %s via %s (owner %s)", a.Canary.Label, a.Canary.Location, a.Source, a.OwnerID)
+	case a.Risk != nil:
+		return fmt.Sprintf("High-risk prompt %s via %s (owner %s, score %d)", a.Risk.Outcome, a.Source, a.OwnerID, a.Risk.Score)
+	}
+	return fmt.Sprintf("%s alert via %s (owner %s)", a.Kind, a.Source, a.OwnerID)
+}
+
+// Text describes the alert in a few lines, for chat and email
+func (a Alert) Text() string {
+	lines := []string{a.Summary(), ""}
+	add := func(name, value string) {
+		if value != "" {
+			lines = append(lines, name+": "+value)
+		}
+	}
+
+	add("Time", a.Time.UTC().Format(time.RFC3339))
+	if a.Risk != nil {
+		add("Use case", a.Risk.UseCase)
+		add("Risk level", a.Risk.RiskLevel)
+		add("Threats", strings.Join(a.Risk.Threats, ", "))
+		add("Issues", strings.Join(a.Risk.IssueTypes, ", "))
+		add("Prompt fingerprint", a.Risk.PromptFingerprint)
+	}
+	if a.Canary != nil {
+		add("Canary", a.Canary.ID)
+		add("Incident", a.Canary.IncidentID)
+	}
+	add("Validation event", a.ValidationEventID)
+	add("Alert", a.ID)
+	return strings.Join(lines, "\n")
+}
+
+// dedupeKey identifies repeats of an alert: the same prompt from the same
+// owner, or the same canary leaking in the same place
+func (a Alert) dedupeKey() string {
+	switch {
+	case a.Canary != nil:
+		return a.Kind + "/" + a.Canary.ID + "/" + a.Canary.Location
+	case a.Risk != nil:
+		return a.Kind + "/" + a.OwnerID + "/" + a.Risk.PromptFingerprint
+	}
+	return a.Kind + "/" + a.ID
+}
+
+// Sink delivers alerts to one destination.
+type Sink interface {
+	// Name identifies the sink in logs without revealing credentials.
+	Name() string
+	// Send delivers alert once. Errors wrapped with Permanent are not
+	// retried.
+	Send(ctx context.Context, alert Alert) error
+}
+
+// permanentError marks a failure that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the Notifier gives up on the delivery instead of
+// retrying it.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// retryAfterError asks for the next attempt to wait at least after
+type retryAfterError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *retryAfterError) Error() string { return e.err.Error() }
+func (e *retryAfterError) Unwrap() error { return e.err }
+
+// Options configures a Notifier. Zero values use the defaults.
+type Options struct {
+	Sinks []Sink
+	// MaxAttempts is how many times each sink is tried per alert.
+	MaxAttempts int
+	// Backoff is the wait before the first retry. It doubles with each
+	// retry, with jitter, up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// DedupeWindow is how long an alert suppresses its repeats. A negative
+	// window sends every alert.
+	DedupeWindow time.Duration
+	// MaxPending bounds the deliveries in progress. Alerts arriving while
+	// it is reached are dropped and logged.
+	MaxPending int
+	// Logger receives delivery failures and drops.
+	Logger *slog.Logger
+}
+
+// Notifier delivers alerts to its sinks in the background. A nil
+// *Notifier drops every alert, so callers need not check whether
+// notifications are enabled.
+type Notifier struct {
+	opts    Options
+	logger  *slog.Logger
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	pending chan struct{}
+
+	mu     sync.Mutex
+	closed bool
+	seen   map[string]time.Time
+	now    func() time.Time
+}
+
+// New returns a Notifier delivering to opts.Sinks. Close it to wait for
+// deliveries in progress.
+func New(opts Options) *Notifier {
+	if opts.MaxAttempts < 1 {
+		opts.MaxAttempts = DefaultMaxAttempts
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = DefaultBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = DefaultMaxBackoff
+	}
+	if opts.DedupeWindow == 0 {
+		opts.DedupeWindow = DefaultDedupeWindow
+	}
+	if opts.MaxPending < 1 {
+		opts.MaxPending = DefaultMaxPending
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Notifier{
+		opts:    opts,
+		logger:  logging.Redact(opts.Logger),
+		ctx:     ctx,
+		cancel:  cancel,
+		pending: make(chan struct{}, opts.MaxPending),
+		seen:    make(map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+// Notify queues alert for every sink and returns without waiting. It
+// reports whether the alert was queued: repeats within the dedupe window,
+// alerts after Close, and alerts over MaxPending are not. An alert counts
+// towards the dedupe window once it is queued, until every delivery of it
+// fails.
+func (n *Notifier) Notify(alert Alert) bool {
+	if n == nil {
+		return false
+	}
+
+	if alert.ID == "" {
+		alert.ID = newAlertID()
+	}
+	if alert.Time.IsZero() {
+		alert.Time = n.now()
+	}
+	alert.Time = alert.Time.UTC()
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	key, now := alert.dedupeKey(), n.now()
+	if n.closed || n.duplicate(key, now) {
+		return false
+	}
+
+	var queued []Sink
+	for _, sink := range n.opts.Sinks {
+		select {
+		case n.pending <- struct{}{}:
+			queued = append(queued, sink)
+		default:
+			n.logger.Warn("dropped notification", "alert", alert.ID, "kind", alert.Kind, "sink", sink.Name(), "reason", "too many pending")
+		}
+	}
+	if len(queued) == 0 {
+		return false
+	}
+
+	n.remember(key, now)
+	outcome := &deliveries{remaining: len(queued)}
+	for _, sink := range queued {
+		n.wg.Add(1)
+		go func(sink Sink) {
+			defer n.wg.Done()
+			defer func() { <-n.pending }()
+			if outcome.done(n.deliver(sink, alert)) {
+				n.forget(key, now)
+			}
+		}(sink)
+	}
+	return true
+}
+
+// deliveries tracks the deliveries of one alert in progress
+type deliveries struct {
+	mu        sync.Mutex
+	remaining int
+	delivered bool
+}
+
+// done records the result of one delivery, reporting whether it was the
+// last and none succeeded
+func (d *deliveries) done(delivered bool) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.remaining--
+	d.delivered = d.delivered || delivered
+	return d.remaining == 0 && !d.delivered
+}
+
+// duplicate reports whether key repeats an alert seen within the dedupe
+// window, expiring older ones. n.mu must be held.
+func (n *Notifier) duplicate(key string, now time.Time) bool {
+	if n.opts.DedupeWindow < 0 {
+		return false
+	}
+
+	for seenKey, seen := range n.seen {
+		if now.Sub(seen) >= n.opts.DedupeWindow {
+			delete(n.seen, seenKey)
+		}
+	}
+	_, ok := n.seen[key]
+	return ok
+}
+
+// remember starts the dedupe window for key. n.mu must be held.
+func (n *Notifier) remember(key string, now time.Time) {
+	if n.opts.DedupeWindow >= 0 {
+		n.seen[key] = now
+	}
+}
+
+// forget ends the dedupe window for key started at seen, so a repeat of an
+// alert that was never delivered is sent. A later window is left alone.
+func (n *Notifier) forget(key string, seen time.Time) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if at, ok := n.seen[key]; ok && at.Equal(seen) {
+		delete(n.seen, key)
+	}
+}
+
+// deliver sends alert to sink, retrying until it succeeds, fails
+// permanently, runs out of attempts, or the Notifier is closed. It reports
+// whether the alert was delivered.
+func (n *Notifier) deliver(sink Sink, alert Alert) bool {
+	var err error
+	for attempt := 1; attempt <= n.opts.MaxAttempts; attempt++ {
+		if err = sink.Send(n.ctx, alert); err == nil {
+			n.logger.Info("sent notification", "alert", alert.ID, "kind", alert.Kind, "sink", sink.Name(), "attempts", attempt)
+			return true
+		}
+
+		var permanent *permanentError
+		if errors.As(err, &permanent) || attempt == n.opts.MaxAttempts {
+			break
+		}
+
+		n.logger.Debug("retrying notification", "alert", alert.ID, "sink", sink.Name(), "attempt", attempt, "error", err)
+		timer := time.NewTimer(n.backoff(attempt, err))
+		select {
+		case <-timer.C:
+		case <-n.ctx.Done():
+			timer.Stop()
+			n.logger.Error("abandoned notification", "alert", alert.ID, "kind", alert.Kind, "sink", sink.Name(), "error", err)
+			return false
+		}
+	}
+	n.logger.Error("notification failed", "alert", alert.ID, "kind", alert.Kind, "sink", sink.Name(), "error", err)
+	return false
+}
+
+// backoff returns the wait after a failed attempt: Backoff doubled per
+// attempt, plus up to half again as jitter, capped at MaxBackoff. A sink
+// asking for a longer wait gets it, within the cap.
+func (n *Notifier) backoff(attempt int, err error) time.Duration {
+	delay := n.opts.Backoff << (attempt - 1)
+	if delay <= 0 || delay > n.opts.MaxBackoff {
+		delay = n.opts.MaxBackoff
+	}
+	delay += rand.N(delay/2 + 1)
+
+	var retryAfter *retryAfterError
+	if errors.As(err, &retryAfter) && retryAfter.after > delay {
+		delay = retryAfter.after
+	}
+	return min(delay, n.opts.MaxBackoff)
+}
+
+// Close stops accepting alerts and waits for deliveries in progress, or
+// until ctx is done, when retries still waiting are abandoned.
+func (n *Notifier) Close(ctx context.Context) error {
+	if n == nil {
+		return nil
+	}
+
+	n.mu.Lock()
+	n.closed = true
+	n.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		n.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		n.cancel()
+		return nil
+	case <-ctx.Done():
+		n.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// newAlertID returns a random alert ID
+func newAlertID() string {
+	b := make([]byte, 16)
+	if _, err := cryptorand.Read(b); err != nil {
+		// crypto/rand does not fail on supported platforms.
+		panic(fmt.Sprintf("generate alert id: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,280 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"promptsentinel/internal/promptdb"
+	"promptsentinel/internal/validator"
+)
+
+// stubSink records the alerts sent to it, failing the first failures
+// attempts with err
+type stubSink struct {
+	mu       sync.Mutex
+	failures int
+	err      error
+	attempts int
+	sent     []Alert
+}
+
+func (s *stubSink) Name() string { return "stub" }
+
+func (s *stubSink) Send(ctx context.Context, alert Alert) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts++
+	if s.attempts <= s.failures {
+		return s.err
+	}
+	s.sent = append(s.sent, alert)
+	return nil
+}
+
+func newTestNotifier(sinks ...Sink) *Notifier {
+	return New(Options{Sinks: sinks, MaxAttempts: 3, Backoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond})
+}
+
+func closeNotifier(t *testing.T, n *Notifier) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := n.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func testLeak(canaryID string) Alert {
+	return CanaryLeak(promptdb.LeakageIncident{
+		ID:         "incident-1",
+		DetectedAt: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+		CanaryID:   canaryID,
+		Label:      "support bot v3",
+		Source:     "validate-output",
+		Location:   promptdb.LeakLocationResponse,
+		OwnerID:    "team-x",
+	})
+}
+
+func TestHighRisk(t *testing.T) {
+	event := promptdb.ValidationEvent{ID: "event-1", Source: "validate", OwnerID: "team-x", UseCase: "general", IssueTypes: []string{"pattern"}, PromptFingerprint: promptdb.PromptFingerprint("drop table users")}
+
+	tests := []struct {
+		name     string
+		security string
+		turns    string
+		want     bool
+	}{
+		{"high security risk", "high", "", true},
+		{"high conversation risk", "low", "high", true},
+		{"medium risk", "medium", "medium", false},
+		{"low risk", "low", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &validator.ComprehensiveValidationResult{SecurityAnalysis: validator.SecurityAnalysis{
+				RiskLevel: tt.security,
+				Threats:   []string{"Potential injection attempt", "Potential injection attempt"},
+			}}
+			result.Score = 40
+			result.Outcome = validator.OutcomeBlocked
+			if tt.turns != "" {
+				result.ConversationAnalysis = &validator.ConversationAnalysis{RiskLevel: tt.turns}
+			}
+
+			alert, ok := HighRisk(event, result)
+			if ok != tt.want {
+				t.Fatalf("expected %t, got %t", tt.want, ok)
+			}
+			if !ok {
+				return
+			}
+			if alert.Kind != KindHighRisk || alert.ValidationEventID != "event-1" || alert.Risk.RiskLevel != "high" || alert.Risk.Score != 40 {
+				t.Errorf("unexpected alert: %#v", alert)
+			}
+			if len(alert.Risk.Threats) != 1 {
+				t.Errorf("expected repeated threats to be listed once, got %v", alert.Risk.Threats)
+			}
+		})
+	}
+}
+
+func TestAlertText(t *testing.T) {
+	alert := testLeak("canary-1")
+	alert.ID = "alert-1"
+
+	text := alert.Text()
+	for _, want := range []string{`Canary "support bot v3" leaked in a response via validate-output (owner team-x)`, "Canary: canary-1", "Incident: incident-1", "Alert: alert-1", "Time: 2025-01-01T12:00:00Z"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in %s", want, text)
+		}
+	}
+}
+
+func TestNotifierRetries(t *testing.T) {
+	flaky := &stubSink{failures: 2, err: errors.New("connection refused")}
+	broken := &stubSink{failures: 5, err: Permanent(errors.New("bad request"))}
+	n := newTestNotifier(flaky, broken)
+
+	if !n.Notify(testLeak("canary-1")) {
+		t.Fatal("expected the alert to be queued")
+	}
+	closeNotifier(t, n)
+
+	if flaky.attempts != 3 || len(flaky.sent) != 1 {
+		t.Errorf("expected delivery on the third attempt, got %d attempts and %d sent", flaky.attempts, len(flaky.sent))
+	}
+	if flaky.sent[0].ID == "" || flaky.sent[0].Time.IsZero() {
+		t.Errorf("expected the alert to get an ID and keep its time, got %#v", flaky.sent[0])
+	}
+	if broken.attempts != 1 || len(broken.sent) != 0 {
+		t.Errorf("expected a permanent failure to stop retries, got %d attempts", broken.attempts)
+	}
+}
+
+func TestNotifierGivesUp(t *testing.T) {
+	down := &stubSink{failures: 10, err: errors.New("timeout")}
+	n := newTestNotifier(down)
+
+	n.Notify(testLeak("canary-1"))
+	closeNotifier(t, n)
+
+	if down.attempts != 3 {
+		t.Errorf("expected MaxAttempts attempts, got %d", down.attempts)
+	}
+}
+
+func TestNotifierDedupe(t *testing.T) {
+	sink := &stubSink{}
+	n := newTestNotifier(sink)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	n.now = func() time.Time { return now }
+
+	if !n.Notify(testLeak("canary-1")) {
+		t.Fatal("expected the first alert to be queued")
+	}
+	if n.Notify(testLeak("canary-1")) {
+		t.Error("expected a repeat within the window to be dropped")
+	}
+	if !n.Notify(testLeak("canary-2")) {
+		t.Error("expected a different canary to be queued")
+	}
+
+	now = now.Add(DefaultDedupeWindow)
+	if !n.Notify(testLeak("canary-1")) {
+		t.Error("expected a repeat after the window to be queued")
+	}
+	closeNotifier(t, n)
+
+	if len(sink.sent) != 3 {
+		t.Errorf("expected 3 alerts sent, got %d", len(sink.sent))
+	}
+	if n.Notify(testLeak("canary-3")) {
+		t.Error("expected alerts after Close to be dropped")
+	}
+}
+
+func TestNotifierDedupeSkipsUndelivered(t *testing.T) {
+	t.Run("all deliveries fail", func(t *testing.T) {
+		down := &stubSink{failures: 1, err: Permanent(errors.New("bad request"))}
+		n := newTestNotifier(down)
+
+		if !n.Notify(testLeak("canary-1")) {
+			t.Fatal("expected the first alert to be queued")
+		}
+		n.wg.Wait()
+		if !n.Notify(testLeak("canary-1")) {
+			t.Error("expected a repeat of an undelivered alert to be queued")
+		}
+		closeNotifier(t, n)
+
+		if len(down.sent) != 1 {
+			t.Errorf("expected the repeat to be delivered, got %d sent", len(down.sent))
+		}
+	})
+
+	t.Run("one delivery succeeds", func(t *testing.T) {
+		down := &stubSink{failures: 10, err: Permanent(errors.New("bad request"))}
+		n := newTestNotifier(down, &stubSink{})
+
+		n.Notify(testLeak("canary-1"))
+		n.wg.Wait()
+		if n.Notify(testLeak("canary-1")) {
+			t.Error("expected a repeat of a delivered alert to be dropped")
+		}
+		closeNotifier(t, n)
+	})
+
+	t.Run("nothing queued", func(t *testing.T) {
+		n := New(Options{Sinks: []Sink{&stubSink{}}, MaxPending: 1})
+		n.pending <- struct{}{}
+
+		if n.Notify(testLeak("canary-1")) {
+			t.Fatal("expected the alert to be dropped while MaxPending is reached")
+		}
+		<-n.pending
+		if !n.Notify(testLeak("canary-1")) {
+			t.Error("expected a repeat of a dropped alert to be queued")
+		}
+		closeNotifier(t, n)
+	})
+}
+
+func TestNotifierDedupeDisabled(t *testing.T) {
+	sink := &stubSink{}
+	n := New(Options{Sinks: []Sink{sink}, DedupeWindow: -1})
+
+	n.Notify(testLeak("canary-1"))
+	n.Notify(testLeak("canary-1"))
+	closeNotifier(t, n)
+
+	if len(sink.sent) != 2 {
+		t.Errorf("expected both alerts sent, got %d", len(sink.sent))
+	}
+}
+
+func TestNotifierCloseAbandonsRetries(t *testing.T) {
+	down := &stubSink{failures: 10, err: errors.New("timeout")}
+	n := New(Options{Sinks: []Sink{down}, Backoff: time.Hour, MaxBackoff: time.Hour})
+	n.Notify(testLeak("canary-1"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := n.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected Close to give up at the deadline, got %v", err)
+	}
+	if down.attempts != 1 {
+		t.Errorf("expected the waiting retry to be abandoned, got %d attempts", down.attempts)
+	}
+}
+
+func TestNilNotifier(t *testing.T) {
+	var n *Notifier
+	if n.Notify(testLeak("canary-1")) {
+		t.Error("expected a nil notifier to drop alerts")
+	}
+	if err := n.Close(context.Background()); err != nil {
+		t.Errorf("expected Close on nil to succeed, got %v", err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	n := New(Options{Backoff: time.Second, MaxBackoff: 10 * time.Second})
+
+	for attempt, base := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second} {
+		if got := n.backoff(attempt, errors.New("failed")); got < base || got > base+base/2 {
+			t.Errorf("attempt %d: expected %v plus up to half again, got %v", attempt, base, got)
+		}
+	}
+	if got := n.backoff(10, errors.New("failed")); got != 10*time.Second {
+		t.Errorf("expected the cap, got %v", got)
+	}
+	if got := n.backoff(1, &retryAfterError{err: errors.New("busy"), after: 8 * time.Second}); got != 8*time.Second {
+		t.Errorf("expected Retry-After to be honored, got %v", got)
+	}
+}
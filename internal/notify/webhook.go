@@ -0,0 +1,192 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers sent with webhook deliveries
+const (
+	HeaderSignature = "X-PromptSentinel-Signature"
+	HeaderDelivery  = "X-PromptSentinel-Delivery"
+)
+
+// DefaultSignatureTolerance is how old a signed delivery VerifySignature
+// accepts by default.
+const DefaultSignatureTolerance = 5 * time.Minute
+
+// webhookTimeout bounds each delivery attempt
+const webhookTimeout = 10 * time.Second
+
+// Webhook posts each alert as JSON to a URL, signed with HMAC-SHA256 when a
+// secret is set. The signature header has the form
+//
+//	X-PromptSentinel-Signature: t=<unix seconds>,v1=<hex HMAC of "<t>.<body>">
+//
+// so a receiver can reject forged and replayed deliveries with
+// VerifySignature.
+type Webhook struct {
+	URL    string
+	Secret string
+	// Client sends the requests. Nil uses a client with a ten second
+	// timeout.
+	Client *http.Client
+}
+
+// Name returns the webhook's host; paths and queries can hold tokens.
+func (w *Webhook) Name() string {
+	return "webhook " + hostOf(w.URL)
+}
+
+// Send posts alert to the webhook
+func (w *Webhook) Send(ctx context.Context, alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return Permanent(fmt.Errorf("encode alert: %w", err))
+	}
+
+	header := http.Header{}
+	header.Set(HeaderDelivery, alert.ID)
+	if w.Secret != "" {
+		header.Set(HeaderSignature, Sign(w.Secret, time.Now(), body))
+	}
+	return post(ctx, w.Client, w.URL, header, body)
+}
+
+// Slack posts each alert as a message to a Slack incoming webhook, or any
+// service that accepts the same {"text": ...} payload.
+type Slack struct {
+	URL    string
+	Client *http.Client
+}
+
+// Name returns the webhook's host; Slack webhook paths are secrets.
+func (s *Slack) Name() string {
+	return "slack " + hostOf(s.URL)
+}
+
+// Send posts alert to the Slack webhook
+func (s *Slack) Send(ctx context.Context, alert Alert) error {
+	summary, details, _ := strings.Cut(alert.Text(), "\n\n")
+	body, err := json.Marshal(map[string]string{"text": ":rotating_light: *" + summary + "*\n" + details})
+	if err != nil {
+		return Permanent(fmt.Errorf("encode alert: %w", err))
+	}
+	return post(ctx, s.Client, s.URL, http.Header{}, body)
+}
+
+// post sends body to rawURL as JSON. Responses that retrying cannot change,
+// such as 400 or 404, fail permanently; 408, 429, and 5xx may be retried,
+// no sooner than a Retry-After header asks.
+func post(ctx context.Context, client *http.Client, rawURL string, header http.Header, body []byte) error {
+	if client == nil {
+		client = &http.Client{Timeout: webhookTimeout}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return Permanent(fmt.Errorf("build request: %w", redactURLError(err)))
+	}
+	req.Header = header
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "promptsentinel-notify")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return redactURLError(err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	err = fmt.Errorf("unexpected status %s", resp.Status)
+	switch {
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		if seconds, convErr := strconv.Atoi(resp.Header.Get("Retry-After")); convErr == nil && seconds > 0 {
+			return &retryAfterError{err: err, after: time.Duration(seconds) * time.Second}
+		}
+		return err
+	default:
+		return Permanent(err)
+	}
+}
+
+// redactURLError drops the URL from an HTTP client error, since webhook
+// URLs can carry credentials
+func redactURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("%s request: %w", urlErr.Op, urlErr.Err)
+	}
+	return err
+}
+
+// hostOf returns the host of rawURL, or "(invalid URL)"
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "(invalid URL)"
+	}
+	return u.Host
+}
+
+// Sign returns the signature header value for a delivery of body at t.
+func Sign(secret string, t time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + timestamp + ",v1=" + signature(secret, timestamp, body)
+}
+
+func signature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks a delivery's signature header against its body,
+// rejecting deliveries signed more than tolerance from now. Receivers
+// written in Go can use it as is; others follow the format on Webhook.
+func VerifySignature(secret, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var timestamp, sig string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			sig = value
+		}
+	}
+	if timestamp == "" || sig == "" {
+		return errors.New("malformed signature header")
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("malformed signature timestamp")
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); age > tolerance || age < -tolerance {
+		return errors.New("signature timestamp outside tolerance")
+	}
+
+	if !hmac.Equal([]byte(sig), []byte(signature(secret, timestamp, body))) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookStub records the requests it receives and answers each with the
+// next of statuses, then 200
+type webhookStub struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newWebhookStub(t *testing.T, statuses ...int) *webhookStub {
+	t.Helper()
+
+	stub := &webhookStub{statuses: statuses}
+	stub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		stub.mu.Lock()
+		stub.requests = append(stub.requests, r)
+		stub.bodies = append(stub.bodies, body)
+		status := http.StatusOK
+		if len(stub.statuses) > 0 {
+			status, stub.statuses = stub.statuses[0], stub.statuses[1:]
+		}
+		stub.mu.Unlock()
+
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "1")
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(stub.Close)
+	return stub
+}
+
+func TestWebhookSignsDeliveries(t *testing.T) {
+	stub := newWebhookStub(t)
+	const secret = "whsec-test"
+	alert := testLeak("canary-1")
+	alert.ID = "alert-1"
+
+	sink := &Webhook{URL: stub.URL + "/hooks/security?token=abc", Secret: secret}
+	if err := sink.Send(context.Background(), alert); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	req, body := stub.requests[0], stub.bodies[0]
+	if req.Method != http.MethodPost || req.Header.Get("Content-Type") != "application/json" || req.Header.Get(HeaderDelivery) != "alert-1" {
+		t.Errorf("unexpected request: %s %v", req.Method, req.Header)
+	}
+	if err := VerifySignature(secret, req.Header.Get(HeaderSignature), body, time.Now(), DefaultSignatureTolerance); err != nil {
+		t.Errorf("expected a valid signature, got %v", err)
+	}
+
+	var decoded Alert
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		t.Fatalf("body is not an alert: %v", err)
+	}
+	if decoded.Kind != KindCanaryLeak || decoded.Canary.Label != "support bot v3" {
+		t.Errorf("unexpected payload: %s", body)
+	}
+	if strings.Contains(sink.Name(), "token") || strings.Contains(sink.Name(), "hooks") {
+		t.Errorf("expected the name to leave out the path and query, got %q", sink.Name())
+	}
+
+	unsigned := &Webhook{URL: stub.URL}
+	if err := unsigned.Send(context.Background(), alert); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if stub.requests[1].Header.Get(HeaderSignature) != "" {
+		t.Error("expected no signature without a secret")
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	body := []byte(`{"id":"alert-1"}`)
+	now := time.Unix(1700000000, 0)
+	header := Sign("secret", now, body)
+
+	tests := []struct {
+		name    string
+		secret  string
+		header  string
+		body    []byte
+		now     time.Time
+		wantErr bool
+	}{
+		{"valid", "secret", header, body, now, false},
+		{"wrong secret", "other", header, body, now, true},
+		{"altered body", "secret", header, []byte(`{"id":"alert-2"}`), now, true},
+		{"replayed", "secret", header, body, now.Add(DefaultSignatureTolerance + time.Second), true},
+		{"malformed", "secret", "sha256=abc", body, now, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifySignature(tt.secret, tt.header, tt.body, tt.now, DefaultSignatureTolerance)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifySignature error = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebhookStatuses(t *testing.T) {
+	tests := []struct {
+		status     int
+		permanent  bool
+		retryAfter bool
+	}{
+		{http.StatusBadRequest, true, false},
+		{http.StatusNotFound, true, false},
+		{http.StatusTooManyRequests, false, true},
+		{http.StatusServiceUnavailable, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			stub := newWebhookStub(t, tt.status)
+			err := (&Webhook{URL: stub.URL}).Send(context.Background(), testLeak("canary-1"))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+
+			var permanent *permanentError
+			if errors.As(err, &permanent) != tt.permanent {
+				t.Errorf("expected permanent %t, got %v", tt.permanent, err)
+			}
+			var retryAfter *retryAfterError
+			if errors.As(err, &retryAfter) != tt.retryAfter {
+				t.Errorf("expected Retry-After %t, got %v", tt.retryAfter, err)
+			}
+		})
+	}
+}
+
+func TestWebhookErrorHidesURL(t *testing.T) {
+	stub := newWebhookStub(t)
+	stub.Close()
+
+	err := (&Webhook{URL: stub.URL + "/hook?token=s3cret"}).Send(context.Background(), testLeak("canary-1"))
+	if err == nil {
+		t.Fatal("expected an error from a closed server")
+	}
+	if strings.Contains(err.Error(), "s3cret") {
+		t.Errorf("expected the URL to stay out of the error, got %v", err)
+	}
+}
+
+func TestSlack(t *testing.T) {
+	stub := newWebhookStub(t)
+	alert := testLeak("canary-1")
+	alert.ID = "alert-1"
+
+	if err := (&Slack{URL: stub.URL + "/services/T000/B000/XXXX"}).Send(context.Background(), alert); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	var payload map[string]string
+	if err := json.Unmarshal(stub.bodies[0], &payload); err != nil {
+		t.Fatalf("payload is not JSON: %v", err)
+	}
+	text := payload["text"]
+	if !strings.HasPrefix(text, `:rotating_light: *Canary "support bot v3" leaked`) || !strings.Contains(text, "\nIncident: incident-1") {
+		t.Errorf("unexpected Slack text: %q", text)
+	}
+}
+
+func TestNotifierWithWebhook(t *testing.T) {
+	stub := newWebhookStub(t, http.StatusBadGateway, http.StatusServiceUnavailable)
+	n := newTestNotifier(&Webhook{URL: stub.URL, Secret: "secret"})
+
+	n.Notify(testLeak("canary-1"))
+	closeNotifier(t, n)
+
+	if len(stub.requests) != 3 {
+		t.Fatalf("expected 3 attempts, got %d", len(stub.requests))
+	}
+	if first, last := stub.requests[0].Header.Get(HeaderDelivery), stub.requests[2].Header.Get(HeaderDelivery); first == "" || first != last {
+		t.Errorf("expected retries to share a delivery ID, got %q and %q", first, last)
+	}
+}
@@ -0,0 +1,181 @@
+package promptdb
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"promptsentinel/internal/auth"
+)
+
+var (
+	// ErrAPIKeyNotFound is returned when no active key matches a prefix.
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	// ErrMalformedAPIKey is returned by AuthenticateAPIKey for a credential
+	// too short to be a key, including a missing one.
+	ErrMalformedAPIKey = errors.New("malformed API key")
+)
+
+// ScopeReviewer lets a key list and decide every owner's approval tickets.
+// Keys without it only validate prompts and read their own tickets.
+const ScopeReviewer = "reviewer"
+
+// knownScopes are the scopes a key may be created with.
+var knownScopes = map[string]bool{ScopeReviewer: true}
+
+// HasScope reports whether the key was granted scope.
+func (r APIKeyRecord) HasScope(scope string) bool {
+	for _, granted := range r.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyInfo is the non-secret view of an api_keys row, safe to display.
+type APIKeyInfo struct {
+	Prefix    string     `json:"prefix"`
+	OwnerID   string     `json:"owner_id"`
+	Scopes    []string   `json:"scopes,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+const (
+	revokeAPIKeyQuery = `UPDATE api_keys SET revoked_at = $1 WHERE key_prefix = $2 AND revoked_at IS NULL`
+	listAPIKeysQuery  = `SELECT key_prefix, owner_id, scopes, created_at, revoked_at FROM api_keys ORDER BY created_at, key_prefix`
+	lookupAPIKeyQuery = `SELECT key_prefix, key_hash, owner_id, scopes FROM api_keys WHERE key_prefix = $1 AND revoked_at IS NULL`
+)
+
+// CreateAPIKey inserts record and audits the creation in one transaction.
+// actor identifies who created the key.
+func (s *Store) CreateAPIKey(ctx context.Context, record APIKeyRecord, actor string) error {
+	if err := record.validate(); err != nil {
+		return err
+	}
+
+	entry := AuditEntry{
+		Actor:   actor,
+		Action:  AuditActionKeyCreated,
+		Subject: record.Prefix,
+		Details: NewAuditDetails(map[string]any{"owner_id": record.OwnerID, "scopes": record.Scopes}),
+	}
+
+	_, err := s.withAudit(ctx, entry, func(tx *sql.Tx) error {
+		return InsertAPIKey(ctx, tx, record)
+	})
+	if err != nil {
+		return fmt.Errorf("create api key: %w", err)
+	}
+	return nil
+}
+
+// RevokeAPIKey marks the active key with prefix as revoked and audits the
+// change. It returns ErrAPIKeyNotFound when no active key matches.
+func (s *Store) RevokeAPIKey(ctx context.Context, prefix, actor string) error {
+	entry := AuditEntry{
+		Actor:   actor,
+		Action:  AuditActionKeyRevoked,
+		Subject: prefix,
+	}
+
+	_, err := s.withAudit(ctx, entry, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, revokeAPIKeyQuery, time.Now().UTC(), prefix)
+		if err != nil {
+			return err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return ErrAPIKeyNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("revoke api key: %w", err)
+	}
+	return nil
+}
+
+// LookupAPIKey returns the active key stored under prefix. Revoked and
+// unknown keys both return ErrAPIKeyNotFound so callers cannot tell them
+// apart.
+func (s *Store) LookupAPIKey(ctx context.Context, prefix string) (APIKeyRecord, error) {
+	var record APIKeyRecord
+	var scopes string
+	err := s.db.QueryRowContext(ctx, lookupAPIKeyQuery, prefix).Scan(&record.Prefix, &record.Hash, &record.OwnerID, &scopes)
+	if errors.Is(err, sql.ErrNoRows) {
+		return APIKeyRecord{}, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return APIKeyRecord{}, fmt.Errorf("lookup api key: %w", err)
+	}
+	record.Scopes = splitScopes(scopes)
+	return record, nil
+}
+
+// AuthenticateAPIKey resolves a raw API key, as a client sent it, to its
+// active record. A key whose hash does not match is reported as
+// ErrAPIKeyNotFound, like an unknown or revoked one.
+func (s *Store) AuthenticateAPIKey(ctx context.Context, raw string) (APIKeyRecord, error) {
+	key, err := auth.NewAPIKey(raw)
+	if err != nil {
+		return APIKeyRecord{}, ErrMalformedAPIKey
+	}
+
+	record, err := s.LookupAPIKey(ctx, key.Prefix(auth.LookupPrefixLength))
+	if err != nil {
+		return APIKeyRecord{}, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(record.Hash), []byte(key.Hash())) != 1 {
+		return APIKeyRecord{}, ErrAPIKeyNotFound
+	}
+	return record, nil
+}
+
+// ListAPIKeys returns every key, oldest first, without their hashes.
+func (s *Store) ListAPIKeys(ctx context.Context) ([]APIKeyInfo, error) {
+	rows, err := s.db.QueryContext(ctx, listAPIKeysQuery)
+	if err != nil {
+		return nil, fmt.Errorf("list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []APIKeyInfo
+	for rows.Next() {
+		var info APIKeyInfo
+		var scopes string
+		var revokedAt sql.NullTime
+		if err := rows.Scan(&info.Prefix, &info.OwnerID, &scopes, &info.CreatedAt, &revokedAt); err != nil {
+			return nil, fmt.Errorf("scan api key: %w", err)
+		}
+		info.Scopes = splitScopes(scopes)
+		if revokedAt.Valid {
+			info.RevokedAt = &revokedAt.Time
+		}
+		keys = append(keys, info)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate api keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// splitScopes reverses the comma join scopes are stored with.
+func splitScopes(joined string) []string {
+	if joined == "" {
+		return nil
+	}
+	return strings.Split(joined, ",")
+}
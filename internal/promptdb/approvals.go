@@ -0,0 +1,281 @@
+package promptdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Approval statuses. A ticket starts pending and moves to exactly one of the
+// other states.
+const (
+	ApprovalPending  = "pending"
+	ApprovalApproved = "approved"
+	ApprovalRejected = "rejected"
+	ApprovalExpired  = "expired"
+)
+
+// Audit actions recorded for approval decisions.
+const (
+	AuditActionApprovalApproved = "approval.approved"
+	AuditActionApprovalRejected = "approval.rejected"
+)
+
+// DefaultApprovalTTL is how long a ticket stays pending before it expires.
+const DefaultApprovalTTL = 24 * time.Hour
+
+var (
+	// ErrApprovalNotFound is returned when no ticket has the requested ID.
+	ErrApprovalNotFound = errors.New("approval not found")
+	// ErrApprovalNotPending is returned when deciding a ticket that was
+	// already approved, rejected, or expired.
+	ErrApprovalNotPending = errors.New("approval is no longer pending")
+	// ErrSelfApproval is returned when the owner of a ticket tries to
+	// decide it.
+	ErrSelfApproval = errors.New("approval owner cannot decide their own ticket")
+)
+
+// Approval is a flagged prompt waiting for, or having received, a human
+// decision. Like ValidationEvent it never holds the prompt in clear text.
+type Approval struct {
+	ID                string     `json:"id"`
+	Status            string     `json:"status"`
+	CreatedAt         time.Time  `json:"created_at"`
+	ExpiresAt         time.Time  `json:"expires_at"`
+	Source            string     `json:"source"`
+	OwnerID           string     `json:"owner_id"`
+	UseCase           string     `json:"use_case"`
+	PolicyVersion     string     `json:"policy_version"`
+	Score             int        `json:"score"`
+	IssueTypes        []string   `json:"issue_types"`
+	PromptFingerprint string     `json:"prompt_fingerprint"`
+	EncryptedPrompt   string     `json:"encrypted_prompt,omitempty"`
+	ValidationEventID string     `json:"validation_event_id,omitempty"`
+	DecidedBy         string     `json:"decided_by,omitempty"`
+	DecidedAt         *time.Time `json:"decided_at,omitempty"`
+	Reason            string     `json:"reason,omitempty"`
+}
+
+// NewApproval opens a pending ticket for a recorded validation event. ttl
+// controls when the ticket expires; zero uses DefaultApprovalTTL.
+func NewApproval(event ValidationEvent, ttl time.Duration) Approval {
+	if ttl <= 0 {
+		ttl = DefaultApprovalTTL
+	}
+
+	now := time.Now().UTC()
+	return Approval{
+		Status:            ApprovalPending,
+		CreatedAt:         now,
+		ExpiresAt:         now.Add(ttl),
+		Source:            event.Source,
+		OwnerID:           event.OwnerID,
+		UseCase:           event.UseCase,
+		PolicyVersion:     event.PolicyVersion,
+		Score:             event.Score,
+		IssueTypes:        event.IssueTypes,
+		PromptFingerprint: event.PromptFingerprint,
+		EncryptedPrompt:   event.EncryptedPrompt,
+		ValidationEventID: event.ID,
+	}
+}
+
+const (
+	insertApprovalQuery  = `INSERT INTO approvals (id, created_at, expires_at, status, source, owner_id, use_case, policy_version, score, issue_types, prompt_fingerprint, encrypted_prompt, validation_event_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
+	selectApprovalsBase  = `SELECT id, created_at, expires_at, status, source, owner_id, use_case, policy_version, score, issue_types, prompt_fingerprint, encrypted_prompt, validation_event_id, decided_by, decided_at, reason FROM approvals`
+	expireApprovalsQuery = `UPDATE approvals SET status = $1 WHERE status = $2 AND expires_at <= $3`
+	decideApprovalQuery  = `UPDATE approvals SET status = $1, decided_by = $2, decided_at = $3, reason = $4 WHERE id = $5 AND status = $6 AND expires_at > $7 AND owner_id <> $2`
+	countApprovalsQuery  = `SELECT COUNT(*) FROM approvals WHERE status = $1`
+)
+
+// CreateApproval stores a pending ticket and returns it with its ID.
+func (s *Store) CreateApproval(ctx context.Context, approval Approval) (Approval, error) {
+	if strings.TrimSpace(approval.OwnerID) == "" {
+		return Approval{}, errors.New("owner id is required")
+	}
+	if approval.ID == "" {
+		approval.ID = newID()
+	}
+	if approval.Status == "" {
+		approval.Status = ApprovalPending
+	}
+
+	if _, err := s.db.ExecContext(ctx, insertApprovalQuery,
+		approval.ID, approval.CreatedAt.UTC(), approval.ExpiresAt.UTC(), approval.Status,
+		approval.Source, approval.OwnerID, approval.UseCase, approval.PolicyVersion,
+		approval.Score, strings.Join(approval.IssueTypes, ","), approval.PromptFingerprint,
+		nullString(approval.EncryptedPrompt), nullString(approval.ValidationEventID),
+	); err != nil {
+		return Approval{}, fmt.Errorf("create approval: %w", err)
+	}
+
+	return approval, nil
+}
+
+// GetApproval returns a ticket by ID. Pending tickets past their deadline are
+// reported as expired.
+func (s *Store) GetApproval(ctx context.Context, id string) (Approval, error) {
+	if _, err := s.ExpireApprovals(ctx); err != nil {
+		return Approval{}, err
+	}
+
+	approvals, err := s.queryApprovals(ctx, selectApprovalsBase+` WHERE id = $1`, id)
+	if err != nil {
+		return Approval{}, err
+	}
+	if len(approvals) == 0 {
+		return Approval{}, ErrApprovalNotFound
+	}
+	return approvals[0], nil
+}
+
+// ListApprovals returns tickets with the given status, oldest first so that
+// reviewers work through the queue in order. An empty status lists all.
+func (s *Store) ListApprovals(ctx context.Context, status string, limit int) ([]Approval, error) {
+	if _, err := s.ExpireApprovals(ctx); err != nil {
+		return nil, err
+	}
+
+	query := selectApprovalsBase
+	var args []any
+	if status != "" {
+		query += ` WHERE status = $1`
+		args = append(args, status)
+	}
+	query += ` ORDER BY created_at, id`
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+
+	return s.queryApprovals(ctx, query, args...)
+}
+
+// CountApprovals returns how many tickets currently have status.
+func (s *Store) CountApprovals(ctx context.Context, status string) (int, error) {
+	var count int
+	if err := s.db.QueryRowContext(ctx, countApprovalsQuery, status).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count approvals: %w", err)
+	}
+	return count, nil
+}
+
+// DecideApproval approves or rejects a pending ticket and audits the decision
+// in the same transaction. It returns ErrApprovalNotPending when the ticket
+// was already decided or has expired, and ErrSelfApproval when actor owns
+// it, since nobody may sign off on their own flagged prompt.
+func (s *Store) DecideApproval(ctx context.Context, id string, approve bool, actor, reason string) (Approval, error) {
+	if strings.TrimSpace(actor) == "" {
+		return Approval{}, errors.New("actor is required")
+	}
+
+	status, action := ApprovalRejected, AuditActionApprovalRejected
+	if approve {
+		status, action = ApprovalApproved, AuditActionApprovalApproved
+	}
+
+	entry := AuditEntry{
+		Actor:   actor,
+		Action:  action,
+		Subject: id,
+		Details: NewAuditDetails(map[string]any{"reason": reason}),
+	}
+
+	now := time.Now().UTC()
+	_, err := s.withAudit(ctx, entry, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, decideApprovalQuery, status, actor, now, nullString(reason), id, ApprovalPending, now)
+		if err != nil {
+			return err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return ErrApprovalNotPending
+		}
+		return nil
+	})
+	if errors.Is(err, ErrApprovalNotPending) {
+		// Distinguish a missing ticket, and one the actor owns, from one
+		// that can no longer change.
+		approval, getErr := s.GetApproval(ctx, id)
+		if getErr != nil {
+			return Approval{}, getErr
+		}
+		if approval.Status == ApprovalPending && approval.OwnerID == actor {
+			return Approval{}, ErrSelfApproval
+		}
+		return Approval{}, ErrApprovalNotPending
+	}
+	if err != nil {
+		return Approval{}, fmt.Errorf("decide approval: %w", err)
+	}
+
+	return s.GetApproval(ctx, id)
+}
+
+// ExpireApprovals moves pending tickets past their deadline to expired and
+// returns how many changed. Reads call it first so callers never see a stale
+// pending ticket.
+func (s *Store) ExpireApprovals(ctx context.Context) (int64, error) {
+	result, err := s.db.ExecContext(ctx, expireApprovalsQuery, ApprovalExpired, ApprovalPending, time.Now().UTC())
+	if err != nil {
+		return 0, fmt.Errorf("expire approvals: %w", err)
+	}
+
+	expired, err := result.RowsAffected()
+	if expired > 0 {
+		s.logger.InfoContext(ctx, "expired approval tickets", "count", expired)
+	}
+	return expired, err
+}
+
+func (s *Store) queryApprovals(ctx context.Context, query string, args ...any) ([]Approval, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query approvals: %w", err)
+	}
+	defer rows.Close()
+
+	var approvals []Approval
+	for rows.Next() {
+		var approval Approval
+		var types string
+		var encrypted, eventID, decidedBy, reason sql.NullString
+		var decidedAt sql.NullTime
+		if err := rows.Scan(&approval.ID, &approval.CreatedAt, &approval.ExpiresAt, &approval.Status,
+			&approval.Source, &approval.OwnerID, &approval.UseCase, &approval.PolicyVersion,
+			&approval.Score, &types, &approval.PromptFingerprint, &encrypted, &eventID,
+			&decidedBy, &decidedAt, &reason); err != nil {
+			return nil, fmt.Errorf("scan approval: %w", err)
+		}
+
+		approval.IssueTypes = splitIssueTypes(types)
+		approval.EncryptedPrompt = encrypted.String
+		approval.ValidationEventID = eventID.String
+		approval.DecidedBy = decidedBy.String
+		approval.Reason = reason.String
+		if decidedAt.Valid {
+			approval.DecidedAt = &decidedAt.Time
+		}
+		approvals = append(approvals, approval)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate approvals: %w", err)
+	}
+
+	return approvals, nil
+}
+
+// nullString stores empty strings as SQL NULL.
+func nullString(value string) any {
+	if value == "" {
+		return nil
+	}
+	return value
+}
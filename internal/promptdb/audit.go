@@ -0,0 +1,325 @@
+package promptdb
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Audit actions recorded by PromptSentinel.
+const (
+	AuditActionValidation    = "validation"
+	AuditActionKeyCreated    = "api_key.created"
+	AuditActionKeyRevoked    = "api_key.revoked"
+	AuditActionConfigChanged = "config.set"
+)
+
+// auditGenesisHash is the prev_hash of the first entry in the chain.
+const auditGenesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+// AuditEntry is one link in the append-only audit chain. Hash covers every
+// other field plus the previous entry's hash, so modifying or deleting any
+// entry breaks verification of everything after it.
+type AuditEntry struct {
+	Seq       int64     `json:"seq"`
+	CreatedAt time.Time `json:"created_at"`
+	Actor     string    `json:"actor"`
+	Action    string    `json:"action"`
+	Subject   string    `json:"subject"`
+	Details   string    `json:"details"`
+	PrevHash  string    `json:"prev_hash"`
+	Hash      string    `json:"hash"`
+}
+
+// AuditProblem describes one inconsistency found by VerifyAuditLog.
+type AuditProblem struct {
+	Seq     int64  `json:"seq"`
+	Message string `json:"message"`
+}
+
+// AuditVerification summarizes a full walk of the audit chain. HeadHash can
+// be recorded out of band; comparing it on the next verification also
+// detects entries deleted from the end of the log.
+type AuditVerification struct {
+	Entries  int            `json:"entries"`
+	HeadSeq  int64          `json:"head_seq"`
+	HeadHash string         `json:"head_hash"`
+	Problems []AuditProblem `json:"problems"`
+}
+
+// Valid reports whether the chain verified without problems.
+func (v AuditVerification) Valid() bool {
+	return len(v.Problems) == 0
+}
+
+const (
+	selectAuditHeadQuery   = `SELECT seq, hash FROM audit_log ORDER BY seq DESC LIMIT 1`
+	insertAuditEntryQuery  = `INSERT INTO audit_log (seq, created_at, actor, action, subject, details, prev_hash, hash) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	selectAuditEntriesBase = `SELECT seq, created_at, actor, action, subject, details, prev_hash, hash FROM audit_log`
+)
+
+// auditAppendAttempts bounds retries when concurrent writers race for the
+// next sequence number. The primary key on seq guarantees that only one of
+// them wins, so the chain can never fork.
+const auditAppendAttempts = 5
+
+// errAuditConflict marks an append that lost the race for the next sequence
+// number. Only such appends are retried.
+var errAuditConflict = errors.New("audit sequence conflict")
+
+// NewAuditDetails encodes details as the JSON string stored with an entry.
+func NewAuditDetails(details map[string]any) string {
+	if len(details) == 0 {
+		return "{}"
+	}
+
+	data, err := json.Marshal(details)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// AppendAudit adds entry to the end of the audit chain and returns it with its
+// sequence number and hashes filled in.
+func (s *Store) AppendAudit(ctx context.Context, entry AuditEntry) (AuditEntry, error) {
+	appended, err := s.withAudit(ctx, entry, nil)
+	if err != nil {
+		return AuditEntry{}, fmt.Errorf("append audit entry: %w", err)
+	}
+	return appended, nil
+}
+
+// withAudit runs fn and appends entry in the same transaction so an audited
+// change never commits without its log entry. The transaction is retried when
+// a concurrent writer claims the next sequence number first; any other
+// error, including one from fn, is returned at once.
+func (s *Store) withAudit(ctx context.Context, entry AuditEntry, fn func(tx *sql.Tx) error) (AuditEntry, error) {
+	var appended AuditEntry
+	var err error
+	for attempt := 0; attempt < auditAppendAttempts; attempt++ {
+		err = inTx(ctx, s.db, func(tx *sql.Tx) error {
+			if fn != nil {
+				if err := fn(tx); err != nil {
+					return err
+				}
+			}
+
+			var appendErr error
+			appended, appendErr = appendAuditTx(ctx, tx, entry)
+			return appendErr
+		})
+		if !errors.Is(err, errAuditConflict) || ctx.Err() != nil {
+			break
+		}
+	}
+
+	if err == nil {
+		level, ok := auditLogLevels[appended.Action]
+		if !ok {
+			level = slog.LevelInfo
+		}
+		s.logger.Log(ctx, level, "audit entry appended",
+			"seq", appended.Seq, "action", appended.Action, "actor", appended.Actor, "subject", appended.Subject)
+	}
+	return appended, err
+}
+
+// auditLogLevels are the levels audited actions are logged at, when not
+// info. Validations are audited on every request, so they are logged only
+// for debugging; a leaked canary is a warning. Details are never logged, as
+// they can hold a reviewer's free-text reason.
+var auditLogLevels = map[string]slog.Level{
+	AuditActionValidation:   slog.LevelDebug,
+	AuditActionCanaryLeaked: slog.LevelWarn,
+}
+
+// appendAuditTx appends entry inside an existing transaction so that the
+// audited change and its log entry commit together.
+func appendAuditTx(ctx context.Context, tx *sql.Tx, entry AuditEntry) (AuditEntry, error) {
+	if strings.TrimSpace(entry.Actor) == "" {
+		return AuditEntry{}, errors.New("audit actor is required")
+	}
+	if strings.TrimSpace(entry.Action) == "" {
+		return AuditEntry{}, errors.New("audit action is required")
+	}
+	if entry.Details == "" {
+		entry.Details = "{}"
+	}
+
+	var headSeq int64
+	headHash := auditGenesisHash
+	err := tx.QueryRowContext(ctx, selectAuditHeadQuery).Scan(&headSeq, &headHash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		if isAuditConflict(err) {
+			err = fmt.Errorf("%w: %w", errAuditConflict, err)
+		}
+		return AuditEntry{}, fmt.Errorf("read audit head: %w", err)
+	}
+
+	entry.Seq = headSeq + 1
+	// PostgreSQL stores microseconds, so hash exactly what will be read back.
+	entry.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	entry.PrevHash = headHash
+	entry.Hash = entry.computeHash()
+
+	if _, err := tx.ExecContext(ctx, insertAuditEntryQuery,
+		entry.Seq, entry.CreatedAt, entry.Actor, entry.Action, entry.Subject,
+		entry.Details, entry.PrevHash, entry.Hash,
+	); err != nil {
+		if isAuditConflict(err) {
+			return AuditEntry{}, fmt.Errorf("%w: %w", errAuditConflict, err)
+		}
+		return AuditEntry{}, err
+	}
+
+	return entry, nil
+}
+
+// isAuditConflict reports whether err, from reading the audit head or
+// inserting after it, means a concurrent writer got there first: a
+// duplicate seq, a PostgreSQL serialization failure, or SQLite refusing a
+// lock it would otherwise deadlock waiting for.
+func isAuditConflict(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "23505":
+			return pqErr.Constraint == "audit_log_pkey"
+		case "40001":
+			return true
+		}
+		return false
+	}
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() & 0xff {
+		case sqlite3.SQLITE_CONSTRAINT:
+			return strings.Contains(sqliteErr.Error(), "audit_log.seq")
+		case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+			return true
+		}
+	}
+	return false
+}
+
+// computeHash returns the SHA-256 of the previous hash followed by a JSON
+// encoding of the entry's fields. JSON keeps field boundaries unambiguous.
+func (e AuditEntry) computeHash() string {
+	fields, _ := json.Marshal([]any{
+		e.Seq,
+		e.CreatedAt.UTC().Format(time.RFC3339Nano),
+		e.Actor,
+		e.Action,
+		e.Subject,
+		e.Details,
+	})
+
+	h := sha256.New()
+	h.Write([]byte(e.PrevHash))
+	h.Write([]byte{'\n'})
+	h.Write(fields)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ListAuditEntries returns the most recent entries, newest first. A limit of
+// zero returns the whole log.
+func (s *Store) ListAuditEntries(ctx context.Context, action string, limit int) ([]AuditEntry, error) {
+	query := selectAuditEntriesBase
+	var args []any
+	if action != "" {
+		query += ` WHERE action = $1`
+		args = append(args, action)
+	}
+	query += ` ORDER BY seq DESC`
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+
+	return s.queryAuditEntries(ctx, query, args...)
+}
+
+// VerifyAuditLog walks the whole chain in order and reports every entry whose
+// hash, link, or sequence number is inconsistent.
+func (s *Store) VerifyAuditLog(ctx context.Context) (AuditVerification, error) {
+	entries, err := s.queryAuditEntries(ctx, selectAuditEntriesBase+` ORDER BY seq`)
+	if err != nil {
+		return AuditVerification{}, err
+	}
+
+	return verifyAuditChain(entries), nil
+}
+
+func verifyAuditChain(entries []AuditEntry) AuditVerification {
+	verification := AuditVerification{
+		Entries:  len(entries),
+		HeadHash: auditGenesisHash,
+		Problems: []AuditProblem{},
+	}
+
+	expectedSeq := int64(1)
+	prevHash := auditGenesisHash
+	for _, entry := range entries {
+		if entry.Seq != expectedSeq {
+			verification.Problems = append(verification.Problems, AuditProblem{
+				Seq:     entry.Seq,
+				Message: fmt.Sprintf("sequence gap: expected %d, entries may have been deleted", expectedSeq),
+			})
+		}
+		if entry.PrevHash != prevHash {
+			verification.Problems = append(verification.Problems, AuditProblem{
+				Seq:     entry.Seq,
+				Message: "previous hash does not match the preceding entry",
+			})
+		}
+		if entry.computeHash() != entry.Hash {
+			verification.Problems = append(verification.Problems, AuditProblem{
+				Seq:     entry.Seq,
+				Message: "entry hash does not match its contents, entry was modified",
+			})
+		}
+
+		expectedSeq = entry.Seq + 1
+		prevHash = entry.Hash
+		verification.HeadSeq = entry.Seq
+		verification.HeadHash = entry.Hash
+	}
+
+	return verification
+}
+
+func (s *Store) queryAuditEntries(ctx context.Context, query string, args ...any) ([]AuditEntry, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var entry AuditEntry
+		if err := rows.Scan(&entry.Seq, &entry.CreatedAt, &entry.Actor, &entry.Action,
+			&entry.Subject, &entry.Details, &entry.PrevHash, &entry.Hash); err != nil {
+			return nil, fmt.Errorf("scan audit entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate audit log: %w", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,70 @@
+package promptdb
+
+import (
+	"testing"
+	"time"
+)
+
+func buildAuditChain(n int) []AuditEntry {
+	entries := make([]AuditEntry, 0, n)
+	prevHash := auditGenesisHash
+	for i := 1; i <= n; i++ {
+		entry := AuditEntry{
+			Seq:       int64(i),
+			CreatedAt: time.Date(2025, 1, 1, 0, 0, i, 0, time.UTC),
+			Actor:     "admin",
+			Action:    AuditActionConfigChanged,
+			Subject:   "max_length",
+			Details:   "{}",
+			PrevHash:  prevHash,
+		}
+		entry.Hash = entry.computeHash()
+		prevHash = entry.Hash
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestVerifyAuditChainValid(t *testing.T) {
+	entries := buildAuditChain(3)
+	verification := verifyAuditChain(entries)
+
+	if !verification.Valid() {
+		t.Fatalf("expected valid chain, got %#v", verification.Problems)
+	}
+	if verification.HeadSeq != 3 || verification.HeadHash != entries[2].Hash {
+		t.Fatalf("unexpected head: %d %s", verification.HeadSeq, verification.HeadHash)
+	}
+}
+
+func TestVerifyAuditChainDetectsModification(t *testing.T) {
+	entries := buildAuditChain(3)
+	entries[1].Actor = "mallory"
+
+	verification := verifyAuditChain(entries)
+	if verification.Valid() || verification.Problems[0].Seq != 2 {
+		t.Fatalf("expected modification of entry 2 to be detected, got %#v", verification.Problems)
+	}
+}
+
+func TestVerifyAuditChainDetectsDeletion(t *testing.T) {
+	entries := buildAuditChain(3)
+	entries = append(entries[:1], entries[2:]...)
+
+	verification := verifyAuditChain(entries)
+	if len(verification.Problems) != 2 {
+		t.Fatalf("expected sequence gap and broken link, got %#v", verification.Problems)
+	}
+}
+
+func TestVerifyAuditChainDetectsRehashedEdit(t *testing.T) {
+	// Recomputing the hash of an edited entry still breaks the next link.
+	entries := buildAuditChain(3)
+	entries[0].Subject = "min_length"
+	entries[0].Hash = entries[0].computeHash()
+
+	verification := verifyAuditChain(entries)
+	if verification.Valid() || verification.Problems[0].Seq != 2 {
+		t.Fatalf("expected broken link at entry 2, got %#v", verification.Problems)
+	}
+}
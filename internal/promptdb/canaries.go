@@ -0,0 +1,314 @@
+package promptdb
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"promptsentinel/internal/validator"
+)
+
+// CanaryTokenPrefix starts every canary token so they are easy to spot and
+// unlikely to occur in ordinary text.
+const CanaryTokenPrefix = "psc-"
+
+// Audit actions recorded for canaries.
+const (
+	AuditActionCanaryCreated = "canary.created"
+	AuditActionCanaryRevoked = "canary.revoked"
+	AuditActionCanaryLeaked  = "canary.leaked"
+)
+
+// Where a leaked canary was found.
+const (
+	LeakLocationResponse = "response"
+	LeakLocationPrompt   = "prompt"
+)
+
+// ErrCanaryNotFound is returned when no active canary has the requested ID.
+var ErrCanaryNotFound = errors.New("canary not found")
+
+// Canary is a registered canary token. Label names the system prompt the
+// token is embedded in.
+type Canary struct {
+	ID        string     `json:"id"`
+	Token     string     `json:"token"`
+	Label     string     `json:"label"`
+	OwnerID   string     `json:"owner_id"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// LeakageIncident records a canary seen where it should not be. Label and
+// CanaryCreatedAt come from the canary so an incident names the leaked
+// prompt on its own.
+type LeakageIncident struct {
+	ID                string    `json:"id"`
+	DetectedAt        time.Time `json:"detected_at"`
+	CanaryID          string    `json:"canary_id"`
+	Label             string    `json:"label"`
+	CanaryCreatedAt   time.Time `json:"canary_created_at"`
+	Source            string    `json:"source"`
+	Location          string    `json:"location"`
+	OwnerID           string    `json:"owner_id"`
+	ValidationEventID string    `json:"validation_event_id,omitempty"`
+}
+
+const (
+	insertCanaryQuery    = `INSERT INTO canaries (id, token, label, owner_id, created_at) VALUES ($1, $2, $3, $4, $5)`
+	selectCanariesBase   = `SELECT id, token, label, owner_id, created_at, revoked_at FROM canaries`
+	revokeCanaryQuery    = `UPDATE canaries SET revoked_at = $1 WHERE id = $2 AND revoked_at IS NULL`
+	insertIncidentQuery  = `INSERT INTO leakage_incidents (id, detected_at, canary_id, source, location, owner_id, validation_event_id) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	selectIncidentsBase  = `SELECT i.id, i.detected_at, i.canary_id, c.label, c.created_at, i.source, i.location, i.owner_id, i.validation_event_id FROM leakage_incidents i JOIN canaries c ON c.id = i.canary_id`
+	incidentsOrderClause = ` ORDER BY i.detected_at DESC, i.id`
+)
+
+// GenerateCanaryToken returns a new random canary token.
+func GenerateCanaryToken() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate canary token: %w", err)
+	}
+	return CanaryTokenPrefix + hex.EncodeToString(b), nil
+}
+
+// CreateCanary generates and registers a canary for the system prompt named
+// by label, auditing the creation. The token is not written to the audit
+// log.
+func (s *Store) CreateCanary(ctx context.Context, label, ownerID, actor string) (Canary, error) {
+	if strings.TrimSpace(label) == "" {
+		return Canary{}, errors.New("label is required")
+	}
+	if strings.TrimSpace(ownerID) == "" {
+		return Canary{}, errors.New("owner id is required")
+	}
+
+	token, err := GenerateCanaryToken()
+	if err != nil {
+		return Canary{}, err
+	}
+
+	canary := Canary{
+		ID:        newID(),
+		Token:     token,
+		Label:     label,
+		OwnerID:   ownerID,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	entry := AuditEntry{
+		Actor:   actor,
+		Action:  AuditActionCanaryCreated,
+		Subject: canary.ID,
+		Details: NewAuditDetails(map[string]any{"label": label, "owner_id": ownerID}),
+	}
+
+	_, err = s.withAudit(ctx, entry, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, insertCanaryQuery, canary.ID, canary.Token, canary.Label, canary.OwnerID, canary.CreatedAt)
+		return err
+	})
+	if err != nil {
+		return Canary{}, fmt.Errorf("create canary: %w", err)
+	}
+	return canary, nil
+}
+
+// ListCanaries returns registered canaries, oldest first. Revoked canaries
+// are included only when includeRevoked is set.
+func (s *Store) ListCanaries(ctx context.Context, includeRevoked bool) ([]Canary, error) {
+	query := selectCanariesBase
+	if !includeRevoked {
+		query += ` WHERE revoked_at IS NULL`
+	}
+	query += ` ORDER BY created_at, id`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("query canaries: %w", err)
+	}
+	defer rows.Close()
+
+	var canaries []Canary
+	for rows.Next() {
+		var canary Canary
+		var revokedAt sql.NullTime
+		if err := rows.Scan(&canary.ID, &canary.Token, &canary.Label, &canary.OwnerID, &canary.CreatedAt, &revokedAt); err != nil {
+			return nil, fmt.Errorf("scan canary: %w", err)
+		}
+		if revokedAt.Valid {
+			canary.RevokedAt = &revokedAt.Time
+		}
+		canaries = append(canaries, canary)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate canaries: %w", err)
+	}
+	return canaries, nil
+}
+
+// ActiveCanaryTokens returns the unrevoked canaries in the form the
+// validator searches for.
+func (s *Store) ActiveCanaryTokens(ctx context.Context) ([]validator.CanaryToken, error) {
+	canaries, err := s.ListCanaries(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make([]validator.CanaryToken, 0, len(canaries))
+	for _, canary := range canaries {
+		tokens = append(tokens, validator.CanaryToken{ID: canary.ID, Label: canary.Label, Token: canary.Token})
+	}
+	return tokens, nil
+}
+
+// RevokeCanary stops detection of the canary with id and audits the change.
+// Its past incidents are kept. It returns ErrCanaryNotFound when no active
+// canary matches.
+func (s *Store) RevokeCanary(ctx context.Context, id, actor string) error {
+	entry := AuditEntry{
+		Actor:   actor,
+		Action:  AuditActionCanaryRevoked,
+		Subject: id,
+	}
+
+	_, err := s.withAudit(ctx, entry, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, revokeCanaryQuery, time.Now().UTC(), id)
+		if err != nil {
+			return err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return ErrCanaryNotFound
+		}
+		return nil
+	})
+	if errors.Is(err, ErrCanaryNotFound) {
+		return ErrCanaryNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("revoke canary: %w", err)
+	}
+	return nil
+}
+
+// RecordLeakageIncident stores an incident for a leaked canary and audits
+// it, returning the incident with its ID and canary details filled in.
+func (s *Store) RecordLeakageIncident(ctx context.Context, incident LeakageIncident) (LeakageIncident, error) {
+	if strings.TrimSpace(incident.CanaryID) == "" {
+		return LeakageIncident{}, errors.New("canary id is required")
+	}
+	if strings.TrimSpace(incident.OwnerID) == "" {
+		return LeakageIncident{}, errors.New("owner id is required")
+	}
+	if incident.ID == "" {
+		incident.ID = newID()
+	}
+	if incident.DetectedAt.IsZero() {
+		incident.DetectedAt = time.Now()
+	}
+	incident.DetectedAt = incident.DetectedAt.UTC()
+
+	entry := AuditEntry{
+		Actor:   incident.OwnerID,
+		Action:  AuditActionCanaryLeaked,
+		Subject: incident.CanaryID,
+		Details: NewAuditDetails(map[string]any{
+			"incident_id":         incident.ID,
+			"source":              incident.Source,
+			"location":            incident.Location,
+			"validation_event_id": incident.ValidationEventID,
+		}),
+	}
+
+	_, err := s.withAudit(ctx, entry, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, insertIncidentQuery,
+			incident.ID, incident.DetectedAt, incident.CanaryID, incident.Source,
+			incident.Location, incident.OwnerID, nullString(incident.ValidationEventID))
+		return err
+	})
+	if err != nil {
+		return LeakageIncident{}, fmt.Errorf("record leakage incident: %w", err)
+	}
+
+	incidents, err := s.queryIncidents(ctx, selectIncidentsBase+` WHERE i.id = $1`, incident.ID)
+	if err != nil {
+		return LeakageIncident{}, err
+	}
+	if len(incidents) == 0 {
+		return LeakageIncident{}, ErrCanaryNotFound
+	}
+	return incidents[0], nil
+}
+
+// RecordCanaryLeaks records an incident for each canary found by the
+// validator. eventID links the incidents to a validation history event and
+// may be empty.
+func (s *Store) RecordCanaryLeaks(ctx context.Context, canaries []validator.CanaryToken, source, location, ownerID, eventID string) ([]LeakageIncident, error) {
+	var incidents []LeakageIncident
+	for _, canary := range canaries {
+		incident, err := s.RecordLeakageIncident(ctx, LeakageIncident{
+			CanaryID:          canary.ID,
+			Source:            source,
+			Location:          location,
+			OwnerID:           ownerID,
+			ValidationEventID: eventID,
+		})
+		if err != nil {
+			return incidents, err
+		}
+		incidents = append(incidents, incident)
+	}
+	return incidents, nil
+}
+
+// ListLeakageIncidents returns incidents, newest first. A non-empty canaryID
+// limits them to one canary.
+func (s *Store) ListLeakageIncidents(ctx context.Context, canaryID string, limit int) ([]LeakageIncident, error) {
+	query := selectIncidentsBase
+	var args []any
+	if canaryID != "" {
+		query += ` WHERE i.canary_id = $1`
+		args = append(args, canaryID)
+	}
+	query += incidentsOrderClause
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+
+	return s.queryIncidents(ctx, query, args...)
+}
+
+func (s *Store) queryIncidents(ctx context.Context, query string, args ...any) ([]LeakageIncident, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query leakage incidents: %w", err)
+	}
+	defer rows.Close()
+
+	var incidents []LeakageIncident
+	for rows.Next() {
+		var incident LeakageIncident
+		var eventID sql.NullString
+		if err := rows.Scan(&incident.ID, &incident.DetectedAt, &incident.CanaryID, &incident.Label,
+			&incident.CanaryCreatedAt, &incident.Source, &incident.Location, &incident.OwnerID, &eventID); err != nil {
+			return nil, fmt.Errorf("scan leakage incident: %w", err)
+		}
+		incident.ValidationEventID = eventID.String
+		incidents = append(incidents, incident)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate leakage incidents: %w", err)
+	}
+	return incidents, nil
+}
@@ -0,0 +1,101 @@
+package promptdb
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// PromptKeySize is the length in bytes of the AES-256 key used to encrypt raw
+// prompts at rest.
+const PromptKeySize = 32
+
+// PromptFingerprint returns a stable, non-reversible identifier for a prompt.
+// Surrounding whitespace is ignored so that trivially different copies of the
+// same prompt group together in history queries.
+func PromptFingerprint(prompt string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(prompt)))
+	return hex.EncodeToString(sum[:])
+}
+
+// ParsePromptKey decodes a PromptKeySize key from hex or standard base64. Keys
+// are usually supplied through an environment variable, so both encodings are
+// accepted for convenience.
+func ParsePromptKey(encoded string) ([]byte, error) {
+	encoded = strings.TrimSpace(encoded)
+	if encoded == "" {
+		return nil, errors.New("prompt encryption key is empty")
+	}
+
+	if key, err := hex.DecodeString(encoded); err == nil && len(key) == PromptKeySize {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(encoded); err == nil && len(key) == PromptKeySize {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("prompt encryption key must be %d bytes encoded as hex or base64", PromptKeySize)
+}
+
+// PromptCipher encrypts raw prompts with AES-256-GCM before they are written
+// to the database. Each sealed value carries its own random nonce.
+type PromptCipher struct {
+	aead cipher.AEAD
+}
+
+// NewPromptCipher builds a PromptCipher from a PromptKeySize key.
+func NewPromptCipher(key []byte) (*PromptCipher, error) {
+	if len(key) != PromptKeySize {
+		return nil, fmt.Errorf("prompt encryption key must be %d bytes", PromptKeySize)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("create cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("create gcm: %w", err)
+	}
+
+	return &PromptCipher{aead: aead}, nil
+}
+
+// Seal encrypts prompt and returns base64(nonce || ciphertext).
+func (c *PromptCipher) Seal(prompt string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("generate nonce: %w", err)
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, []byte(prompt), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open reverses Seal. It fails if the value was produced with a different key
+// or has been modified.
+func (c *PromptCipher) Open(sealed string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", fmt.Errorf("decode sealed prompt: %w", err)
+	}
+
+	nonceSize := c.aead.NonceSize()
+	if len(data) < nonceSize {
+		return "", errors.New("sealed prompt is too short")
+	}
+
+	plaintext, err := c.aead.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypt prompt: %w", err)
+	}
+
+	return string(plaintext), nil
+}
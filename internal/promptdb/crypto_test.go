@@ -0,0 +1,67 @@
+package promptdb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestPromptFingerprint(t *testing.T) {
+	if PromptFingerprint("hello") != PromptFingerprint("  hello\n") {
+		t.Fatal("expected surrounding whitespace to be ignored")
+	}
+	if PromptFingerprint("hello") == PromptFingerprint("world") {
+		t.Fatal("expected different prompts to have different fingerprints")
+	}
+	if strings.Contains(PromptFingerprint("hello"), "hello") {
+		t.Fatal("fingerprint must not contain the prompt")
+	}
+}
+
+func TestParsePromptKey(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, PromptKeySize)
+
+	for _, encoded := range []string{hex.EncodeToString(key), base64.StdEncoding.EncodeToString(key)} {
+		parsed, err := ParsePromptKey(encoded)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %v", encoded, err)
+		}
+		if !bytes.Equal(parsed, key) {
+			t.Fatalf("expected key to round-trip for %q", encoded)
+		}
+	}
+
+	if _, err := ParsePromptKey("abcd"); err == nil {
+		t.Fatal("expected error for short key")
+	}
+}
+
+func TestPromptCipherRoundTrip(t *testing.T) {
+	c, err := NewPromptCipher(bytes.Repeat([]byte{1}, PromptKeySize))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sealed, err := c.Seal("my secret prompt")
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	if strings.Contains(sealed, "secret") {
+		t.Fatal("sealed value must not contain plaintext")
+	}
+
+	opened, err := c.Open(sealed)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if opened != "my secret prompt" {
+		t.Fatalf("expected round trip, got %q", opened)
+	}
+
+	other, _ := NewPromptCipher(bytes.Repeat([]byte{2}, PromptKeySize))
+	if _, err := other.Open(sealed); err == nil {
+		t.Fatal("expected open with a different key to fail")
+	}
+}
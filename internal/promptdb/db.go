@@ -1,7 +1,8 @@
 // Package promptdb centralizes helpers for working with the PromptSentinel
-// PostgreSQL database. The functions in this file intentionally focus on small
-// and easily testable behaviors so that students can understand each step of
-// the data flow when reviewing the repository.
+// database, backed by either PostgreSQL or embedded SQLite. The functions in
+// this file intentionally focus on small and easily testable behaviors so that
+// students can understand each step of the data flow when reviewing the
+// repository.
 package promptdb
 
 import (
@@ -10,6 +11,8 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+
+	_ "github.com/lib/pq" // registers the "postgres" driver
 )
 
 const defaultPostgresPort = 5432
@@ -66,17 +69,28 @@ func (c Config) ConnString() (string, error) {
 }
 
 // Open creates a *sql.DB and ensures that the database is reachable with the
-// provided configuration. Callers must import a PostgreSQL driver (for example
-// github.com/lib/pq) in their main package before invoking this helper. Open is
-// intentionally optional for tests because most unit tests should rely on
-// lightweight stubs instead of a real database connection.
+// provided configuration. The lib/pq driver is registered by this package, so
+// callers do not need a blank import. Open is intentionally optional for tests
+// because most unit tests should rely on lightweight stubs instead of a real
+// database connection.
 func Open(ctx context.Context, cfg Config) (*sql.DB, error) {
 	connString, err := cfg.ConnString()
 	if err != nil {
 		return nil, err
 	}
 
-	db, err := sql.Open("postgres", connString)
+	return OpenDSN(ctx, connString)
+}
+
+// OpenDSN behaves like Open but accepts a ready-made connection string, either
+// in key=value form or as a postgres:// URL. The CLI uses it so operators can
+// pass a single PROMPTSENTINEL_DATABASE_URL value.
+func OpenDSN(ctx context.Context, dsn string) (*sql.DB, error) {
+	if strings.TrimSpace(dsn) == "" {
+		return nil, errors.New("database connection string is required")
+	}
+
+	db, err := sql.Open("postgres", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("open postgres: %w", err)
 	}
@@ -95,6 +109,8 @@ type APIKeyRecord struct {
 	Prefix  string
 	Hash    string
 	OwnerID string
+	// Scopes grant access beyond validating prompts, such as ScopeReviewer.
+	Scopes []string
 }
 
 func (r APIKeyRecord) validate() error {
@@ -107,6 +123,11 @@ func (r APIKeyRecord) validate() error {
 	if strings.TrimSpace(r.OwnerID) == "" {
 		return errors.New("owner id is required")
 	}
+	for _, scope := range r.Scopes {
+		if !knownScopes[scope] {
+			return fmt.Errorf("unknown scope %q", scope)
+		}
+	}
 
 	return nil
 }
@@ -118,7 +139,7 @@ type execContext interface {
 	ExecContext(context.Context, string, ...any) (sql.Result, error)
 }
 
-const insertAPIKeyQuery = `INSERT INTO api_keys (key_prefix, key_hash, owner_id) VALUES ($1, $2, $3)`
+const insertAPIKeyQuery = `INSERT INTO api_keys (key_prefix, key_hash, owner_id, scopes) VALUES ($1, $2, $3, $4)`
 
 // InsertAPIKey writes an APIKeyRecord to the database. The function validates
 // the record before executing the INSERT statement so that students understand
@@ -128,7 +149,7 @@ func InsertAPIKey(ctx context.Context, db execContext, record APIKeyRecord) erro
 		return err
 	}
 
-	if _, err := db.ExecContext(ctx, insertAPIKeyQuery, record.Prefix, record.Hash, record.OwnerID); err != nil {
+	if _, err := db.ExecContext(ctx, insertAPIKeyQuery, record.Prefix, record.Hash, record.OwnerID, strings.Join(record.Scopes, ",")); err != nil {
 		return fmt.Errorf("insert api key: %w", err)
 	}
 
@@ -37,7 +37,7 @@ func TestInsertAPIKey(t *testing.T) {
 	if stub.query != insertAPIKeyQuery {
 		t.Fatalf("expected query %q, got %q", insertAPIKeyQuery, stub.query)
 	}
-	if len(stub.args) != 3 || stub.args[0] != "abc123" || stub.args[1] != "hash" || stub.args[2] != "owner-1" {
+	if len(stub.args) != 4 || stub.args[0] != "abc123" || stub.args[1] != "hash" || stub.args[2] != "owner-1" || stub.args[3] != "" {
 		t.Fatalf("unexpected arguments: %#v", stub.args)
 	}
 }
@@ -0,0 +1,269 @@
+package promptdb
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"promptsentinel/internal/validator"
+)
+
+// ValidationEvent is one row of validation history. The raw prompt is never
+// stored in clear text: PromptFingerprint always identifies it, and
+// EncryptedPrompt optionally holds a PromptCipher-sealed copy.
+type ValidationEvent struct {
+	ID                string    `json:"id"`
+	CreatedAt         time.Time `json:"created_at"`
+	Source            string    `json:"source"`
+	OwnerID           string    `json:"owner_id"`
+	UseCase           string    `json:"use_case"`
+	PolicyVersion     string    `json:"policy_version"`
+	Score             int       `json:"score"`
+	IsValid           bool      `json:"is_valid"`
+	IssueTypes        []string  `json:"issue_types"`
+	PromptFingerprint string    `json:"prompt_fingerprint"`
+	EncryptedPrompt   string    `json:"encrypted_prompt,omitempty"`
+}
+
+func (e ValidationEvent) validate() error {
+	if strings.TrimSpace(e.Source) == "" {
+		return errors.New("source is required")
+	}
+	if strings.TrimSpace(e.OwnerID) == "" {
+		return errors.New("owner id is required")
+	}
+	if strings.TrimSpace(e.PromptFingerprint) == "" {
+		return errors.New("prompt fingerprint is required")
+	}
+
+	return nil
+}
+
+// NewValidationEvent builds a ValidationEvent from a validation result. When
+// promptCipher is non-nil the raw prompt is sealed and stored alongside the
+// fingerprint; otherwise only the fingerprint is kept.
+func NewValidationEvent(source, ownerID, prompt string, config *validator.Config, result *validator.ValidationResult, promptCipher *PromptCipher) (ValidationEvent, error) {
+	event := ValidationEvent{
+		CreatedAt:         result.Timestamp,
+		Source:            source,
+		OwnerID:           ownerID,
+		UseCase:           config.UseCase,
+		PolicyVersion:     validator.PolicyVersion(config),
+		Score:             result.Score,
+		IsValid:           result.IsValid,
+		IssueTypes:        issueTypes(result.Issues),
+		PromptFingerprint: PromptFingerprint(prompt),
+	}
+
+	if promptCipher != nil {
+		sealed, err := promptCipher.Seal(prompt)
+		if err != nil {
+			return ValidationEvent{}, err
+		}
+		event.EncryptedPrompt = sealed
+	}
+
+	return event, nil
+}
+
+// issueTypes returns the distinct issue types in a stable order.
+func issueTypes(issues []validator.ValidationIssue) []string {
+	seen := make(map[string]bool)
+	types := []string{}
+	for _, issue := range issues {
+		if issue.Type == "" || seen[issue.Type] {
+			continue
+		}
+		seen[issue.Type] = true
+		types = append(types, issue.Type)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// HistoryFilter narrows QueryValidationEvents. Zero values mean "no filter".
+// IssueType matches events where the named detector reported at least one
+// issue, and Valid selects passed (true) or failed (false) validations.
+type HistoryFilter struct {
+	Since     time.Time
+	Until     time.Time
+	OwnerID   string
+	UseCase   string
+	IssueType string
+	Valid     *bool
+	Limit     int
+}
+
+// HistorySummary aggregates the events matched by a HistoryFilter.
+type HistorySummary struct {
+	Total  int `json:"total"`
+	Passed int `json:"passed"`
+	Failed int `json:"failed"`
+}
+
+const (
+	insertValidationEventQuery = `INSERT INTO validation_events (id, created_at, source, owner_id, use_case, policy_version, score, is_valid, issue_types, prompt_fingerprint, encrypted_prompt) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+	insertValidationIssueQuery = `INSERT INTO validation_event_issues (event_id, issue_type) VALUES ($1, $2)`
+	selectValidationEventsBase = `SELECT id, created_at, source, owner_id, use_case, policy_version, score, is_valid, issue_types, prompt_fingerprint, encrypted_prompt FROM validation_events`
+	summarizeValidationsBase   = `SELECT COUNT(*), COALESCE(SUM(CASE WHEN is_valid THEN 1 ELSE 0 END), 0) FROM validation_events`
+)
+
+// RecordValidationEvent stores event and its issue types, and appends a
+// validation entry to the audit log naming the policy version that judged the
+// prompt, all in one transaction. An ID and timestamp are assigned when the
+// caller leaves them empty, and the stored ID is returned.
+func (s *Store) RecordValidationEvent(ctx context.Context, event ValidationEvent) (string, error) {
+	if err := event.validate(); err != nil {
+		return "", err
+	}
+	if event.ID == "" {
+		event.ID = newID()
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	entry := AuditEntry{
+		Actor:   event.OwnerID,
+		Action:  AuditActionValidation,
+		Subject: event.ID,
+		Details: NewAuditDetails(map[string]any{
+			"source":             event.Source,
+			"use_case":           event.UseCase,
+			"policy_version":     event.PolicyVersion,
+			"is_valid":           event.IsValid,
+			"score":              event.Score,
+			"issue_types":        event.IssueTypes,
+			"prompt_fingerprint": event.PromptFingerprint,
+		}),
+	}
+
+	_, err := s.withAudit(ctx, entry, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, insertValidationEventQuery,
+			event.ID, event.CreatedAt.UTC(), event.Source, event.OwnerID, event.UseCase,
+			event.PolicyVersion, event.Score, event.IsValid, strings.Join(event.IssueTypes, ","),
+			event.PromptFingerprint, nullString(event.EncryptedPrompt),
+		); err != nil {
+			return err
+		}
+
+		for _, issueType := range event.IssueTypes {
+			if _, err := tx.ExecContext(ctx, insertValidationIssueQuery, event.ID, issueType); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("record validation event: %w", err)
+	}
+
+	return event.ID, nil
+}
+
+// QueryValidationEvents returns matching events, newest first.
+func (s *Store) QueryValidationEvents(ctx context.Context, filter HistoryFilter) ([]ValidationEvent, error) {
+	where, args := filter.whereClause()
+	query := selectValidationEventsBase + where + ` ORDER BY created_at DESC, id`
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query validation events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []ValidationEvent
+	for rows.Next() {
+		var event ValidationEvent
+		var types string
+		var encrypted sql.NullString
+		if err := rows.Scan(&event.ID, &event.CreatedAt, &event.Source, &event.OwnerID, &event.UseCase,
+			&event.PolicyVersion, &event.Score, &event.IsValid, &types, &event.PromptFingerprint, &encrypted); err != nil {
+			return nil, fmt.Errorf("scan validation event: %w", err)
+		}
+		event.IssueTypes = splitIssueTypes(types)
+		event.EncryptedPrompt = encrypted.String
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate validation events: %w", err)
+	}
+
+	return events, nil
+}
+
+// SummarizeValidationEvents counts matching events by outcome. The Limit field
+// of filter is ignored.
+func (s *Store) SummarizeValidationEvents(ctx context.Context, filter HistoryFilter) (HistorySummary, error) {
+	where, args := filter.whereClause()
+
+	var summary HistorySummary
+	if err := s.db.QueryRowContext(ctx, summarizeValidationsBase+where, args...).Scan(&summary.Total, &summary.Passed); err != nil {
+		return HistorySummary{}, fmt.Errorf("summarize validation events: %w", err)
+	}
+	summary.Failed = summary.Total - summary.Passed
+
+	return summary, nil
+}
+
+// whereClause renders the filter as a WHERE clause. Placeholders are numbered
+// in the order they appear so the same SQL works for both backends.
+func (f HistoryFilter) whereClause() (string, []any) {
+	var conditions []string
+	var args []any
+
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if !f.Since.IsZero() {
+		add("created_at >= $%d", f.Since.UTC())
+	}
+	if !f.Until.IsZero() {
+		add("created_at < $%d", f.Until.UTC())
+	}
+	if f.OwnerID != "" {
+		add("owner_id = $%d", f.OwnerID)
+	}
+	if f.UseCase != "" {
+		add("use_case = $%d", f.UseCase)
+	}
+	if f.IssueType != "" {
+		add("EXISTS (SELECT 1 FROM validation_event_issues i WHERE i.event_id = validation_events.id AND i.issue_type = $%d)", f.IssueType)
+	}
+	if f.Valid != nil {
+		add("is_valid = $%d", *f.Valid)
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+func splitIssueTypes(joined string) []string {
+	if joined == "" {
+		return []string{}
+	}
+	return strings.Split(joined, ",")
+}
+
+// newID returns a random 128-bit identifier encoded as hex.
+func newID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("promptdb: read random id: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,473 @@
+package promptdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"promptsentinel/internal/validator"
+)
+
+// Job statuses. A job is queued until a worker claims it, running while a
+// worker holds its lease, and then moves to exactly one of the final states.
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// Where a job's dataset came from.
+const (
+	JobInputUpload = "upload"
+	JobInputPath   = "path"
+)
+
+// How a job validates each item: with the quick check or the comprehensive
+// analysis.
+const (
+	JobModeCheck    = "check"
+	JobModeValidate = "validate"
+)
+
+// Audit actions recorded for jobs.
+const (
+	AuditActionJobSubmitted = "job.submitted"
+	AuditActionJobCancelled = "job.cancelled"
+)
+
+var (
+	// ErrJobNotFound is returned when no job has the requested ID.
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobFinished is returned when cancelling a job that already
+	// succeeded, failed, or was cancelled.
+	ErrJobFinished = errors.New("job has already finished")
+	// ErrJobLeaseLost is returned when a worker saves progress on a job it
+	// no longer holds, because the job was cancelled or its lease expired
+	// and another worker claimed it.
+	ErrJobLeaseLost = errors.New("job lease lost")
+	// ErrNoQueuedJobs is returned by ClaimJob when there is nothing to run.
+	ErrNoQueuedJobs = errors.New("no jobs are waiting")
+)
+
+// Job is a batch validation of a JSONL dataset. Offset and Line are the
+// checkpoint: the byte offset of the first line not yet processed and the
+// number of lines before it, saved with the results of those lines so a
+// claimed job resumes where the last worker stopped. Attempts counts the
+// claims that ended without the job being released.
+type Job struct {
+	ID             string     `json:"id"`
+	Status         string     `json:"status"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	OwnerID        string     `json:"owner_id"`
+	Input          string     `json:"input"`
+	InputName      string     `json:"input_name"`
+	InputPath      string     `json:"-"`
+	Mode           string     `json:"mode"`
+	UseCase        string     `json:"use_case,omitempty"`
+	Total          *int64     `json:"total,omitempty"`
+	Processed      int64      `json:"processed"`
+	Flagged        int64      `json:"flagged"`
+	Errors         int64      `json:"errors"`
+	Offset         int64      `json:"-"`
+	Line           int64      `json:"-"`
+	Attempts       int        `json:"attempts"`
+	StartedAt      *time.Time `json:"started_at,omitempty"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+	Error          string     `json:"error,omitempty"`
+	LeaseOwner     string     `json:"-"`
+	LeaseExpiresAt *time.Time `json:"-"`
+}
+
+// Finished reports whether the job has reached a final status.
+func (j Job) Finished() bool {
+	switch j.Status {
+	case JobSucceeded, JobFailed, JobCancelled:
+		return true
+	}
+	return false
+}
+
+// JobResult is the verdict on one line of a job's dataset. Like
+// ValidationEvent it never holds the prompt, only its fingerprint. Error is
+// set instead of a verdict when the line could not be validated.
+type JobResult struct {
+	Line              int64    `json:"line"`
+	ItemID            string   `json:"id,omitempty"`
+	PolicyVersion     string   `json:"policy_version,omitempty"`
+	Score             int      `json:"score"`
+	IsValid           bool     `json:"is_valid"`
+	Outcome           string   `json:"outcome,omitempty"`
+	RiskLevel         string   `json:"risk_level,omitempty"`
+	IssueTypes        []string `json:"issue_types"`
+	PromptFingerprint string   `json:"prompt_fingerprint,omitempty"`
+	Error             string   `json:"error,omitempty"`
+}
+
+// NewJobResult builds the result for line of a dataset from a validation of
+// prompt under config.
+func NewJobResult(line int64, itemID, prompt string, config *validator.Config, result *validator.ValidationResult) JobResult {
+	return JobResult{
+		Line:              line,
+		ItemID:            itemID,
+		PolicyVersion:     validator.PolicyVersion(config),
+		Score:             result.Score,
+		IsValid:           result.IsValid,
+		Outcome:           result.Outcome,
+		IssueTypes:        issueTypes(result.Issues),
+		PromptFingerprint: PromptFingerprint(prompt),
+	}
+}
+
+const (
+	insertJobQuery        = `INSERT INTO jobs (id, created_at, updated_at, status, owner_id, input, input_name, input_path, mode, use_case, total, processed, flagged, errors, input_offset, input_line, attempts) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, 0, 0, 0, 0, 0, 0)`
+	selectJobsBase        = `SELECT id, created_at, updated_at, status, owner_id, input, input_name, input_path, mode, use_case, total, processed, flagged, errors, input_offset, input_line, attempts, lease_owner, lease_expires_at, started_at, finished_at, error FROM jobs`
+	nextJobQuery          = `SELECT id FROM jobs WHERE status = $1 OR (status = $2 AND lease_expires_at < $3) ORDER BY created_at, id LIMIT 1`
+	claimJobQuery         = `UPDATE jobs SET status = $1, lease_owner = $2, lease_expires_at = $3, started_at = COALESCE(started_at, $4), updated_at = $4, attempts = attempts + 1 WHERE id = $5 AND (status = $6 OR (status = $1 AND lease_expires_at < $4))`
+	checkpointJobQuery    = `UPDATE jobs SET total = $1, processed = $2, flagged = $3, errors = $4, input_offset = $5, input_line = $6, lease_expires_at = $7, updated_at = $8 WHERE id = $9 AND status = $10 AND lease_owner = $11`
+	finishJobQuery        = `UPDATE jobs SET status = $1, error = $2, finished_at = $3, updated_at = $3, lease_owner = NULL, lease_expires_at = NULL WHERE id = $4 AND status = $5 AND lease_owner = $6`
+	releaseJobQuery       = `UPDATE jobs SET status = $1, updated_at = $2, lease_owner = NULL, lease_expires_at = NULL, attempts = attempts - 1 WHERE id = $3 AND status = $4 AND lease_owner = $5`
+	cancelJobQuery        = `UPDATE jobs SET status = $1, finished_at = $2, updated_at = $2, lease_owner = NULL, lease_expires_at = NULL WHERE id = $3 AND (status = $4 OR status = $5)`
+	insertJobResultQuery  = `INSERT INTO job_results (job_id, line, item_id, policy_version, score, is_valid, outcome, risk_level, issue_types, prompt_fingerprint, error) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+	selectJobResultsQuery = `SELECT line, item_id, policy_version, score, is_valid, outcome, risk_level, issue_types, prompt_fingerprint, error FROM job_results WHERE job_id = $1 AND line > $2 ORDER BY line`
+)
+
+// CreateJob queues a job and audits its submission by its owner, returning
+// the job with its ID.
+func (s *Store) CreateJob(ctx context.Context, job Job) (Job, error) {
+	if strings.TrimSpace(job.OwnerID) == "" {
+		return Job{}, errors.New("owner id is required")
+	}
+	if job.InputPath == "" {
+		return Job{}, errors.New("input path is required")
+	}
+	switch job.Input {
+	case JobInputUpload, JobInputPath:
+	default:
+		return Job{}, fmt.Errorf("unknown job input %q", job.Input)
+	}
+	switch job.Mode {
+	case "":
+		job.Mode = JobModeCheck
+	case JobModeCheck, JobModeValidate:
+	default:
+		return Job{}, fmt.Errorf("unknown job mode %q", job.Mode)
+	}
+	if job.ID == "" {
+		job.ID = newID()
+	}
+
+	now := time.Now().UTC()
+	job.Status, job.CreatedAt, job.UpdatedAt = JobQueued, now, now
+
+	var total any
+	if job.Total != nil {
+		total = *job.Total
+	}
+
+	entry := AuditEntry{
+		Actor:   job.OwnerID,
+		Action:  AuditActionJobSubmitted,
+		Subject: job.ID,
+		Details: NewAuditDetails(map[string]any{"input": job.Input, "input_name": job.InputName, "mode": job.Mode}),
+	}
+	if _, err := s.withAudit(ctx, entry, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, insertJobQuery,
+			job.ID, job.CreatedAt, job.UpdatedAt, job.Status, job.OwnerID, job.Input,
+			job.InputName, job.InputPath, job.Mode, nullString(job.UseCase), total)
+		return err
+	}); err != nil {
+		return Job{}, fmt.Errorf("create job: %w", err)
+	}
+
+	return job, nil
+}
+
+// GetJob returns a job by ID.
+func (s *Store) GetJob(ctx context.Context, id string) (Job, error) {
+	jobs, err := s.queryJobs(ctx, selectJobsBase+` WHERE id = $1`, id)
+	if err != nil {
+		return Job{}, err
+	}
+	if len(jobs) == 0 {
+		return Job{}, ErrJobNotFound
+	}
+	return jobs[0], nil
+}
+
+// ListJobs returns jobs newest first. An empty ownerID or status matches
+// every owner or status.
+func (s *Store) ListJobs(ctx context.Context, ownerID, status string, limit int) ([]Job, error) {
+	var conditions []string
+	var args []any
+	if ownerID != "" {
+		args = append(args, ownerID)
+		conditions = append(conditions, fmt.Sprintf("owner_id = $%d", len(args)))
+	}
+	if status != "" {
+		args = append(args, status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+
+	query := selectJobsBase
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += ` ORDER BY created_at DESC, id`
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+
+	return s.queryJobs(ctx, query, args...)
+}
+
+// ClaimJob leases the oldest queued job, or a running one whose lease has
+// expired because its worker stopped, to worker for lease. It returns
+// ErrNoQueuedJobs when there is none. Claims are conditional updates, so
+// workers in separate processes sharing the database never run the same
+// job at once.
+func (s *Store) ClaimJob(ctx context.Context, worker string, lease time.Duration) (Job, error) {
+	if strings.TrimSpace(worker) == "" {
+		return Job{}, errors.New("worker is required")
+	}
+
+	// Another worker can claim the job between the select and the update;
+	// then look again.
+	for attempt := 0; attempt < 5; attempt++ {
+		now := time.Now().UTC()
+		var id string
+		err := s.db.QueryRowContext(ctx, nextJobQuery, JobQueued, JobRunning, now).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return Job{}, ErrNoQueuedJobs
+		}
+		if err != nil {
+			return Job{}, fmt.Errorf("claim job: %w", err)
+		}
+
+		result, err := s.db.ExecContext(ctx, claimJobQuery, JobRunning, worker, now.Add(lease), now, id, JobQueued)
+		if err != nil {
+			return Job{}, fmt.Errorf("claim job: %w", err)
+		}
+		if affected, err := result.RowsAffected(); err != nil {
+			return Job{}, fmt.Errorf("claim job: %w", err)
+		} else if affected == 1 {
+			return s.GetJob(ctx, id)
+		}
+	}
+	return Job{}, ErrNoQueuedJobs
+}
+
+// CheckpointJob saves results together with job's progress counters and
+// offset, and extends the lease of job.LeaseOwner, in one transaction. It
+// returns ErrJobLeaseLost, saving nothing, when that worker no longer holds
+// the job.
+func (s *Store) CheckpointJob(ctx context.Context, job Job, lease time.Duration, results []JobResult) error {
+	var total any
+	if job.Total != nil {
+		total = *job.Total
+	}
+
+	now := time.Now().UTC()
+	err := inTx(ctx, s.db, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, checkpointJobQuery,
+			total, job.Processed, job.Flagged, job.Errors, job.Offset, job.Line, now.Add(lease), now,
+			job.ID, JobRunning, job.LeaseOwner)
+		if err != nil {
+			return err
+		}
+		if affected, err := result.RowsAffected(); err != nil {
+			return err
+		} else if affected == 0 {
+			return ErrJobLeaseLost
+		}
+
+		for _, r := range results {
+			if _, err := tx.ExecContext(ctx, insertJobResultQuery,
+				job.ID, r.Line, nullString(r.ItemID), nullString(r.PolicyVersion), r.Score, r.IsValid,
+				nullString(r.Outcome), nullString(r.RiskLevel), strings.Join(r.IssueTypes, ","),
+				nullString(r.PromptFingerprint), nullString(r.Error)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, ErrJobLeaseLost) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("checkpoint job: %w", err)
+	}
+	return nil
+}
+
+// FinishJob moves a job held by job.LeaseOwner to status, which must be
+// JobSucceeded or JobFailed, with message as its error, and releases the
+// lease. It returns ErrJobLeaseLost when that worker no longer holds the
+// job.
+func (s *Store) FinishJob(ctx context.Context, job Job, status, message string) error {
+	if status != JobSucceeded && status != JobFailed {
+		return fmt.Errorf("cannot finish a job as %q", status)
+	}
+
+	result, err := s.db.ExecContext(ctx, finishJobQuery, status, nullString(message), time.Now().UTC(), job.ID, JobRunning, job.LeaseOwner)
+	if err != nil {
+		return fmt.Errorf("finish job: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("finish job: %w", err)
+	}
+	if affected == 0 {
+		return ErrJobLeaseLost
+	}
+	return nil
+}
+
+// ReleaseJob puts a job held by job.LeaseOwner back in the queue, to resume
+// from its last checkpoint, without counting the claim as an attempt.
+// Workers release their jobs when shutting down so another can pick them up
+// without waiting for the lease to expire.
+func (s *Store) ReleaseJob(ctx context.Context, job Job) error {
+	result, err := s.db.ExecContext(ctx, releaseJobQuery, JobQueued, time.Now().UTC(), job.ID, JobRunning, job.LeaseOwner)
+	if err != nil {
+		return fmt.Errorf("release job: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("release job: %w", err)
+	}
+	if affected == 0 {
+		return ErrJobLeaseLost
+	}
+	return nil
+}
+
+// CancelJob cancels a queued or running job and audits the cancellation in
+// the same transaction. A running job's worker stops at its next
+// checkpoint. It returns ErrJobFinished when the job had already finished.
+func (s *Store) CancelJob(ctx context.Context, id, actor string) (Job, error) {
+	if strings.TrimSpace(actor) == "" {
+		return Job{}, errors.New("actor is required")
+	}
+
+	entry := AuditEntry{Actor: actor, Action: AuditActionJobCancelled, Subject: id}
+	_, err := s.withAudit(ctx, entry, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, cancelJobQuery, JobCancelled, time.Now().UTC(), id, JobQueued, JobRunning)
+		if err != nil {
+			return err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return ErrJobFinished
+		}
+		return nil
+	})
+	if errors.Is(err, ErrJobFinished) {
+		// Distinguish a missing job from one that can no longer change.
+		if _, getErr := s.GetJob(ctx, id); getErr != nil {
+			return Job{}, getErr
+		}
+		return Job{}, ErrJobFinished
+	}
+	if err != nil {
+		return Job{}, fmt.Errorf("cancel job: %w", err)
+	}
+
+	return s.GetJob(ctx, id)
+}
+
+// ListJobResults returns up to limit results of a job in line order,
+// starting after line afterLine, so callers can page through millions of
+// results without holding them in memory.
+func (s *Store) ListJobResults(ctx context.Context, jobID string, afterLine int64, limit int) ([]JobResult, error) {
+	query := selectJobResultsQuery
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, jobID, afterLine)
+	if err != nil {
+		return nil, fmt.Errorf("query job results: %w", err)
+	}
+	defer rows.Close()
+
+	var results []JobResult
+	for rows.Next() {
+		var result JobResult
+		var types string
+		var itemID, policyVersion, outcome, riskLevel, fingerprint, message sql.NullString
+		if err := rows.Scan(&result.Line, &itemID, &policyVersion, &result.Score, &result.IsValid,
+			&outcome, &riskLevel, &types, &fingerprint, &message); err != nil {
+			return nil, fmt.Errorf("scan job result: %w", err)
+		}
+
+		result.ItemID = itemID.String
+		result.PolicyVersion = policyVersion.String
+		result.Outcome = outcome.String
+		result.RiskLevel = riskLevel.String
+		result.IssueTypes = splitIssueTypes(types)
+		result.PromptFingerprint = fingerprint.String
+		result.Error = message.String
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate job results: %w", err)
+	}
+
+	return results, nil
+}
+
+func (s *Store) queryJobs(ctx context.Context, query string, args ...any) ([]Job, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []Job
+	for rows.Next() {
+		var job Job
+		var useCase, leaseOwner, message sql.NullString
+		var total sql.NullInt64
+		var leaseExpiresAt, startedAt, finishedAt sql.NullTime
+		if err := rows.Scan(&job.ID, &job.CreatedAt, &job.UpdatedAt, &job.Status, &job.OwnerID,
+			&job.Input, &job.InputName, &job.InputPath, &job.Mode, &useCase, &total,
+			&job.Processed, &job.Flagged, &job.Errors, &job.Offset, &job.Line, &job.Attempts,
+			&leaseOwner, &leaseExpiresAt, &startedAt, &finishedAt, &message); err != nil {
+			return nil, fmt.Errorf("scan job: %w", err)
+		}
+
+		job.UseCase = useCase.String
+		job.LeaseOwner = leaseOwner.String
+		job.Error = message.String
+		if total.Valid {
+			job.Total = &total.Int64
+		}
+		if leaseExpiresAt.Valid {
+			job.LeaseExpiresAt = &leaseExpiresAt.Time
+		}
+		if startedAt.Valid {
+			job.StartedAt = &startedAt.Time
+		}
+		if finishedAt.Valid {
+			job.FinishedAt = &finishedAt.Time
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate jobs: %w", err)
+	}
+
+	return jobs, nil
+}
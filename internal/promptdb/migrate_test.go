@@ -0,0 +1,92 @@
+package promptdb
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadMigrationsEmbedded(t *testing.T) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		t.Fatalf("unexpected error loading migrations: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("expected at least one embedded migration")
+	}
+
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			t.Fatalf("expected contiguous versions, got %d at position %d", migration.Version, i)
+		}
+	}
+
+	if !strings.Contains(migrations[0].Up, "CREATE TABLE api_keys") {
+		t.Fatalf("expected first migration to create api_keys, got %q", migrations[0].Up)
+	}
+}
+
+func TestLoadMigrationsOrdersAndPairs(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/0002_second.up.sql":   {Data: []byte("CREATE TABLE b (id TEXT)")},
+		"m/0002_second.down.sql": {Data: []byte("DROP TABLE b")},
+		"m/0001_first.up.sql":    {Data: []byte("CREATE TABLE a (id TEXT)")},
+		"m/0001_first.down.sql":  {Data: []byte("DROP TABLE a")},
+	}
+
+	migrations, err := loadMigrations(fsys, "m")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(migrations) != 2 || migrations[0].Name != "first" || migrations[1].Name != "second" {
+		t.Fatalf("unexpected migrations: %#v", migrations)
+	}
+	if migrations[1].Down != "DROP TABLE b" {
+		t.Fatalf("expected down script to be paired, got %q", migrations[1].Down)
+	}
+}
+
+func TestLoadMigrationsRequiresDown(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/0001_first.up.sql": {Data: []byte("CREATE TABLE a (id TEXT)")},
+	}
+
+	if _, err := loadMigrations(fsys, "m"); err == nil {
+		t.Fatal("expected error for migration without a down script")
+	}
+}
+
+func TestParseMigrationFilename(t *testing.T) {
+	tests := []struct {
+		filename  string
+		version   int
+		name      string
+		direction string
+		wantErr   bool
+	}{
+		{filename: "0001_api_keys.up.sql", version: 1, name: "api_keys", direction: "up"},
+		{filename: "0012_audit_log.down.sql", version: 12, name: "audit_log", direction: "down"},
+		{filename: "0001_api_keys.sql", wantErr: true},
+		{filename: "abcd_api_keys.up.sql", wantErr: true},
+		{filename: "0001.up.sql", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			version, name, direction, err := parseMigrationFilename(tt.filename)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if version != tt.version || name != tt.name || direction != tt.direction {
+				t.Fatalf("got (%d, %q, %q)", version, name, direction)
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+CREATE TABLE api_keys (
+    key_prefix TEXT PRIMARY KEY,
+    key_hash   TEXT NOT NULL,
+    owner_id   TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_api_keys_owner_id ON api_keys (owner_id);
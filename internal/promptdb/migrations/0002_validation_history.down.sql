@@ -0,0 +1,5 @@
+DROP INDEX idx_validation_event_issues_type;
+DROP TABLE validation_event_issues;
+DROP INDEX idx_validation_events_owner_id;
+DROP INDEX idx_validation_events_created_at;
+DROP TABLE validation_events;
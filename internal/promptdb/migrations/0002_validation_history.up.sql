@@ -0,0 +1,23 @@
+CREATE TABLE validation_events (
+    id                 TEXT PRIMARY KEY,
+    created_at         TIMESTAMP NOT NULL,
+    source             TEXT NOT NULL,
+    owner_id           TEXT NOT NULL,
+    use_case           TEXT NOT NULL,
+    score              INTEGER NOT NULL,
+    is_valid           BOOLEAN NOT NULL,
+    issue_types        TEXT NOT NULL,
+    prompt_fingerprint TEXT NOT NULL,
+    encrypted_prompt   TEXT
+);
+
+CREATE INDEX idx_validation_events_created_at ON validation_events (created_at);
+CREATE INDEX idx_validation_events_owner_id ON validation_events (owner_id, created_at);
+
+CREATE TABLE validation_event_issues (
+    event_id   TEXT NOT NULL REFERENCES validation_events (id) ON DELETE CASCADE,
+    issue_type TEXT NOT NULL,
+    PRIMARY KEY (event_id, issue_type)
+);
+
+CREATE INDEX idx_validation_event_issues_type ON validation_event_issues (issue_type);
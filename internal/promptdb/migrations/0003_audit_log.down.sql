@@ -0,0 +1,4 @@
+ALTER TABLE validation_events DROP COLUMN policy_version;
+
+DROP INDEX idx_audit_log_action;
+DROP TABLE audit_log;
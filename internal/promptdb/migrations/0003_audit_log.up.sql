@@ -0,0 +1,14 @@
+CREATE TABLE audit_log (
+    seq        BIGINT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    actor      TEXT NOT NULL,
+    action     TEXT NOT NULL,
+    subject    TEXT NOT NULL,
+    details    TEXT NOT NULL,
+    prev_hash  TEXT NOT NULL,
+    hash       TEXT NOT NULL
+);
+
+CREATE INDEX idx_audit_log_action ON audit_log (action, created_at);
+
+ALTER TABLE validation_events ADD COLUMN policy_version TEXT NOT NULL DEFAULT '';
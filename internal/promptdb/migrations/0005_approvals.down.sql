@@ -0,0 +1,2 @@
+DROP INDEX idx_approvals_status;
+DROP TABLE approvals;
@@ -0,0 +1,20 @@
+CREATE TABLE approvals (
+    id                  TEXT PRIMARY KEY,
+    created_at          TIMESTAMP NOT NULL,
+    expires_at          TIMESTAMP NOT NULL,
+    status              TEXT NOT NULL,
+    source              TEXT NOT NULL,
+    owner_id            TEXT NOT NULL,
+    use_case            TEXT NOT NULL,
+    policy_version      TEXT NOT NULL,
+    score               INTEGER NOT NULL,
+    issue_types         TEXT NOT NULL,
+    prompt_fingerprint  TEXT NOT NULL,
+    encrypted_prompt    TEXT,
+    validation_event_id TEXT,
+    decided_by          TEXT,
+    decided_at          TIMESTAMP,
+    reason              TEXT
+);
+
+CREATE INDEX idx_approvals_status ON approvals (status, created_at);
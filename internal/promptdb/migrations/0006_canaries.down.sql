@@ -0,0 +1,3 @@
+DROP INDEX idx_leakage_incidents_canary;
+DROP TABLE leakage_incidents;
+DROP TABLE canaries;
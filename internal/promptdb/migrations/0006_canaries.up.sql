@@ -0,0 +1,20 @@
+CREATE TABLE canaries (
+    id         TEXT PRIMARY KEY,
+    token      TEXT NOT NULL UNIQUE,
+    label      TEXT NOT NULL,
+    owner_id   TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP
+);
+
+CREATE TABLE leakage_incidents (
+    id                  TEXT PRIMARY KEY,
+    detected_at         TIMESTAMP NOT NULL,
+    canary_id           TEXT NOT NULL REFERENCES canaries (id),
+    source              TEXT NOT NULL,
+    location            TEXT NOT NULL,
+    owner_id            TEXT NOT NULL,
+    validation_event_id TEXT
+);
+
+CREATE INDEX idx_leakage_incidents_canary ON leakage_incidents (canary_id, detected_at);
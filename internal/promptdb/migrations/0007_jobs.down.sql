@@ -0,0 +1,4 @@
+DROP TABLE job_results;
+DROP INDEX idx_jobs_owner;
+DROP INDEX idx_jobs_status;
+DROP TABLE jobs;
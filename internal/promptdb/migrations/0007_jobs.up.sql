@@ -0,0 +1,42 @@
+CREATE TABLE jobs (
+    id               TEXT PRIMARY KEY,
+    created_at       TIMESTAMP NOT NULL,
+    updated_at       TIMESTAMP NOT NULL,
+    status           TEXT NOT NULL,
+    owner_id         TEXT NOT NULL,
+    input            TEXT NOT NULL,
+    input_name       TEXT NOT NULL,
+    input_path       TEXT NOT NULL,
+    mode             TEXT NOT NULL,
+    use_case         TEXT,
+    total            BIGINT,
+    processed        BIGINT NOT NULL,
+    flagged          BIGINT NOT NULL,
+    errors           BIGINT NOT NULL,
+    input_offset     BIGINT NOT NULL,
+    input_line       BIGINT NOT NULL,
+    attempts         INTEGER NOT NULL,
+    lease_owner      TEXT,
+    lease_expires_at TIMESTAMP,
+    started_at       TIMESTAMP,
+    finished_at      TIMESTAMP,
+    error            TEXT
+);
+
+CREATE INDEX idx_jobs_status ON jobs (status, created_at);
+CREATE INDEX idx_jobs_owner ON jobs (owner_id, created_at);
+
+CREATE TABLE job_results (
+    job_id             TEXT NOT NULL REFERENCES jobs (id),
+    line               BIGINT NOT NULL,
+    item_id            TEXT,
+    policy_version     TEXT,
+    score              INTEGER NOT NULL,
+    is_valid           BOOLEAN NOT NULL,
+    outcome            TEXT,
+    risk_level         TEXT,
+    issue_types        TEXT NOT NULL,
+    prompt_fingerprint TEXT,
+    error              TEXT,
+    PRIMARY KEY (job_id, line)
+);
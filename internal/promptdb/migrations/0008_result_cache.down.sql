@@ -0,0 +1,2 @@
+DROP INDEX idx_result_cache_expires;
+DROP TABLE result_cache;
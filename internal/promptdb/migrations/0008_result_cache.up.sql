@@ -0,0 +1,8 @@
+CREATE TABLE result_cache (
+    cache_key  TEXT PRIMARY KEY,
+    value      TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_result_cache_expires ON result_cache (expires_at);
@@ -0,0 +1,83 @@
+package promptdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	selectCachedResultQuery = `SELECT value FROM result_cache WHERE cache_key = $1 AND expires_at > $2`
+	putCachedResultQuery    = `INSERT INTO result_cache (cache_key, value, created_at, expires_at) VALUES ($1, $2, $3, $4) ON CONFLICT (cache_key) DO UPDATE SET value = excluded.value, created_at = excluded.created_at, expires_at = excluded.expires_at`
+	pruneExpiredQuery       = `DELETE FROM result_cache WHERE expires_at <= $1`
+	pruneOverflowQuery      = `DELETE FROM result_cache WHERE expires_at < (SELECT expires_at FROM result_cache ORDER BY expires_at DESC LIMIT 1 OFFSET $1)`
+)
+
+// GetCachedResult returns the value cached under key, reporting false when
+// there is none or it has expired.
+func (s *Store) GetCachedResult(ctx context.Context, key string) ([]byte, bool, error) {
+	var value string
+	err := s.db.QueryRowContext(ctx, selectCachedResultQuery, key, time.Now().UTC()).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("get cached result: %w", err)
+	}
+	return []byte(value), true, nil
+}
+
+// PutCachedResult caches value under key for ttl, replacing any value
+// already there. Keys are expected to be hashes and values encoded results;
+// neither should hold prompt text.
+func (s *Store) PutCachedResult(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if strings.TrimSpace(key) == "" {
+		return errors.New("cache key is required")
+	}
+	if ttl <= 0 {
+		return errors.New("cache ttl must be positive")
+	}
+
+	now := time.Now().UTC()
+	if _, err := s.db.ExecContext(ctx, putCachedResultQuery, key, string(value), now, now.Add(ttl)); err != nil {
+		return fmt.Errorf("put cached result: %w", err)
+	}
+	return nil
+}
+
+// PruneCachedResults deletes expired results and then, when more than
+// maxEntries remain, those closest to expiring. A maxEntries of zero or
+// less only deletes expired results. It returns the number deleted.
+func (s *Store) PruneCachedResults(ctx context.Context, maxEntries int) (int64, error) {
+	var pruned int64
+	err := inTx(ctx, s.db, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, pruneExpiredQuery, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		if pruned, err = result.RowsAffected(); err != nil {
+			return err
+		}
+		if maxEntries <= 0 {
+			return nil
+		}
+
+		if result, err = tx.ExecContext(ctx, pruneOverflowQuery, maxEntries-1); err != nil {
+			return err
+		}
+		overflow, err := result.RowsAffected()
+		pruned += overflow
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("prune cached results: %w", err)
+	}
+
+	if pruned > 0 {
+		s.logger.DebugContext(ctx, "pruned cached results", "count", pruned)
+	}
+	return pruned, nil
+}
@@ -0,0 +1,55 @@
+package promptdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	_ "modernc.org/sqlite" // registers the pure-Go "sqlite" driver
+)
+
+// sqlitePragmas are applied to every SQLite connection. Foreign keys are off
+// by default in SQLite, and the busy timeout lets concurrent CLI invocations
+// wait for a lock instead of failing immediately.
+var sqlitePragmas = []string{
+	"foreign_keys(1)",
+	"busy_timeout(5000)",
+}
+
+// sqliteConnString turns the path portion of a sqlite:// DSN into a modernc
+// connection string with the standard pragmas attached.
+func sqliteConnString(path string) string {
+	params := make([]string, 0, len(sqlitePragmas))
+	for _, pragma := range sqlitePragmas {
+		params = append(params, "_pragma="+pragma)
+	}
+
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+
+	return path + separator + strings.Join(params, "&")
+}
+
+// openSQLite opens and pings an embedded SQLite database.
+func openSQLite(ctx context.Context, connString string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", connString)
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite: %w", err)
+	}
+
+	// An in-memory database lives inside a single connection, so the pool
+	// must never open a second one.
+	if strings.HasPrefix(connString, ":memory:") {
+		db.SetMaxOpenConns(1)
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("ping sqlite: %w", err)
+	}
+
+	return db, nil
+}
@@ -0,0 +1,701 @@
+package promptdb
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"promptsentinel/internal/logging"
+)
+
+// postgresTestURLEnv names the environment variable that enables the
+// PostgreSQL run of the conformance suite. The database it points at is
+// migrated up and down by the tests, so it must be disposable.
+const postgresTestURLEnv = "PROMPTSENTINEL_TEST_POSTGRES_URL"
+
+func TestParseDSN(t *testing.T) {
+	tests := []struct {
+		dsn        string
+		backend    Backend
+		connPrefix string
+		wantErr    bool
+	}{
+		{dsn: "sqlite:///var/lib/ps.db", backend: BackendSQLite, connPrefix: "/var/lib/ps.db?_pragma="},
+		{dsn: "sqlite://local.db", backend: BackendSQLite, connPrefix: "local.db?_pragma="},
+		{dsn: "sqlite://:memory:", backend: BackendSQLite, connPrefix: ":memory:?_pragma="},
+		{dsn: "postgres://u:p@localhost/db", backend: BackendPostgres, connPrefix: "postgres://u:p@localhost/db"},
+		{dsn: "postgresql://localhost/db", backend: BackendPostgres, connPrefix: "postgresql://localhost/db"},
+		{dsn: "host=localhost user=prompt", backend: BackendPostgres, connPrefix: "host=localhost user=prompt"},
+		{dsn: "mysql://localhost/db", wantErr: true},
+		{dsn: "sqlite://", wantErr: true},
+		{dsn: "  ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dsn, func(t *testing.T) {
+			backend, conn, err := ParseDSN(tt.dsn)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if backend != tt.backend {
+				t.Fatalf("expected backend %q, got %q", tt.backend, backend)
+			}
+			if !strings.HasPrefix(conn, tt.connPrefix) {
+				t.Fatalf("expected connection string starting with %q, got %q", tt.connPrefix, conn)
+			}
+		})
+	}
+}
+
+func TestSQLiteStoreConformance(t *testing.T) {
+	dsn := "sqlite://" + filepath.Join(t.TempDir(), "conformance.db")
+	runStoreConformance(t, dsn)
+}
+
+func TestPostgresStoreConformance(t *testing.T) {
+	dsn := os.Getenv(postgresTestURLEnv)
+	if dsn == "" {
+		t.Skipf("set %s to run the PostgreSQL conformance suite", postgresTestURLEnv)
+	}
+	runStoreConformance(t, dsn)
+}
+
+func TestStoreLogs(t *testing.T) {
+	ctx := context.Background()
+	store, err := OpenStore(ctx, "sqlite://"+filepath.Join(t.TempDir(), "logs.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, logging.FormatJSON, slog.LevelDebug)
+	if err != nil {
+		t.Fatalf("new logger: %v", err)
+	}
+	store.SetLogger(logger)
+
+	if _, err := store.Migrate(ctx); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if err := store.CreateAPIKey(ctx, APIKeyRecord{Prefix: "ps_logs", Hash: "hash-logs", OwnerID: "team-x"}, "admin"); err != nil {
+		t.Fatalf("create api key: %v", err)
+	}
+	canary, err := store.CreateCanary(ctx, "support bot", "team-x", "admin")
+	if err != nil {
+		t.Fatalf("create canary: %v", err)
+	}
+	tokens, err := store.ActiveCanaryTokens(ctx)
+	if err != nil {
+		t.Fatalf("active canaries: %v", err)
+	}
+	if _, err := store.RecordCanaryLeaks(ctx, tokens, "proxy", LeakLocationResponse, "team-x", "event-1"); err != nil {
+		t.Fatalf("record leaks: %v", err)
+	}
+
+	logs := buf.String()
+	for _, want := range []string{
+		`"msg":"applied migration"`,
+		`"action":"` + AuditActionKeyCreated + `"`,
+		`"level":"WARN","msg":"audit entry appended"`,
+	} {
+		if !strings.Contains(logs, want) {
+			t.Errorf("expected logs to contain %s, got %s", want, logs)
+		}
+	}
+	for _, secret := range []string{canary.Token, "hash-logs"} {
+		if strings.Contains(logs, secret) {
+			t.Errorf("expected %q to stay out of the logs", secret)
+		}
+	}
+}
+
+// runStoreConformance exercises the Store API against a real backend. Every
+// backend must pass the same cases so that switching the DSN never changes
+// behavior.
+func runStoreConformance(t *testing.T, dsn string) {
+	t.Helper()
+
+	ctx := context.Background()
+	store, err := OpenStore(ctx, dsn)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	// Start from an empty schema in case a previous run left tables behind.
+	if _, err := store.Rollback(ctx, 1<<20); err != nil {
+		t.Fatalf("reset schema: %v", err)
+	}
+	if _, err := store.Migrate(ctx); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	t.Run("MigrationStatuses", func(t *testing.T) {
+		statuses, err := store.MigrationStatuses(ctx)
+		if err != nil {
+			t.Fatalf("migration statuses: %v", err)
+		}
+		for _, status := range statuses {
+			if !status.Applied {
+				t.Fatalf("expected migration %04d to be applied", status.Version)
+			}
+			if status.AppliedAt.IsZero() {
+				t.Fatalf("expected migration %04d to record applied_at", status.Version)
+			}
+		}
+
+		applied, err := store.Migrate(ctx)
+		if err != nil {
+			t.Fatalf("second migrate: %v", err)
+		}
+		if len(applied) != 0 {
+			t.Fatalf("expected migrate to be idempotent, applied %d", len(applied))
+		}
+	})
+
+	t.Run("APIKeys", func(t *testing.T) {
+		for _, record := range []APIKeyRecord{
+			{Prefix: "conf-b", Hash: "hash-b", OwnerID: "owner-b"},
+			{Prefix: "conf-a", Hash: "hash-a", OwnerID: "owner-a"},
+		} {
+			if err := store.InsertAPIKey(ctx, record); err != nil {
+				t.Fatalf("insert api key: %v", err)
+			}
+		}
+
+		owners, err := store.ListAPIKeyOwners(ctx)
+		if err != nil {
+			t.Fatalf("list owners: %v", err)
+		}
+		if len(owners) != 2 || owners[0] != "owner-a" || owners[1] != "owner-b" {
+			t.Fatalf("unexpected owners: %#v", owners)
+		}
+
+		if err := store.InsertAPIKey(ctx, APIKeyRecord{Prefix: "conf-a", Hash: "other", OwnerID: "owner-c"}); err == nil {
+			t.Fatal("expected duplicate prefix to be rejected")
+		}
+	})
+
+	t.Run("ValidationHistory", func(t *testing.T) {
+		base := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+		events := []ValidationEvent{
+			{CreatedAt: base, Source: "check", OwnerID: "team-x", UseCase: "general", Score: 60, IsValid: false, IssueTypes: []string{"length", "pattern"}, PromptFingerprint: PromptFingerprint("one")},
+			{CreatedAt: base.Add(time.Hour), Source: "validate", OwnerID: "team-x", UseCase: "general", Score: 100, IsValid: true, IssueTypes: []string{}, PromptFingerprint: PromptFingerprint("two"), EncryptedPrompt: "c2VhbGVk"},
+			{CreatedAt: base.Add(-48 * time.Hour), Source: "check", OwnerID: "team-y", UseCase: "business", Score: 90, IsValid: true, IssueTypes: []string{"pattern"}, PromptFingerprint: PromptFingerprint("three")},
+		}
+		for _, event := range events {
+			if _, err := store.RecordValidationEvent(ctx, event); err != nil {
+				t.Fatalf("record event: %v", err)
+			}
+		}
+
+		all, err := store.QueryValidationEvents(ctx, HistoryFilter{})
+		if err != nil {
+			t.Fatalf("query all: %v", err)
+		}
+		if len(all) != 3 || all[0].Source != "validate" || all[2].OwnerID != "team-y" {
+			t.Fatalf("expected events newest first, got %#v", all)
+		}
+		if all[0].EncryptedPrompt != "c2VhbGVk" || all[1].EncryptedPrompt != "" {
+			t.Fatalf("unexpected encrypted prompts: %q, %q", all[0].EncryptedPrompt, all[1].EncryptedPrompt)
+		}
+		if !all[1].CreatedAt.Equal(base) {
+			t.Fatalf("expected created_at %v, got %v", base, all[1].CreatedAt)
+		}
+
+		failed := false
+		blocked, err := store.QueryValidationEvents(ctx, HistoryFilter{OwnerID: "team-x", Valid: &failed, Since: base.Add(-time.Hour)})
+		if err != nil {
+			t.Fatalf("query blocked: %v", err)
+		}
+		if len(blocked) != 1 || len(blocked[0].IssueTypes) != 2 || blocked[0].IssueTypes[1] != "pattern" {
+			t.Fatalf("unexpected blocked events: %#v", blocked)
+		}
+
+		byDetector, err := store.QueryValidationEvents(ctx, HistoryFilter{IssueType: "pattern", Until: base})
+		if err != nil {
+			t.Fatalf("query by detector: %v", err)
+		}
+		if len(byDetector) != 1 || byDetector[0].OwnerID != "team-y" {
+			t.Fatalf("unexpected detector events: %#v", byDetector)
+		}
+
+		summary, err := store.SummarizeValidationEvents(ctx, HistoryFilter{OwnerID: "team-x"})
+		if err != nil {
+			t.Fatalf("summarize: %v", err)
+		}
+		if summary != (HistorySummary{Total: 2, Passed: 1, Failed: 1}) {
+			t.Fatalf("unexpected summary: %#v", summary)
+		}
+
+		limited, err := store.QueryValidationEvents(ctx, HistoryFilter{Limit: 1})
+		if err != nil {
+			t.Fatalf("query limited: %v", err)
+		}
+		if len(limited) != 1 {
+			t.Fatalf("expected limit to apply, got %d events", len(limited))
+		}
+	})
+
+	t.Run("APIKeyLifecycle", func(t *testing.T) {
+		record := APIKeyRecord{Prefix: "ps_lifecycle", Hash: "hash-l", OwnerID: "owner-l", Scopes: []string{ScopeReviewer}}
+		if err := store.CreateAPIKey(ctx, record, "admin"); err != nil {
+			t.Fatalf("create api key: %v", err)
+		}
+		if found, err := store.LookupAPIKey(ctx, record.Prefix); err != nil || found.Hash != record.Hash || !found.HasScope(ScopeReviewer) {
+			t.Fatalf("expected active reviewer key lookup, got %#v (%v)", found, err)
+		}
+		if err := store.CreateAPIKey(ctx, APIKeyRecord{Prefix: "ps_root", Hash: "hash-r", OwnerID: "owner-r", Scopes: []string{"root"}}, "admin"); err == nil {
+			t.Fatal("expected an unknown scope to be rejected")
+		}
+		if err := store.RevokeAPIKey(ctx, record.Prefix, "admin"); err != nil {
+			t.Fatalf("revoke api key: %v", err)
+		}
+		if _, err := store.LookupAPIKey(ctx, record.Prefix); !errors.Is(err, ErrAPIKeyNotFound) {
+			t.Fatalf("expected revoked key lookup to fail, got %v", err)
+		}
+		if err := store.RevokeAPIKey(ctx, record.Prefix, "admin"); !errors.Is(err, ErrAPIKeyNotFound) {
+			t.Fatalf("expected ErrAPIKeyNotFound for second revoke, got %v", err)
+		}
+
+		keys, err := store.ListAPIKeys(ctx)
+		if err != nil {
+			t.Fatalf("list api keys: %v", err)
+		}
+		var found bool
+		for _, key := range keys {
+			if key.Prefix != record.Prefix {
+				if key.RevokedAt != nil {
+					t.Fatalf("expected %s to remain active", key.Prefix)
+				}
+				continue
+			}
+			found = true
+			if key.RevokedAt == nil || key.CreatedAt.IsZero() || len(key.Scopes) != 1 {
+				t.Fatalf("expected revoked key with created_at, got %#v", key)
+			}
+		}
+		if !found {
+			t.Fatal("expected created key to be listed")
+		}
+
+		entries, err := store.ListAuditEntries(ctx, AuditActionKeyRevoked, 0)
+		if err != nil {
+			t.Fatalf("list audit entries: %v", err)
+		}
+		if len(entries) != 1 || entries[0].Subject != record.Prefix || entries[0].Actor != "admin" {
+			t.Fatalf("expected one revocation audit entry, got %#v", entries)
+		}
+	})
+
+	t.Run("AuditLog", func(t *testing.T) {
+		if _, err := store.AppendAudit(ctx, AuditEntry{Actor: "admin", Action: AuditActionConfigChanged, Subject: "max_length", Details: NewAuditDetails(map[string]any{"old": "10000", "new": "5000"})}); err != nil {
+			t.Fatalf("append audit: %v", err)
+		}
+
+		verification, err := store.VerifyAuditLog(ctx)
+		if err != nil {
+			t.Fatalf("verify: %v", err)
+		}
+		if !verification.Valid() {
+			t.Fatalf("expected untouched chain to verify, got %#v", verification.Problems)
+		}
+		// Validation history and key changes above are audited too.
+		if verification.Entries < 5 {
+			t.Fatalf("expected audited validations and key changes, got %d entries", verification.Entries)
+		}
+
+		validations, err := store.ListAuditEntries(ctx, AuditActionValidation, 0)
+		if err != nil {
+			t.Fatalf("list validations: %v", err)
+		}
+		if len(validations) != 3 {
+			t.Fatalf("expected one audit entry per recorded validation, got %d", len(validations))
+		}
+
+		if _, err := store.DB().ExecContext(ctx, `UPDATE audit_log SET details = '{"new":"99999"}' WHERE seq = 2`); err != nil {
+			t.Fatalf("tamper update: %v", err)
+		}
+		if _, err := store.DB().ExecContext(ctx, `DELETE FROM audit_log WHERE seq = 4`); err != nil {
+			t.Fatalf("tamper delete: %v", err)
+		}
+
+		verification, err = store.VerifyAuditLog(ctx)
+		if err != nil {
+			t.Fatalf("verify tampered: %v", err)
+		}
+		problemSeqs := map[int64]bool{}
+		for _, problem := range verification.Problems {
+			problemSeqs[problem.Seq] = true
+		}
+		if !problemSeqs[2] || !problemSeqs[5] {
+			t.Fatalf("expected modified entry 2 and gap before 5 to be reported, got %#v", verification.Problems)
+		}
+	})
+
+	t.Run("AuditRetriesOnlyConflicts", func(t *testing.T) {
+		calls := 0
+		_, err := store.withAudit(ctx, AuditEntry{Actor: "admin", Action: AuditActionKeyRevoked, Subject: "missing"}, func(tx *sql.Tx) error {
+			calls++
+			return ErrAPIKeyNotFound
+		})
+		if !errors.Is(err, ErrAPIKeyNotFound) || calls != 1 {
+			t.Fatalf("expected a domain error to be returned after one attempt, got %v after %d", err, calls)
+		}
+
+		head, err := store.AppendAudit(ctx, AuditEntry{Actor: "admin", Action: AuditActionConfigChanged, Subject: "max_length"})
+		if err != nil {
+			t.Fatalf("append audit: %v", err)
+		}
+		_, err = store.DB().ExecContext(ctx, insertAuditEntryQuery, head.Seq, head.CreatedAt, head.Actor, head.Action, head.Subject, head.Details, head.PrevHash, head.Hash)
+		if !isAuditConflict(err) {
+			t.Fatalf("expected a duplicate seq to be a conflict, got %v", err)
+		}
+		if isAuditConflict(ErrAPIKeyNotFound) {
+			t.Fatal("expected a domain error not to be a conflict")
+		}
+	})
+
+	t.Run("Approvals", func(t *testing.T) {
+		event := ValidationEvent{ID: "event-approval", Source: "check", OwnerID: "team-x", UseCase: "general", Score: 80, IssueTypes: []string{"pattern"}, PromptFingerprint: PromptFingerprint("flagged")}
+
+		approved, err := store.CreateApproval(ctx, NewApproval(event, time.Hour))
+		if err != nil {
+			t.Fatalf("create approval: %v", err)
+		}
+		rejected, err := store.CreateApproval(ctx, NewApproval(event, time.Hour))
+		if err != nil {
+			t.Fatalf("create approval: %v", err)
+		}
+		expired := NewApproval(event, time.Hour)
+		expired.ExpiresAt = time.Now().Add(-time.Minute)
+		if expired, err = store.CreateApproval(ctx, expired); err != nil {
+			t.Fatalf("create approval: %v", err)
+		}
+
+		pending, err := store.ListApprovals(ctx, ApprovalPending, 0)
+		if err != nil {
+			t.Fatalf("list pending: %v", err)
+		}
+		if len(pending) != 2 || pending[0].IssueTypes[0] != "pattern" || pending[0].ValidationEventID != "event-approval" {
+			t.Fatalf("expected two pending approvals, got %#v", pending)
+		}
+		if count, err := store.CountApprovals(ctx, ApprovalPending); err != nil || count != 2 {
+			t.Fatalf("expected pending count 2, got %d (%v)", count, err)
+		}
+
+		if got, err := store.GetApproval(ctx, expired.ID); err != nil || got.Status != ApprovalExpired {
+			t.Fatalf("expected expired ticket, got %#v (%v)", got, err)
+		}
+		if _, err := store.DecideApproval(ctx, expired.ID, true, "reviewer", ""); !errors.Is(err, ErrApprovalNotPending) {
+			t.Fatalf("expected ErrApprovalNotPending for expired ticket, got %v", err)
+		}
+
+		if _, err := store.DecideApproval(ctx, approved.ID, true, "team-x", ""); !errors.Is(err, ErrSelfApproval) {
+			t.Fatalf("expected ErrSelfApproval for the ticket's owner, got %v", err)
+		}
+
+		decided, err := store.DecideApproval(ctx, approved.ID, true, "reviewer", "looks fine")
+		if err != nil {
+			t.Fatalf("approve: %v", err)
+		}
+		if decided.Status != ApprovalApproved || decided.DecidedBy != "reviewer" || decided.Reason != "looks fine" || decided.DecidedAt == nil {
+			t.Fatalf("unexpected approved ticket: %#v", decided)
+		}
+		if decided, err = store.DecideApproval(ctx, rejected.ID, false, "reviewer", ""); err != nil || decided.Status != ApprovalRejected {
+			t.Fatalf("expected rejected ticket, got %#v (%v)", decided, err)
+		}
+		if _, err := store.DecideApproval(ctx, approved.ID, false, "reviewer", ""); !errors.Is(err, ErrApprovalNotPending) {
+			t.Fatalf("expected ErrApprovalNotPending for decided ticket, got %v", err)
+		}
+		if _, err := store.DecideApproval(ctx, "missing", true, "reviewer", ""); !errors.Is(err, ErrApprovalNotFound) {
+			t.Fatalf("expected ErrApprovalNotFound, got %v", err)
+		}
+
+		entries, err := store.ListAuditEntries(ctx, AuditActionApprovalApproved, 0)
+		if err != nil || len(entries) != 1 || entries[0].Subject != approved.ID {
+			t.Fatalf("expected approval to be audited, got %#v (%v)", entries, err)
+		}
+	})
+
+	t.Run("Canaries", func(t *testing.T) {
+		leaked, err := store.CreateCanary(ctx, "support bot v3", "team-x", "admin")
+		if err != nil {
+			t.Fatalf("create canary: %v", err)
+		}
+		retired, err := store.CreateCanary(ctx, "old bot", "team-x", "admin")
+		if err != nil {
+			t.Fatalf("create canary: %v", err)
+		}
+		if !strings.HasPrefix(leaked.Token, CanaryTokenPrefix) || leaked.Token == retired.Token {
+			t.Fatalf("expected unique prefixed tokens, got %q and %q", leaked.Token, retired.Token)
+		}
+		if _, err := store.CreateCanary(ctx, " ", "team-x", "admin"); err == nil {
+			t.Fatal("expected error for empty label")
+		}
+
+		if err := store.RevokeCanary(ctx, retired.ID, "admin"); err != nil {
+			t.Fatalf("revoke canary: %v", err)
+		}
+		if err := store.RevokeCanary(ctx, retired.ID, "admin"); !errors.Is(err, ErrCanaryNotFound) {
+			t.Fatalf("expected ErrCanaryNotFound for revoked canary, got %v", err)
+		}
+
+		tokens, err := store.ActiveCanaryTokens(ctx)
+		if err != nil {
+			t.Fatalf("active canaries: %v", err)
+		}
+		if len(tokens) != 1 || tokens[0].ID != leaked.ID || tokens[0].Token != leaked.Token {
+			t.Fatalf("expected only the active canary, got %#v", tokens)
+		}
+		if all, err := store.ListCanaries(ctx, true); err != nil || len(all) != 2 || all[1].RevokedAt == nil {
+			t.Fatalf("expected both canaries with revocation, got %#v (%v)", all, err)
+		}
+
+		incidents, err := store.RecordCanaryLeaks(ctx, tokens, "proxy", LeakLocationResponse, "team-x", "event-1")
+		if err != nil {
+			t.Fatalf("record leaks: %v", err)
+		}
+		if len(incidents) != 1 || incidents[0].Label != "support bot v3" || incidents[0].CanaryCreatedAt.Sub(leaked.CreatedAt).Abs() > time.Millisecond {
+			t.Fatalf("expected incident naming the leaked prompt, got %#v", incidents)
+		}
+
+		listed, err := store.ListLeakageIncidents(ctx, leaked.ID, 0)
+		if err != nil {
+			t.Fatalf("list incidents: %v", err)
+		}
+		if len(listed) != 1 || listed[0].Location != LeakLocationResponse || listed[0].ValidationEventID != "event-1" {
+			t.Fatalf("unexpected incidents: %#v", listed)
+		}
+
+		entries, err := store.ListAuditEntries(ctx, AuditActionCanaryLeaked, 0)
+		if err != nil || len(entries) != 1 || entries[0].Subject != leaked.ID {
+			t.Fatalf("expected leak to be audited, got %#v (%v)", entries, err)
+		}
+		if created, err := store.ListAuditEntries(ctx, AuditActionCanaryCreated, 0); err != nil || strings.Contains(created[0].Details, leaked.Token) {
+			t.Fatalf("expected canary token to stay out of the audit log, got %#v (%v)", created, err)
+		}
+	})
+
+	t.Run("Jobs", func(t *testing.T) {
+		if _, err := store.CreateJob(ctx, Job{OwnerID: "team-x", Input: "ftp", InputPath: "/data/x.jsonl"}); err == nil {
+			t.Fatal("expected error for unknown input")
+		}
+
+		first, err := store.CreateJob(ctx, Job{OwnerID: "team-x", Input: JobInputPath, InputName: "prompts.jsonl", InputPath: "/data/prompts.jsonl"})
+		if err != nil {
+			t.Fatalf("create job: %v", err)
+		}
+		total := int64(3)
+		second, err := store.CreateJob(ctx, Job{OwnerID: "team-y", Input: JobInputUpload, InputName: "upload.jsonl", InputPath: "/jobs/upload.jsonl", Mode: JobModeValidate, UseCase: "customer_support", Total: &total})
+		if err != nil {
+			t.Fatalf("create job: %v", err)
+		}
+		if first.Status != JobQueued || first.Mode != JobModeCheck {
+			t.Fatalf("expected a queued check job, got %#v", first)
+		}
+
+		if owned, err := store.ListJobs(ctx, "team-y", "", 0); err != nil || len(owned) != 1 || owned[0].ID != second.ID || *owned[0].Total != 3 || owned[0].UseCase != "customer_support" {
+			t.Fatalf("expected the owner's job, got %#v (%v)", owned, err)
+		}
+
+		claimed, err := store.ClaimJob(ctx, "worker-1", time.Hour)
+		if err != nil {
+			t.Fatalf("claim job: %v", err)
+		}
+		if claimed.ID != first.ID || claimed.Status != JobRunning || claimed.LeaseOwner != "worker-1" || claimed.Attempts != 1 || claimed.StartedAt == nil {
+			t.Fatalf("expected the oldest job leased to worker-1, got %#v", claimed)
+		}
+
+		claimed.Processed, claimed.Flagged, claimed.Offset = 2, 1, 120
+		results := []JobResult{
+			{Line: 1, ItemID: "a", Score: 100, IsValid: true, Outcome: "allowed", IssueTypes: []string{}, PromptFingerprint: PromptFingerprint("hello")},
+			{Line: 2, Score: 40, Outcome: "blocked", IssueTypes: []string{"pattern", "security"}},
+		}
+		if err := store.CheckpointJob(ctx, claimed, time.Hour, results); err != nil {
+			t.Fatalf("checkpoint job: %v", err)
+		}
+		stolen := claimed
+		stolen.LeaseOwner = "worker-2"
+		if err := store.CheckpointJob(ctx, stolen, time.Hour, []JobResult{{Line: 3}}); !errors.Is(err, ErrJobLeaseLost) {
+			t.Fatalf("expected ErrJobLeaseLost for another worker, got %v", err)
+		}
+
+		if got, err := store.GetJob(ctx, first.ID); err != nil || got.Processed != 2 || got.Flagged != 1 || got.Offset != 120 {
+			t.Fatalf("expected the checkpoint to be saved, got %#v (%v)", got, err)
+		}
+		saved, err := store.ListJobResults(ctx, first.ID, 0, 0)
+		if err != nil {
+			t.Fatalf("list job results: %v", err)
+		}
+		if len(saved) != 2 || saved[0].ItemID != "a" || !saved[0].IsValid || saved[1].IssueTypes[1] != "security" {
+			t.Fatalf("unexpected results: %#v", saved)
+		}
+		if page, err := store.ListJobResults(ctx, first.ID, 1, 10); err != nil || len(page) != 1 || page[0].Line != 2 {
+			t.Fatalf("expected results after line 1, got %#v (%v)", page, err)
+		}
+
+		if err := store.FinishJob(ctx, claimed, JobSucceeded, ""); err != nil {
+			t.Fatalf("finish job: %v", err)
+		}
+		if got, err := store.GetJob(ctx, first.ID); err != nil || got.Status != JobSucceeded || got.FinishedAt == nil || got.LeaseOwner != "" {
+			t.Fatalf("expected a finished job, got %#v (%v)", got, err)
+		}
+		if _, err := store.CancelJob(ctx, first.ID, "team-x"); !errors.Is(err, ErrJobFinished) {
+			t.Fatalf("expected ErrJobFinished, got %v", err)
+		}
+
+		// A lease that expires is claimed again by the next worker.
+		if _, err := store.ClaimJob(ctx, "worker-1", -time.Minute); err != nil {
+			t.Fatalf("claim job: %v", err)
+		}
+		reclaimed, err := store.ClaimJob(ctx, "worker-2", time.Hour)
+		if err != nil {
+			t.Fatalf("reclaim job: %v", err)
+		}
+		if reclaimed.ID != second.ID || reclaimed.LeaseOwner != "worker-2" || reclaimed.Attempts != 2 {
+			t.Fatalf("expected the abandoned job to be reclaimed, got %#v", reclaimed)
+		}
+		if _, err := store.ClaimJob(ctx, "worker-3", time.Hour); !errors.Is(err, ErrNoQueuedJobs) {
+			t.Fatalf("expected ErrNoQueuedJobs, got %v", err)
+		}
+
+		// A released job is claimed again at once, and the release does not
+		// count as an attempt.
+		if err := store.ReleaseJob(ctx, reclaimed); err != nil {
+			t.Fatalf("release job: %v", err)
+		}
+		if reclaimed, err = store.ClaimJob(ctx, "worker-3", time.Hour); err != nil || reclaimed.ID != second.ID || reclaimed.Attempts != 2 {
+			t.Fatalf("expected the released job to be claimed without an attempt, got %#v (%v)", reclaimed, err)
+		}
+
+		cancelled, err := store.CancelJob(ctx, second.ID, "team-y")
+		if err != nil || cancelled.Status != JobCancelled {
+			t.Fatalf("expected a cancelled job, got %#v (%v)", cancelled, err)
+		}
+		if err := store.CheckpointJob(ctx, reclaimed, time.Hour, nil); !errors.Is(err, ErrJobLeaseLost) {
+			t.Fatalf("expected a cancelled job to stop its worker, got %v", err)
+		}
+		if _, err := store.CancelJob(ctx, "missing", "team-y"); !errors.Is(err, ErrJobNotFound) {
+			t.Fatalf("expected ErrJobNotFound, got %v", err)
+		}
+
+		entries, err := store.ListAuditEntries(ctx, AuditActionJobCancelled, 0)
+		if err != nil || len(entries) != 1 || entries[0].Subject != second.ID || entries[0].Actor != "team-y" {
+			t.Fatalf("expected cancellation to be audited, got %#v (%v)", entries, err)
+		}
+	})
+
+	t.Run("ResultCache", func(t *testing.T) {
+		if _, ok, err := store.GetCachedResult(ctx, "missing"); err != nil || ok {
+			t.Fatalf("expected a miss, got %t (%v)", ok, err)
+		}
+		if err := store.PutCachedResult(ctx, "", []byte(`{}`), time.Hour); err == nil {
+			t.Fatal("expected error for empty key")
+		}
+
+		if err := store.PutCachedResult(ctx, "first", []byte(`{"score": 100}`), time.Hour); err != nil {
+			t.Fatalf("put: %v", err)
+		}
+		if err := store.PutCachedResult(ctx, "first", []byte(`{"score": 90}`), 2*time.Hour); err != nil {
+			t.Fatalf("replace: %v", err)
+		}
+		if value, ok, err := store.GetCachedResult(ctx, "first"); err != nil || !ok || string(value) != `{"score": 90}` {
+			t.Fatalf("expected the replaced value, got %q %t (%v)", value, ok, err)
+		}
+
+		// An expired entry is a miss before it is pruned.
+		if err := store.PutCachedResult(ctx, "expired", []byte(`{}`), time.Nanosecond); err != nil {
+			t.Fatalf("put: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+		if _, ok, err := store.GetCachedResult(ctx, "expired"); err != nil || ok {
+			t.Fatalf("expected an expired entry to miss, got %t (%v)", ok, err)
+		}
+		if err := store.PutCachedResult(ctx, "second", []byte(`{}`), 3*time.Hour); err != nil {
+			t.Fatalf("put: %v", err)
+		}
+
+		// Pruning to one entry keeps the one expiring last.
+		if pruned, err := store.PruneCachedResults(ctx, 1); err != nil || pruned != 2 {
+			t.Fatalf("expected 2 entries pruned, got %d (%v)", pruned, err)
+		}
+		if _, ok, _ := store.GetCachedResult(ctx, "first"); ok {
+			t.Fatal("expected the entry expiring first to be pruned")
+		}
+		if _, ok, _ := store.GetCachedResult(ctx, "second"); !ok {
+			t.Fatal("expected the entry expiring last to be kept")
+		}
+		if pruned, err := store.PruneCachedResults(ctx, 0); err != nil || pruned != 0 {
+			t.Fatalf("expected nothing left to prune, got %d (%v)", pruned, err)
+		}
+	})
+
+	t.Run("RollbackAndReapply", func(t *testing.T) {
+		migrations, err := LoadMigrations()
+		if err != nil {
+			t.Fatalf("load migrations: %v", err)
+		}
+
+		reverted, err := store.Rollback(ctx, len(migrations))
+		if err != nil {
+			t.Fatalf("rollback: %v", err)
+		}
+		if len(reverted) != len(migrations) {
+			t.Fatalf("expected %d migrations rolled back, got %d", len(migrations), len(reverted))
+		}
+
+		applied, err := store.Migrate(ctx)
+		if err != nil {
+			t.Fatalf("re-migrate: %v", err)
+		}
+		if len(applied) != len(migrations) {
+			t.Fatalf("expected %d migrations re-applied, got %d", len(migrations), len(applied))
+		}
+	})
+}
+
+func TestCheckSchema(t *testing.T) {
+	ctx := context.Background()
+	store, err := OpenStore(ctx, "sqlite://"+filepath.Join(t.TempDir(), "schema.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	if err := store.CheckSchema(ctx); !errors.Is(err, ErrSchemaOutdated) {
+		t.Fatalf("expected ErrSchemaOutdated before migrating, got %v", err)
+	}
+	var tables int
+	if err := store.DB().QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table'`).Scan(&tables); err != nil || tables != 0 {
+		t.Fatalf("expected checking to create no tables, got %d (%v)", tables, err)
+	}
+
+	if _, err := store.Migrate(ctx); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if err := store.CheckSchema(ctx); err != nil {
+		t.Fatalf("expected a migrated schema to pass, got %v", err)
+	}
+
+	if _, err := store.Rollback(ctx, 1); err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+	if err := store.CheckSchema(ctx); !errors.Is(err, ErrSchemaOutdated) || !strings.Contains(err.Error(), "1 migration(s) pending") {
+		t.Fatalf("expected one pending migration, got %v", err)
+	}
+}
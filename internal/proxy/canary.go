@@ -0,0 +1,158 @@
+package proxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"promptsentinel/internal/validator"
+)
+
+// canaryReader passes a response body through unchanged while watching the
+// generated text for canary tokens. Event streams are decoded so that a
+// token split across several deltas is still found. onLeak is called once
+// per canary, the first time it is seen.
+type canaryReader struct {
+	body     io.ReadCloser
+	stream   bool
+	canaries []validator.CanaryToken
+	onLeak   func(validator.CanaryToken)
+
+	seen    map[string]bool
+	pending []byte // partial event stream line
+	window  string // tail of the text searched so far
+	keep    int    // length of window to carry between reads
+}
+
+// decodeBody replaces a gzip or deflate encoded response body with the text
+// it encodes, so that canaries can be found in it, and passes that text on
+// to the client. Compression is not asked for upstream, but an upstream may
+// apply it anyway. It returns an error for an encoding it cannot decode.
+func decodeBody(resp *http.Response) error {
+	var decoded io.Reader
+	var err error
+	switch encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))); encoding {
+	case "", "identity":
+		return nil
+	case "gzip", "x-gzip":
+		decoded, err = gzip.NewReader(resp.Body)
+	case "deflate":
+		decoded, err = zlib.NewReader(resp.Body)
+	default:
+		return fmt.Errorf("cannot scan a response with content encoding %q for canaries", encoding)
+	}
+	if err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{decoded, resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
+
+func newCanaryReader(body io.ReadCloser, stream bool, canaries []validator.CanaryToken, onLeak func(validator.CanaryToken)) *canaryReader {
+	keep := 0
+	for _, canary := range canaries {
+		if len(canary.Token) > keep {
+			keep = len(canary.Token)
+		}
+	}
+	return &canaryReader{
+		body:     body,
+		stream:   stream,
+		canaries: canaries,
+		onLeak:   onLeak,
+		seen:     make(map[string]bool),
+		keep:     keep,
+	}
+}
+
+func (r *canaryReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	if n > 0 {
+		if r.stream {
+			r.scanEvents(p[:n])
+		} else {
+			r.scan(string(p[:n]))
+		}
+	}
+	return n, err
+}
+
+func (r *canaryReader) Close() error {
+	return r.body.Close()
+}
+
+// scanEvents decodes complete "data:" lines of a server-sent event stream
+// and scans the text they carry
+func (r *canaryReader) scanEvents(chunk []byte) {
+	r.pending = append(r.pending, chunk...)
+	for {
+		end := bytes.IndexByte(r.pending, '\n')
+		if end < 0 {
+			return
+		}
+		line := bytes.TrimSpace(r.pending[:end])
+		r.pending = r.pending[end+1:]
+
+		data, ok := bytes.CutPrefix(line, []byte("data:"))
+		if !ok {
+			continue
+		}
+		r.scan(eventText(bytes.TrimSpace(data)))
+	}
+}
+
+// scan searches text, together with the end of the previous text, for
+// canaries not reported yet
+func (r *canaryReader) scan(text string) {
+	if text == "" {
+		return
+	}
+	r.window += text
+
+	for _, canary := range validator.FindCanaries(r.window, r.canaries) {
+		if !r.seen[canary.ID] {
+			r.seen[canary.ID] = true
+			r.onLeak(canary)
+		}
+	}
+
+	if len(r.window) > r.keep {
+		r.window = r.window[len(r.window)-r.keep:]
+	}
+}
+
+// eventText returns the generated text in one streamed chat or legacy
+// completion chunk
+func eventText(data []byte) string {
+	var chunk struct {
+		Choices []struct {
+			Text  string `json:"text"`
+			Delta struct {
+				Content string `json:"content"`
+			} `json:"delta"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		return ""
+	}
+
+	var text strings.Builder
+	for _, choice := range chunk.Choices {
+		text.WriteString(choice.Text)
+		text.WriteString(choice.Delta.Content)
+	}
+	return text.String()
+}
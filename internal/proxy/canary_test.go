@@ -0,0 +1,70 @@
+package proxy
+
+import (
+	"io"
+	"testing"
+
+	"promptsentinel/internal/validator"
+)
+
+func TestCanaryReaderFindsSplitStreamTokens(t *testing.T) {
+	canaries := []validator.CanaryToken{
+		{ID: "c1", Token: "psc-0123456789abcdef01234567"},
+		{ID: "c2", Token: "psc-fedcba9876543210fedcba98"},
+	}
+
+	// The token arrives in three deltas, and one event is split across
+	// reads.
+	stream := "data: {\"choices\":[{\"delta\":{\"content\":\"My rules: psc-0123\"}}]}\n\n" +
+		"data: {\"choices\":[{\"delta\":{\"content\":\"456789abcdef\"}}]}\n\n" +
+		"data: {\"choices\":[{\"delta\":{\"content\":\"01234567 and psc-0123456789abcdef01234567 again\"}}]}\n\n" +
+		"data: [DONE]\n\n"
+
+	var leaks []string
+	reader := newCanaryReader(io.NopCloser(&chunkedReader{data: stream, size: 7}), true, canaries, func(canary validator.CanaryToken) {
+		leaks = append(leaks, canary.ID)
+	})
+
+	out, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if string(out) != stream {
+		t.Error("expected the stream to pass through unchanged")
+	}
+	if len(leaks) != 1 || leaks[0] != "c1" {
+		t.Errorf("expected one leak of c1, got %v", leaks)
+	}
+}
+
+func TestCanaryReaderPlainBody(t *testing.T) {
+	canaries := []validator.CanaryToken{{ID: "c1", Token: "psc-0123456789abcdef01234567"}}
+
+	var leaks []string
+	body := `{"choices":[{"message":{"content":"psc-0123456789abcdef01234567"}}]}`
+	reader := newCanaryReader(io.NopCloser(&chunkedReader{data: body, size: 5}), false, canaries, func(canary validator.CanaryToken) {
+		leaks = append(leaks, canary.ID)
+	})
+	if _, err := io.ReadAll(reader); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if len(leaks) != 1 {
+		t.Errorf("expected token split across reads to be found, got %v", leaks)
+	}
+}
+
+// chunkedReader returns data a few bytes at a time.
+type chunkedReader struct {
+	data string
+	size int
+}
+
+func (r *chunkedReader) Read(p []byte) (int, error) {
+	if r.data == "" {
+		return 0, io.EOF
+	}
+	n := min(r.size, len(p), len(r.data))
+	copy(p, r.data[:n])
+	r.data = r.data[n:]
+	return n, nil
+}
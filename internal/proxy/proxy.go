@@ -0,0 +1,641 @@
+// Package proxy enforces PromptSentinel policy on OpenAI-compatible traffic.
+// It sits in front of a chat/completions endpoint, validates the text of
+// every request, and then blocks, redacts, or annotates the request before
+// forwarding it upstream. Responses, including server-sent event streams, are
+// passed back unchanged apart from the decision headers.
+//
+// Every client authenticates with a PromptSentinel API key, which is never
+// forwarded. Reads such as GET /v1/models pass through; any other request
+// the proxy cannot validate is refused rather than forwarded unchecked.
+//
+// When a canary store is configured, registered canary tokens are watched
+// for in both directions: a canary in a user or tool message is treated as
+// a flagged request, and a canary in a response is recorded as a leakage
+// incident as the response streams past. This works whether or not
+// validation history is recorded.
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"promptsentinel/internal/logging"
+	"promptsentinel/internal/notify"
+	"promptsentinel/internal/promptdb"
+	"promptsentinel/internal/validator"
+)
+
+// Modes select what the proxy does with a flagged request.
+const (
+	// ModeBlock rejects flagged requests without contacting the upstream.
+	ModeBlock = "block"
+	// ModeRedact replaces flagged content with validator.RedactionMarker and
+	// forwards the request. Requests that are still invalid afterwards, for
+	// example because they are too long, are rejected.
+	ModeRedact = "redact"
+	// ModeAnnotate forwards every request unchanged and only reports the
+	// decision in the response headers.
+	ModeAnnotate = "annotate"
+)
+
+// Decisions reported in the DecisionHeader.
+const (
+	DecisionAllowed   = "allowed"
+	DecisionBlocked   = "blocked"
+	DecisionRedacted  = "redacted"
+	DecisionAnnotated = "annotated"
+)
+
+// Response headers describing how a request was handled.
+const (
+	DecisionHeader      = "X-PromptSentinel-Decision"
+	ScoreHeader         = "X-PromptSentinel-Score"
+	IssuesHeader        = "X-PromptSentinel-Issues"
+	PolicyVersionHeader = "X-PromptSentinel-Policy-Version"
+	EventIDHeader       = "X-PromptSentinel-Event-Id"
+)
+
+// APIKeyHeader carries the client's PromptSentinel API key when its
+// Authorization header holds a provider key for the upstream.
+const APIKeyHeader = "X-API-Key"
+
+// maxRequestBytes bounds request bodies. It is larger than the API server's
+// limit because chat requests may carry inline images.
+const maxRequestBytes = 10 << 20
+
+// Options configures a Proxy.
+type Options struct {
+	// Upstream is the base URL of the OpenAI-compatible API, without the
+	// /v1 suffix. Required.
+	Upstream *url.URL
+	// LoadConfig returns the validation policy. It is called per request so
+	// configuration changes apply without a restart. Required.
+	LoadConfig func() (*validator.Config, error)
+	// Mode is one of ModeBlock, ModeRedact, or ModeAnnotate. Empty means
+	// ModeBlock.
+	Mode string
+	// UpstreamAPIKey, when set, replaces the client's Authorization header so
+	// clients never see the real provider key.
+	UpstreamAPIKey string
+	// Transport sends requests upstream. Nil uses http.DefaultTransport.
+	Transport http.RoundTripper
+	// Keys verifies the PromptSentinel API key every client must send.
+	// Required.
+	Keys *promptdb.Store
+	// Store, when set, records each checked request in validation history,
+	// under the owner of the client's key.
+	Store *promptdb.Store
+	// Canaries, when set, supplies the canary tokens to watch for and
+	// records leakage incidents, with or without history. Nil uses Store.
+	Canaries *promptdb.Store
+	// PromptCipher, when set, stores an encrypted copy of each request's text
+	// with its history event.
+	PromptCipher *promptdb.PromptCipher
+	// ValidationTimeout bounds the validation of each request. When it
+	// passes, the policy's fail mode decides whether the request is
+	// forwarded. Zero means no limit.
+	ValidationTimeout time.Duration
+	// Notifier, when set, is told about each canary leak recorded.
+	Notifier *notify.Notifier
+	// Logger receives failures that cannot be reported to the client, and
+	// the details of those reported only as internal errors.
+	Logger *slog.Logger
+}
+
+// errNoText is returned by inspect for a completion request with no text it
+// can find to validate.
+var errNoText = errors.New("no text to validate")
+
+// Proxy is an http.Handler that validates and forwards OpenAI-compatible
+// requests.
+type Proxy struct {
+	opts    Options
+	logger  *slog.Logger
+	forward *httputil.ReverseProxy
+}
+
+// ValidMode reports whether mode is a supported proxy mode.
+func ValidMode(mode string) bool {
+	switch mode {
+	case ModeBlock, ModeRedact, ModeAnnotate:
+		return true
+	}
+	return false
+}
+
+// New creates a Proxy. It returns an error when the options are incomplete.
+func New(opts Options) (*Proxy, error) {
+	if opts.Upstream == nil || opts.Upstream.Scheme == "" || opts.Upstream.Host == "" {
+		return nil, fmt.Errorf("upstream must be an absolute URL")
+	}
+	if opts.LoadConfig == nil {
+		return nil, fmt.Errorf("LoadConfig is required")
+	}
+	if opts.Keys == nil {
+		return nil, errors.New("a key store is required to authenticate clients")
+	}
+	if opts.Mode == "" {
+		opts.Mode = ModeBlock
+	}
+	if opts.Canaries == nil {
+		opts.Canaries = opts.Store
+	}
+	if !ValidMode(opts.Mode) {
+		return nil, fmt.Errorf("unknown proxy mode %q (expected block, redact, or annotate)", opts.Mode)
+	}
+
+	p := &Proxy{opts: opts, logger: logging.Redact(opts.Logger)}
+	p.forward = &httputil.ReverseProxy{
+		Rewrite:   p.rewrite,
+		Transport: opts.Transport,
+		// Flush every write so streamed completions reach the client as soon
+		// as the upstream produces them.
+		FlushInterval: -1,
+		ModifyResponse: func(resp *http.Response) error {
+			if decision, ok := resp.Request.Context().Value(decisionKey{}).(*Decision); ok {
+				decision.setHeaders(resp.Header)
+				return p.watchResponse(resp, decision)
+			}
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			if decision, ok := r.Context().Value(decisionKey{}).(*Decision); ok {
+				decision.setHeaders(w.Header())
+			}
+			writeError(w, http.StatusBadGateway, "upstream_error", "upstream request failed: "+err.Error())
+		},
+	}
+	return p, nil
+}
+
+// Decision summarizes how the proxy handled one request.
+type Decision struct {
+	Decision      string
+	Score         int
+	IssueTypes    []string
+	PolicyVersion string
+	EventID       string
+
+	// owner owns the client's API key.
+	owner string
+	// canaries are the active canary tokens to watch for in the response.
+	canaries []validator.CanaryToken
+}
+
+func (d *Decision) setHeaders(header http.Header) {
+	header.Set(DecisionHeader, d.Decision)
+	header.Set(ScoreHeader, strconv.Itoa(d.Score))
+	header.Set(IssuesHeader, strings.Join(d.IssueTypes, ","))
+	header.Set(PolicyVersionHeader, d.PolicyVersion)
+	if d.EventID != "" {
+		header.Set(EventIDHeader, d.EventID)
+	}
+}
+
+type decisionKey struct{}
+
+// ServeHTTP authenticates the client, then validates chat and legacy
+// completion requests and forwards them upstream. Reads from other
+// endpoints, such as /v1/models, are forwarded without inspection; writes to
+// them, such as /v1/responses or /v1/embeddings, are refused, since their
+// text would reach the upstream unvalidated.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	caller, err := p.authenticate(r)
+	switch {
+	case errors.Is(err, promptdb.ErrAPIKeyNotFound) || errors.Is(err, promptdb.ErrMalformedAPIKey):
+		w.Header().Set("WWW-Authenticate", `Bearer realm="promptsentinel"`)
+		writeError(w, http.StatusUnauthorized, "unauthorized", "a valid PromptSentinel API key is required")
+		return
+	case err != nil:
+		p.internalError(w, r, "storage_error", err)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		p.forward.ServeHTTP(w, r)
+		return
+	case r.Method != http.MethodPost || !isCompletionPath(r.URL.Path):
+		writeError(w, http.StatusForbidden, "unsupported_endpoint",
+			fmt.Sprintf("PromptSentinel cannot validate %s %s, so it is not forwarded", r.Method, r.URL.Path))
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, "request_too_large", "request body is too large")
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(body, &payload); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "request body must be a JSON object")
+		return
+	}
+
+	config, err := p.opts.LoadConfig()
+	if err != nil {
+		p.internalError(w, r, "config_error", err)
+		return
+	}
+
+	decision, rewritten, err := p.inspect(r.Context(), caller.OwnerID, payload, config)
+	switch {
+	case errors.Is(err, errNoText):
+		writeError(w, http.StatusForbidden, "unscreened_request",
+			"the request holds no text PromptSentinel can validate, so it is not forwarded")
+		return
+	case err != nil:
+		p.internalError(w, r, "validation_failed", err)
+		return
+	}
+
+	if decision.Decision == DecisionBlocked {
+		decision.setHeaders(w.Header())
+		writeError(w, http.StatusForbidden, "prompt_blocked",
+			fmt.Sprintf("request blocked by PromptSentinel policy (issues: %s)", strings.Join(decision.IssueTypes, ", ")))
+		return
+	}
+
+	if rewritten {
+		if body, err = json.Marshal(payload); err != nil {
+			p.internalError(w, r, "internal_error", err)
+			return
+		}
+	}
+
+	r = r.WithContext(context.WithValue(r.Context(), decisionKey{}, decision))
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	r.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	p.forward.ServeHTTP(w, r)
+}
+
+// internalError logs err and sends the client a 500 that does not describe
+// it, since storage and config errors can name files, hosts, and queries.
+func (p *Proxy) internalError(w http.ResponseWriter, r *http.Request, code string, err error) {
+	p.logger.ErrorContext(r.Context(), "request failed", "method", r.Method, "path", r.URL.Path, "code", code, "error", err)
+	writeError(w, http.StatusInternalServerError, code, "PromptSentinel could not process the request")
+}
+
+// authenticate verifies the client's PromptSentinel API key and removes it
+// from the request, so it is never forwarded. The key is read from
+// APIKeyHeader, or else from a bearer token, leaving Authorization to carry
+// a provider key when the client needs to send one.
+func (p *Proxy) authenticate(r *http.Request) (promptdb.APIKeyRecord, error) {
+	raw := r.Header.Get(APIKeyHeader)
+	if raw != "" {
+		r.Header.Del(APIKeyHeader)
+	} else {
+		if scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+			raw = strings.TrimSpace(token)
+		}
+		r.Header.Del("Authorization")
+	}
+	return p.opts.Keys.AuthenticateAPIKey(r.Context(), raw)
+}
+
+// validationContext bounds a validation by ctx and Options.ValidationTimeout
+func (p *Proxy) validationContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.opts.ValidationTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, p.opts.ValidationTimeout)
+}
+
+// inspect validates the text of payload, sent by owner, as a conversation,
+// so that each message is checked under its role's policy, and applies the
+// mode. It reports whether payload was modified.
+func (p *Proxy) inspect(ctx context.Context, owner string, payload map[string]any, config *validator.Config) (*Decision, bool, error) {
+	decision := &Decision{
+		Decision:      DecisionAllowed,
+		Score:         100,
+		IssueTypes:    []string{},
+		PolicyVersion: validator.PolicyVersion(config),
+		owner:         owner,
+	}
+
+	// Text in a shape textSegments does not know, such as an unfamiliar
+	// content block, would reach the upstream unvalidated.
+	segments := textSegments(payload)
+	if len(segments) == 0 {
+		if p.opts.Mode != ModeAnnotate {
+			return nil, false, errNoText
+		}
+		return decision, false, nil
+	}
+
+	messages := make([]validator.Message, len(segments))
+	for i, segment := range segments {
+		messages[i] = validator.Message{Role: segment.role, Content: segment.get()}
+	}
+
+	validationCtx, cancel := p.validationContext(ctx)
+	defer cancel()
+
+	result, err := validator.ValidateConversationContext(validationCtx, messages, config)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// A canary coming back in user or tool content means a system prompt
+	// has already leaked and is being replayed.
+	var promptLeaks []validator.CanaryToken
+	if p.opts.Canaries != nil {
+		if decision.canaries, err = p.opts.Canaries.ActiveCanaryTokens(ctx); err != nil {
+			return nil, false, err
+		}
+		promptLeaks = findPromptCanaries(messages, decision.canaries, &result.ValidationResult)
+	}
+
+	decision.Score = result.Score
+	decision.IssueTypes = issueTypes(result.Issues)
+
+	var flagged []validator.MessageResult
+	for _, message := range result.Messages {
+		if isFlagged(message.IsValid, message.Issues) {
+			flagged = append(flagged, message)
+		}
+	}
+
+	// Findings that span several turns, replayed canaries, and validation
+	// that failed closed cannot be fixed by redacting any one message.
+	unfixable := len(promptLeaks) > 0 || (result.Analysis != nil && isFlagged(true, result.Analysis.Issues)) ||
+		(result.Incomplete && !result.IsValid)
+
+	rewritten := false
+	switch {
+	case len(flagged) == 0 && !unfixable:
+	case p.opts.Mode == ModeAnnotate:
+		decision.Decision = DecisionAnnotated
+	case p.opts.Mode == ModeRedact:
+		decision.Decision = DecisionRedacted
+		for _, message := range flagged {
+			segment := segments[message.Index]
+			redacted, count := validator.RedactPrompt(segment.get(), config)
+			if count > 0 {
+				segment.set(redacted)
+				rewritten = true
+			}
+
+			// Redaction cannot fix problems such as an oversized message or
+			// an injection attempt.
+			after, err := validator.ValidateConversationContext(validationCtx, []validator.Message{{Role: segment.role, Content: redacted}}, config)
+			if err != nil {
+				return nil, false, err
+			}
+			if !after.IsValid {
+				decision.Decision = DecisionBlocked
+			}
+		}
+		if unfixable {
+			decision.Decision = DecisionBlocked
+		}
+	default:
+		decision.Decision = DecisionBlocked
+	}
+
+	if p.opts.Store != nil {
+		result.Outcome = validator.OutcomeAllowed
+		if decision.Decision == DecisionBlocked {
+			result.IsValid = false
+			result.Outcome = validator.OutcomeBlocked
+		}
+
+		event, err := promptdb.NewValidationEvent("proxy", owner, validator.ConversationText(messages), config, &result.ValidationResult, p.opts.PromptCipher)
+		if err != nil {
+			return nil, false, err
+		}
+		if decision.EventID, err = p.opts.Store.RecordValidationEvent(ctx, event); err != nil {
+			return nil, false, err
+		}
+	}
+	if len(promptLeaks) > 0 {
+		incidents, err := p.opts.Canaries.RecordCanaryLeaks(ctx, promptLeaks, "proxy", promptdb.LeakLocationPrompt, owner, decision.EventID)
+		if err != nil {
+			return nil, false, err
+		}
+		p.notifyLeaks(incidents)
+	}
+
+	return decision, rewritten, nil
+}
+
+// findPromptCanaries looks for canaries in user and tool messages, adding an
+// issue to result for each one found, and returns the distinct canaries
+func findPromptCanaries(messages []validator.Message, canaries []validator.CanaryToken, result *validator.ValidationResult) []validator.CanaryToken {
+	var leaks []validator.CanaryToken
+	seen := make(map[string]bool)
+	for i, message := range messages {
+		if message.Role != validator.RoleUser && message.Role != validator.RoleTool {
+			continue
+		}
+
+		for _, canary := range validator.FindCanaries(message.Content, canaries) {
+			index := i
+			issue := validator.CanaryIssue(canary, "Prompt")
+			issue.MessageIndex = &index
+			issue.Role = message.Role
+			result.Issues = append(result.Issues, issue)
+			result.IsValid = false
+			result.Score = max(result.Score-25, 0)
+
+			if !seen[canary.ID] {
+				seen[canary.ID] = true
+				leaks = append(leaks, canary)
+			}
+		}
+	}
+	return leaks
+}
+
+// watchResponse scans the response body for canaries as it is copied to the
+// client, decoding it first when the upstream compressed it. The response
+// has already started by the time a canary is seen, so leaks are recorded
+// as incidents rather than blocked. A body that cannot be scanned is an
+// error, rather than passed on unchecked.
+func (p *Proxy) watchResponse(resp *http.Response, decision *Decision) error {
+	if len(decision.canaries) == 0 || resp.Body == nil {
+		return nil
+	}
+	if err := decodeBody(resp); err != nil {
+		return err
+	}
+
+	stream := strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream")
+	resp.Body = newCanaryReader(resp.Body, stream, decision.canaries, func(canary validator.CanaryToken) {
+		// The request context ends with the response, so record the
+		// incident independently of it.
+		leaks := []validator.CanaryToken{canary}
+		incidents, err := p.opts.Canaries.RecordCanaryLeaks(context.Background(), leaks, "proxy", promptdb.LeakLocationResponse, decision.owner, decision.EventID)
+		if err != nil {
+			p.logger.Error("record canary leak failed", "canary", canary.ID, "error", err)
+			return
+		}
+		p.notifyLeaks(incidents)
+	})
+	return nil
+}
+
+// notifyLeaks tells the notifier about each leakage incident
+func (p *Proxy) notifyLeaks(incidents []promptdb.LeakageIncident) {
+	for _, incident := range incidents {
+		p.opts.Notifier.Notify(notify.CanaryLeak(incident))
+	}
+}
+
+// rewrite points an outgoing request at the upstream. The client's
+// Accept-Encoding is dropped so responses arrive as text the canary scan
+// can read; the transport may still compress the upstream hop itself.
+func (p *Proxy) rewrite(pr *httputil.ProxyRequest) {
+	pr.SetURL(p.opts.Upstream)
+	pr.SetXForwarded()
+	pr.Out.Header.Del("Accept-Encoding")
+	if p.opts.UpstreamAPIKey != "" {
+		pr.Out.Header.Set("Authorization", "Bearer "+p.opts.UpstreamAPIKey)
+	}
+}
+
+func isCompletionPath(path string) bool {
+	// Matches both /v1/chat/completions and the legacy /v1/completions.
+	return strings.HasSuffix(strings.TrimSuffix(path, "/"), "/completions")
+}
+
+// isFlagged reports whether a message has an issue the mode must act on.
+func isFlagged(valid bool, issues []validator.ValidationIssue) bool {
+	if !valid {
+		return true
+	}
+	for _, issue := range issues {
+		if issue.Severity == "warning" || issue.Severity == "error" {
+			return true
+		}
+	}
+	return false
+}
+
+func issueTypes(issues []validator.ValidationIssue) []string {
+	seen := make(map[string]bool)
+	types := []string{}
+	for _, issue := range issues {
+		if !seen[issue.Type] {
+			seen[issue.Type] = true
+			types = append(types, issue.Type)
+		}
+	}
+	sort.Strings(types)
+	return types
+}
+
+// segment is a piece of text inside a decoded request that can be read and
+// replaced in place, with the role of the message it belongs to.
+type segment struct {
+	role string
+	get  func() string
+	set  func(string)
+}
+
+// textSegments finds the validated text in a chat completion ("messages")
+// or legacy completion ("prompt") request. Message content may be a string
+// or an array of content parts; only text parts are returned.
+func textSegments(payload map[string]any) []segment {
+	var segments []segment
+
+	if messages, ok := payload["messages"].([]any); ok {
+		for _, m := range messages {
+			message, ok := m.(map[string]any)
+			if !ok {
+				continue
+			}
+			// Unknown roles are treated as user input, the strictest policy.
+			role, err := validator.NormalizeRole(fmt.Sprint(message["role"]))
+			if err != nil {
+				role = validator.RoleUser
+			}
+			segments = append(segments, contentSegments(message, "content", role)...)
+		}
+	}
+
+	switch prompt := payload["prompt"].(type) {
+	case string:
+		segments = append(segments, contentSegments(payload, "prompt", validator.RoleUser)...)
+	case []any:
+		for i := range prompt {
+			if text, ok := prompt[i].(string); ok && strings.TrimSpace(text) != "" {
+				segments = append(segments, sliceSegment(prompt, i, validator.RoleUser))
+			}
+		}
+	}
+
+	return segments
+}
+
+func contentSegments(object map[string]any, key, role string) []segment {
+	switch content := object[key].(type) {
+	case string:
+		if strings.TrimSpace(content) == "" {
+			return nil
+		}
+		return []segment{{
+			role: role,
+			get:  func() string { return object[key].(string) },
+			set:  func(value string) { object[key] = value },
+		}}
+	case []any:
+		var segments []segment
+		for _, p := range content {
+			part, ok := p.(map[string]any)
+			if !ok || part["type"] != "text" {
+				continue
+			}
+			if text, ok := part["text"].(string); ok && strings.TrimSpace(text) != "" {
+				segments = append(segments, segment{
+					role: role,
+					get:  func() string { return part["text"].(string) },
+					set:  func(value string) { part["text"] = value },
+				})
+			}
+		}
+		return segments
+	}
+	return nil
+}
+
+func sliceSegment(values []any, i int, role string) segment {
+	return segment{
+		role: role,
+		get:  func() string { return values[i].(string) },
+		set:  func(value string) { values[i] = value },
+	}
+}
+
+// ErrorResponse mirrors the OpenAI error format so existing client libraries
+// surface proxy errors the same way as provider errors.
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// ErrorBody describes a proxy error.
+type ErrorBody struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+	Code    string `json:"code"`
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{Error: ErrorBody{Message: message, Type: "promptsentinel_error", Code: code}})
+}
@@ -0,0 +1,190 @@
+// Package resultcache caches validation results, so a prompt sent again
+// under the same policy is answered without validating it again. Entries are
+// keyed by a hash of the prompt, or of the conversation after its messages
+// are normalized, together with the kind of validation, the policy version,
+// and the build. Any change to the configuration therefore misses every
+// entry made under the old one, with nothing to flush.
+//
+// Entries hold encoded results, which name the rules and detectors that
+// fired but never quote the prompt. As a safeguard, a result whose encoding
+// contains any of the prompt's text is not cached at all.
+package resultcache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"promptsentinel/internal/logging"
+	"promptsentinel/internal/metrics"
+	"promptsentinel/internal/validator"
+)
+
+// Defaults for the zero values of the backends' size and TTL.
+const (
+	DefaultSize = 10000
+	DefaultTTL  = time.Hour
+)
+
+// keyVersion changes when the layout of keys or entries does, so entries
+// written by older code are never read.
+const keyVersion = "1"
+
+// minQuoteLength is the shortest text, in bytes, whose appearance in an
+// encoded result counts as quoting it. Shorter prompts such as "hi" turn up
+// inside ordinary words, and would otherwise never be cached.
+const minQuoteLength = 8
+
+// Backend stores encoded results by key.
+type Backend interface {
+	// Get returns the value stored under key, reporting false when there
+	// is none or it has expired.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key, replacing any value already there.
+	Set(ctx context.Context, key string, value []byte) error
+}
+
+// Options configures a Cache.
+type Options struct {
+	// Metrics, when set, counts lookups by source and result.
+	Metrics *metrics.Metrics
+	// Logger, when set, receives backend failures, through
+	// logging.Redact.
+	Logger *slog.Logger
+}
+
+// Cache encodes results into a Backend and decodes them back. A nil *Cache
+// caches nothing, so callers need not check whether caching is enabled.
+type Cache struct {
+	backend Backend
+	opts    Options
+	logger  *slog.Logger
+}
+
+// New creates a Cache storing results in backend.
+func New(backend Backend, opts Options) *Cache {
+	return &Cache{backend: backend, opts: opts, logger: logging.Redact(opts.Logger)}
+}
+
+// Normalize returns text with CRLF line endings turned into LF and outer
+// whitespace trimmed. Key hashes normalized text, so callers must validate
+// the normalized text too: otherwise variants sharing a key could differ in
+// length and be judged differently.
+func Normalize(text string) string {
+	return strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+}
+
+// Key returns the key of source's validation of prompt, or of messages when
+// they are set, under config. The prompt and message contents are hashed
+// after Normalize, so whitespace variants of a prompt share an entry.
+func Key(source string, config *validator.Config, prompt string, messages []validator.Message) string {
+	hash := sha256.New()
+	for _, part := range []string{keyVersion, build(), source, validator.PolicyVersion(config)} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+
+	if messages != nil {
+		normalized := make([]validator.Message, len(messages))
+		for i, message := range messages {
+			normalized[i] = message
+			normalized[i].Content = Normalize(message.Content)
+		}
+		// Messages hold only strings, so they always encode.
+		data, _ := json.Marshal(normalized)
+		hash.Write([]byte("messages\x00"))
+		hash.Write(data)
+	} else {
+		hash.Write([]byte("prompt\x00"))
+		hash.Write([]byte(Normalize(prompt)))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Get decodes the result cached under key into result, reporting whether
+// there was one. A backend failure or an entry that cannot be decoded is
+// logged and reported as a miss.
+func (c *Cache) Get(ctx context.Context, source, key string, result any) bool {
+	if c == nil {
+		return false
+	}
+
+	value, ok, err := c.backend.Get(ctx, key)
+	if err == nil && ok {
+		err = json.Unmarshal(value, result)
+	}
+	switch {
+	case err != nil:
+		c.logger.WarnContext(ctx, "result cache lookup failed", "source", source, "error", err)
+		c.opts.Metrics.ObserveCacheLookup(source, metrics.CacheError)
+		return false
+	case !ok:
+		c.opts.Metrics.ObserveCacheLookup(source, metrics.CacheMiss)
+		return false
+	}
+	c.opts.Metrics.ObserveCacheLookup(source, metrics.CacheHit)
+	return true
+}
+
+// Put caches result under key. texts are the prompt or messages the result
+// was validated from; when the encoded result contains any of them at least
+// minQuoteLength bytes long, it is not cached. A backend failure is logged rather than returned, since the
+// result is still good.
+func (c *Cache) Put(ctx context.Context, key string, result any, texts ...string) {
+	if c == nil {
+		return
+	}
+
+	value, err := json.Marshal(result)
+	if err != nil {
+		c.logger.WarnContext(ctx, "result cache store failed", "error", err)
+		return
+	}
+	if containsAny(value, texts) {
+		c.logger.DebugContext(ctx, "result quotes its prompt; not caching it")
+		return
+	}
+	if err := c.backend.Set(ctx, key, value); err != nil {
+		c.logger.WarnContext(ctx, "result cache store failed", "error", err)
+	}
+}
+
+// containsAny reports whether value, a JSON encoding, contains any of texts
+// as they would be encoded in it, ignoring texts shorter than
+// minQuoteLength.
+func containsAny(value []byte, texts []string) bool {
+	for _, text := range texts {
+		text = strings.TrimSpace(text)
+		if len(text) < minQuoteLength {
+			continue
+		}
+		encoded, err := json.Marshal(text)
+		if err != nil || bytes.Contains(value, encoded[1:len(encoded)-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// build identifies the running build, so a cache shared by servers on
+// different versions of the validator does not mix their results.
+var build = sync.OnceValue(func() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	parts := []string{info.Main.Version}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" || setting.Key == "vcs.modified" {
+			parts = append(parts, setting.Value)
+		}
+	}
+	return strings.Join(parts, " ")
+})
@@ -0,0 +1,242 @@
+package resultcache
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"promptsentinel/internal/metrics"
+	"promptsentinel/internal/promptdb"
+	"promptsentinel/internal/validator"
+)
+
+func TestKey(t *testing.T) {
+	config := validator.DefaultConfig()
+	prompt := "Summarize the quarterly report"
+	key := Key("check", config, prompt, nil)
+
+	if len(key) != 64 || strings.Contains(key, "quarterly") {
+		t.Fatalf("expected a hex SHA-256 key, got %q", key)
+	}
+
+	touched := *config
+	touched.LastUpdated = time.Now().Add(time.Hour)
+	if Key("check", &touched, prompt, nil) != key {
+		t.Error("expected LastUpdated not to change the key")
+	}
+
+	business := *config
+	business.UseCase = "business"
+	stricter := *config
+	stricter.MaxLength = 20
+	messages := []validator.Message{{Role: validator.RoleUser, Content: prompt}}
+
+	for name, other := range map[string]string{
+		"another prompt":   Key("check", config, prompt+"!", nil),
+		"another source":   Key("validate", config, prompt, nil),
+		"another use case": Key("check", &business, prompt, nil),
+		"another policy":   Key("check", &stricter, prompt, nil),
+		"a conversation":   Key("check", config, "", messages),
+	} {
+		if other == key {
+			t.Errorf("%s: expected a different key", name)
+		}
+	}
+	if Key("check", config, "", messages) != Key("check", config, "", []validator.Message{{Role: validator.RoleUser, Content: prompt}}) {
+		t.Error("expected equal conversations to share a key")
+	}
+
+	for name, variant := range map[string]string{
+		"outer whitespace": " \t" + prompt + "\r\n",
+		"trailing space":   prompt + " ",
+	} {
+		if Key("check", config, variant, nil) != key {
+			t.Errorf("%s: expected the key of the normalized prompt", name)
+		}
+	}
+	lines := Key("check", config, "first line\nsecond line", nil)
+	if Key("check", config, "first line\r\nsecond line", nil) != lines {
+		t.Error("expected CRLF and LF line endings to share a key")
+	}
+	if Key("check", config, "", []validator.Message{{Role: validator.RoleUser, Content: prompt + "\r\n"}}) != Key("check", config, "", messages) {
+		t.Error("expected message contents to be normalized")
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	for text, want := range map[string]string{
+		"plain":               "plain",
+		"  padded\n\t":        "padded",
+		"first\r\nsecond\r\n": "first\nsecond",
+		"inner  spaces stay":  "inner  spaces stay",
+		"lone\rreturn":        "lone\rreturn",
+	} {
+		if got := Normalize(text); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestMemoryEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	memory := NewMemory(2, time.Hour)
+
+	_ = memory.Set(ctx, "a", []byte("1"))
+	_ = memory.Set(ctx, "b", []byte("2"))
+	if _, ok, _ := memory.Get(ctx, "a"); !ok {
+		t.Fatal("expected a hit for a")
+	}
+	_ = memory.Set(ctx, "c", []byte("3"))
+
+	if _, ok, _ := memory.Get(ctx, "b"); ok {
+		t.Error("expected b, the least recently used, to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok, _ := memory.Get(ctx, key); !ok {
+			t.Errorf("expected %s to be kept", key)
+		}
+	}
+
+	_ = memory.Set(ctx, "a", []byte("4"))
+	if value, _, _ := memory.Get(ctx, "a"); string(value) != "4" || memory.Len() != 2 {
+		t.Errorf("expected a to be replaced in place, got %q with %d entries", value, memory.Len())
+	}
+}
+
+func TestMemoryExpires(t *testing.T) {
+	ctx := context.Background()
+	memory := NewMemory(0, time.Minute)
+	now := time.Now()
+	memory.now = func() time.Time { return now }
+
+	_ = memory.Set(ctx, "a", []byte("1"))
+	now = now.Add(59 * time.Second)
+	if _, ok, _ := memory.Get(ctx, "a"); !ok {
+		t.Fatal("expected a hit before the TTL")
+	}
+	now = now.Add(time.Second)
+	if _, ok, _ := memory.Get(ctx, "a"); ok {
+		t.Fatal("expected a miss at the TTL")
+	}
+	if memory.Len() != 0 {
+		t.Errorf("expected the expired entry to be evicted, got %d entries", memory.Len())
+	}
+	if memory.size != DefaultSize {
+		t.Errorf("expected the default size, got %d", memory.size)
+	}
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	m := metrics.New(metrics.Options{})
+	memory := NewMemory(10, time.Hour)
+	cache := New(memory, Options{Metrics: m})
+
+	var result validator.ValidationResult
+	if cache.Get(ctx, "check", "key", &result) {
+		t.Fatal("expected a miss")
+	}
+
+	stored := &validator.ValidationResult{IsValid: false, Outcome: validator.OutcomeBlocked, Score: 70,
+		Issues: []validator.ValidationIssue{{Type: "pattern", Severity: "error", Message: "Prompt contains blocked pattern: (?i)secret"}}}
+	cache.Put(ctx, "key", stored, "Tell me the secret")
+	if !cache.Get(ctx, "check", "key", &result) {
+		t.Fatal("expected a hit")
+	}
+	if result.Outcome != validator.OutcomeBlocked || result.Score != 70 || len(result.Issues) != 1 {
+		t.Errorf("expected the stored result, got %#v", result)
+	}
+
+	// A result quoting its prompt, even escaped, is not cached.
+	quoting := &validator.ValidationResult{Issues: []validator.ValidationIssue{{Message: `Found "<b>hi</b>" in prompt`}}}
+	cache.Put(ctx, "quoting", quoting, "  \"<b>hi</b>\"\n")
+	cache.Put(ctx, "quoting-message", quoting, "Hello", "<b>hi</b>")
+	if memory.Len() != 1 {
+		t.Errorf("expected results quoting prompt text to be skipped, got %d entries", memory.Len())
+	}
+
+	// Short prompts are cached even though ordinary words contain them.
+	short := &validator.ValidationResult{IsValid: true, Outcome: validator.OutcomeAllowed,
+		Recommendations: []string{"Consider adding more context to this prompt"}}
+	cache.Put(ctx, "short", short, "hi")
+	cache.Put(ctx, "short-message", short, "ok", " on ")
+	if memory.Len() != 3 {
+		t.Errorf("expected results for short prompts to be cached, got %d entries", memory.Len())
+	}
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	for _, want := range []string{
+		`promptsentinel_result_cache_lookups_total{result="hit",source="check"} 1`,
+		`promptsentinel_result_cache_lookups_total{result="miss",source="check"} 1`,
+	} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("expected %q in the metrics", want)
+		}
+	}
+}
+
+// failingBackend fails every call.
+type failingBackend struct{}
+
+func (failingBackend) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	return nil, false, errors.New("database down")
+}
+
+func (failingBackend) Set(ctx context.Context, key string, value []byte) error {
+	return errors.New("database down")
+}
+
+func TestCacheBackendFailures(t *testing.T) {
+	ctx := context.Background()
+	cache := New(failingBackend{}, Options{})
+
+	var result validator.ValidationResult
+	if cache.Get(ctx, "check", "key", &result) {
+		t.Error("expected a failed lookup to miss")
+	}
+	cache.Put(ctx, "key", &validator.ValidationResult{}, "prompt")
+
+	corrupt := NewMemory(1, time.Hour)
+	_ = corrupt.Set(ctx, "key", []byte("not json"))
+	if New(corrupt, Options{}).Get(ctx, "check", "key", &result) {
+		t.Error("expected an undecodable entry to miss")
+	}
+}
+
+func TestNilCache(t *testing.T) {
+	var cache *Cache
+	var result validator.ValidationResult
+	cache.Put(context.Background(), "key", &result, "prompt")
+	if cache.Get(context.Background(), "check", "key", &result) {
+		t.Error("expected a nil cache to miss")
+	}
+}
+
+func TestSQL(t *testing.T) {
+	ctx := context.Background()
+	store, err := promptdb.OpenStore(ctx, "sqlite://"+filepath.Join(t.TempDir(), "cache.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	if _, err := store.Migrate(ctx); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	cache := New(NewSQL(store, 0, 0), Options{})
+	key := Key("check", validator.DefaultConfig(), "Write a haiku", nil)
+	cache.Put(ctx, key, &validator.ValidationResult{IsValid: true, Outcome: validator.OutcomeAllowed, Score: 100}, "Write a haiku")
+
+	var result validator.ValidationResult
+	if !cache.Get(ctx, "check", key, &result) || !result.IsValid || result.Score != 100 {
+		t.Fatalf("expected the stored result, got %#v", result)
+	}
+	if cache.Get(ctx, "check", Key("check", validator.DefaultConfig(), "Write a sonnet", nil), &result) {
+		t.Error("expected another prompt to miss")
+	}
+}
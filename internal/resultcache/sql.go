@@ -0,0 +1,53 @@
+package resultcache
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"promptsentinel/internal/promptdb"
+)
+
+// pruneEvery is how many writes an SQL backend makes between prunes.
+const pruneEvery = 1000
+
+// SQL is a Backend in a promptdb store, shared by every server using the
+// database. Entries expire after its TTL. Every pruneEvery writes, expired
+// entries are deleted and the rest trimmed to its size, those expiring
+// first going first; until then the table may briefly hold more.
+type SQL struct {
+	store  *promptdb.Store
+	size   int
+	ttl    time.Duration
+	writes atomic.Int64
+}
+
+// NewSQL creates an SQL backend in store holding about size entries for ttl
+// each. Zero values use DefaultSize and DefaultTTL.
+func NewSQL(store *promptdb.Store, size int, ttl time.Duration) *SQL {
+	if size <= 0 {
+		size = DefaultSize
+	}
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &SQL{store: store, size: size, ttl: ttl}
+}
+
+// Get returns the value stored under key.
+func (s *SQL) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	return s.store.GetCachedResult(ctx, key)
+}
+
+// Set stores value under key, pruning the table every pruneEvery writes.
+func (s *SQL) Set(ctx context.Context, key string, value []byte) error {
+	if err := s.store.PutCachedResult(ctx, key, value, s.ttl); err != nil {
+		return err
+	}
+	if s.writes.Add(1)%pruneEvery == 0 {
+		if _, err := s.store.PruneCachedResults(ctx, s.size); err != nil {
+			return err
+		}
+	}
+	return nil
+}
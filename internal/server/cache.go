@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"promptsentinel/internal/resultcache"
+	"promptsentinel/internal/validator"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// check runs a quick check of prompt, or of messages when they are set,
+// through the result cache. conversation is set for messages.
+func (s *Server) check(ctx context.Context, prompt string, messages []validator.Message, config *validator.Config) (result *validator.ValidationResult, conversation *validator.ConversationResult, err error) {
+	if messages != nil {
+		conversation, err = cached(ctx, s, "check", prompt, messages, config, conversationBase,
+			func() (*validator.ConversationResult, error) {
+				return validator.ValidateConversationContext(ctx, messages, config)
+			})
+		if err != nil {
+			return nil, nil, err
+		}
+		return &conversation.ValidationResult, conversation, nil
+	}
+
+	result, err = cached(ctx, s, "check", prompt, nil, config, promptBase,
+		func() (*validator.ValidationResult, error) {
+			return validator.ValidatePromptContext(ctx, prompt, config)
+		})
+	return result, nil, err
+}
+
+// validate runs a comprehensive validation of prompt, or of messages when
+// they are set, through the result cache.
+func (s *Server) validate(ctx context.Context, prompt string, messages []validator.Message, config *validator.Config) (*validator.ComprehensiveValidationResult, error) {
+	return cached(ctx, s, "validate", prompt, messages, config, comprehensiveBase,
+		func() (*validator.ComprehensiveValidationResult, error) {
+			if messages != nil {
+				return validator.ValidateConversationComprehensiveContext(ctx, messages, config)
+			}
+			return validator.ValidatePromptComprehensiveContext(ctx, prompt, config)
+		})
+}
+
+// normalize returns text as the result cache keys it when a cache is
+// configured, so variants sharing an entry are judged alike, and text
+// unchanged otherwise, as the CLI and library would validate it.
+func (s *Server) normalize(text string) string {
+	if s.opts.Cache == nil {
+		return text
+	}
+	return resultcache.Normalize(text)
+}
+
+// cached returns source's result for prompt or messages from
+// Options.Cache, or runs validate and caches its result. base returns the
+// ValidationResult embedded in a result. Results cut short by the
+// validation timeout are not cached, and cached results are copies made
+// before recording, so they never carry an approval ticket.
+func cached[T any](ctx context.Context, s *Server, source, prompt string, messages []validator.Message, config *validator.Config, base func(*T) *validator.ValidationResult, validate func() (*T, error)) (*T, error) {
+	if s.opts.Cache == nil {
+		return validate()
+	}
+
+	key := resultcache.Key(source, config, prompt, messages)
+	var hit T
+	if s.opts.Cache.Get(ctx, source, key, &hit) {
+		trace.SpanFromContext(ctx).SetAttributes(attrCacheHit.Bool(true))
+		base(&hit).Timestamp = time.Now()
+		return &hit, nil
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attrCacheHit.Bool(false))
+
+	result, err := validate()
+	if err != nil || base(result).Incomplete {
+		return result, err
+	}
+
+	texts := []string{prompt}
+	for _, message := range messages {
+		texts = append(texts, message.Content)
+	}
+	s.opts.Cache.Put(ctx, key, result, texts...)
+	return result, nil
+}
+
+// The ValidationResult of each kind of result, for cached.
+func promptBase(r *validator.ValidationResult) *validator.ValidationResult { return r }
+
+func conversationBase(r *validator.ConversationResult) *validator.ValidationResult {
+	return &r.ValidationResult
+}
+
+func comprehensiveBase(r *validator.ComprehensiveValidationResult) *validator.ValidationResult {
+	return &r.ValidationResult
+}
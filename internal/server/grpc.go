@@ -0,0 +1,296 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	pb "promptsentinel/api/promptsentinel/v1"
+	"promptsentinel/internal/validator"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// maxBatchSize bounds the prompts in one Batch call, as maxRequestBytes
+// bounds an HTTP request.
+const maxBatchSize = 100
+
+// GRPCServer returns a gRPC server for the PromptSentinel service in
+// api/promptsentinel/v1. It shares this Server's store, policy, API keys,
+// and rate limits, so it can run beside the HTTP API in the same process.
+// opts are passed on to grpc.NewServer; their interceptors run after
+// authentication.
+func (s *Server) GRPCServer(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(s.traceUnary, s.logUnary, s.authenticateUnary),
+		grpc.ChainStreamInterceptor(s.traceStream, s.logStream, s.authenticateStream),
+		grpc.MaxRecvMsgSize(maxRequestBytes),
+	}, opts...)
+
+	server := grpc.NewServer(opts...)
+	pb.RegisterPromptSentinelServer(server, &grpcService{s: s})
+	return server
+}
+
+// authenticateCall is authenticate for gRPC: it resolves the API key in the
+// call's metadata and stores the owner in the context.
+func (s *Server) authenticateCall(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	caller, err := s.opts.Store.AuthenticateAPIKey(ctx, credential(firstValue(md, "authorization"), firstValue(md, "x-api-key")))
+	if err != nil {
+		s.logRejectedKey(ctx, err)
+		return nil, status.Error(codes.Unauthenticated, "a valid API key is required")
+	}
+	return withCaller(ctx, caller), nil
+}
+
+// allowCall applies the rate limit of the owner in ctx to one request.
+func (s *Server) allowCall(ctx context.Context) error {
+	owner := ownerFromContext(ctx)
+	if wait, ok := s.limiter.allow(owner); !ok {
+		s.logRateLimited(ctx, owner, wait)
+		return status.Errorf(codes.ResourceExhausted, "rate limit exceeded; retry in %ds", int(math.Ceil(wait.Seconds())))
+	}
+	return nil
+}
+
+func (s *Server) authenticateUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := s.authenticateCall(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.allowCall(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// authenticateStream authenticates a stream once, when it opens. Opening it
+// is not rate limited; ValidateStream counts each message it receives as a
+// request instead.
+func (s *Server) authenticateStream(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authenticateCall(stream.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+}
+
+// contextStream replaces a stream's context, to carry the owner or a span
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// grpcService implements the PromptSentinel gRPC service on a Server
+type grpcService struct {
+	pb.UnimplementedPromptSentinelServer
+	s *Server
+}
+
+func (g *grpcService) Check(ctx context.Context, req *pb.PromptRequest) (*pb.CheckResponse, error) {
+	prompt, messages, config, err := g.decodePromptRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return g.check(ctx, prompt, messages, config)
+}
+
+func (g *grpcService) Validate(ctx context.Context, req *pb.PromptRequest) (*pb.ComprehensiveValidationResult, error) {
+	prompt, messages, config, err := g.decodePromptRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	validationCtx, cancel := g.s.validationContext(ctx)
+	defer cancel()
+	start := time.Now()
+
+	result, err := g.s.validate(validationCtx, prompt, messages, config)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "validation failed: %v", err)
+	}
+	elapsed := time.Since(start)
+
+	if err := g.s.recordComprehensive(ctx, "validate", requestText(PromptRequest{Prompt: prompt}, messages), config, result); err != nil {
+		return nil, status.Errorf(codes.Internal, "storage error: %v", err)
+	}
+	g.s.observe(validationCtx, "validate", config.UseCase, &result.ValidationResult, elapsed)
+
+	return toProtoComprehensive(result), nil
+}
+
+// Batch checks every request only after all of them decode, so a malformed
+// request does not leave the batch half recorded.
+func (g *grpcService) Batch(ctx context.Context, req *pb.BatchRequest) (*pb.BatchResponse, error) {
+	if len(req.GetRequests()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "requests cannot be empty")
+	}
+	if len(req.GetRequests()) > maxBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "a batch holds at most %d requests", maxBatchSize)
+	}
+
+	type decoded struct {
+		prompt   string
+		messages []validator.Message
+		config   *validator.Config
+	}
+	requests := make([]decoded, len(req.GetRequests()))
+	for i, r := range req.GetRequests() {
+		prompt, messages, config, err := g.decodePromptRequest(ctx, r)
+		if err != nil {
+			return nil, status.Errorf(status.Code(err), "requests[%d]: %s", i, status.Convert(err).Message())
+		}
+		requests[i] = decoded{prompt, messages, config}
+	}
+
+	resp := &pb.BatchResponse{Results: make([]*pb.CheckResponse, len(requests))}
+	for i, r := range requests {
+		result, err := g.check(ctx, r.prompt, r.messages, r.config)
+		if err != nil {
+			return nil, err
+		}
+		resp.Results[i] = result
+	}
+	return resp, nil
+}
+
+// Redact does not record history: the check it runs is of text the caller
+// did not send.
+func (g *grpcService) Redact(ctx context.Context, req *pb.RedactRequest) (*pb.RedactResponse, error) {
+	if strings.TrimSpace(req.GetPrompt()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "prompt cannot be empty")
+	}
+
+	config, err := g.loadConfig(ctx, req.GetUseCase())
+	if err != nil {
+		return nil, err
+	}
+
+	redacted, count := validator.RedactPrompt(req.GetPrompt(), config)
+
+	validationCtx, cancel := g.s.validationContext(ctx)
+	defer cancel()
+	start := time.Now()
+
+	result, err := validator.ValidatePromptContext(validationCtx, redacted, config)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "validation failed: %v", err)
+	}
+	g.s.observe(validationCtx, "redact", config.UseCase, result, time.Since(start))
+
+	return &pb.RedactResponse{Redacted: redacted, Redactions: int32(count), Result: toProtoResult(result)}, nil
+}
+
+// ValidateStream ends the stream at the first request that fails to decode
+// or check, or that exceeds the owner's rate limit.
+func (g *grpcService) ValidateStream(stream grpc.BidiStreamingServer[pb.PromptRequest, pb.CheckResponse]) error {
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := g.s.allowCall(stream.Context()); err != nil {
+			return err
+		}
+
+		prompt, messages, config, err := g.decodePromptRequest(stream.Context(), req)
+		if err != nil {
+			return err
+		}
+		resp, err := g.check(stream.Context(), prompt, messages, config)
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// check runs and records a check, as handleCheck does
+func (g *grpcService) check(ctx context.Context, prompt string, messages []validator.Message, config *validator.Config) (*pb.CheckResponse, error) {
+	validationCtx, cancel := g.s.validationContext(ctx)
+	defer cancel()
+	start := time.Now()
+
+	result, conversation, err := g.s.check(validationCtx, prompt, messages, config)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "validation failed: %v", err)
+	}
+	elapsed := time.Since(start)
+
+	if _, err := g.s.recordResult(ctx, "check", requestText(PromptRequest{Prompt: prompt}, messages), config, result); err != nil {
+		return nil, status.Errorf(codes.Internal, "storage error: %v", err)
+	}
+	g.s.observe(validationCtx, "check", config.UseCase, result, elapsed)
+
+	// The result is copied after recording, which may mark it pending.
+	return toProtoCheck(result, conversation), nil
+}
+
+// decodePromptRequest validates a PromptRequest and loads the policy, as
+// Server.decodePromptRequest does for HTTP. Conversations go through
+// validator.ParseMessages so both APIs accept the same roles, and text is
+// normalized alike.
+func (g *grpcService) decodePromptRequest(ctx context.Context, req *pb.PromptRequest) (string, []validator.Message, *validator.Config, error) {
+	var messages []validator.Message
+	switch {
+	case len(req.GetMessages()) > 0 && req.GetPrompt() != "":
+		return "", nil, nil, status.Error(codes.InvalidArgument, "send either prompt or messages, not both")
+	case len(req.GetMessages()) > 0:
+		raw := make([]validator.Message, len(req.GetMessages()))
+		for i, m := range req.GetMessages() {
+			raw[i] = validator.Message{Role: m.GetRole(), Content: m.GetContent()}
+		}
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return "", nil, nil, status.Errorf(codes.Internal, "encode messages: %v", err)
+		}
+		if messages, err = g.s.normalizeMessages(ctx, data); err != nil {
+			return "", nil, nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	case strings.TrimSpace(req.GetPrompt()) == "":
+		return "", nil, nil, status.Error(codes.InvalidArgument, "prompt cannot be empty")
+	}
+
+	config, err := g.loadConfig(ctx, req.GetUseCase())
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return g.s.normalize(req.GetPrompt()), messages, config, nil
+}
+
+// loadConfig loads the policy with useCase, when set, overriding its use
+// case
+func (g *grpcService) loadConfig(ctx context.Context, useCase string) (*validator.Config, error) {
+	config, err := g.s.loadConfig(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "config error: %v", err)
+	}
+	if useCase != "" {
+		config.UseCase = useCase
+	}
+	return config, nil
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"encoding/json"
+
+	pb "promptsentinel/api/promptsentinel/v1"
+	"promptsentinel/internal/validator"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// The functions below copy validator results into their protobuf messages,
+// field for field, so both APIs report the same thing.
+
+func toProtoCheck(result *validator.ValidationResult, conversation *validator.ConversationResult) *pb.CheckResponse {
+	resp := &pb.CheckResponse{Result: toProtoResult(result)}
+	if conversation == nil {
+		return resp
+	}
+
+	for _, m := range conversation.Messages {
+		resp.Messages = append(resp.Messages, &pb.MessageResult{
+			Index:   int32(m.Index),
+			Role:    m.Role,
+			IsValid: m.IsValid,
+			Score:   int32(m.Score),
+			Issues:  toProtoIssues(m.Issues),
+		})
+	}
+	resp.ConversationAnalysis = toProtoConversationAnalysis(conversation.Analysis)
+	return resp
+}
+
+func toProtoResult(result *validator.ValidationResult) *pb.ValidationResult {
+	return &pb.ValidationResult{
+		IsValid:         result.IsValid,
+		Outcome:         result.Outcome,
+		ApprovalId:      result.ApprovalID,
+		Score:           int32(result.Score),
+		Issues:          toProtoIssues(result.Issues),
+		Recommendations: result.Recommendations,
+		Metadata:        toProtoMetadata(result.Metadata),
+		Timestamp:       timestamppb.New(result.Timestamp),
+		Incomplete:      result.Incomplete,
+	}
+}
+
+func toProtoIssues(issues []validator.ValidationIssue) []*pb.ValidationIssue {
+	out := make([]*pb.ValidationIssue, len(issues))
+	for i, issue := range issues {
+		out[i] = &pb.ValidationIssue{
+			Type:         issue.Type,
+			Severity:     issue.Severity,
+			Message:      issue.Message,
+			Suggestion:   issue.Suggestion,
+			Line:         int32(issue.Line),
+			Column:       int32(issue.Column),
+			MessageIndex: toProtoIndex(issue.MessageIndex),
+			Role:         issue.Role,
+			ChunkIndex:   toProtoIndex(issue.ChunkIndex),
+			Path:         issue.Path,
+		}
+	}
+	return out
+}
+
+func toProtoIndex(index *int) *int32 {
+	if index == nil {
+		return nil
+	}
+	i := int32(*index)
+	return &i
+}
+
+// toProtoMetadata converts metadata through its JSON form, which is what
+// the HTTP API returns. Metadata that cannot be encoded is left out rather
+// than failing a validation that succeeded.
+func toProtoMetadata(metadata map[string]interface{}) *structpb.Struct {
+	if metadata == nil {
+		return nil
+	}
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return nil
+	}
+	var out structpb.Struct
+	if err := protojson.Unmarshal(data, &out); err != nil {
+		return nil
+	}
+	return &out
+}
+
+func toProtoComprehensive(result *validator.ComprehensiveValidationResult) *pb.ComprehensiveValidationResult {
+	security, compliance, performance := result.SecurityAnalysis, result.ComplianceCheck, result.PerformanceMetrics
+
+	out := &pb.ComprehensiveValidationResult{
+		Result: toProtoResult(&result.ValidationResult),
+		SecurityAnalysis: &pb.SecurityAnalysis{
+			HasInjectionAttempts: security.HasInjectionAttempts,
+			HasSensitiveData:     security.HasSensitiveData,
+			RiskLevel:            security.RiskLevel,
+			Threats:              security.Threats,
+		},
+		ComplianceCheck: &pb.ComplianceCheck{
+			GdprCompliant:    compliance.GDPRCompliant,
+			HipaaCompliant:   compliance.HIPAACompliant,
+			SoxCompliant:     compliance.SOXCompliant,
+			ComplianceIssues: compliance.ComplianceIssues,
+		},
+		PerformanceMetrics: &pb.PerformanceMetrics{
+			EstimatedTokens:   int32(performance.EstimatedTokens),
+			Tokenizer:         performance.Tokenizer,
+			ComplexityScore:   performance.ComplexityScore,
+			ProcessingTimeMs:  performance.ProcessingTime,
+			ProcessingTimeUs:  performance.ProcessingTimeMicros,
+			ResourceIntensive: performance.ResourceIntensive,
+			Stages:            toProtoTimings(performance.Stages),
+			Detectors:         toProtoTimings(performance.Detectors),
+		},
+		ConversationAnalysis: toProtoConversationAnalysis(result.ConversationAnalysis),
+	}
+
+	for _, fit := range performance.ModelFit {
+		out.PerformanceMetrics.ModelFit = append(out.PerformanceMetrics.ModelFit, &pb.ModelFit{
+			Model:            fit.Model,
+			Tokens:           int32(fit.Tokens),
+			ContextWindow:    int32(fit.ContextWindow),
+			Fits:             fit.Fits,
+			ContextUsed:      fit.ContextUsed,
+			EstimatedCostUsd: fit.EstimatedCostUSD,
+		})
+	}
+	return out
+}
+
+func toProtoTimings(timings []validator.Timing) []*pb.Timing {
+	out := make([]*pb.Timing, len(timings))
+	for i, t := range timings {
+		out[i] = &pb.Timing{Name: t.Name, Detail: t.Detail, Stage: t.Stage, Calls: int32(t.Calls), DurationUs: t.DurationMicros}
+	}
+	return out
+}
+
+func toProtoConversationAnalysis(analysis *validator.ConversationAnalysis) *pb.ConversationAnalysis {
+	if analysis == nil {
+		return nil
+	}
+
+	out := &pb.ConversationAnalysis{
+		RiskLevel:      analysis.RiskLevel,
+		CumulativeRisk: int32(analysis.CumulativeRisk),
+		PeakRisk:       int32(analysis.PeakRisk),
+		Crescendo:      analysis.Crescendo,
+		SplitPayload:   analysis.SplitPayload,
+		CoercedPersona: analysis.CoercedPersona,
+		Issues:         toProtoIssues(analysis.Issues),
+	}
+	for _, turn := range analysis.Timeline {
+		out.Timeline = append(out.Timeline, &pb.TurnRisk{
+			Index:          int32(turn.Index),
+			Role:           turn.Role,
+			Risk:           int32(turn.Risk),
+			CumulativeRisk: int32(turn.CumulativeRisk),
+			Signals:        turn.Signals,
+		})
+	}
+	return out
+}
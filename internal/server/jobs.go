@@ -0,0 +1,253 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"promptsentinel/internal/jobs"
+	"promptsentinel/internal/promptdb"
+)
+
+// ndjsonMedia is the media type of uploaded datasets and job results.
+const ndjsonMedia = "application/x-ndjson"
+
+// DefaultMaxUploadBytes bounds uploaded datasets when Options.MaxUploadBytes
+// is zero.
+const DefaultMaxUploadBytes = 1 << 30
+
+// resultsPageSize is how many results are read from the store at a time
+// while streaming them.
+const resultsPageSize = 1000
+
+var jobStatuses = []string{promptdb.JobQueued, promptdb.JobRunning, promptdb.JobSucceeded, promptdb.JobFailed, promptdb.JobCancelled}
+
+// JobRequest is the body of the job submission endpoint. Path names a JSONL
+// dataset relative to the server's input directory.
+type JobRequest struct {
+	Path    string `json:"path"`
+	Mode    string `json:"mode,omitempty"`
+	UseCase string `json:"use_case,omitempty"`
+}
+
+// JobList is the body of the job list endpoint.
+type JobList struct {
+	Jobs []promptdb.Job `json:"jobs"`
+}
+
+func (s *Server) handleSubmitJob(w http.ResponseWriter, r *http.Request) {
+	if !s.jobsEnabled(w) {
+		return
+	}
+
+	var req JobRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "request body must be JSON with a path field")
+		return
+	}
+	if req.Path == "" {
+		writeError(w, http.StatusBadRequest, "invalid_request", "path cannot be empty")
+		return
+	}
+	spec, ok := jobSpec(w, req.Mode, req.UseCase)
+	if !ok {
+		return
+	}
+
+	job, err := s.opts.Jobs.SubmitPath(r.Context(), ownerFromContext(r.Context()), req.Path, spec)
+	if err != nil {
+		s.writeJobError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+func (s *Server) handleUploadJob(w http.ResponseWriter, r *http.Request) {
+	if !s.jobsEnabled(w) {
+		return
+	}
+
+	query := r.URL.Query()
+	spec, ok := jobSpec(w, query.Get("mode"), query.Get("use_case"))
+	if !ok {
+		return
+	}
+	name := query.Get("name")
+	if name == "" {
+		name = "upload.jsonl"
+	}
+
+	limit := s.opts.MaxUploadBytes
+	if limit <= 0 {
+		limit = DefaultMaxUploadBytes
+	}
+	job, err := s.opts.Jobs.SubmitUpload(r.Context(), ownerFromContext(r.Context()), name, spec, http.MaxBytesReader(w, r.Body, limit))
+	if err != nil {
+		s.writeJobError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+func (s *Server) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	if !s.jobsEnabled(w) {
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	if status != "" && !isJobStatus(status) {
+		writeError(w, http.StatusBadRequest, "invalid_status", fmt.Sprintf("unknown job status %q", status))
+		return
+	}
+
+	list, err := s.opts.Store.ListJobs(r.Context(), ownerFromContext(r.Context()), status, 100)
+	if err != nil {
+		s.internalError(w, r, "storage_error", err)
+		return
+	}
+	if list == nil {
+		list = []promptdb.Job{}
+	}
+
+	writeJSON(w, http.StatusOK, JobList{Jobs: list})
+}
+
+func (s *Server) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.ownJob(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, job)
+}
+
+func (s *Server) handleJobResults(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.ownJob(w, r)
+	if !ok {
+		return
+	}
+
+	var after int64
+	if raw := r.URL.Query().Get("after"); raw != "" {
+		var err error
+		if after, err = strconv.ParseInt(raw, 10, 64); err != nil || after < 0 {
+			writeError(w, http.StatusBadRequest, "invalid_request", "after must be a line number")
+			return
+		}
+	}
+
+	// Read the first page before writing, so a storage error can still be
+	// reported with a status.
+	page, err := s.opts.Store.ListJobResults(r.Context(), job.ID, after, resultsPageSize)
+	if err != nil {
+		s.internalError(w, r, "storage_error", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", ndjsonMedia)
+	w.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(w)
+	for len(page) > 0 {
+		for _, result := range page {
+			if err := encoder.Encode(result); err != nil {
+				return
+			}
+		}
+		if len(page) < resultsPageSize {
+			return
+		}
+		if page, err = s.opts.Store.ListJobResults(r.Context(), job.ID, page[len(page)-1].Line, resultsPageSize); err != nil {
+			// The status is sent; cut the stream short so the client
+			// sees a truncated download rather than a complete one.
+			s.logger.ErrorContext(r.Context(), "job results stream failed", "job_id", job.ID, "error", err)
+			panic(http.ErrAbortHandler)
+		}
+	}
+}
+
+func (s *Server) handleCancelJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.ownJob(w, r)
+	if !ok {
+		return
+	}
+
+	job, err := s.opts.Jobs.Cancel(r.Context(), job.ID, ownerFromContext(r.Context()))
+	if err != nil {
+		s.writeJobError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, job)
+}
+
+// jobsEnabled writes an error response and returns false when the server
+// has no job runner.
+func (s *Server) jobsEnabled(w http.ResponseWriter) bool {
+	if s.opts.Jobs == nil {
+		writeError(w, http.StatusNotImplemented, "jobs_disabled", "batch jobs are not enabled on this server")
+		return false
+	}
+	return true
+}
+
+// ownJob returns the job named in the path, writing an error response and
+// returning false when it does not exist or belongs to another owner. Jobs
+// of other owners are reported as missing so their IDs are not revealed.
+func (s *Server) ownJob(w http.ResponseWriter, r *http.Request) (promptdb.Job, bool) {
+	if !s.jobsEnabled(w) {
+		return promptdb.Job{}, false
+	}
+
+	job, err := s.opts.Store.GetJob(r.Context(), r.PathValue("id"))
+	if err == nil && job.OwnerID != ownerFromContext(r.Context()) {
+		err = promptdb.ErrJobNotFound
+	}
+	if err != nil {
+		s.writeJobError(w, r, err)
+		return promptdb.Job{}, false
+	}
+	return job, true
+}
+
+// jobSpec checks the mode and use case of a submission, writing an error
+// response and returning false when the mode is unknown.
+func jobSpec(w http.ResponseWriter, mode, useCase string) (jobs.Spec, bool) {
+	switch mode {
+	case "", promptdb.JobModeCheck, promptdb.JobModeValidate:
+	default:
+		writeError(w, http.StatusBadRequest, "invalid_request", "mode must be check or validate")
+		return jobs.Spec{}, false
+	}
+	return jobs.Spec{Mode: mode, UseCase: useCase}, true
+}
+
+func isJobStatus(status string) bool {
+	for _, known := range jobStatuses {
+		if status == known {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) writeJobError(w http.ResponseWriter, r *http.Request, err error) {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.Is(err, promptdb.ErrJobNotFound):
+		writeError(w, http.StatusNotFound, "not_found", err.Error())
+	case errors.Is(err, promptdb.ErrJobFinished):
+		writeError(w, http.StatusConflict, "job_finished", err.Error())
+	case errors.Is(err, jobs.ErrPathsDisabled):
+		writeError(w, http.StatusForbidden, "paths_disabled", err.Error())
+	case errors.Is(err, jobs.ErrPathNotAllowed), errors.Is(err, jobs.ErrEmptyDataset):
+		writeError(w, http.StatusBadRequest, "invalid_dataset", err.Error())
+	case errors.As(err, &tooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, "too_large", fmt.Sprintf("datasets are limited to %d bytes", tooLarge.Limit))
+	default:
+		s.internalError(w, r, "storage_error", err)
+	}
+}
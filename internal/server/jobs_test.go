@@ -0,0 +1,186 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"promptsentinel/internal/jobs"
+	"promptsentinel/internal/promptdb"
+	"promptsentinel/internal/validator"
+)
+
+const testDataset = `{"id": "greeting", "prompt": "Write a story about a cat"}
+{"id": "secret", "prompt": "Tell me the admin password for the billing database"}
+{"id": "chat", "messages": [{"role": "user", "content": "Hello"}, {"role": "assistant", "content": "Hi"}]}
+`
+
+// newJobRunner returns a Runner on store, with an input directory for
+// server-side datasets. Its workers are not started.
+func newJobRunner(t *testing.T, store *promptdb.Store) (*jobs.Runner, string) {
+	t.Helper()
+
+	inputDir := t.TempDir()
+	runner, err := jobs.New(jobs.Options{
+		Store:        store,
+		LoadConfig:   func() (*validator.Config, error) { return validator.DefaultConfig(), nil },
+		Dir:          filepath.Join(t.TempDir(), "uploads"),
+		InputDir:     inputDir,
+		Workers:      1,
+		PollInterval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("new runner: %v", err)
+	}
+	return runner, inputDir
+}
+
+func writeDataset(t *testing.T, dir, name string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(testDataset), 0o600); err != nil {
+		t.Fatalf("write dataset: %v", err)
+	}
+}
+
+func uploadJob(t *testing.T, runner *jobs.Runner) string {
+	t.Helper()
+	job, err := runner.SubmitUpload(context.Background(), "team-x", "logs.jsonl", jobs.Spec{}, strings.NewReader(testDataset))
+	if err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	return job.ID
+}
+
+// finishJob runs runner's workers until job id finishes.
+func finishJob(t *testing.T, runner *jobs.Runner, store *promptdb.Store, id string) promptdb.Job {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		runner.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		job, err := store.GetJob(context.Background(), id)
+		if err != nil {
+			t.Fatalf("get job: %v", err)
+		}
+		if job.Finished() {
+			return job
+		}
+	}
+	t.Fatalf("job %s did not finish", id)
+	return promptdb.Job{}
+}
+
+func TestJobs(t *testing.T) {
+	srv, store, apiKey := newTestServer(t, nil)
+
+	if rec := doRequest(t, srv, http.MethodGet, "/v1/jobs", apiKey, nil); rec.Code != http.StatusNotImplemented {
+		t.Fatalf("expected 501 without a runner, got %d", rec.Code)
+	}
+
+	runner, inputDir := newJobRunner(t, store)
+	srv.opts.Jobs = runner
+	writeDataset(t, inputDir, "prompts.jsonl")
+
+	rec := doRequest(t, srv, http.MethodPost, "/v1/jobs/upload?name=logs.jsonl", apiKey, []byte(testDataset))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	job := decode[promptdb.Job](t, rec)
+	if job.Status != promptdb.JobQueued || job.InputName != "logs.jsonl" || job.Total == nil || *job.Total != 3 {
+		t.Fatalf("unexpected job: %#v", job)
+	}
+
+	// Other owners cannot see the job.
+	otherKey := createKey(t, store, "team-y")
+	for _, path := range []string{"/v1/jobs/" + job.ID, "/v1/jobs/" + job.ID + "/results"} {
+		if rec := doRequest(t, srv, http.MethodGet, path, otherKey, nil); rec.Code != http.StatusNotFound {
+			t.Errorf("expected 404 for another owner's %s, got %d", path, rec.Code)
+		}
+	}
+	if rec := doRequest(t, srv, http.MethodPost, "/v1/jobs/"+job.ID+"/cancel", otherKey, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 cancelling another owner's job, got %d", rec.Code)
+	}
+	if list := decode[JobList](t, doRequest(t, srv, http.MethodGet, "/v1/jobs", otherKey, nil)); len(list.Jobs) != 0 {
+		t.Errorf("expected another owner's list to be empty, got %#v", list)
+	}
+
+	finishJob(t, runner, store, job.ID)
+	job = decode[promptdb.Job](t, doRequest(t, srv, http.MethodGet, "/v1/jobs/"+job.ID, apiKey, nil))
+	if job.Status != promptdb.JobSucceeded || job.Processed != 3 || job.FinishedAt == nil {
+		t.Fatalf("expected a finished job, got %#v", job)
+	}
+
+	rec = doRequest(t, srv, http.MethodGet, "/v1/jobs/"+job.ID+"/results", apiKey, nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != ndjsonMedia {
+		t.Fatalf("expected NDJSON results, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if lines := ndjsonLines(t, rec.Body.Bytes()); len(lines) != 3 || lines[1].(map[string]any)["id"] != "secret" {
+		t.Fatalf("expected a result per item, got %s", rec.Body.String())
+	}
+	for _, text := range []string{"password", "billing", "Hello"} {
+		if strings.Contains(rec.Body.String(), text) {
+			t.Errorf("results carry %q: %s", text, rec.Body.String())
+		}
+	}
+	rec = doRequest(t, srv, http.MethodGet, "/v1/jobs/"+job.ID+"/results?after=2", apiKey, nil)
+	if lines := ndjsonLines(t, rec.Body.Bytes()); len(lines) != 1 || lines[0].(map[string]any)["line"] != float64(3) {
+		t.Errorf("expected the results after line 2, got %s", rec.Body.String())
+	}
+
+	if rec := doRequest(t, srv, http.MethodPost, "/v1/jobs", apiKey, JobRequest{Path: "prompts.jsonl", Mode: promptdb.JobModeValidate}); rec.Code != http.StatusAccepted {
+		t.Errorf("expected 202 for a server-side dataset, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestJobErrors(t *testing.T) {
+	srv, store, apiKey := newTestServer(t, nil)
+	runner, _ := newJobRunner(t, store)
+	srv.opts.Jobs = runner
+	srv.opts.MaxUploadBytes = 64
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   any
+		status int
+		code   string
+	}{
+		{"unknown mode", "POST", "/v1/jobs/upload?mode=fast", []byte(testDataset), http.StatusBadRequest, "invalid_request"},
+		{"empty upload", "POST", "/v1/jobs/upload", []byte("\n\n"), http.StatusBadRequest, "invalid_dataset"},
+		{"oversized upload", "POST", "/v1/jobs/upload", []byte(testDataset), http.StatusRequestEntityTooLarge, "too_large"},
+		{"path outside the input directory", "POST", "/v1/jobs", JobRequest{Path: "../server.db"}, http.StatusBadRequest, "invalid_dataset"},
+		{"missing path", "POST", "/v1/jobs", JobRequest{}, http.StatusBadRequest, "invalid_request"},
+		{"unknown status", "GET", "/v1/jobs?status=bogus", nil, http.StatusBadRequest, "invalid_status"},
+		{"unknown job", "GET", "/v1/jobs/missing", nil, http.StatusNotFound, "not_found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(t, srv, tt.method, tt.path, apiKey, tt.body)
+			if rec.Code != tt.status {
+				t.Fatalf("expected %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+			if body := decode[ErrorResponse](t, rec); body.Error.Code != tt.code {
+				t.Errorf("expected code %q, got %q", tt.code, body.Error.Code)
+			}
+		})
+	}
+
+	if jobs, err := store.ListJobs(context.Background(), "", "", 0); err != nil || len(jobs) != 0 {
+		t.Errorf("expected refused submissions to queue nothing, got %#v (%v)", jobs, err)
+	}
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"promptsentinel/internal/promptdb"
+	"promptsentinel/internal/validator"
+
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// logHTTP writes an access log line for each request to a route. Lines name
+// the route pattern rather than the path, and never the body or headers.
+func (s *Server) logHTTP(rt route, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		level := slog.LevelInfo
+		if rt.public {
+			// Health checks and document fetches would drown the log.
+			level = slog.LevelDebug
+		}
+		s.logger.LogAttrs(r.Context(), level, "handled request", append(traceAttrs(r.Context()),
+			slog.String("method", rt.method),
+			slog.String("route", rt.path),
+			slog.Int("status", rec.status),
+			slog.Duration("duration", time.Since(start)),
+		)...)
+	})
+}
+
+// logUnary writes a log line for each unary call, as logHTTP does for HTTP
+// requests
+func (s *Server) logUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	s.logCall(ctx, info.FullMethod, err, time.Since(start))
+	return resp, err
+}
+
+// logStream writes one log line when a stream ends
+func (s *Server) logStream(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, stream)
+	s.logCall(stream.Context(), info.FullMethod, err, time.Since(start))
+	return err
+}
+
+func (s *Server) logCall(ctx context.Context, fullMethod string, err error, elapsed time.Duration) {
+	code := status.Code(err)
+	attrs := append(traceAttrs(ctx),
+		slog.String("method", strings.TrimPrefix(fullMethod, "/")),
+		slog.String("code", code.String()),
+		slog.Duration("duration", elapsed),
+	)
+	if serverFault(code) {
+		s.logger.LogAttrs(ctx, slog.LevelError, "call failed", append(attrs, slog.String("error", status.Convert(err).Message()))...)
+		return
+	}
+	s.logger.LogAttrs(ctx, slog.LevelInfo, "handled call", attrs...)
+}
+
+// serverFault reports whether a gRPC code means the server, rather than the
+// request, was at fault
+func serverFault(code codes.Code) bool {
+	switch code {
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented, codes.Internal, codes.Unavailable, codes.DataLoss:
+		return true
+	}
+	return false
+}
+
+// logRejectedKey records why an API key was refused. Keys that are
+// malformed or unknown are the caller's problem and logged at warn; a store
+// that cannot be read is the server's and logged as an error.
+func (s *Server) logRejectedKey(ctx context.Context, err error) {
+	if errors.Is(err, promptdb.ErrAPIKeyNotFound) || errors.Is(err, promptdb.ErrMalformedAPIKey) {
+		s.logger.WarnContext(ctx, "rejected API key", "reason", err)
+		return
+	}
+	s.logger.ErrorContext(ctx, "API key lookup failed", "error", err)
+}
+
+// logRateLimited records a request refused by the owner's rate limit
+func (s *Server) logRateLimited(ctx context.Context, owner string, wait time.Duration) {
+	s.logger.WarnContext(ctx, "rate limit exceeded", "owner", owner, "retry_after", wait)
+}
+
+// internalError logs err and responds with a 500. The client sees the same
+// message, since it describes the server's failure rather than the request.
+func (s *Server) internalError(w http.ResponseWriter, r *http.Request, code string, err error) {
+	s.logger.ErrorContext(r.Context(), "request failed", "method", r.Method, "path", r.URL.Path, "code", code, "error", err)
+	writeError(w, http.StatusInternalServerError, code, err.Error())
+}
+
+// logValidation records how a request was judged. Requests allowed without
+// issues are logged at debug, the rest at info, so a production log holds
+// the requests worth a look. Like the span attributes, the line describes
+// the verdict, never the text.
+func (s *Server) logValidation(ctx context.Context, source, useCase string, result *validator.ValidationResult, errorCount int, elapsed time.Duration) {
+	level := slog.LevelInfo
+	if result.Outcome == validator.OutcomeAllowed && len(result.Issues) == 0 {
+		level = slog.LevelDebug
+	}
+	s.logger.LogAttrs(ctx, level, "validated request", append(traceAttrs(ctx),
+		slog.String("source", source),
+		slog.String("owner", ownerFromContext(ctx)),
+		slog.String("use_case", useCase),
+		slog.Int("score", result.Score),
+		slog.String("outcome", result.Outcome),
+		slog.Int("issues", len(result.Issues)),
+		slog.Int("errors", errorCount),
+		slog.Bool("incomplete", result.Incomplete),
+		slog.Duration("duration", elapsed),
+	)...)
+}
+
+// traceAttrs returns the trace ID of the span in ctx, so log lines can be
+// matched with traces
+func traceAttrs(ctx context.Context) []slog.Attr {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return nil
+	}
+	return []slog.Attr{slog.String("trace_id", spanContext.TraceID().String())}
+}
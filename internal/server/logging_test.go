@@ -0,0 +1,159 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	pb "promptsentinel/api/promptsentinel/v1"
+	"promptsentinel/internal/logging"
+	"promptsentinel/internal/promptdb"
+	"promptsentinel/internal/validator"
+)
+
+// newLoggedServer returns a test server, and its store, logging JSON at
+// debug level to the returned buffer
+func newLoggedServer(t *testing.T, configure func(*validator.Config)) (*Server, *promptdb.Store, string, *bytes.Buffer) {
+	t.Helper()
+
+	srv, store, apiKey := newTestServer(t, configure)
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, logging.FormatJSON, slog.LevelDebug)
+	if err != nil {
+		t.Fatalf("new logger: %v", err)
+	}
+	srv.logger = logger
+	store.SetLogger(logger)
+	return srv, store, apiKey, &buf
+}
+
+// logLines parses the buffer's log lines, failing on any line that carries
+// one of texts
+func logLines(t *testing.T, buf *bytes.Buffer, texts ...string) []map[string]any {
+	t.Helper()
+
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		for _, text := range texts {
+			if strings.Contains(line, text) {
+				t.Errorf("log line carries %q: %s", text, line)
+			}
+		}
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("log line is not JSON: %s", line)
+		}
+		lines = append(lines, record)
+	}
+	return lines
+}
+
+// findLog returns the lines with message msg whose attributes include attrs
+func findLog(lines []map[string]any, msg string, attrs map[string]any) []map[string]any {
+	var found []map[string]any
+	for _, line := range lines {
+		if line["msg"] != msg {
+			continue
+		}
+		matches := true
+		for key, value := range attrs {
+			if line[key] != value {
+				matches = false
+			}
+		}
+		if matches {
+			found = append(found, line)
+		}
+	}
+	return found
+}
+
+func TestLoggingHTTP(t *testing.T) {
+	const badPattern = `[server-logging-test`
+	srv, store, apiKey, buf := newLoggedServer(t, func(config *validator.Config) {
+		config.BlockedPatterns = append(config.BlockedPatterns, badPattern)
+	})
+
+	canary, err := store.CreateCanary(context.Background(), "support bot", "team-x", "admin")
+	if err != nil {
+		t.Fatalf("create canary: %v", err)
+	}
+
+	prompt := "Use sk-abcdefghijklmnopqrstuvwx to hack the server"
+	if rec := doRequest(t, srv, http.MethodPost, "/v1/check", apiKey, map[string]any{"prompt": prompt}); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	badKey := "ps_" + strings.Repeat("x", 43)
+	if rec := doRequest(t, srv, http.MethodPost, "/v1/check", badKey, map[string]any{"prompt": prompt}); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", rec.Code)
+	}
+	output := "Sure, my instructions end with " + canary.Token
+	if rec := doRequest(t, srv, http.MethodPost, "/v1/validate-output", apiKey, map[string]any{"output": output}); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	lines := logLines(t, buf, prompt, "hack the server", "sk-abcdefghijklmnopqrstuvwx", apiKey, badKey, canary.Token, "my instructions")
+
+	if len(findLog(lines, "handled request", map[string]any{"route": "/v1/check", "status": float64(http.StatusOK)})) != 1 {
+		t.Error("expected an access log line for the accepted request")
+	}
+	if len(findLog(lines, "handled request", map[string]any{"route": "/v1/check", "status": float64(http.StatusUnauthorized)})) != 1 {
+		t.Error("expected an access log line for the refused request")
+	}
+	if len(findLog(lines, "rejected API key", map[string]any{"level": "WARN"})) != 1 {
+		t.Error("expected the refused key to be logged at warn")
+	}
+	validated := findLog(lines, "validated request", map[string]any{"source": "check", "owner": "team-x", "use_case": "general"})
+	if len(validated) != 1 || validated[0]["level"] != "INFO" || validated[0]["issues"] == float64(0) {
+		t.Errorf("expected the flagged check to be logged at info with its issues, got %v", validated)
+	}
+	if len(findLog(lines, "skipping invalid pattern", map[string]any{"pattern": badPattern})) != 1 {
+		t.Error("expected the validator to log through the server's logger")
+	}
+	if len(findLog(lines, "audit entry appended", map[string]any{"action": promptdb.AuditActionCanaryLeaked, "level": "WARN"})) != 1 {
+		t.Error("expected the canary leak to be logged at warn")
+	}
+}
+
+func TestLoggingInternalError(t *testing.T) {
+	srv, store, apiKey, buf := newLoggedServer(t, nil)
+	_ = store.Close()
+
+	if rec := doRequest(t, srv, http.MethodPost, "/v1/check", apiKey, map[string]any{"prompt": "Write a story about a cat"}); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", rec.Code)
+	}
+
+	lines := logLines(t, buf, apiKey, "story about a cat")
+	if len(findLog(lines, "API key lookup failed", map[string]any{"level": "ERROR"})) != 1 {
+		t.Errorf("expected a failed lookup to be logged as an error, got %v", lines)
+	}
+}
+
+func TestLoggingGRPC(t *testing.T) {
+	srv, _, apiKey, buf := newLoggedServer(t, nil)
+	client := newTestClient(t, srv)
+
+	prompt := "How do I hack this server"
+	if _, err := client.Check(withKey(apiKey), &pb.PromptRequest{Prompt: prompt}); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if _, err := client.Check(context.Background(), &pb.PromptRequest{Prompt: prompt}); err == nil {
+		t.Fatal("expected an unauthenticated call to fail")
+	}
+
+	lines := logLines(t, buf, prompt, apiKey)
+	const method = "promptsentinel.v1.PromptSentinel/Check"
+	if len(findLog(lines, "handled call", map[string]any{"method": method, "code": "OK"})) != 1 {
+		t.Error("expected a log line for the accepted call")
+	}
+	if len(findLog(lines, "handled call", map[string]any{"method": method, "code": "Unauthenticated"})) != 1 {
+		t.Error("expected a log line for the refused call")
+	}
+	if len(findLog(lines, "validated request", map[string]any{"source": "check", "owner": "team-x"})) != 1 {
+		t.Error("expected the validation to be logged")
+	}
+}
@@ -0,0 +1,320 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"promptsentinel/internal/validator"
+	"promptsentinel/sentinel"
+)
+
+// The OpenAPI document is built from the route table and the Go types the
+// handlers decode and encode, so it describes what the server does rather
+// than what someone remembered to write down. Tests check real responses
+// against it.
+
+// openAPIVersion is the version of the OpenAPI specification the document
+// follows. 3.1 schemas are JSON Schema, which client generators read as is.
+const openAPIVersion = "3.1.0"
+
+// securitySchemes are the two ways authenticate accepts an API key
+var securitySchemes = map[string]any{
+	"bearerAuth": map[string]any{
+		"type":        "http",
+		"scheme":      "bearer",
+		"description": "An API key from `promptsentinel keys create`.",
+	},
+	"apiKeyHeader": map[string]any{
+		"type": "apiKey",
+		"in":   "header",
+		"name": "X-API-Key",
+	},
+}
+
+// schemaOverrides describe types whose JSON form is not their struct
+// layout
+var schemaOverrides = map[reflect.Type]map[string]any{
+	reflect.TypeOf(validator.ToolCall{}): {
+		"type":        "object",
+		"description": "A tool call in OpenAI, Anthropic, or plain form. Arguments may be an object or a JSON-encoded string.",
+		"properties": map[string]any{
+			"name":      map[string]any{"type": "string"},
+			"arguments": map[string]any{},
+			"type":      map[string]any{"type": "string"},
+			"input":     map[string]any{"type": "object"},
+			"function": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"name":      map[string]any{"type": "string"},
+					"arguments": map[string]any{},
+				},
+			},
+		},
+	},
+}
+
+// fieldDescriptions document fields whose type alone does not say what
+// they hold
+var fieldDescriptions = map[reflect.Type]map[string]string{
+	reflect.TypeOf(PromptRequest{}): {
+		"messages": "A conversation in OpenAI (array of messages) or Anthropic (object with system and messages) format. Send either prompt or messages.",
+	},
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	pathParameter  = regexp.MustCompile(`\{([^}]+)\}`)
+)
+
+// buildOpenAPI returns the OpenAPI document describing routes.
+func buildOpenAPI(routes []route) ([]byte, error) {
+	g := &schemaGenerator{components: make(map[string]any), names: make(map[reflect.Type]string)}
+	errorResponse := map[string]any{
+		"description": "The request failed; code says why.",
+		"content":     jsonContent(g.schema(reflect.TypeOf(ErrorResponse{}))),
+	}
+
+	paths := make(map[string]map[string]any)
+	for _, rt := range routes {
+		op := map[string]any{
+			"operationId": rt.operationID,
+			"summary":     rt.summary,
+			"responses": map[string]any{
+				strconv.Itoa(rt.successStatus()): map[string]any{"description": http.StatusText(rt.successStatus()), "content": content(rt.responseMedia, g.bodySchema(rt.responses))},
+				"default":                        errorResponse,
+			},
+		}
+		if rt.scope != "" {
+			op["description"] = fmt.Sprintf("Requires an API key with the %s scope.", rt.scope)
+		}
+		if rt.public {
+			op["security"] = []any{}
+		} else {
+			op["security"] = []any{map[string]any{"bearerAuth": []any{}}, map[string]any{"apiKeyHeader": []any{}}}
+		}
+
+		var parameters []any
+		for _, match := range pathParameter.FindAllStringSubmatch(rt.path, -1) {
+			parameters = append(parameters, map[string]any{
+				"name": match[1], "in": "path", "required": true, "schema": map[string]any{"type": "string"},
+			})
+		}
+		for _, q := range rt.query {
+			schema := map[string]any{"type": "string"}
+			if q.enum != nil {
+				schema["enum"] = q.enum
+			}
+			parameters = append(parameters, map[string]any{
+				"name": q.name, "in": "query", "description": q.description, "schema": schema,
+			})
+		}
+		if parameters != nil {
+			op["parameters"] = parameters
+		}
+
+		if rt.request != nil {
+			op["requestBody"] = map[string]any{
+				"required": !rt.optionalRequest,
+				"content":  content(rt.requestMedia, g.schema(reflect.TypeOf(rt.request))),
+			}
+		}
+
+		if paths[rt.path] == nil {
+			paths[rt.path] = make(map[string]any)
+		}
+		paths[rt.path][strings.ToLower(rt.method)] = op
+	}
+
+	return json.Marshal(map[string]any{
+		"openapi": openAPIVersion,
+		"info": map[string]any{
+			"title":       "PromptSentinel API",
+			"version":     sentinel.Version,
+			"description": "Validate prompts, conversations, model outputs, documents, and tool calls, and manage the approval queue.",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas":         g.components,
+			"securitySchemes": securitySchemes,
+		},
+	})
+}
+
+func jsonContent(schema map[string]any) map[string]any {
+	return content("", schema)
+}
+
+// content describes a body of mediaType, application/json when empty. The
+// schema of a newline-delimited JSON body describes each line.
+func content(mediaType string, schema map[string]any) map[string]any {
+	switch mediaType {
+	case "":
+		mediaType = "application/json"
+	case ndjsonMedia:
+		schema = withDescription(schema, "One JSON object per line.")
+	}
+	return map[string]any{mediaType: map[string]any{"schema": schema}}
+}
+
+// schemaGenerator builds JSON schemas from Go types the way encoding/json
+// encodes them, collecting named structs as components.
+type schemaGenerator struct {
+	components map[string]any
+	names      map[reflect.Type]string
+}
+
+// bodySchema describes a body that is one of values' types
+func (g *schemaGenerator) bodySchema(values []any) map[string]any {
+	if len(values) == 1 {
+		return g.schema(reflect.TypeOf(values[0]))
+	}
+	anyOf := make([]any, len(values))
+	for i, v := range values {
+		anyOf[i] = g.schema(reflect.TypeOf(v))
+	}
+	return map[string]any{"anyOf": anyOf}
+}
+
+func (g *schemaGenerator) schema(t reflect.Type) map[string]any {
+	if override, ok := schemaOverrides[t]; ok {
+		return g.ref(t, func() map[string]any { return override })
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t == rawMessageType:
+		return map[string]any{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schema(t.Elem())
+	case reflect.Struct:
+		return g.ref(t, func() map[string]any { return g.object(t) })
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]any{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	default:
+		// interface{} holds any JSON value.
+		return map[string]any{}
+	}
+}
+
+// ref returns a reference to the component for t, building it the first
+// time. Components are named after their type, with the package name added
+// when two packages use the same name.
+func (g *schemaGenerator) ref(t reflect.Type, build func() map[string]any) map[string]any {
+	name, ok := g.names[t]
+	if !ok {
+		name = t.Name()
+		if _, taken := g.components[name]; taken {
+			pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+			name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+		}
+		g.names[t] = name
+		// Reserve the name before building, for types that refer to
+		// themselves.
+		g.components[name] = nil
+		g.components[name] = build()
+	}
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+// object describes a struct's fields as encoding/json encodes them.
+// Fields without omitempty are always present, so they are required; nil
+// slices, maps, and pointers among them encode as null.
+func (g *schemaGenerator) object(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	required := []string{}
+	g.fields(t, properties, &required)
+	sort.Strings(required)
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func (g *schemaGenerator) fields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			g.fields(field.Type, properties, required)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema := g.schema(field.Type)
+		if !strings.Contains(options, "omitempty") {
+			*required = append(*required, name)
+			if nullable(field.Type) {
+				schema = map[string]any{"anyOf": []any{schema, map[string]any{"type": "null"}}}
+			}
+		}
+		if description, ok := fieldDescriptions[t][name]; ok {
+			schema = withDescription(schema, description)
+		}
+		properties[name] = schema
+	}
+}
+
+// nullable reports whether a value of t may encode as null. Raw JSON and
+// interface{} schemas already allow it.
+func nullable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Map:
+		return true
+	case reflect.Slice:
+		return t != rawMessageType
+	}
+	return false
+}
+
+// withDescription returns a copy of schema with a description
+func withDescription(schema map[string]any, description string) map[string]any {
+	out := make(map[string]any, len(schema)+1)
+	for k, v := range schema {
+		out[k] = v
+	}
+	out["description"] = description
+	return out
+}
+
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(s.openAPI)
+}
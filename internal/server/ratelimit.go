@@ -0,0 +1,57 @@
+package server
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// rateLimiter limits the requests of each API key owner. One limiter is
+// shared by the HTTP and gRPC APIs, so a client cannot double its quota by
+// switching protocols. The number of owners is bounded by the API keys
+// issued, so limiters are never evicted.
+type rateLimiter struct {
+	limit rate.Limit
+	burst int
+
+	mu     sync.Mutex
+	owners map[string]*rate.Limiter
+}
+
+// newRateLimiter returns a limiter allowing perSecond requests per owner,
+// with bursts of up to burst requests, or nil when perSecond is not
+// positive. A burst below one uses perSecond rounded up.
+func newRateLimiter(perSecond float64, burst int) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = int(math.Ceil(perSecond))
+	}
+	return &rateLimiter{limit: rate.Limit(perSecond), burst: burst, owners: make(map[string]*rate.Limiter)}
+}
+
+// allow reports whether owner may make a request now and, when it may not,
+// how long until it may. A nil limiter allows everything.
+func (l *rateLimiter) allow(owner string) (time.Duration, bool) {
+	if l == nil {
+		return 0, true
+	}
+
+	l.mu.Lock()
+	limiter, ok := l.owners[owner]
+	if !ok {
+		limiter = rate.NewLimiter(l.limit, l.burst)
+		l.owners[owner] = limiter
+	}
+	l.mu.Unlock()
+
+	reservation := limiter.Reserve()
+	if delay := reservation.Delay(); delay > 0 {
+		reservation.Cancel()
+		return delay, false
+	}
+	return 0, true
+}
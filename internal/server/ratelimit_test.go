@@ -0,0 +1,54 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRateLimiter(t *testing.T) {
+	if newRateLimiter(0, 5) != nil {
+		t.Fatal("expected no limiter without a rate")
+	}
+	var unlimited *rateLimiter
+	if _, ok := unlimited.allow("team-x"); !ok {
+		t.Fatal("expected a nil limiter to allow requests")
+	}
+
+	limiter := newRateLimiter(0.5, 0)
+	if limiter.burst != 1 {
+		t.Fatalf("expected the burst to default to the rate rounded up, got %d", limiter.burst)
+	}
+	if _, ok := limiter.allow("team-x"); !ok {
+		t.Fatal("expected the first request to be allowed")
+	}
+	wait, ok := limiter.allow("team-x")
+	if ok || wait <= 0 {
+		t.Fatalf("expected the second request to wait, got %v, %t", wait, ok)
+	}
+	if _, ok := limiter.allow("team-y"); !ok {
+		t.Fatal("expected owners to have separate limits")
+	}
+}
+
+func TestRateLimitedEndpoint(t *testing.T) {
+	srv, _, apiKey := newTestServer(t, nil)
+	srv.limiter = newRateLimiter(0.001, 1)
+
+	body := PromptRequest{Prompt: "Write a story about a cat"}
+	if rec := doRequest(t, srv, http.MethodPost, "/v1/check", apiKey, body); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	rec := doRequest(t, srv, http.MethodPost, "/v1/check", apiKey, body)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected 429 with Retry-After, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if errBody := decode[ErrorResponse](t, rec); errBody.Error.Code != "rate_limited" {
+		t.Fatalf("unexpected error body: %#v", errBody)
+	}
+
+	// Unauthenticated requests are rejected before they count.
+	if rec := doRequest(t, srv, http.MethodPost, "/v1/check", "", body); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a key, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,742 @@
+// Package server exposes PromptSentinel validation over HTTP and gRPC. Every
+// endpoint except the health check requires an API key created with
+// `promptsentinel keys create`, sent as "Authorization: Bearer <key>".
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"promptsentinel/internal/jobs"
+	"promptsentinel/internal/logging"
+	"promptsentinel/internal/metrics"
+	"promptsentinel/internal/notify"
+	"promptsentinel/internal/promptdb"
+	"promptsentinel/internal/resultcache"
+	"promptsentinel/internal/validator"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// maxRequestBytes bounds request bodies so a single client cannot exhaust
+// memory with an oversized prompt.
+const maxRequestBytes = 1 << 20
+
+// Options configures a Server.
+type Options struct {
+	// Store persists history, approvals, and API keys. Required.
+	Store *promptdb.Store
+	// LoadConfig returns the validation policy. It is called per request so
+	// configuration changes apply without a restart. Required.
+	LoadConfig func() (*validator.Config, error)
+	// PromptCipher, when set, stores an encrypted copy of each prompt with
+	// its history and approval records so reviewers can read it.
+	PromptCipher *promptdb.PromptCipher
+	// ApprovalTTL is how long approval tickets stay pending. Zero uses
+	// promptdb.DefaultApprovalTTL.
+	ApprovalTTL time.Duration
+	// ValidationTimeout bounds each validation. When it passes, or the
+	// client disconnects, the remaining checks are skipped and the policy's
+	// fail mode decides the result. Zero means no limit.
+	ValidationTimeout time.Duration
+	// Metrics, when set, records every validation.
+	Metrics *metrics.Metrics
+	// RateLimit is how many requests per second each API key owner may
+	// make, across the HTTP and gRPC APIs. Zero means no limit.
+	RateLimit float64
+	// RateBurst is how many requests an owner may make at once before
+	// RateLimit applies. Zero uses RateLimit rounded up.
+	RateBurst int
+	// TracerProvider, when set, records a span for each request with the
+	// config load, normalization, validation, and each detector beneath
+	// it. Spans carry use cases, scores, and issue counts, never prompts.
+	TracerProvider trace.TracerProvider
+	// Logger, when set, receives access logs, validation verdicts, refused
+	// API keys, and internal errors, through logging.Redact so prompts and
+	// keys stay out of it.
+	Logger *slog.Logger
+	// Notifier, when set, is told about comprehensive validations rated
+	// high risk and canaries leaked in outputs.
+	Notifier *notify.Notifier
+	// Jobs, when set, accepts batch validation jobs and reports on them.
+	// Without it the job endpoints answer 501.
+	Jobs *jobs.Runner
+	// MaxUploadBytes bounds datasets uploaded for jobs. Zero uses
+	// DefaultMaxUploadBytes.
+	MaxUploadBytes int64
+	// Cache, when set, answers checks and validations of prompts and
+	// conversations seen before under the same policy without validating
+	// them again. History, approvals, metrics, and notifications still
+	// apply to every request.
+	Cache *resultcache.Cache
+}
+
+// Server serves the PromptSentinel HTTP API.
+type Server struct {
+	opts    Options
+	mux     *http.ServeMux
+	limiter *rateLimiter
+	tracer  trace.Tracer
+	logger  *slog.Logger
+	// openAPI is the encoded OpenAPI document served at /openapi.json.
+	openAPI []byte
+}
+
+// New creates a Server and registers its routes.
+func New(opts Options) *Server {
+	s := &Server{
+		opts:    opts,
+		mux:     http.NewServeMux(),
+		limiter: newRateLimiter(opts.RateLimit, opts.RateBurst),
+		logger:  logging.Redact(opts.Logger),
+	}
+	provider := opts.TracerProvider
+	if provider == nil {
+		provider = noop.NewTracerProvider()
+	}
+	s.tracer = provider.Tracer(tracerName)
+	s.routes()
+	return s
+}
+
+// route is one endpoint of the API. The OpenAPI document is built from the
+// same table, so every endpoint is documented.
+type route struct {
+	method      string
+	path        string
+	operationID string
+	summary     string
+	// public routes skip authentication.
+	public bool
+	// scope, when set, is the scope the API key must have been granted.
+	scope string
+	// request and responses hold values of the body types, for the
+	// document. request is nil when there is no body; a successful response
+	// has one of the responses' types.
+	request         any
+	optionalRequest bool
+	responses       []any
+	// status is the status of a successful response, 200 when zero.
+	status int
+	// requestMedia and responseMedia are the media types of the bodies
+	// when they are not application/json.
+	requestMedia  string
+	responseMedia string
+	query         []queryParameter
+	handler       http.Handler
+}
+
+// successStatus returns the status of rt's successful response.
+func (rt route) successStatus() int {
+	if rt.status == 0 {
+		return http.StatusOK
+	}
+	return rt.status
+}
+
+// queryParameter documents a query string parameter of a route
+type queryParameter struct {
+	name        string
+	description string
+	enum        []string
+}
+
+func (s *Server) apiRoutes() []route {
+	return []route{
+		{method: "GET", path: "/healthz", operationID: "health", summary: "Report that the server is up",
+			public: true, responses: []any{HealthResponse{}}, handler: http.HandlerFunc(s.handleHealth)},
+		{method: "GET", path: "/openapi.json", operationID: "openapi", summary: "Describe the API in OpenAPI 3.1",
+			public: true, responses: []any{map[string]any{}}, handler: http.HandlerFunc(s.handleOpenAPI)},
+
+		{method: "POST", path: "/v1/check", operationID: "check", summary: "Check a prompt or conversation",
+			request: PromptRequest{}, responses: []any{validator.ValidationResult{}, validator.ConversationResult{}}, handler: http.HandlerFunc(s.handleCheck)},
+		{method: "POST", path: "/v1/validate", operationID: "validate", summary: "Run the comprehensive analysis of a prompt or conversation",
+			request: PromptRequest{}, responses: []any{validator.ComprehensiveValidationResult{}}, handler: http.HandlerFunc(s.handleValidate)},
+		{method: "POST", path: "/v1/validate-output", operationID: "validateOutput", summary: "Check a model response for leaks and unsafe content",
+			request: OutputRequest{}, responses: []any{validator.OutputValidationResult{}}, handler: http.HandlerFunc(s.handleValidateOutput)},
+		{method: "POST", path: "/v1/scan-document", operationID: "scanDocument", summary: "Scan a retrieved document for indirect prompt injection",
+			request: DocumentRequest{}, responses: []any{validator.DocumentScanResult{}}, handler: http.HandlerFunc(s.handleScanDocument)},
+		{method: "POST", path: "/v1/validate-tool-call", operationID: "validateToolCall", summary: "Check a tool call against the tool policies",
+			request: validator.ToolCall{}, responses: []any{validator.ToolCallResult{}}, handler: http.HandlerFunc(s.handleValidateToolCall)},
+
+		{method: "GET", path: "/v1/approvals", operationID: "listApprovals", summary: "List approval tickets, newest first",
+			query: []queryParameter{{"status", "Only list tickets with this status.", []string{promptdb.ApprovalPending, promptdb.ApprovalApproved, promptdb.ApprovalRejected, promptdb.ApprovalExpired}}},
+			scope: promptdb.ScopeReviewer, responses: []any{ApprovalList{}}, handler: http.HandlerFunc(s.handleListApprovals)},
+		{method: "GET", path: "/v1/approvals/{id}", operationID: "getApproval", summary: "Get an approval ticket you own, or any ticket as a reviewer",
+			responses: []any{promptdb.Approval{}}, handler: http.HandlerFunc(s.handleGetApproval)},
+		{method: "POST", path: "/v1/approvals/{id}/approve", operationID: "approve", summary: "Approve a pending ticket",
+			scope: promptdb.ScopeReviewer, request: DecisionRequest{}, optionalRequest: true, responses: []any{promptdb.Approval{}}, handler: s.handleDecideApproval(true)},
+		{method: "POST", path: "/v1/approvals/{id}/reject", operationID: "reject", summary: "Reject a pending ticket",
+			scope: promptdb.ScopeReviewer, request: DecisionRequest{}, optionalRequest: true, responses: []any{promptdb.Approval{}}, handler: s.handleDecideApproval(false)},
+
+		{method: "POST", path: "/v1/jobs", operationID: "submitJob", summary: "Queue a batch validation of a JSONL dataset on the server",
+			request: JobRequest{}, status: http.StatusAccepted, responses: []any{promptdb.Job{}}, handler: http.HandlerFunc(s.handleSubmitJob)},
+		{method: "POST", path: "/v1/jobs/upload", operationID: "uploadJob", summary: "Upload a JSONL dataset and queue a batch validation of it",
+			query: []queryParameter{
+				{"name", "A name for the dataset, shown with the job.", nil},
+				{"mode", "Run the quick check (the default) or the comprehensive analysis on each item.", []string{promptdb.JobModeCheck, promptdb.JobModeValidate}},
+				{"use_case", "Use case for items that do not set their own.", nil},
+			},
+			request: jobs.Item{}, requestMedia: ndjsonMedia, status: http.StatusAccepted, responses: []any{promptdb.Job{}}, handler: http.HandlerFunc(s.handleUploadJob)},
+		{method: "GET", path: "/v1/jobs", operationID: "listJobs", summary: "List your jobs, newest first",
+			query:     []queryParameter{{"status", "Only list jobs with this status.", jobStatuses}},
+			responses: []any{JobList{}}, handler: http.HandlerFunc(s.handleListJobs)},
+		{method: "GET", path: "/v1/jobs/{id}", operationID: "getJob", summary: "Get a job and its progress",
+			responses: []any{promptdb.Job{}}, handler: http.HandlerFunc(s.handleGetJob)},
+		{method: "GET", path: "/v1/jobs/{id}/results", operationID: "getJobResults", summary: "Download the results of a job so far, one JSON object per line",
+			query:     []queryParameter{{"after", "Only return results for lines after this line number, to resume a download.", nil}},
+			responses: []any{promptdb.JobResult{}}, responseMedia: ndjsonMedia, handler: http.HandlerFunc(s.handleJobResults)},
+		{method: "POST", path: "/v1/jobs/{id}/cancel", operationID: "cancelJob", summary: "Cancel a queued or running job",
+			responses: []any{promptdb.Job{}}, handler: http.HandlerFunc(s.handleCancelJob)},
+	}
+}
+
+func (s *Server) routes() {
+	routes := s.apiRoutes()
+	for _, rt := range routes {
+		handler := rt.handler
+		if rt.scope != "" {
+			handler = requireScope(rt.scope, handler)
+		}
+		if !rt.public {
+			handler = s.authenticate(handler)
+		}
+		s.mux.Handle(rt.method+" "+rt.path, s.traceHTTP(rt, s.logHTTP(rt, handler)))
+	}
+
+	// The document is built from types known at compile time, so failing
+	// to encode it is a programming error.
+	document, err := buildOpenAPI(routes)
+	if err != nil {
+		panic(fmt.Sprintf("build OpenAPI document: %v", err))
+	}
+	s.openAPI = document
+}
+
+// Handler returns the root http.Handler for the API.
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// PromptRequest is the body accepted by the check and validate endpoints.
+// Exactly one of Prompt or Messages is set; Messages holds a conversation in
+// OpenAI or Anthropic format, as accepted by validator.ParseMessages.
+type PromptRequest struct {
+	Prompt   string          `json:"prompt,omitempty"`
+	Messages json.RawMessage `json:"messages,omitempty"`
+	UseCase  string          `json:"use_case,omitempty"`
+}
+
+// OutputRequest is the body accepted by the validate-output endpoint. When
+// SystemPrompt is set the output is checked for leaking it.
+type OutputRequest struct {
+	Output       string `json:"output"`
+	SystemPrompt string `json:"system_prompt,omitempty"`
+	UseCase      string `json:"use_case,omitempty"`
+}
+
+// DocumentRequest is the body accepted by the scan-document endpoint. Type
+// is detected from the content when empty.
+type DocumentRequest struct {
+	Content   string `json:"content"`
+	Type      string `json:"type,omitempty"`
+	ChunkSize int    `json:"chunk_size,omitempty"`
+}
+
+// DecisionRequest is the optional body of the approve and reject endpoints.
+type DecisionRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// HealthResponse is the body of the health check.
+type HealthResponse struct {
+	Status string `json:"status"`
+}
+
+// ApprovalList is the body of the approval list endpoint.
+type ApprovalList struct {
+	Approvals []promptdb.Approval `json:"approvals"`
+}
+
+// ErrorResponse is returned with every non-2xx status.
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// ErrorBody describes what went wrong. Code is stable and machine readable;
+// Message is meant for humans.
+type ErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, HealthResponse{Status: "ok"})
+}
+
+func (s *Server) handleCheck(w http.ResponseWriter, r *http.Request) {
+	req, messages, config, ok := s.decodePromptRequest(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := s.validationContext(r.Context())
+	defer cancel()
+	start := time.Now()
+
+	result, conversation, err := s.check(ctx, req.Prompt, messages, config)
+	if err != nil {
+		s.internalError(w, r, "validation_failed", err)
+		return
+	}
+	elapsed := time.Since(start)
+
+	if _, err := s.recordResult(r.Context(), "check", requestText(req, messages), config, result); err != nil {
+		s.internalError(w, r, "storage_error", err)
+		return
+	}
+	s.observe(ctx, "check", config.UseCase, result, elapsed)
+
+	// Conversations respond with per-message results as well.
+	if conversation != nil {
+		writeJSON(w, http.StatusOK, conversation)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleValidate(w http.ResponseWriter, r *http.Request) {
+	req, messages, config, ok := s.decodePromptRequest(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := s.validationContext(r.Context())
+	defer cancel()
+	start := time.Now()
+
+	result, err := s.validate(ctx, req.Prompt, messages, config)
+	if err != nil {
+		s.internalError(w, r, "validation_failed", err)
+		return
+	}
+	elapsed := time.Since(start)
+
+	if err := s.recordComprehensive(r.Context(), "validate", requestText(req, messages), config, result); err != nil {
+		s.internalError(w, r, "storage_error", err)
+		return
+	}
+	s.observe(ctx, "validate", config.UseCase, &result.ValidationResult, elapsed)
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleValidateOutput(w http.ResponseWriter, r *http.Request) {
+	var req OutputRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "request body must be JSON with an output field")
+		return
+	}
+	if strings.TrimSpace(req.Output) == "" {
+		writeError(w, http.StatusBadRequest, "invalid_request", "output cannot be empty")
+		return
+	}
+
+	config, err := s.loadConfig(r.Context())
+	if err != nil {
+		s.internalError(w, r, "config_error", err)
+		return
+	}
+	if req.UseCase != "" {
+		config.UseCase = req.UseCase
+	}
+
+	canaries, err := s.opts.Store.ActiveCanaryTokens(r.Context())
+	if err != nil {
+		s.internalError(w, r, "storage_error", err)
+		return
+	}
+
+	ctx, cancel := s.validationContext(r.Context())
+	defer cancel()
+	start := time.Now()
+
+	result, err := validator.ValidateOutputContext(ctx, req.Output, validator.OutputOptions{SystemPrompt: req.SystemPrompt, Canaries: canaries}, config)
+	if err != nil {
+		s.internalError(w, r, "validation_failed", err)
+		return
+	}
+	s.observe(ctx, "validate-output", config.UseCase, &result.ValidationResult, time.Since(start))
+
+	// Responses are recorded for audit but never queued for approval; by
+	// the time they are checked the model has already produced them.
+	if err := s.recordOutput(r.Context(), req.Output, config, result); err != nil {
+		s.internalError(w, r, "storage_error", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleScanDocument(w http.ResponseWriter, r *http.Request) {
+	var req DocumentRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "request body must be JSON with a content field")
+		return
+	}
+	if strings.TrimSpace(req.Content) == "" {
+		writeError(w, http.StatusBadRequest, "invalid_request", "content cannot be empty")
+		return
+	}
+	switch req.Type {
+	case "", validator.FormatText, validator.FormatMarkdown, validator.FormatHTML, validator.FormatJSON:
+	default:
+		writeError(w, http.StatusBadRequest, "invalid_request", "type must be text, markdown, html, or json")
+		return
+	}
+
+	config, err := s.loadConfig(r.Context())
+	if err != nil {
+		s.internalError(w, r, "config_error", err)
+		return
+	}
+
+	ctx, cancel := s.validationContext(r.Context())
+	defer cancel()
+	start := time.Now()
+
+	// Retrieved documents are not prompts anyone wrote, so they are not
+	// recorded in validation history.
+	result, err := validator.ScanDocumentContext(ctx, req.Content, validator.DocumentOptions{Format: req.Type, ChunkSize: req.ChunkSize}, config)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_document", err.Error())
+		return
+	}
+	s.observe(ctx, "scan-document", config.UseCase, &result.ValidationResult, time.Since(start))
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleValidateToolCall checks a tool call, given in any of the formats
+// validator.ToolCall accepts, against the configured tool policies
+func (s *Server) handleValidateToolCall(w http.ResponseWriter, r *http.Request) {
+	var call validator.ToolCall
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&call); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "request body must be a JSON tool call with a name: "+err.Error())
+		return
+	}
+
+	config, err := s.loadConfig(r.Context())
+	if err != nil {
+		s.internalError(w, r, "config_error", err)
+		return
+	}
+
+	ctx, cancel := s.validationContext(r.Context())
+	defer cancel()
+	start := time.Now()
+
+	result, err := validator.ValidateToolCallContext(ctx, call, config)
+	if err != nil {
+		s.internalError(w, r, "validation_failed", err)
+		return
+	}
+	s.observe(ctx, "validate-tool-call", config.UseCase, &result.ValidationResult, time.Since(start))
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// validationContext bounds a validation by the request's context and
+// Options.ValidationTimeout, and starts the span observe records the result
+// on. The span ends when the validation is cancelled.
+func (s *Server) validationContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, span := s.tracer.Start(ctx, "validate")
+	ctx = validator.WithLogger(ctx, s.logger)
+
+	var cancel context.CancelFunc
+	if s.opts.ValidationTimeout <= 0 {
+		ctx, cancel = context.WithCancel(ctx)
+	} else {
+		ctx, cancel = context.WithTimeout(ctx, s.opts.ValidationTimeout)
+	}
+	return ctx, func() {
+		cancel()
+		span.End()
+	}
+}
+
+// recordOutput stores an output validation in history and opens a leakage
+// incident, and notifies, for each canary found in it.
+func (s *Server) recordOutput(ctx context.Context, output string, config *validator.Config, result *validator.OutputValidationResult) error {
+	owner := ownerFromContext(ctx)
+	event, err := promptdb.NewValidationEvent("validate-output", owner, output, config, &result.ValidationResult, s.opts.PromptCipher)
+	if err != nil {
+		return err
+	}
+
+	if event.ID, err = s.opts.Store.RecordValidationEvent(ctx, event); err != nil {
+		return err
+	}
+
+	incidents, err := s.opts.Store.RecordCanaryLeaks(ctx, result.CanaryLeaks, "validate-output", promptdb.LeakLocationResponse, owner, event.ID)
+	if err != nil {
+		return err
+	}
+	for _, incident := range incidents {
+		s.opts.Notifier.Notify(notify.CanaryLeak(incident))
+	}
+	return nil
+}
+
+// recordResult stores the result in validation history and, when the policy
+// requires it, opens an approval ticket and marks the result pending. It
+// returns the history event.
+func (s *Server) recordResult(ctx context.Context, source, prompt string, config *validator.Config, result *validator.ValidationResult) (promptdb.ValidationEvent, error) {
+	event, err := promptdb.NewValidationEvent(source, ownerFromContext(ctx), prompt, config, result, s.opts.PromptCipher)
+	if err != nil {
+		return promptdb.ValidationEvent{}, err
+	}
+
+	if event.ID, err = s.opts.Store.RecordValidationEvent(ctx, event); err != nil {
+		return promptdb.ValidationEvent{}, err
+	}
+
+	if !validator.RequiresApproval(config, result) {
+		return event, nil
+	}
+
+	approval, err := s.opts.Store.CreateApproval(ctx, promptdb.NewApproval(event, s.opts.ApprovalTTL))
+	if err != nil {
+		return promptdb.ValidationEvent{}, err
+	}
+
+	result.Outcome = validator.OutcomePendingApproval
+	result.ApprovalID = approval.ID
+	return event, nil
+}
+
+// recordComprehensive records a comprehensive validation as recordResult
+// does, and notifies when its risk is high.
+func (s *Server) recordComprehensive(ctx context.Context, source, prompt string, config *validator.Config, result *validator.ComprehensiveValidationResult) error {
+	event, err := s.recordResult(ctx, source, prompt, config, &result.ValidationResult)
+	if err != nil {
+		return err
+	}
+	if alert, ok := notify.HighRisk(event, result); ok {
+		s.opts.Notifier.Notify(alert)
+	}
+	return nil
+}
+
+func (s *Server) handleListApprovals(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", promptdb.ApprovalPending, promptdb.ApprovalApproved, promptdb.ApprovalRejected, promptdb.ApprovalExpired:
+	default:
+		writeError(w, http.StatusBadRequest, "invalid_status", fmt.Sprintf("unknown approval status %q", status))
+		return
+	}
+
+	approvals, err := s.opts.Store.ListApprovals(r.Context(), status, 100)
+	if err != nil {
+		s.internalError(w, r, "storage_error", err)
+		return
+	}
+	if approvals == nil {
+		approvals = []promptdb.Approval{}
+	}
+
+	writeJSON(w, http.StatusOK, ApprovalList{Approvals: approvals})
+}
+
+func (s *Server) handleGetApproval(w http.ResponseWriter, r *http.Request) {
+	approval, err := s.opts.Store.GetApproval(r.Context(), r.PathValue("id"))
+	if err == nil && approval.OwnerID != ownerFromContext(r.Context()) && !hasScope(r.Context(), promptdb.ScopeReviewer) {
+		// Other owners' tickets are not found, rather than forbidden, so
+		// their IDs cannot be probed.
+		err = promptdb.ErrApprovalNotFound
+	}
+	if err != nil {
+		s.writeApprovalError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, approval)
+}
+
+func (s *Server) handleDecideApproval(approve bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req DecisionRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&req); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid_request", "request body must be JSON")
+				return
+			}
+		}
+
+		approval, err := s.opts.Store.DecideApproval(r.Context(), r.PathValue("id"), approve, ownerFromContext(r.Context()), req.Reason)
+		if err != nil {
+			s.writeApprovalError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, approval)
+	}
+}
+
+// decodePromptRequest parses a PromptRequest, including its conversation
+// when one is given, and loads the policy, writing an error response and
+// returning false when any step fails.
+func (s *Server) decodePromptRequest(w http.ResponseWriter, r *http.Request) (PromptRequest, []validator.Message, *validator.Config, bool) {
+	var req PromptRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "request body must be JSON with a prompt or messages field")
+		return PromptRequest{}, nil, nil, false
+	}
+
+	var messages []validator.Message
+	switch {
+	case len(req.Messages) > 0 && req.Prompt != "":
+		writeError(w, http.StatusBadRequest, "invalid_request", "send either prompt or messages, not both")
+		return PromptRequest{}, nil, nil, false
+	case len(req.Messages) > 0:
+		var err error
+		if messages, err = s.normalizeMessages(r.Context(), req.Messages); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+			return PromptRequest{}, nil, nil, false
+		}
+	case strings.TrimSpace(req.Prompt) == "":
+		writeError(w, http.StatusBadRequest, "invalid_request", "prompt cannot be empty")
+		return PromptRequest{}, nil, nil, false
+	}
+	req.Prompt = s.normalize(req.Prompt)
+
+	config, err := s.loadConfig(r.Context())
+	if err != nil {
+		s.internalError(w, r, "config_error", err)
+		return PromptRequest{}, nil, nil, false
+	}
+	if req.UseCase != "" {
+		config.UseCase = req.UseCase
+	}
+
+	return req, messages, config, true
+}
+
+// requestText returns the text recorded in history for a request.
+func requestText(req PromptRequest, messages []validator.Message) string {
+	if messages != nil {
+		return validator.ConversationText(messages)
+	}
+	return req.Prompt
+}
+
+type callerKey struct{}
+
+// withCaller stores the API key that authenticated a request, without its
+// hash, in ctx.
+func withCaller(ctx context.Context, record promptdb.APIKeyRecord) context.Context {
+	record.Hash = ""
+	return context.WithValue(ctx, callerKey{}, record)
+}
+
+// ownerFromContext returns the owner of the API key that authenticated the
+// request.
+func ownerFromContext(ctx context.Context) string {
+	caller, _ := ctx.Value(callerKey{}).(promptdb.APIKeyRecord)
+	return caller.OwnerID
+}
+
+// hasScope reports whether the API key that authenticated the request was
+// granted scope.
+func hasScope(ctx context.Context, scope string) bool {
+	caller, _ := ctx.Value(callerKey{}).(promptdb.APIKeyRecord)
+	return caller.HasScope(scope)
+}
+
+// requireScope rejects requests whose API key lacks scope. It runs after
+// authenticate.
+func requireScope(scope string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !hasScope(r.Context(), scope) {
+			writeError(w, http.StatusForbidden, "forbidden", fmt.Sprintf("this API key lacks the %s scope", scope))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authenticate rejects requests without a valid, unrevoked API key or over
+// the owner's rate limit, and stores the key's owner in the request context.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		caller, err := s.opts.Store.AuthenticateAPIKey(r.Context(), bearerToken(r))
+		if err != nil {
+			s.logRejectedKey(r.Context(), err)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="promptsentinel"`)
+			writeError(w, http.StatusUnauthorized, "unauthorized", "a valid API key is required")
+			return
+		}
+
+		if wait, ok := s.limiter.allow(caller.OwnerID); !ok {
+			s.logRateLimited(r.Context(), caller.OwnerID, wait)
+			seconds := int(math.Ceil(wait.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			writeError(w, http.StatusTooManyRequests, "rate_limited", fmt.Sprintf("rate limit exceeded; retry in %ds", seconds))
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(withCaller(r.Context(), caller)))
+	})
+}
+
+// bearerToken extracts the API key from the Authorization header, falling
+// back to X-API-Key for clients that cannot set bearer tokens.
+func bearerToken(r *http.Request) string {
+	return credential(r.Header.Get("Authorization"), r.Header.Get("X-API-Key"))
+}
+
+// credential picks the API key from an Authorization value, which must use
+// the Bearer scheme, or else from an X-API-Key value.
+func credential(authorization, apiKey string) string {
+	if authorization != "" {
+		scheme, token, ok := strings.Cut(authorization, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+		return ""
+	}
+	return apiKey
+}
+
+func (s *Server) writeApprovalError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, promptdb.ErrApprovalNotFound):
+		writeError(w, http.StatusNotFound, "not_found", err.Error())
+	case errors.Is(err, promptdb.ErrApprovalNotPending):
+		writeError(w, http.StatusConflict, "not_pending", err.Error())
+	case errors.Is(err, promptdb.ErrSelfApproval):
+		writeError(w, http.StatusForbidden, "self_approval", err.Error())
+	default:
+		s.internalError(w, r, "storage_error", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, ErrorResponse{Error: ErrorBody{Code: code, Message: message}})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,198 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"promptsentinel/internal/validator"
+
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// tracerName names the tracer that records request, config, normalization,
+// and validation spans. The validator adds stage and detector spans beneath
+// the validation span.
+const tracerName = "promptsentinel/internal/server"
+
+// Span attributes. They describe how a request was judged, never what it
+// said.
+const (
+	attrSource     = attribute.Key("promptsentinel.source")
+	attrUseCase    = attribute.Key("promptsentinel.use_case")
+	attrScore      = attribute.Key("promptsentinel.score")
+	attrOutcome    = attribute.Key("promptsentinel.outcome")
+	attrIssues     = attribute.Key("promptsentinel.issues")
+	attrErrors     = attribute.Key("promptsentinel.issues.errors")
+	attrIncomplete = attribute.Key("promptsentinel.incomplete")
+	attrMessages   = attribute.Key("promptsentinel.messages")
+	attrCacheHit   = attribute.Key("promptsentinel.cache_hit")
+)
+
+// propagator reads the W3C trace context callers send, so a request's spans
+// join the caller's trace.
+var propagator = propagation.TraceContext{}
+
+// traceHTTP wraps a route's handler in a server span named after the route
+func (s *Server) traceHTTP(rt route, next http.Handler) http.Handler {
+	name := rt.method + " " + rt.path
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := s.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(rt.method),
+			semconv.HTTPRoute(rt.path),
+		))
+		defer span.End()
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		span.SetAttributes(semconv.HTTPResponseStatusCode(rec.status))
+		if rec.status >= http.StatusInternalServerError {
+			span.SetStatus(otelcodes.Error, http.StatusText(rec.status))
+		}
+	})
+}
+
+// statusRecorder remembers the status a handler responded with
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// traceUnary records a server span for a unary call, as traceHTTP does for
+// HTTP requests
+func (s *Server) traceUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, span := s.startRPC(ctx, info.FullMethod)
+	defer span.End()
+
+	resp, err := handler(ctx, req)
+	endRPC(span, err)
+	return resp, err
+}
+
+// traceStream records one span for the whole of a stream
+func (s *Server) traceStream(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, span := s.startRPC(stream.Context(), info.FullMethod)
+	defer span.End()
+
+	err := handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+	endRPC(span, err)
+	return err
+}
+
+func (s *Server) startRPC(ctx context.Context, fullMethod string) (context.Context, trace.Span) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = propagator.Extract(ctx, metadataCarrier(md))
+
+	name := strings.TrimPrefix(fullMethod, "/")
+	service, method, _ := strings.Cut(name, "/")
+	return s.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+		semconv.RPCSystemGRPC,
+		semconv.RPCService(service),
+		semconv.RPCMethod(method),
+	))
+}
+
+// endRPC records a call's status code, marking the span failed when the
+// server was at fault
+func endRPC(span trace.Span, err error) {
+	code := status.Code(err)
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+	if serverFault(code) {
+		span.SetStatus(otelcodes.Error, code.String())
+	}
+}
+
+// metadataCarrier lets the propagator read gRPC metadata
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	return firstValue(metadata.MD(c), key)
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// loadConfig loads the policy in a span of its own, since LoadConfig may
+// read it from disk on every request
+func (s *Server) loadConfig(ctx context.Context) (*validator.Config, error) {
+	_, span := s.tracer.Start(ctx, "load config")
+	defer span.End()
+
+	config, err := s.opts.LoadConfig()
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(otelcodes.Error, "config failed to load")
+		return nil, err
+	}
+	return config, nil
+}
+
+// normalizeMessages parses a conversation with validator.ParseMessages,
+// mapping provider-specific roles onto the validator's, in a span of its
+// own. Contents are normalized as the result cache keys them, when there is
+// one.
+func (s *Server) normalizeMessages(ctx context.Context, raw json.RawMessage) ([]validator.Message, error) {
+	_, span := s.tracer.Start(ctx, "normalize messages")
+	defer span.End()
+
+	messages, err := validator.ParseMessages(raw)
+	if err != nil {
+		// Parse errors can quote the conversation, so only the failure is
+		// recorded.
+		span.SetStatus(otelcodes.Error, "invalid conversation")
+		return nil, err
+	}
+	for i := range messages {
+		messages[i].Content = s.normalize(messages[i].Content)
+	}
+	span.SetAttributes(attrMessages.Int(len(messages)))
+	return messages, nil
+}
+
+// observe records a validation in the metrics, on the validation span in
+// ctx, and in the log
+func (s *Server) observe(ctx context.Context, source, useCase string, result *validator.ValidationResult, elapsed time.Duration) {
+	s.opts.Metrics.ObserveValidation(source, useCase, result, elapsed)
+
+	errorCount := 0
+	for _, issue := range result.Issues {
+		if issue.Severity == "error" {
+			errorCount++
+		}
+	}
+	trace.SpanFromContext(ctx).SetAttributes(
+		attrSource.String(source),
+		attrUseCase.String(useCase),
+		attrScore.Int(result.Score),
+		attrOutcome.String(result.Outcome),
+		attrIssues.Int(len(result.Issues)),
+		attrErrors.Int(errorCount),
+		attrIncomplete.Bool(result.Incomplete),
+	)
+	s.logValidation(ctx, source, useCase, result, errorCount, elapsed)
+}
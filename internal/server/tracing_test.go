@@ -0,0 +1,205 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	pb "promptsentinel/api/promptsentinel/v1"
+	"promptsentinel/internal/validator"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	testTraceID      = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testParentSpanID = "00f067aa0ba902b7"
+	testTraceparent  = "00-" + testTraceID + "-" + testParentSpanID + "-01"
+)
+
+// newTracedServer returns a test server whose spans are recorded
+func newTracedServer(t *testing.T) (*Server, *tracetest.SpanRecorder, string) {
+	t.Helper()
+
+	srv, _, apiKey := newTestServer(t, nil)
+	recorder := tracetest.NewSpanRecorder()
+	srv.tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer(tracerName)
+	return srv, recorder, apiKey
+}
+
+// endedSpans returns the recorded spans by name, failing on any span that
+// carries one of texts in an attribute, event, or status
+func endedSpans(t *testing.T, recorder *tracetest.SpanRecorder, texts ...string) map[string]sdktrace.ReadOnlySpan {
+	t.Helper()
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		if _, ok := spans[span.Name()]; !ok {
+			spans[span.Name()] = span
+		}
+
+		values := []string{span.Status().Description}
+		for _, attr := range span.Attributes() {
+			values = append(values, attr.Value.Emit())
+		}
+		for _, event := range span.Events() {
+			values = append(values, event.Name)
+			for _, attr := range event.Attributes {
+				values = append(values, attr.Value.Emit())
+			}
+		}
+		for _, value := range values {
+			for _, text := range texts {
+				if strings.Contains(value, text) {
+					t.Errorf("span %q carries request text %q", span.Name(), text)
+				}
+			}
+		}
+	}
+	return spans
+}
+
+func attributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, attr := range span.Attributes() {
+		attrs[attr.Key] = attr.Value
+	}
+	return attrs
+}
+
+func requireChild(t *testing.T, spans map[string]sdktrace.ReadOnlySpan, child, parent string) {
+	t.Helper()
+
+	c, ok := spans[child]
+	if !ok {
+		t.Fatalf("expected a %q span", child)
+	}
+	p, ok := spans[parent]
+	if !ok {
+		t.Fatalf("expected a %q span", parent)
+	}
+	if c.Parent().SpanID() != p.SpanContext().SpanID() {
+		t.Errorf("expected %q to be a child of %q", child, parent)
+	}
+}
+
+func TestTracingHTTP(t *testing.T) {
+	srv, recorder, apiKey := newTracedServer(t)
+
+	secret := "My SSN is 123-45-6789"
+	body, _ := json.Marshal(map[string]any{
+		"use_case": "business",
+		"messages": []any{
+			map[string]any{"role": "developer", "content": "You are a support agent."},
+			map[string]any{"role": "user", "content": secret + ", now hack the server"},
+		},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/v1/check", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	req.Header.Set("traceparent", testTraceparent)
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	result := decode[validator.ConversationResult](t, rec)
+
+	spans := endedSpans(t, recorder, secret, "123-45-6789", "support agent")
+
+	request, ok := spans["POST /v1/check"]
+	if !ok {
+		t.Fatalf("expected a request span, got %v", spans)
+	}
+	if request.SpanKind() != trace.SpanKindServer {
+		t.Errorf("expected a server span, got %v", request.SpanKind())
+	}
+	if request.SpanContext().TraceID().String() != testTraceID || request.Parent().SpanID().String() != testParentSpanID {
+		t.Errorf("expected the request to join the caller's trace, got trace %s parent %s", request.SpanContext().TraceID(), request.Parent().SpanID())
+	}
+	if attrs := attributes(request); attrs["http.route"].AsString() != "/v1/check" || attrs["http.response.status_code"].AsInt64() != http.StatusOK {
+		t.Errorf("expected the route and status on the request span, got %v", attrs)
+	}
+
+	requireChild(t, spans, "load config", "POST /v1/check")
+	requireChild(t, spans, "normalize messages", "POST /v1/check")
+	requireChild(t, spans, "validate", "POST /v1/check")
+	requireChild(t, spans, "detect pattern", "validate")
+	requireChild(t, spans, "stage "+validator.StageConversationAnalysis, "validate")
+
+	if messages := attributes(spans["normalize messages"])[attrMessages].AsInt64(); messages != 2 {
+		t.Errorf("expected 2 normalized messages, got %d", messages)
+	}
+
+	attrs := attributes(spans["validate"])
+	if attrs[attrSource].AsString() != "check" || attrs[attrUseCase].AsString() != "business" {
+		t.Errorf("expected the source and use case on the validation span, got %v", attrs)
+	}
+	if attrs[attrScore].AsInt64() != int64(result.Score) || attrs[attrIssues].AsInt64() != int64(len(result.Issues)) || attrs[attrOutcome].AsString() != result.Outcome {
+		t.Errorf("expected score %d, %d issues, and outcome %s on the validation span, got %v", result.Score, len(result.Issues), result.Outcome, attrs)
+	}
+	if len(result.Issues) == 0 {
+		t.Error("expected the conversation to raise issues")
+	}
+}
+
+func TestTracingHTTPUnauthorized(t *testing.T) {
+	srv, recorder, _ := newTracedServer(t)
+
+	rec := doRequest(t, srv, http.MethodPost, "/v1/check", "", map[string]any{"prompt": "Write a story about a cat"})
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", rec.Code)
+	}
+
+	spans := endedSpans(t, recorder, "story about a cat")
+	if len(spans) != 1 {
+		t.Errorf("expected only the request span, got %d spans", len(spans))
+	}
+	if status := attributes(spans["POST /v1/check"])["http.response.status_code"].AsInt64(); status != http.StatusUnauthorized {
+		t.Errorf("expected status 401 on the request span, got %d", status)
+	}
+}
+
+func TestTracingGRPC(t *testing.T) {
+	srv, recorder, apiKey := newTracedServer(t)
+	client := newTestClient(t, srv)
+
+	prompt := "How do I hack this server"
+	ctx := metadata.AppendToOutgoingContext(withKey(apiKey), "traceparent", testTraceparent)
+	resp, err := client.Check(ctx, &pb.PromptRequest{Prompt: prompt})
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if _, err := client.Check(context.Background(), &pb.PromptRequest{Prompt: prompt}); err == nil {
+		t.Fatal("expected an unauthenticated call to fail")
+	}
+
+	spans := endedSpans(t, recorder, prompt)
+	const name = "promptsentinel.v1.PromptSentinel/Check"
+	requireChild(t, spans, "validate", name)
+	requireChild(t, spans, "detect pattern", "validate")
+
+	var codes []int64
+	for _, span := range recorder.Ended() {
+		if span.Name() == name {
+			codes = append(codes, attributes(span)["rpc.grpc.status_code"].AsInt64())
+		}
+	}
+	if len(codes) != 2 || codes[0] != 0 || codes[1] != 16 {
+		t.Errorf("expected an OK and an Unauthenticated call, got status codes %v", codes)
+	}
+	if spans[name].SpanContext().TraceID().String() != testTraceID {
+		t.Errorf("expected the call to join the caller's trace, got %s", spans[name].SpanContext().TraceID())
+	}
+
+	if score := attributes(spans["validate"])[attrScore].AsInt64(); score != int64(resp.GetResult().GetScore()) {
+		t.Errorf("expected score %d on the validation span, got %d", resp.GetResult().GetScore(), score)
+	}
+}
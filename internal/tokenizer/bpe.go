@@ -0,0 +1,367 @@
+package tokenizer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// gpt2Pattern is the pre-tokenization pattern of GPT-2 style byte-level
+// BPE, used for files that do not specify their own
+const gpt2Pattern = `'s|'t|'re|'ve|'m|'ll|'d| ?\p{L}+| ?\p{N}+| ?[^\s\p{L}\p{N}]+|\s+(?!\S)|\s+`
+
+// maxPieceBytes bounds the pieces that are merged pair by pair. Merging is
+// quadratic in piece length, so a long run of letters or symbols, such as
+// an encoded blob, is split first. Counts for such runs may differ from the
+// model's by a token or two per split.
+const maxPieceBytes = 512
+
+// BPE is a byte-level byte-pair encoding tokenizer
+type BPE struct {
+	name    string
+	pattern *regexp.Regexp
+	// trimSpace emulates the \s+(?!\S) alternative, which RE2 cannot
+	// express: a run of spaces before a word leaves its last space to the
+	// word.
+	trimSpace bool
+	encoder   map[string]int
+	// merges ranks pairs of tokens. When nil, as for tiktoken files, a
+	// pair's rank is the rank of the token it merges into.
+	merges map[[2]string]int
+}
+
+// pair ranks are compared as ints; unmergeable pairs never win
+const noMerge = math.MaxInt
+
+// LoadTiktoken reads a tiktoken file: one base64 token and its rank per
+// line. An empty pattern selects the cl100k_base pre-tokenizer.
+func LoadTiktoken(name string, r io.Reader, pattern string) (*BPE, error) {
+	if pattern == "" {
+		pattern = encodings[CL100KBase]
+	}
+
+	encoder := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		token, rank, ok := strings.Cut(text, " ")
+		if !ok {
+			return nil, fmt.Errorf("%s: line %d: expected a token and a rank", name, line)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(token)
+		if err != nil {
+			return nil, fmt.Errorf("%s: line %d: invalid token: %w", name, line, err)
+		}
+		id, err := strconv.Atoi(rank)
+		if err != nil {
+			return nil, fmt.Errorf("%s: line %d: invalid rank: %w", name, line, err)
+		}
+		encoder[string(decoded)] = id
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+
+	return newBPE(name, encoder, nil, pattern)
+}
+
+// LoadHuggingFace reads the vocabulary and merges of a byte-level BPE model
+// from a Hugging Face tokenizer.json file. The pre-tokenizer pattern is
+// taken from the file's Split pre-tokenizer when it has one.
+func LoadHuggingFace(name string, r io.Reader) (*BPE, error) {
+	var file struct {
+		Model struct {
+			Type   string          `json:"type"`
+			Vocab  map[string]int  `json:"vocab"`
+			Merges json.RawMessage `json:"merges"`
+		} `json:"model"`
+		PreTokenizer json.RawMessage `json:"pre_tokenizer"`
+	}
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if file.Model.Type != "BPE" || len(file.Model.Vocab) == 0 {
+		return nil, fmt.Errorf("%s: only byte-level BPE models are supported", name)
+	}
+
+	// Merges are either "a b" strings or [a, b] pairs.
+	var pairs [][2]string
+	var lines []string
+	if err := json.Unmarshal(file.Model.Merges, &lines); err == nil {
+		for _, line := range lines {
+			a, b, ok := strings.Cut(line, " ")
+			if !ok {
+				return nil, fmt.Errorf("%s: invalid merge %q", name, line)
+			}
+			pairs = append(pairs, [2]string{a, b})
+		}
+	} else if err := json.Unmarshal(file.Model.Merges, &pairs); err != nil {
+		return nil, fmt.Errorf("%s: invalid merges: %w", name, err)
+	}
+
+	decode := byteDecoder()
+	unmap := func(token string) (string, bool) {
+		var raw strings.Builder
+		for _, r := range token {
+			b, ok := decode[r]
+			if !ok {
+				return "", false
+			}
+			raw.WriteByte(b)
+		}
+		return raw.String(), true
+	}
+
+	encoder := make(map[string]int, len(file.Model.Vocab))
+	for token, id := range file.Model.Vocab {
+		// Added tokens outside the byte alphabet can never be produced by
+		// merging, so they are skipped.
+		if raw, ok := unmap(token); ok {
+			encoder[raw] = id
+		}
+	}
+	merges := make(map[[2]string]int, len(pairs))
+	for rank, pair := range pairs {
+		a, okA := unmap(pair[0])
+		b, okB := unmap(pair[1])
+		if okA && okB {
+			merges[[2]string{a, b}] = rank
+		}
+	}
+
+	return newBPE(name, encoder, merges, huggingFacePattern(file.PreTokenizer))
+}
+
+// huggingFacePattern finds the regex of a Split pre-tokenizer, possibly
+// inside a Sequence
+func huggingFacePattern(raw json.RawMessage) string {
+	var pre struct {
+		Type    string `json:"type"`
+		Pattern struct {
+			Regex string `json:"Regex"`
+		} `json:"pattern"`
+		PreTokenizers []json.RawMessage `json:"pretokenizers"`
+	}
+	if len(raw) == 0 || json.Unmarshal(raw, &pre) != nil {
+		return gpt2Pattern
+	}
+	if pre.Type == "Split" && pre.Pattern.Regex != "" {
+		return pre.Pattern.Regex
+	}
+	for _, child := range pre.PreTokenizers {
+		if pattern := huggingFacePattern(child); pattern != gpt2Pattern {
+			return pattern
+		}
+	}
+	return gpt2Pattern
+}
+
+func newBPE(name string, encoder map[string]int, merges map[[2]string]int, pattern string) (*BPE, error) {
+	for b := 0; b < 256; b++ {
+		if _, ok := encoder[string([]byte{byte(b)})]; !ok {
+			return nil, fmt.Errorf("%s: vocabulary does not cover every byte", name)
+		}
+	}
+
+	translated, trimSpace := translatePattern(pattern)
+	re, err := regexp.Compile(`^(?:` + translated + `)`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: unsupported pre-tokenizer pattern: %w", name, err)
+	}
+
+	return &BPE{name: name, pattern: re, trimSpace: trimSpace, encoder: encoder, merges: merges}, nil
+}
+
+// unicodeSpace extends RE2's ASCII \s to Unicode White_Space, which is
+// what the published patterns were written against
+const unicodeSpace = `\s\p{Z}\x{0B}\x{85}`
+
+// translatePattern rewrites a tokenizer pattern for RE2. \s is widened to
+// Unicode whitespace, and the \s+(?!\S) alternative is removed and
+// reported so that the caller can emulate it.
+func translatePattern(pattern string) (string, bool) {
+	trimSpace := false
+	for _, lookahead := range []string{`\s+(?!\S)|`, `|\s+(?!\S)`} {
+		if strings.Contains(pattern, lookahead) {
+			pattern = strings.Replace(pattern, lookahead, "", 1)
+			trimSpace = true
+		}
+	}
+
+	var out strings.Builder
+	inClass := false
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '\\' && i+1 < len(pattern):
+			next := pattern[i+1]
+			i++
+			switch {
+			case next == 's' && inClass:
+				out.WriteString(unicodeSpace)
+			case next == 's':
+				out.WriteString(`[` + unicodeSpace + `]`)
+			case next == 'S' && !inClass:
+				out.WriteString(`[^` + unicodeSpace + `]`)
+			default:
+				out.WriteByte(c)
+				out.WriteByte(next)
+			}
+		case c == '[' && !inClass:
+			inClass = true
+			out.WriteByte(c)
+		case c == ']' && inClass:
+			inClass = false
+			out.WriteByte(c)
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.String(), trimSpace
+}
+
+// Name returns the name the tokenizer was loaded under
+func (t *BPE) Name() string { return t.name }
+
+// Count returns the number of tokens in text
+func (t *BPE) Count(text string) int {
+	count := 0
+	t.pieces(text, func(piece string) {
+		count += len(t.merge(piece))
+	})
+	return count
+}
+
+// Encode returns the token IDs for text
+func (t *BPE) Encode(text string) []int {
+	var ids []int
+	t.pieces(text, func(piece string) {
+		for _, part := range t.merge(piece) {
+			ids = append(ids, t.encoder[part])
+		}
+	})
+	return ids
+}
+
+// pieces splits text with the pre-tokenizer pattern
+func (t *BPE) pieces(text string, fn func(piece string)) {
+	for len(text) > 0 {
+		loc := t.pattern.FindStringIndex(text)
+		end := 0
+		if loc != nil {
+			end = loc[1]
+		}
+		if end == 0 {
+			// Every byte should match some alternative; take one rune
+			// rather than loop forever if a custom pattern does not.
+			_, end = utf8.DecodeRuneInString(text)
+		}
+
+		piece := text[:end]
+		if t.trimSpace && end < len(text) && isSpaceRun(piece) {
+			next, _ := utf8.DecodeRuneInString(text[end:])
+			if !unicode.IsSpace(next) {
+				if _, size := utf8.DecodeLastRuneInString(piece); size < len(piece) {
+					piece = piece[:len(piece)-size]
+				}
+			}
+		}
+
+		for len(piece) > maxPieceBytes {
+			// Cut before the rune at maxPieceBytes, unless the bytes there
+			// are invalid UTF-8 with no rune start close by.
+			cut := maxPieceBytes
+			for cut > maxPieceBytes-utf8.UTFMax && !utf8.RuneStart(piece[cut]) {
+				cut--
+			}
+			if !utf8.RuneStart(piece[cut]) {
+				cut = maxPieceBytes
+			}
+			fn(piece[:cut])
+			text = text[cut:]
+			piece = piece[cut:]
+		}
+		fn(piece)
+		text = text[len(piece):]
+	}
+}
+
+// isSpaceRun reports whether piece is whitespace without line breaks, as
+// matched by the \s+ alternatives
+func isSpaceRun(piece string) bool {
+	for _, r := range piece {
+		if !unicode.IsSpace(r) || r == '\n' || r == '\r' {
+			return false
+		}
+	}
+	return true
+}
+
+// merge splits a piece into tokens by repeatedly merging the adjacent pair
+// with the lowest rank
+func (t *BPE) merge(piece string) []string {
+	if _, ok := t.encoder[piece]; ok {
+		return []string{piece}
+	}
+
+	parts := make([]string, len(piece))
+	for i := range parts {
+		parts[i] = piece[i : i+1]
+	}
+
+	for len(parts) > 1 {
+		best, bestRank := -1, noMerge
+		for i := 0; i < len(parts)-1; i++ {
+			if rank := t.rank(parts[i], parts[i+1]); rank < bestRank {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		parts[best] += parts[best+1]
+		parts = append(parts[:best+1], parts[best+2:]...)
+	}
+	return parts
+}
+
+func (t *BPE) rank(a, b string) int {
+	if t.merges != nil {
+		if rank, ok := t.merges[[2]string{a, b}]; ok {
+			return rank
+		}
+		return noMerge
+	}
+	if rank, ok := t.encoder[a+b]; ok {
+		return rank
+	}
+	return noMerge
+}
+
+// byteDecoder inverts GPT-2's mapping of bytes to printable runes, which
+// byte-level vocabularies use to store arbitrary bytes as text
+func byteDecoder() map[rune]byte {
+	decoder := make(map[rune]byte, 256)
+	next := rune(256)
+	for b := 0; b < 256; b++ {
+		printable := (b >= '!' && b <= '~') || (b >= 0xA1 && b <= 0xAC) || (b >= 0xAE && b <= 0xFF)
+		if printable {
+			decoder[rune(b)] = byte(b)
+		} else {
+			decoder[next] = byte(b)
+			next++
+		}
+	}
+	return decoder
+}
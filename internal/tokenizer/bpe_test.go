@@ -0,0 +1,172 @@
+package tokenizer
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuiltinEncodings_Whitespace(t *testing.T) {
+	tok, err := Get(CL100KBase)
+	if err != nil {
+		t.Fatalf("Get returned error: %v", err)
+	}
+	bpe := tok.(*BPE)
+
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"a   b", []string{"a", "  ", " b"}},
+		{"a\t\tb", []string{"a", "\t", "\tb"}},
+		{"a 1", []string{"a", " ", "1"}},
+		{"end  ", []string{"end", "  "}},
+		{"x  \n  y", []string{"x", "  \n", " ", " y"}},
+		{"a\u3000\u3000b", []string{"a", "\u3000", "\u3000b"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		bpe.pieces(tt.text, func(piece string) { got = append(got, piece) })
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("pieces(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestLongPiecesAreSplit(t *testing.T) {
+	tok, err := Get(CL100KBase)
+	if err != nil {
+		t.Fatalf("Get returned error: %v", err)
+	}
+
+	text := strings.Repeat("é", 3*maxPieceBytes)
+	var total int
+	tok.(*BPE).pieces(text, func(piece string) {
+		if len(piece) > maxPieceBytes {
+			t.Errorf("Expected pieces of at most %d bytes, got %d", maxPieceBytes, len(piece))
+		}
+		total += len(piece)
+	})
+	if total != len(text) {
+		t.Errorf("Expected pieces to cover %d bytes, got %d", len(text), total)
+	}
+	if tok.Count(text) == 0 {
+		t.Error("Expected a long run to count tokens")
+	}
+}
+
+func TestLongInvalidPiecesAreSplit(t *testing.T) {
+	tok, err := Get(CL100KBase)
+	if err != nil {
+		t.Fatalf("Get returned error: %v", err)
+	}
+
+	// Continuation bytes with no rune start once used to stall the split.
+	text := "hi " + strings.Repeat("\x80", 700)
+	done := make(chan int)
+	go func() {
+		var total int
+		tok.(*BPE).pieces(text, func(piece string) {
+			if len(piece) == 0 || len(piece) > maxPieceBytes {
+				t.Errorf("Expected pieces of 1 to %d bytes, got %d", maxPieceBytes, len(piece))
+			}
+			total += len(piece)
+		})
+		done <- total
+	}()
+
+	select {
+	case total := <-done:
+		if total != len(text) {
+			t.Errorf("Expected pieces to cover %d bytes, got %d", len(text), total)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected splitting invalid UTF-8 to finish")
+	}
+	if tok.Count(text) == 0 {
+		t.Error("Expected invalid UTF-8 to count tokens")
+	}
+}
+
+// byteVocabulary returns a tiktoken file covering every byte, in rank
+// order, followed by extra tokens
+func byteVocabulary(extra ...string) string {
+	var file strings.Builder
+	rank := 0
+	for b := 0; b < 256; b++ {
+		fmt.Fprintf(&file, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(b)}), rank)
+		rank++
+	}
+	for _, token := range extra {
+		fmt.Fprintf(&file, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(token)), rank)
+		rank++
+	}
+	return file.String()
+}
+
+func TestLoadTiktoken(t *testing.T) {
+	tok, err := LoadTiktoken("custom", strings.NewReader(byteVocabulary("ab", "abc")), "")
+	if err != nil {
+		t.Fatalf("LoadTiktoken returned error: %v", err)
+	}
+	if got := tok.Encode("abcab"); !reflect.DeepEqual(got, []int{257, 256}) {
+		t.Errorf("Encode(abcab) = %v, want [257 256]", got)
+	}
+
+	if _, err := LoadTiktoken("bad", strings.NewReader("YQ== 0\n"), ""); err == nil {
+		t.Error("Expected an error for a vocabulary missing bytes")
+	}
+	if _, err := LoadTiktoken("bad", strings.NewReader("not-base64! 1\n"), ""); err == nil {
+		t.Error("Expected an error for an invalid token")
+	}
+}
+
+func TestLoadHuggingFace(t *testing.T) {
+	encode := make(map[byte]string)
+	for r, b := range byteDecoder() {
+		encode[b] = string(r)
+	}
+	vocab := make(map[string]int)
+	for b := 0; b < 256; b++ {
+		vocab[encode[byte(b)]] = b
+	}
+	vocab["hi"] = 256
+	vocab["Ġw"] = 257
+	vocab["Ġwo"] = 258
+	vocab["<|special|>"] = 259
+
+	for _, merges := range []any{
+		[]string{"h i", "Ġ w", "Ġw o"},
+		[][]string{{"h", "i"}, {"Ġ", "w"}, {"Ġw", "o"}},
+	} {
+		data, err := json.Marshal(map[string]any{
+			"model":         map[string]any{"type": "BPE", "vocab": vocab, "merges": merges},
+			"pre_tokenizer": map[string]any{"type": "ByteLevel"},
+		})
+		if err != nil {
+			t.Fatalf("Marshal: %v", err)
+		}
+
+		path := filepath.Join(t.TempDir(), "tokenizer.json")
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		tok, err := Get(path)
+		if err != nil {
+			t.Fatalf("Get(%s) returned error: %v", path, err)
+		}
+		if got := tok.Encode("hi wow"); !reflect.DeepEqual(got, []int{256, 258, 119}) {
+			t.Errorf("Encode(hi wow) = %v, want [256 258 119]", got)
+		}
+	}
+
+	if _, err := LoadHuggingFace("bad", strings.NewReader(`{"model": {"type": "Unigram"}}`)); err == nil {
+		t.Error("Expected an error for a non-BPE model")
+	}
+}
@@ -0,0 +1,37 @@
+package tokenizer
+
+import (
+	"compress/gzip"
+	"embed"
+	"fmt"
+)
+
+// The encodings are the tiktoken files OpenAI publishes with its MIT-licensed
+// tiktoken library, gzipped.
+//
+//go:embed encodings/*.tiktoken.gz
+var encodingFiles embed.FS
+
+// encodings maps each built-in encoding to its pre-tokenization pattern
+var encodings = map[string]string{
+	CL100KBase: `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+`,
+	O200KBase: `[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?` +
+		`|[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?` +
+		`|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n/]*|\s*[\r\n]+|\s+(?!\S)|\s+`,
+}
+
+func loadEmbedded(name string) (Tokenizer, error) {
+	file, err := encodingFiles.Open("encodings/" + name + ".tiktoken.gz")
+	if err != nil {
+		return nil, fmt.Errorf("encoding %s is not embedded: %w", name, err)
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read encoding %s: %w", name, err)
+	}
+	defer reader.Close()
+
+	return LoadTiktoken(name, reader, encodings[name])
+}
@@ -0,0 +1,29 @@
+package tokenizer
+
+// Model describes a model for context-window and cost estimates
+type Model struct {
+	// Encoding is the tokenizer the model uses, as accepted by Get.
+	Encoding      string `json:"encoding"`
+	ContextWindow int    `json:"context_window"`
+	// InputPricePerMillion is the price of a million prompt tokens in USD.
+	InputPricePerMillion float64 `json:"input_price_per_million"`
+}
+
+// DefaultModels returns the built-in price table. Prices change, so
+// deployments that rely on cost estimates should set their own in the
+// configuration.
+func DefaultModels() map[string]Model {
+	return map[string]Model{
+		"gpt-4o":        {Encoding: O200KBase, ContextWindow: 128000, InputPricePerMillion: 2.50},
+		"gpt-4o-mini":   {Encoding: O200KBase, ContextWindow: 128000, InputPricePerMillion: 0.15},
+		"gpt-4.1":       {Encoding: O200KBase, ContextWindow: 1047576, InputPricePerMillion: 2.00},
+		"gpt-4.1-mini":  {Encoding: O200KBase, ContextWindow: 1047576, InputPricePerMillion: 0.40},
+		"gpt-4-turbo":   {Encoding: CL100KBase, ContextWindow: 128000, InputPricePerMillion: 10.00},
+		"gpt-3.5-turbo": {Encoding: CL100KBase, ContextWindow: 16385, InputPricePerMillion: 0.50},
+	}
+}
+
+// Cost returns the price in USD of sending tokens to the model
+func (m Model) Cost(tokens int) float64 {
+	return float64(tokens) * m.InputPricePerMillion / 1_000_000
+}
@@ -0,0 +1,19 @@
+package tokenizer
+
+import "testing"
+
+func TestDefaultModels(t *testing.T) {
+	for name, model := range DefaultModels() {
+		if _, err := Get(model.Encoding); err != nil {
+			t.Errorf("Model %s uses unavailable encoding %s: %v", name, model.Encoding, err)
+		}
+		if model.ContextWindow <= 0 || model.InputPricePerMillion <= 0 {
+			t.Errorf("Model %s needs a context window and price, got %#v", name, model)
+		}
+	}
+
+	model := Model{InputPricePerMillion: 2.5}
+	if got := model.Cost(2000); got != 0.005 {
+		t.Errorf("Cost(2000) = %v, want 0.005", got)
+	}
+}
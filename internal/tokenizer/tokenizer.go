@@ -0,0 +1,107 @@
+// Package tokenizer counts tokens the way language models do. It ships the
+// cl100k_base and o200k_base byte-pair encodings as embedded data and can
+// load other byte-level BPE vocabularies from disk, so counting never needs
+// the network.
+package tokenizer
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Names of the built-in tokenizers
+const (
+	CL100KBase = "cl100k_base"
+	O200KBase  = "o200k_base"
+	// Estimate is the word-based approximation used before real
+	// tokenizers were available. It needs no vocabulary.
+	Estimate = "estimate"
+)
+
+// Default is the tokenizer used when none is configured
+const Default = O200KBase
+
+// Tokenizer splits text into model tokens
+type Tokenizer interface {
+	// Name identifies the encoding.
+	Name() string
+	// Encode returns the token IDs for text.
+	Encode(text string) []int
+	// Count returns the number of tokens in text.
+	Count(text string) int
+}
+
+var (
+	loadedMu sync.Mutex
+	loaded   = make(map[string]*loadResult)
+)
+
+type loadResult struct {
+	once      sync.Once
+	tokenizer Tokenizer
+	err       error
+}
+
+// Get returns the tokenizer with the given name: a built-in encoding,
+// Estimate, or the path of a .tiktoken or tokenizer.json file. Tokenizers
+// are loaded once and shared, and are safe for concurrent use.
+func Get(name string) (Tokenizer, error) {
+	if name == "" {
+		name = Default
+	}
+	if name == Estimate {
+		return estimator{}, nil
+	}
+
+	loadedMu.Lock()
+	result, ok := loaded[name]
+	if !ok {
+		result = &loadResult{}
+		loaded[name] = result
+	}
+	loadedMu.Unlock()
+
+	result.once.Do(func() {
+		if _, builtin := encodings[name]; builtin {
+			result.tokenizer, result.err = loadEmbedded(name)
+		} else {
+			result.tokenizer, result.err = LoadFile(name)
+		}
+	})
+	return result.tokenizer, result.err
+}
+
+// LoadFile loads a byte-level BPE tokenizer from disk. Files ending in
+// .json are read as Hugging Face tokenizer.json files, which hold both the
+// vocabulary and the merges; anything else is read as a tiktoken file of
+// base64 tokens and ranks.
+func LoadFile(path string) (Tokenizer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("unknown tokenizer %q (expected %s, %s, %s, or a vocabulary file)", path, O200KBase, CL100KBase, Estimate)
+		}
+		return nil, fmt.Errorf("failed to open tokenizer: %w", err)
+	}
+	defer file.Close()
+
+	if strings.HasSuffix(strings.ToLower(path), ".json") {
+		return LoadHuggingFace(path, file)
+	}
+	return LoadTiktoken(path, file, "")
+}
+
+// estimator approximates 1.3 tokens per word
+type estimator struct{}
+
+func (estimator) Name() string { return Estimate }
+
+func (e estimator) Encode(text string) []int {
+	return make([]int, e.Count(text))
+}
+
+func (estimator) Count(text string) int {
+	return int(float64(len(strings.Fields(text))) * 1.3)
+}
@@ -0,0 +1,63 @@
+package tokenizer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuiltinEncodings(t *testing.T) {
+	tests := []struct {
+		encoding string
+		text     string
+		want     []int
+	}{
+		{CL100KBase, "hello world", []int{15339, 1917}},
+		{CL100KBase, "Hello, world!", []int{9906, 11, 1917, 0}},
+		{CL100KBase, "tiktoken is great!", []int{83, 1609, 5963, 374, 2294, 0}},
+		{O200KBase, "hello world", []int{24912, 2375}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoding+"/"+tt.text, func(t *testing.T) {
+			tok, err := Get(tt.encoding)
+			if err != nil {
+				t.Fatalf("Get(%s) returned error: %v", tt.encoding, err)
+			}
+			if got := tok.Encode(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Encode(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+			if got := tok.Count(tt.text); got != len(tt.want) {
+				t.Errorf("Count(%q) = %d, want %d", tt.text, got, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestCountDiffersFromEstimate(t *testing.T) {
+	tok, err := Get(O200KBase)
+	if err != nil {
+		t.Fatalf("Get returned error: %v", err)
+	}
+	estimate, _ := Get(Estimate)
+
+	for _, text := range []string{
+		"こんにちは世界、今日はいい天気ですね",
+		"func (s *Server) handleValidateToolCall(w http.ResponseWriter, r *http.Request) {",
+		"AbstractSingletonProxyFactoryBeanConfigurationManagerImpl",
+	} {
+		if got, approx := tok.Count(text), estimate.Count(text); got <= approx {
+			t.Errorf("Expected %q to count more tokens than the estimate %d, got %d", text, approx, got)
+		}
+	}
+}
+
+func TestGetUnknown(t *testing.T) {
+	if _, err := Get("p99k_base"); err == nil || !strings.Contains(err.Error(), "unknown tokenizer") {
+		t.Errorf("Expected an unknown tokenizer error, got %v", err)
+	}
+	tok, err := Get("")
+	if err != nil || tok.Name() != Default {
+		t.Errorf("Expected the default tokenizer, got %v (%v)", tok, err)
+	}
+}
@@ -0,0 +1,134 @@
+// Package tracing sets up the OpenTelemetry tracer provider the server
+// records spans with. Spans are exported over OTLP to a collector, or
+// written as JSON to stdout or a file where no collector is available.
+//
+// The spans carry use cases, scores, issue counts, and detector names,
+// never the text being validated, so traces need no more care than
+// metrics.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// Exporters for Options.Exporter
+const (
+	// ExporterNone records no spans.
+	ExporterNone = "none"
+	// ExporterOTLP sends spans to an OTLP collector over gRPC.
+	ExporterOTLP = "otlp"
+	// ExporterStdout writes spans to standard output as indented JSON.
+	ExporterStdout = "stdout"
+	// ExporterFile appends spans to Options.File, one JSON object per line.
+	ExporterFile = "file"
+)
+
+// Options configures Setup.
+type Options struct {
+	// Exporter is where spans go: one of the Exporter constants. Empty
+	// means ExporterNone.
+	Exporter string
+	// Endpoint is the OTLP collector's host:port. Empty uses
+	// $OTEL_EXPORTER_OTLP_ENDPOINT, or localhost:4317.
+	Endpoint string
+	// Insecure sends spans to the collector without TLS.
+	Insecure bool
+	// File is the path ExporterFile appends to.
+	File string
+	// SampleRatio is the fraction of traces recorded, from 0 to 1. Traces
+	// started by a caller that propagated a sampling decision follow it.
+	SampleRatio float64
+	// ServiceVersion is reported with every span.
+	ServiceVersion string
+}
+
+// Setup returns a tracer provider exporting spans as opts describes, and a
+// function that flushes any spans not yet exported and releases the
+// exporter. The provider is a no-op with ExporterNone.
+func Setup(ctx context.Context, opts Options) (trace.TracerProvider, func(context.Context) error, error) {
+	if opts.SampleRatio < 0 || opts.SampleRatio > 1 {
+		return nil, nil, fmt.Errorf("trace sample ratio must be between 0 and 1, got %v", opts.SampleRatio)
+	}
+
+	var exporter sdktrace.SpanExporter
+	var closer io.Closer
+	switch opts.Exporter {
+	case "", ExporterNone:
+		return noop.NewTracerProvider(), func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		var otlpOpts []otlptracegrpc.Option
+		if opts.Endpoint != "" {
+			otlpOpts = append(otlpOpts, otlptracegrpc.WithEndpoint(opts.Endpoint))
+		}
+		if opts.Insecure {
+			otlpOpts = append(otlpOpts, otlptracegrpc.WithInsecure())
+		}
+		// The client connects in the background, so a collector that is
+		// down does not stop the server from starting.
+		var err error
+		if exporter, err = otlptracegrpc.New(ctx, otlpOpts...); err != nil {
+			return nil, nil, fmt.Errorf("create OTLP exporter: %w", err)
+		}
+	case ExporterStdout:
+		var err error
+		if exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint()); err != nil {
+			return nil, nil, fmt.Errorf("create stdout exporter: %w", err)
+		}
+	case ExporterFile:
+		if opts.File == "" {
+			return nil, nil, errors.New("the file exporter needs a file path")
+		}
+		file, err := os.OpenFile(opts.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, nil, fmt.Errorf("open trace file: %w", err)
+		}
+		if exporter, err = stdouttrace.New(stdouttrace.WithWriter(file)); err != nil {
+			file.Close()
+			return nil, nil, fmt.Errorf("create file exporter: %w", err)
+		}
+		closer = file
+	default:
+		return nil, nil, fmt.Errorf("unknown trace exporter %q (want %s, %s, %s, or %s)", opts.Exporter, ExporterNone, ExporterOTLP, ExporterStdout, ExporterFile)
+	}
+
+	// $OTEL_SERVICE_NAME and $OTEL_RESOURCE_ATTRIBUTES override the
+	// defaults.
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName("promptsentinel"), semconv.ServiceVersion(opts.ServiceVersion)),
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		_ = exporter.Shutdown(ctx)
+		if closer != nil {
+			closer.Close()
+		}
+		return nil, nil, fmt.Errorf("describe trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	shutdown := func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			err = errors.Join(err, closer.Close())
+		}
+		return err
+	}
+	return provider, shutdown, nil
+}
@@ -0,0 +1,95 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetup_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	provider, shutdown, err := Setup(context.Background(), Options{Exporter: ExporterFile, File: path, SampleRatio: 1, ServiceVersion: "1.2.3"})
+	if err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+
+	tracer := provider.Tracer("test")
+	for _, name := range []string{"first", "second"} {
+		_, span := tracer.Start(context.Background(), name)
+		span.End()
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read spans: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected one line per span, got %d: %s", len(lines), data)
+	}
+
+	var span struct {
+		Name     string
+		Resource []struct {
+			Key   string
+			Value struct{ Value any }
+		}
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &span); err != nil {
+		t.Fatalf("span is not JSON: %v", err)
+	}
+	if span.Name != "first" {
+		t.Errorf("expected the first span first, got %q", span.Name)
+	}
+	resource := make(map[string]any)
+	for _, attr := range span.Resource {
+		resource[attr.Key] = attr.Value.Value
+	}
+	if resource["service.name"] != "promptsentinel" || resource["service.version"] != "1.2.3" {
+		t.Errorf("expected the service in the resource, got %v", resource)
+	}
+}
+
+func TestSetup_None(t *testing.T) {
+	for _, exporter := range []string{"", ExporterNone} {
+		provider, shutdown, err := Setup(context.Background(), Options{Exporter: exporter})
+		if err != nil {
+			t.Fatalf("Setup(%q): %v", exporter, err)
+		}
+		_, span := provider.Tracer("test").Start(context.Background(), "span")
+		if span.IsRecording() {
+			t.Errorf("Setup(%q): expected spans not to be recorded", exporter)
+		}
+		span.End()
+		if err := shutdown(context.Background()); err != nil {
+			t.Errorf("Setup(%q): shutdown: %v", exporter, err)
+		}
+	}
+}
+
+func TestSetup_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{"unknown exporter", Options{Exporter: "zipkin"}, "unknown trace exporter"},
+		{"file without a path", Options{Exporter: ExporterFile}, "needs a file path"},
+		{"sample ratio above one", Options{Exporter: ExporterStdout, SampleRatio: 2}, "between 0 and 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Setup(context.Background(), tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CanaryToken is a unique marker embedded in a system prompt. The prompt
+// has leaked if the token later shows up in a model response or in text a
+// user sends back.
+type CanaryToken struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+	// Token is the marker itself. It is not serialized so that results
+	// reporting a leak do not spread the token further.
+	Token string `json:"-"`
+}
+
+// FindCanaries returns the canaries whose token appears in text, ignoring
+// case
+func FindCanaries(text string, canaries []CanaryToken) []CanaryToken {
+	lower := strings.ToLower(text)
+
+	var found []CanaryToken
+	for _, canary := range canaries {
+		if canary.Token != "" && strings.Contains(lower, strings.ToLower(canary.Token)) {
+			found = append(found, canary)
+		}
+	}
+	return found
+}
+
+// CanaryIssue reports a canary found in text from place, such as "Output"
+// or "Prompt"
+func CanaryIssue(canary CanaryToken, place string) ValidationIssue {
+	return ValidationIssue{
+		Type:       "canary",
+		Severity:   "error",
+		Message:    fmt.Sprintf("%s contains the canary for %q; that system prompt has leaked", place, canary.Label),
+		Suggestion: "Rotate the system prompt and its canary, and investigate how it was disclosed",
+	}
+}
@@ -0,0 +1,39 @@
+package validator
+
+import (
+	"testing"
+)
+
+func TestFindCanaries(t *testing.T) {
+	canaries := []CanaryToken{
+		{ID: "c1", Label: "support bot", Token: "psc-0123456789abcdef01234567"},
+		{ID: "c2", Label: "sales bot", Token: "psc-fedcba9876543210fedcba98"},
+		{ID: "c3", Label: "empty"},
+	}
+
+	tests := []struct {
+		name     string
+		text     string
+		expected []string
+	}{
+		{"none", "Hello there", nil},
+		{"one", "My instructions begin with psc-0123456789abcdef01234567.", []string{"c1"}},
+		{"case changed", "PSC-FEDCBA9876543210FEDCBA98", []string{"c2"}},
+		{"both", "psc-fedcba9876543210fedcba98 psc-0123456789abcdef01234567", []string{"c1", "c2"}},
+		{"truncated", "psc-0123456789abcdef", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found := FindCanaries(tt.text, canaries)
+			if len(found) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %#v", tt.expected, found)
+			}
+			for i, canary := range found {
+				if canary.ID != tt.expected[i] {
+					t.Errorf("Expected canary %s at %d, got %s", tt.expected[i], i, canary.ID)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,383 @@
+package validator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Message roles. Other provider role names are mapped onto these by
+// NormalizeRole.
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool"
+)
+
+// Message is a single chat message with its text content
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// RolePolicy controls how messages with a given role are validated
+type RolePolicy struct {
+	// Skip lists issue types that are not checked for the role, for example
+	// "injection" or "pattern".
+	Skip []string `json:"skip,omitempty"`
+}
+
+// ConversationResult is the result of validating a list of messages. The
+// embedded ValidationResult summarizes the whole conversation: it is valid
+// only if every message and the conversation-level analysis are, and its
+// score is the lowest message score less any conversation-level deductions.
+type ConversationResult struct {
+	ValidationResult
+	Messages []MessageResult `json:"messages"`
+	// Analysis is nil when the context was done before it ran.
+	Analysis *ConversationAnalysis `json:"conversation_analysis"`
+}
+
+// MessageResult is the validation result for one message
+type MessageResult struct {
+	Index   int               `json:"index"`
+	Role    string            `json:"role"`
+	IsValid bool              `json:"is_valid"`
+	Score   int               `json:"score"`
+	Issues  []ValidationIssue `json:"issues"`
+}
+
+// DefaultRolePolicies returns the built-in role policies. Injection markers
+// matter in user and tool content, which may come from untrusted sources,
+// but not in the operator's system prompt or in the model's own replies.
+func DefaultRolePolicies() map[string]RolePolicy {
+	return map[string]RolePolicy{
+		RoleSystem:    {Skip: []string{"injection"}},
+		RoleAssistant: {Skip: []string{"injection"}},
+	}
+}
+
+// rolePolicy returns the policy for role, preferring the configured one
+func rolePolicy(config *Config, role string) RolePolicy {
+	if policy, ok := config.RolePolicies[role]; ok {
+		return policy
+	}
+	return DefaultRolePolicies()[role]
+}
+
+// NormalizeRole maps provider-specific role names onto the roles above
+func NormalizeRole(role string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(role)) {
+	case "system", "developer":
+		return RoleSystem, nil
+	case "user", "human":
+		return RoleUser, nil
+	case "assistant", "model":
+		return RoleAssistant, nil
+	case "tool", "function":
+		return RoleTool, nil
+	}
+	return "", fmt.Errorf("unknown message role %q", role)
+}
+
+// ParseMessages decodes a conversation in OpenAI or Anthropic format. It
+// accepts a bare message array or an object with a "messages" field and, for
+// Anthropic, a top-level "system" prompt. Content may be a string or an array
+// of content blocks; text blocks are joined and Anthropic tool_result blocks
+// become separate tool messages. Message indexes in validation results refer
+// to the returned slice.
+func ParseMessages(data []byte) ([]Message, error) {
+	data = bytes.TrimSpace(data)
+
+	var raws []rawMessage
+	var system json.RawMessage
+	if len(data) > 0 && data[0] == '[' {
+		if err := json.Unmarshal(data, &raws); err != nil {
+			return nil, fmt.Errorf("invalid message array: %w", err)
+		}
+	} else {
+		var envelope struct {
+			System   json.RawMessage `json:"system"`
+			Messages []rawMessage    `json:"messages"`
+		}
+		if err := json.Unmarshal(data, &envelope); err != nil {
+			return nil, fmt.Errorf("invalid conversation: %w", err)
+		}
+		if envelope.Messages == nil {
+			return nil, fmt.Errorf("expected a message array or an object with a messages field")
+		}
+		raws, system = envelope.Messages, envelope.System
+	}
+
+	var messages []Message
+	if len(system) > 0 {
+		text, _, err := contentText(system)
+		if err != nil {
+			return nil, fmt.Errorf("system: %w", err)
+		}
+		if text != "" {
+			messages = append(messages, Message{Role: RoleSystem, Content: text})
+		}
+	}
+
+	for i, raw := range raws {
+		role, err := NormalizeRole(raw.Role)
+		if err != nil {
+			return nil, fmt.Errorf("message %d: %w", i, err)
+		}
+
+		text, toolResults, err := contentText(raw.Content)
+		if err != nil {
+			return nil, fmt.Errorf("message %d: %w", i, err)
+		}
+
+		// A user turn that only carries tool results is really tool output.
+		if text != "" || len(toolResults) == 0 {
+			messages = append(messages, Message{Role: role, Content: text})
+		}
+		for _, result := range toolResults {
+			messages = append(messages, Message{Role: RoleTool, Content: result})
+		}
+	}
+
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("conversation has no messages")
+	}
+	return messages, nil
+}
+
+type rawMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+type rawBlock struct {
+	Type    string          `json:"type"`
+	Text    string          `json:"text"`
+	Content json.RawMessage `json:"content"`
+}
+
+// contentText extracts the text of a message's content, returning the text
+// of any tool_result blocks separately
+func contentText(raw json.RawMessage) (string, []string, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return "", nil, nil
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text, nil, nil
+	}
+
+	var blocks []rawBlock
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return "", nil, fmt.Errorf("content must be a string or an array of content blocks")
+	}
+
+	var texts, toolResults []string
+	for _, block := range blocks {
+		switch block.Type {
+		case "text", "input_text":
+			texts = append(texts, block.Text)
+		case "tool_result":
+			result, _, err := contentText(block.Content)
+			if err != nil {
+				return "", nil, err
+			}
+			toolResults = append(toolResults, result)
+		}
+	}
+	return strings.Join(texts, "\n"), toolResults, nil
+}
+
+// ConversationText renders messages as a plain transcript, one
+// "role: content" paragraph per message
+func ConversationText(messages []Message) string {
+	parts := make([]string, 0, len(messages))
+	for _, message := range messages {
+		parts = append(parts, message.Role+": "+message.Content)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// ValidateConversation validates each message with the checks its role
+// policy allows and attributes every issue to its message index and role.
+// Messages without text, such as assistant turns that only call tools, are
+// skipped.
+func ValidateConversation(messages []Message, config *Config) (*ConversationResult, error) {
+	return ValidateConversationContext(context.Background(), messages, config)
+}
+
+// ValidateConversationContext is ValidateConversation with a context. When
+// the context is done, the remaining checks, including those of later
+// messages, are skipped and the result is marked incomplete.
+func ValidateConversationContext(ctx context.Context, messages []Message, config *Config) (*ConversationResult, error) {
+	return validateConversation(messages, config, newRun(ctx, nil))
+}
+
+func validateConversation(messages []Message, config *Config, r *run) (*ConversationResult, error) {
+	startTime := time.Now()
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("conversation has no messages")
+	}
+
+	result := &ConversationResult{
+		ValidationResult: ValidationResult{
+			IsValid:   true,
+			Score:     100,
+			Issues:    []ValidationIssue{},
+			Metadata:  make(map[string]interface{}),
+			Timestamp: time.Now(),
+		},
+		Messages: []MessageResult{},
+	}
+
+	totalLength := 0
+	for i, message := range messages {
+		if strings.TrimSpace(message.Content) == "" {
+			continue
+		}
+		totalLength += len(message.Content)
+
+		skip := make(map[string]bool)
+		for _, issueType := range rolePolicy(config, message.Role).Skip {
+			skip[issueType] = true
+		}
+
+		messageResult, err := validateText(message.Content, config, func(issueType string) bool {
+			return !skip[issueType]
+		}, r)
+		if err != nil {
+			return nil, fmt.Errorf("message %d: %w", i, err)
+		}
+
+		for j := range messageResult.Issues {
+			index := i
+			messageResult.Issues[j].MessageIndex = &index
+			messageResult.Issues[j].Role = message.Role
+		}
+
+		result.Messages = append(result.Messages, MessageResult{
+			Index:   i,
+			Role:    message.Role,
+			IsValid: messageResult.IsValid,
+			Score:   messageResult.Score,
+			Issues:  messageResult.Issues,
+		})
+
+		result.IsValid = result.IsValid && messageResult.IsValid
+		if messageResult.Score < result.Score {
+			result.Score = messageResult.Score
+		}
+		result.Issues = append(result.Issues, messageResult.Issues...)
+	}
+
+	// Conversation-level detectors catch attacks spread across turns
+	r.runStage(StageConversationAnalysis, func() error {
+		result.Analysis = analyzeConversation(messages, result.Messages, config, r)
+		return nil
+	})
+	var analysisIssues []ValidationIssue
+	if result.Analysis != nil {
+		analysisIssues = result.Analysis.Issues
+	}
+	for _, issue := range analysisIssues {
+		result.Issues = append(result.Issues, issue)
+		if issue.Severity == "error" {
+			result.IsValid = false
+			result.Score -= 25
+		} else {
+			result.Score -= 10
+		}
+	}
+	if result.Score < 0 {
+		result.Score = 0
+	}
+
+	r.finish(&result.ValidationResult, config)
+
+	result.Metadata["processing_time_ms"] = elapsedMillis(startTime)
+	result.Metadata["message_count"] = len(messages)
+	result.Metadata["prompt_length"] = totalLength
+	result.Metadata["use_case"] = config.UseCase
+
+	return result, nil
+}
+
+// ValidateConversationComprehensive validates a conversation and runs the
+// security, compliance, and performance analysis over its transcript. The
+// result includes the conversation analysis with its per-turn risk timeline.
+func ValidateConversationComprehensive(messages []Message, config *Config) (*ComprehensiveValidationResult, error) {
+	return ValidateConversationComprehensiveContext(context.Background(), messages, config)
+}
+
+// ValidateConversationComprehensiveContext is
+// ValidateConversationComprehensive with a context. When the context is
+// done, the remaining checks and stages are skipped and the result is marked
+// incomplete.
+func ValidateConversationComprehensiveContext(ctx context.Context, messages []Message, config *Config) (*ComprehensiveValidationResult, error) {
+	r := newRun(ctx, newProfiler())
+
+	var conversationResult *ConversationResult
+	err := r.stage(StageValidation, func() (err error) {
+		conversationResult, err = validateConversation(messages, config, r)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ComprehensiveValidationResult{
+		ValidationResult:     conversationResult.ValidationResult,
+		ConversationAnalysis: conversationResult.Analysis,
+	}
+	if err := analyzeText(result, ConversationText(messages), config, r); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// injectionMarker is a phrase or token used to smuggle instructions into
+// content that should be treated as data. joined is the same pattern with
+// "." matching newlines, for text spread across turns.
+type injectionMarker struct {
+	name            string
+	pattern, joined *regexp.Regexp
+}
+
+func newInjectionMarker(name, pattern string) injectionMarker {
+	return injectionMarker{name, regexp.MustCompile(pattern), regexp.MustCompile("(?s)" + pattern)}
+}
+
+// injectionMarkers are the markers validateInjectionMarkers looks for
+var injectionMarkers = []injectionMarker{
+	newInjectionMarker("instruction override", `(?i)\b(ignore|disregard|forget)\b.{0,20}\b(previous|prior|above|earlier|all|your)\b.{0,20}\b(instructions|rules|prompts?|guidelines|directions)\b`),
+	newInjectionMarker("role reassignment", `(?i:\b(you are now|from now on,? you (are|will))\b)|\bDAN\b|(?i:developer mode)`),
+	newInjectionMarker("system prompt extraction", `(?i)\b(reveal|print|show|repeat|output)\b.{0,30}\b(system prompt|initial instructions|hidden instructions)\b`),
+	newInjectionMarker("chat template tokens", `<\|im_start\|>|<\|im_end\|>|<\|endoftext\|>|\[/?INST\]|<</?SYS>>`),
+	newInjectionMarker("role spoofing", `(?im)^\s*(system|assistant)\s*:`),
+}
+
+// validateInjectionMarkers flags content that tries to override the
+// instructions of the model it is sent to
+func validateInjectionMarkers(prompt string, result *ValidationResult) {
+	for _, marker := range injectionMarkers {
+		if marker.pattern.MatchString(prompt) {
+			result.Issues = append(result.Issues, ValidationIssue{
+				Type:       "injection",
+				Severity:   "error",
+				Message:    fmt.Sprintf("Possible prompt injection: %s", marker.name),
+				Suggestion: "Treat this content as data, not as instructions",
+			})
+			result.IsValid = false
+			result.Score -= 25
+		}
+	}
+}
@@ -0,0 +1,324 @@
+package validator
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultChunkSize is the chunk size, in characters of visible text, used
+// when DocumentOptions does not set one
+const DefaultChunkSize = 1000
+
+// DocumentOptions controls how a document is parsed and chunked
+type DocumentOptions struct {
+	// Format is one of FormatText, FormatMarkdown, FormatHTML, or
+	// FormatJSON. When empty it is detected from the content.
+	Format    string
+	ChunkSize int
+}
+
+// DocumentScanResult reports on a document retrieved for RAG. It is valid
+// when no chunk is poisoned, and its score is that of the worst chunk.
+type DocumentScanResult struct {
+	ValidationResult
+	Format string          `json:"format"`
+	Chunks []DocumentChunk `json:"chunks"`
+}
+
+// DocumentChunk is a piece of a document scored on its own, so that poisoned
+// chunks can be dropped before the rest reaches the model
+type DocumentChunk struct {
+	Index int `json:"index"`
+	// Path is the JSON pointer of the value the chunk came from in a JSON
+	// document.
+	Path string `json:"path,omitempty"`
+	// Text is the visible text. Hidden content is reported separately.
+	Text     string            `json:"text"`
+	Hidden   []HiddenContent   `json:"hidden,omitempty"`
+	Score    int               `json:"score"`
+	Poisoned bool              `json:"poisoned"`
+	Issues   []ValidationIssue `json:"issues"`
+}
+
+// documentInstructionMarkers are phrases that address the model reading a
+// document rather than the person it was written for
+var documentInstructionMarkers = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"instructions addressed to the model", regexp.MustCompile(`(?i)\b(attention|note|message|instructions?)\s+(to|for)\s+(the\s+)?(ai|llm|language model|assistant|chatbot|model)\b|\b(dear|hey|hello)\s+(ai|llm|assistant|chatbot)\b`)},
+	{"conditional targeting of AI readers", regexp.MustCompile(`(?i)\bif\s+you\s+are\s+(an?\s+)?(ai|llm|language model|assistant|chatbot|gpt|claude|gemini)\b`)},
+	{"response override", regexp.MustCompile(`(?i)\binstead,?\s+(respond|reply|answer|say|tell|output|write)\b|\byou\s+(must|should|will)\s+(now\s+)?(respond|reply|answer|say|recommend|tell\s+the\s+user)\b`)},
+	{"concealment from the user", regexp.MustCompile(`(?i)\b(do\s+not|don't|never)\s+(tell|inform|mention|reveal|disclose)\b.{0,40}\b(the\s+)?users?\b`)},
+	{"summary manipulation", regexp.MustCompile(`(?i)\bwhen\s+(summari[sz]ing|asked\s+about|describing)\b.{0,60}\b(say|state|mention|claim|describe|recommend)\b`)},
+	{"data exfiltration", regexp.MustCompile(`(?i)\b(send|post|forward|include|append|embed)\b.{0,40}\b(conversation|chat history|user'?s?\s+(data|messages?|email|details))\b.{0,40}\b(to|in|into)\b`)},
+}
+
+// ScanDocument checks a document retrieved for RAG for instructions aimed
+// at the model. Hidden content, such as HTML comments, invisible elements,
+// white-on-white text, alt text, and Unicode tag characters, is pulled out
+// of the visible text and scanned separately, since a reader of the rendered
+// page would never see it. The document is split into chunks that are each
+// scored, so a caller can drop poisoned chunks and keep the rest.
+func ScanDocument(content string, opts DocumentOptions, config *Config) (*DocumentScanResult, error) {
+	return ScanDocumentContext(context.Background(), content, opts, config)
+}
+
+// ScanDocumentContext is ScanDocument with a context. When the context is
+// done, the remaining checks and chunks are skipped and the result is marked
+// incomplete; Chunks then holds only the chunks reached before.
+func ScanDocumentContext(ctx context.Context, content string, opts DocumentOptions, config *Config) (*DocumentScanResult, error) {
+	start := time.Now()
+	r := newRun(ctx, nil)
+
+	format := opts.Format
+	if format == "" {
+		format = DetectDocumentFormat("", content)
+	}
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	paragraphs, err := extractParagraphs(content, format)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DocumentScanResult{
+		ValidationResult: ValidationResult{
+			IsValid:   true,
+			Score:     100,
+			Issues:    []ValidationIssue{},
+			Metadata:  make(map[string]interface{}),
+			Timestamp: time.Now(),
+		},
+		Format: format,
+		Chunks: []DocumentChunk{},
+	}
+
+	hiddenCount := 0
+	for i, chunk := range chunkParagraphs(paragraphs, chunkSize) {
+		if r.stopped() {
+			break
+		}
+		chunk.Index = i
+		if err := scanChunk(&chunk, config, r); err != nil {
+			return nil, err
+		}
+		hiddenCount += len(chunk.Hidden)
+
+		for _, issue := range chunk.Issues {
+			index := i
+			issue.ChunkIndex = &index
+			result.Issues = append(result.Issues, issue)
+		}
+		if chunk.Poisoned {
+			result.IsValid = false
+		}
+		if chunk.Score < result.Score {
+			result.Score = chunk.Score
+		}
+		result.Chunks = append(result.Chunks, chunk)
+	}
+
+	r.finish(&result.ValidationResult, config)
+
+	result.Metadata["format"] = format
+	result.Metadata["document_length"] = len(content)
+	result.Metadata["chunk_count"] = len(result.Chunks)
+	result.Metadata["hidden_count"] = hiddenCount
+	result.Metadata["processing_time_ms"] = elapsedMillis(start)
+
+	return result, nil
+}
+
+// PoisonedChunks returns the indexes of the chunks that should be dropped
+func (r *DocumentScanResult) PoisonedChunks() []int {
+	var indexes []int
+	for _, chunk := range r.Chunks {
+		if chunk.Poisoned {
+			indexes = append(indexes, chunk.Index)
+		}
+	}
+	return indexes
+}
+
+// scanChunk scores one chunk. Instructions in hidden content cost more than
+// in visible text: a person reviewing the page could have spotted the
+// latter, but nobody put the former there by accident.
+func scanChunk(chunk *DocumentChunk, config *Config, r *run) error {
+	// Length is a property of the whole document, and the use case checks
+	// are written for prompts, not retrieved content.
+	visible, err := validateText(chunk.Text, config, func(issueType string) bool {
+		return issueType == "pattern" || issueType == "custom_rule"
+	}, r)
+	if err != nil {
+		return err
+	}
+	chunk.Issues = visible.Issues
+	chunk.Score = visible.Score
+
+	r.detect("indirect_injection", "", func() {
+		for _, name := range documentInstructions(chunk.Text) {
+			chunk.Issues = append(chunk.Issues, ValidationIssue{
+				Type:       "indirect_injection",
+				Severity:   "error",
+				Message:    fmt.Sprintf("Document contains instructions for the model: %s", name),
+				Suggestion: "Drop this chunk before it reaches the model",
+			})
+			chunk.Score -= 25
+		}
+	})
+
+	r.detect("hidden_content", "", func() {
+		warned := make(map[string]bool)
+		for _, hidden := range chunk.Hidden {
+			for _, name := range documentInstructions(hidden.Text) {
+				chunk.Issues = append(chunk.Issues, ValidationIssue{
+					Type:       "indirect_injection",
+					Severity:   "error",
+					Message:    fmt.Sprintf("Hidden %s content contains instructions for the model: %s", hiddenKindName(hidden.Kind), name),
+					Suggestion: "Drop this chunk and review the source document",
+				})
+				chunk.Score -= 40
+			}
+
+			// Comments and alt text are routine; text styled to be invisible
+			// rarely is.
+			switch hidden.Kind {
+			case HiddenWhiteText, HiddenDisplayNone, HiddenInvisibleUnicode:
+				if !warned[hidden.Kind] {
+					warned[hidden.Kind] = true
+					chunk.Issues = append(chunk.Issues, ValidationIssue{
+						Type:       "hidden_content",
+						Severity:   "warning",
+						Message:    fmt.Sprintf("Chunk contains %s text that readers cannot see", hiddenKindName(hidden.Kind)),
+						Suggestion: "Check why the source document hides this text",
+					})
+					chunk.Score -= 10
+				}
+			}
+		}
+	})
+
+	for _, issue := range chunk.Issues {
+		if issue.Severity == "error" {
+			chunk.Poisoned = true
+		}
+	}
+	if chunk.Score < 0 {
+		chunk.Score = 0
+	}
+	return nil
+}
+
+// documentInstructions returns the names of the injection and document
+// instruction markers found in text
+func documentInstructions(text string) []string {
+	var names []string
+	for _, marker := range injectionMarkers {
+		if marker.pattern.MatchString(text) {
+			names = append(names, marker.name)
+		}
+	}
+	for _, marker := range documentInstructionMarkers {
+		if marker.pattern.MatchString(text) {
+			names = append(names, marker.name)
+		}
+	}
+	return names
+}
+
+func hiddenKindName(kind string) string {
+	switch kind {
+	case HiddenComment:
+		return "comment"
+	case HiddenDisplayNone:
+		return "invisible element"
+	case HiddenAltText:
+		return "alt text"
+	case HiddenWhiteText:
+		return "white-on-white"
+	case HiddenInvisibleUnicode:
+		return "invisible Unicode"
+	}
+	return kind
+}
+
+// chunkParagraphs packs paragraphs into chunks of at most size characters
+// of visible text. A chunk never spans two JSON values, and a paragraph
+// longer than size is split between words.
+func chunkParagraphs(paragraphs []paragraph, size int) []DocumentChunk {
+	var chunks []DocumentChunk
+	var current *DocumentChunk
+
+	flush := func() {
+		if current != nil {
+			chunks = append(chunks, *current)
+			current = nil
+		}
+	}
+
+	for _, p := range paragraphs {
+		if current != nil && (current.Path != p.path || len(current.Text)+len(p.text)+2 > size) {
+			flush()
+		}
+
+		pieces := splitWords(p.text, size)
+		for i, piece := range pieces {
+			if i > 0 {
+				flush()
+			}
+			if current == nil {
+				current = &DocumentChunk{Path: p.path}
+			}
+			if current.Text != "" && piece != "" {
+				current.Text += "\n\n"
+			}
+			current.Text += piece
+		}
+		// Hidden content stays with the chunk that holds the end of its
+		// paragraph.
+		current.Hidden = append(current.Hidden, p.hidden...)
+	}
+	flush()
+
+	return chunks
+}
+
+// splitWords splits text into pieces of at most size characters, breaking
+// between words where possible
+func splitWords(text string, size int) []string {
+	if len(text) <= size {
+		return []string{text}
+	}
+
+	var pieces []string
+	var piece strings.Builder
+	for _, word := range strings.Fields(text) {
+		for len(word) > size {
+			if piece.Len() > 0 {
+				pieces = append(pieces, piece.String())
+				piece.Reset()
+			}
+			pieces = append(pieces, word[:size])
+			word = word[size:]
+		}
+		if piece.Len() > 0 && piece.Len()+1+len(word) > size {
+			pieces = append(pieces, piece.String())
+			piece.Reset()
+		}
+		if piece.Len() > 0 {
+			piece.WriteByte(' ')
+		}
+		piece.WriteString(word)
+	}
+	if piece.Len() > 0 {
+		pieces = append(pieces, piece.String())
+	}
+	return pieces
+}
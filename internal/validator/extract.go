@@ -0,0 +1,473 @@
+package validator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Document formats accepted by ScanDocument.
+const (
+	FormatText     = "text"
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	FormatJSON     = "json"
+)
+
+// Kinds of hidden content: text a model reads but a person viewing the
+// rendered document does not see.
+const (
+	HiddenComment          = "comment"
+	HiddenDisplayNone      = "display_none"
+	HiddenAltText          = "alt_text"
+	HiddenWhiteText        = "white_text"
+	HiddenInvisibleUnicode = "invisible_unicode"
+)
+
+// HiddenContent is a piece of hidden text removed from a document
+type HiddenContent struct {
+	Kind string `json:"kind"`
+	Text string `json:"text"`
+}
+
+// paragraph is a block of visible text with the hidden content found inside
+// it. Path is the JSON pointer of the string it came from, if any.
+type paragraph struct {
+	text   string
+	hidden []HiddenContent
+	path   string
+}
+
+// DetectDocumentFormat guesses the format of a document from its file name,
+// falling back to its content when the name does not say
+func DetectDocumentFormat(name, content string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".md", ".markdown":
+		return FormatMarkdown
+	case ".html", ".htm":
+		return FormatHTML
+	case ".json":
+		return FormatJSON
+	case ".txt":
+		return FormatText
+	}
+
+	trimmed := strings.TrimSpace(content)
+	switch {
+	case (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)):
+		return FormatJSON
+	case htmlSniffPattern.MatchString(trimmed):
+		return FormatHTML
+	case markdownSniffPattern.MatchString(trimmed):
+		return FormatMarkdown
+	}
+	return FormatText
+}
+
+var (
+	htmlSniffPattern     = regexp.MustCompile(`(?i)<!doctype html|<html[\s>]|</(p|div|span|body|table|li)>`)
+	markdownSniffPattern = regexp.MustCompile("(?m)^#{1,6} |^```|!\\[[^\\]]*\\]\\(|^\\s*[-*] \\S")
+)
+
+// extractParagraphs splits a document into paragraphs of visible text,
+// moving hidden content out of the text and into each paragraph's hidden
+// list
+func extractParagraphs(content, format string) ([]paragraph, error) {
+	switch format {
+	case FormatText:
+		return extractText(content, ""), nil
+	case FormatMarkdown:
+		return extractMarkdown(content, ""), nil
+	case FormatHTML:
+		return extractHTML(content, ""), nil
+	case FormatJSON:
+		return extractJSON(content)
+	}
+	return nil, fmt.Errorf("unknown document format %q (expected text, markdown, html, or json)", format)
+}
+
+func extractText(content, path string) []paragraph {
+	builder := &paragraphBuilder{path: path}
+	builder.addText(content)
+	return builder.finish()
+}
+
+var (
+	markdownImageTagPattern = regexp.MustCompile(`!\[([^\]]*)\]\(\s*<?[^)\s>]*>?(?:\s+["']([^"']*)["'])?\s*\)`)
+	markdownCommentPattern  = regexp.MustCompile(`(?m)^\s*\[[^\]]*\]:\s*(?:#|<>)\s*\((.*)\)\s*$`)
+	markdownRawHTMLDetector = regexp.MustCompile(`<[a-zA-Z!/]`)
+)
+
+// extractMarkdown rewrites Markdown's own hiding places, image alt text and
+// link-reference comments, as HTML so that one pass over the HTML tree finds
+// them in place alongside any inline HTML
+func extractMarkdown(content, path string) []paragraph {
+	rewritten := markdownCommentPattern.ReplaceAllStringFunc(content, func(match string) string {
+		groups := markdownCommentPattern.FindStringSubmatch(match)
+		return "<!--" + strings.ReplaceAll(groups[1], "--", "- -") + "-->"
+	})
+	rewritten = markdownImageTagPattern.ReplaceAllStringFunc(rewritten, func(match string) string {
+		groups := markdownImageTagPattern.FindStringSubmatch(match)
+		return fmt.Sprintf(`<img alt="%s" title="%s">`, html.EscapeString(groups[1]), html.EscapeString(groups[2]))
+	})
+
+	if !markdownRawHTMLDetector.MatchString(rewritten) {
+		return extractText(rewritten, path)
+	}
+	return extractHTML(rewritten, path)
+}
+
+// skippedElements hold code or metadata rather than document text
+var skippedElements = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true, "head": true,
+}
+
+// blockElements start a new paragraph
+var blockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "br": true,
+	"dd": true, "div": true, "dl": true, "dt": true, "figcaption": true, "figure": true,
+	"footer": true, "form": true, "h1": true, "h2": true, "h3": true, "h4": true,
+	"h5": true, "h6": true, "header": true, "hr": true, "li": true, "main": true,
+	"nav": true, "ol": true, "p": true, "pre": true, "section": true, "table": true,
+	"td": true, "th": true, "tr": true, "ul": true,
+}
+
+func extractHTML(content, path string) []paragraph {
+	// The parser recovers from any input, so errors are not possible here.
+	root, _ := html.Parse(strings.NewReader(content))
+	classRules := cssClassRules(root)
+
+	builder := &paragraphBuilder{path: path}
+	var walk func(n *html.Node, hiddenKind string)
+	walk = func(n *html.Node, hiddenKind string) {
+		switch n.Type {
+		case html.TextNode:
+			if hiddenKind != "" {
+				builder.addHidden(hiddenKind, n.Data)
+			} else {
+				builder.addText(n.Data)
+			}
+			return
+		case html.CommentNode:
+			builder.addHidden(HiddenComment, n.Data)
+			return
+		case html.ElementNode:
+			if skippedElements[n.Data] {
+				return
+			}
+			if hiddenKind == "" {
+				hiddenKind = elementHiddenKind(n, classRules)
+			}
+			for _, attr := range n.Attr {
+				if attr.Key == "alt" || attr.Key == "title" {
+					builder.addHidden(HiddenAltText, attr.Val)
+				}
+			}
+		}
+
+		block := n.Type == html.ElementNode && blockElements[n.Data]
+		if block {
+			builder.breakParagraph()
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child, hiddenKind)
+		}
+		if block {
+			builder.breakParagraph()
+		}
+	}
+	walk(root, "")
+
+	return builder.finish()
+}
+
+// extractJSON scans every string value in a JSON document as Markdown, which
+// also covers plain text and inline HTML. Each value starts a new paragraph
+// tagged with its JSON pointer.
+func extractJSON(content string) ([]paragraph, error) {
+	decoder := json.NewDecoder(strings.NewReader(content))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("invalid JSON document: %w", err)
+	}
+
+	var paragraphs []paragraph
+	var walk func(value any, path string)
+	walk = func(value any, path string) {
+		switch v := value.(type) {
+		case string:
+			paragraphs = append(paragraphs, extractMarkdown(v, path)...)
+		case []any:
+			for i, item := range v {
+				walk(item, path+"/"+strconv.Itoa(i))
+			}
+		case map[string]any:
+			keys := make([]string, 0, len(v))
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				walk(v[key], path+"/"+escapePointerToken(key))
+			}
+		}
+	}
+	walk(value, "")
+
+	return paragraphs, nil
+}
+
+// elementHiddenKind reports whether an element's inline style, class rules,
+// or attributes hide it from view
+func elementHiddenKind(n *html.Node, classRules map[string]map[string]string) string {
+	decls := make(map[string]string)
+	for _, attr := range n.Attr {
+		switch attr.Key {
+		case "hidden":
+			return HiddenDisplayNone
+		case "class":
+			for _, class := range strings.Fields(attr.Val) {
+				for prop, value := range classRules[class] {
+					decls[prop] = value
+				}
+			}
+		}
+	}
+	for _, attr := range n.Attr {
+		if attr.Key == "style" {
+			for prop, value := range parseCSSDeclarations(attr.Val) {
+				decls[prop] = value
+			}
+		}
+	}
+
+	if decls["display"] == "none" || decls["visibility"] == "hidden" || decls["opacity"] == "0" ||
+		isZeroLength(decls["font-size"]) || isOffscreen(decls["left"]) || isOffscreen(decls["text-indent"]) {
+		return HiddenDisplayNone
+	}
+
+	color := decls["color"]
+	background := decls["background-color"]
+	if background == "" {
+		background = decls["background"]
+	}
+	if fg, ok := parseCSSColor(color); ok {
+		bg, hasBackground := parseCSSColor(background)
+		// Without a background the page is assumed to be white.
+		if !hasBackground {
+			bg = [3]int{255, 255, 255}
+		}
+		if colorsClose(fg, bg) {
+			return HiddenWhiteText
+		}
+	}
+	return ""
+}
+
+var cssRulePattern = regexp.MustCompile(`([^{}]+)\{([^{}]*)\}`)
+
+// cssClassRules collects the declarations of simple ".class" rules in the
+// document's style elements
+func cssClassRules(root *html.Node) map[string]map[string]string {
+	rules := make(map[string]map[string]string)
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "style" && n.FirstChild != nil {
+			for _, match := range cssRulePattern.FindAllStringSubmatch(n.FirstChild.Data, -1) {
+				decls := parseCSSDeclarations(match[2])
+				for _, selector := range strings.Split(match[1], ",") {
+					selector = strings.TrimSpace(selector)
+					if strings.HasPrefix(selector, ".") && !strings.ContainsAny(selector[1:], " .#:>[") {
+						class := selector[1:]
+						if rules[class] == nil {
+							rules[class] = make(map[string]string)
+						}
+						for prop, value := range decls {
+							rules[class][prop] = value
+						}
+					}
+				}
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(root)
+
+	return rules
+}
+
+func parseCSSDeclarations(style string) map[string]string {
+	decls := make(map[string]string)
+	for _, decl := range strings.Split(style, ";") {
+		prop, value, ok := strings.Cut(decl, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "!important"))
+		decls[strings.ToLower(strings.TrimSpace(prop))] = strings.ToLower(value)
+	}
+	return decls
+}
+
+func isZeroLength(value string) bool {
+	if value == "" {
+		return false
+	}
+	number := strings.TrimRight(value, "abcdefghijklmnopqrstuvwxyz%")
+	n, err := strconv.ParseFloat(number, 64)
+	return err == nil && n == 0
+}
+
+func isOffscreen(value string) bool {
+	number := strings.TrimRight(value, "abcdefghijklmnopqrstuvwxyz%")
+	n, err := strconv.ParseFloat(number, 64)
+	return err == nil && n <= -1000
+}
+
+var cssRGBPattern = regexp.MustCompile(`^rgba?\(\s*(\d+)\s*,\s*(\d+)\s*,\s*(\d+)`)
+
+// parseCSSColor understands the color forms used to hide text: a few named
+// colors, hex, and rgb()
+func parseCSSColor(value string) ([3]int, bool) {
+	value = strings.TrimSpace(value)
+	switch value {
+	case "white":
+		return [3]int{255, 255, 255}, true
+	case "black":
+		return [3]int{0, 0, 0}, true
+	case "transparent":
+		return [3]int{}, false
+	}
+
+	if strings.HasPrefix(value, "#") {
+		hex := value[1:]
+		if len(hex) == 3 || len(hex) == 4 {
+			hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+		}
+		if len(hex) >= 6 {
+			var rgb [3]int
+			for i := range rgb {
+				channel, err := strconv.ParseUint(hex[i*2:i*2+2], 16, 8)
+				if err != nil {
+					return [3]int{}, false
+				}
+				rgb[i] = int(channel)
+			}
+			return rgb, true
+		}
+	}
+
+	if match := cssRGBPattern.FindStringSubmatch(value); match != nil {
+		var rgb [3]int
+		for i := range rgb {
+			rgb[i], _ = strconv.Atoi(match[i+1])
+		}
+		return rgb, true
+	}
+	return [3]int{}, false
+}
+
+func colorsClose(a, b [3]int) bool {
+	for i := range a {
+		diff := a[i] - b[i]
+		if diff < -16 || diff > 16 {
+			return false
+		}
+	}
+	return true
+}
+
+// paragraphBuilder accumulates visible text into paragraphs, splitting on
+// blank lines and block elements, and records hidden content in the
+// paragraph where it appears
+type paragraphBuilder struct {
+	path       string
+	paragraphs []paragraph
+	text       strings.Builder
+	hidden     []HiddenContent
+}
+
+var blankLinePattern = regexp.MustCompile(`\n[ \t\r]*\n`)
+
+func (b *paragraphBuilder) addText(text string) {
+	visible, smuggled := stripInvisibleUnicode(text)
+	if smuggled != "" {
+		b.addHidden(HiddenInvisibleUnicode, smuggled)
+	}
+
+	parts := blankLinePattern.Split(visible, -1)
+	for i, part := range parts {
+		if i > 0 {
+			b.breakParagraph()
+		}
+		b.text.WriteString(part)
+	}
+}
+
+func (b *paragraphBuilder) addHidden(kind, text string) {
+	visible, smuggled := stripInvisibleUnicode(text)
+	if smuggled != "" {
+		b.hidden = append(b.hidden, HiddenContent{Kind: HiddenInvisibleUnicode, Text: smuggled})
+	}
+	if text := strings.Join(strings.Fields(visible), " "); text != "" {
+		b.hidden = append(b.hidden, HiddenContent{Kind: kind, Text: text})
+	}
+}
+
+func (b *paragraphBuilder) breakParagraph() {
+	text := strings.Join(strings.Fields(b.text.String()), " ")
+	if text != "" || len(b.hidden) > 0 {
+		b.paragraphs = append(b.paragraphs, paragraph{text: text, hidden: b.hidden, path: b.path})
+	}
+	b.text.Reset()
+	b.hidden = nil
+}
+
+func (b *paragraphBuilder) finish() []paragraph {
+	b.breakParagraph()
+	return b.paragraphs
+}
+
+// stripInvisibleUnicode removes zero-width characters and Unicode tag
+// characters from text. Tag characters mirror ASCII and can smuggle a
+// whole instruction past a reader, so they are decoded and returned.
+func stripInvisibleUnicode(text string) (string, string) {
+	if isASCII(text) {
+		return text, ""
+	}
+
+	var visible strings.Builder
+	var smuggled bytes.Buffer
+	for _, r := range text {
+		switch {
+		case r >= 0xE0020 && r <= 0xE007E:
+			smuggled.WriteByte(byte(r - 0xE0000))
+		case r >= 0xE0000 && r <= 0xE007F,
+			r == 0x200B, r == 0x200C, r == 0x200D, r == 0x2060, r == 0xFEFF:
+		default:
+			visible.WriteRune(r)
+		}
+	}
+	return visible.String(), smuggled.String()
+}
+
+func isASCII(text string) bool {
+	for i := 0; i < len(text); i++ {
+		if text[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
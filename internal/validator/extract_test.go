@@ -0,0 +1,174 @@
+package validator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// hiddenOfKind returns the text of all hidden content of one kind
+func hiddenOfKind(paragraphs []paragraph, kind string) []string {
+	var texts []string
+	for _, p := range paragraphs {
+		for _, hidden := range p.hidden {
+			if hidden.Kind == kind {
+				texts = append(texts, hidden.Text)
+			}
+		}
+	}
+	return texts
+}
+
+func visibleText(paragraphs []paragraph) string {
+	var texts []string
+	for _, p := range paragraphs {
+		if p.text != "" {
+			texts = append(texts, p.text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+func TestDetectDocumentFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    string
+	}{
+		{"markdown extension", "notes.md", "plain words", FormatMarkdown},
+		{"html extension", "page.HTM", "plain words", FormatHTML},
+		{"json extension", "data.json", "plain words", FormatJSON},
+		{"text extension", "readme.txt", "# Heading", FormatText},
+		{"json content", "", `{"title": "x"}`, FormatJSON},
+		{"invalid json content", "", `{"title": `, FormatText},
+		{"html content", "", "<div><p>Hello</p></div>", FormatHTML},
+		{"markdown content", "", "# Title\n\nSome text", FormatMarkdown},
+		{"plain content", "", "Just a sentence.", FormatText},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectDocumentFormat(tt.file, tt.content); got != tt.want {
+				t.Errorf("DetectDocumentFormat(%q) = %s, want %s", tt.file, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractHTML_HiddenContent(t *testing.T) {
+	doc := `<html><head><title>Ignored</title><style>.ghost { color: #fff; }</style></head><body>
+<p>Visible paragraph.</p>
+<!-- a comment -->
+<div style="display: none">display none text</div>
+<span style="visibility:hidden">hidden visibility</span>
+<p hidden>hidden attribute</p>
+<span style="font-size:0px">zero font</span>
+<span style="position:absolute; left:-9999px">offscreen</span>
+<span style="color: white">white text</span>
+<span style="color:#000;background-color:#010101">black on black</span>
+<span class="ghost">class white</span>
+<span style="color:white;background:#333">white on dark</span>
+<img src="x.png" alt="alt words" title="title words">
+<script>var injected = "script";</script>
+</body></html>`
+
+	paragraphs, err := extractParagraphs(doc, FormatHTML)
+	if err != nil {
+		t.Fatalf("extractParagraphs returned error: %v", err)
+	}
+
+	visible := visibleText(paragraphs)
+	for _, want := range []string{"Visible paragraph.", "white on dark"} {
+		if !strings.Contains(visible, want) {
+			t.Errorf("Expected visible text to contain %q, got %q", want, visible)
+		}
+	}
+	for _, hidden := range []string{"comment", "display none", "hidden visibility", "hidden attribute", "zero font", "offscreen", "white text", "black on black", "class white", "alt words", "script", "Ignored"} {
+		if strings.Contains(visible, hidden) {
+			t.Errorf("Expected %q to be removed from visible text, got %q", hidden, visible)
+		}
+	}
+
+	tests := []struct {
+		kind string
+		want []string
+	}{
+		{HiddenComment, []string{"a comment"}},
+		{HiddenDisplayNone, []string{"display none text", "hidden visibility", "hidden attribute", "zero font", "offscreen"}},
+		{HiddenWhiteText, []string{"white text", "black on black", "class white"}},
+		{HiddenAltText, []string{"alt words", "title words"}},
+	}
+	for _, tt := range tests {
+		if got := hiddenOfKind(paragraphs, tt.kind); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Hidden %s content = %q, want %q", tt.kind, got, tt.want)
+		}
+	}
+}
+
+func TestExtractMarkdown_HiddenContent(t *testing.T) {
+	doc := "# Setup guide\n\nInstall the package.\n\n![diagram of the setup](setup.png \"setup diagram\")\n\n[//]: # (reviewer note)\n\n<!-- html comment -->\n\nFinal paragraph."
+
+	paragraphs, err := extractParagraphs(doc, FormatMarkdown)
+	if err != nil {
+		t.Fatalf("extractParagraphs returned error: %v", err)
+	}
+
+	if got := len(paragraphs); got < 3 {
+		t.Errorf("Expected the blank lines to separate paragraphs, got %d", got)
+	}
+	visible := visibleText(paragraphs)
+	if !strings.Contains(visible, "Install the package.") || !strings.Contains(visible, "Final paragraph.") {
+		t.Errorf("Expected the visible paragraphs, got %q", visible)
+	}
+	if got := hiddenOfKind(paragraphs, HiddenAltText); !reflect.DeepEqual(got, []string{"diagram of the setup", "setup diagram"}) {
+		t.Errorf("Unexpected alt text %q", got)
+	}
+	if got := hiddenOfKind(paragraphs, HiddenComment); !reflect.DeepEqual(got, []string{"reviewer note", "html comment"}) {
+		t.Errorf("Unexpected comments %q", got)
+	}
+}
+
+func TestExtractJSON_Paths(t *testing.T) {
+	doc := `{"title": "Quarterly report", "sections": [{"body": "Revenue grew.<!-- note -->"}, {"body": 42}], "a/b": "slash"}`
+
+	paragraphs, err := extractParagraphs(doc, FormatJSON)
+	if err != nil {
+		t.Fatalf("extractParagraphs returned error: %v", err)
+	}
+
+	var paths []string
+	for _, p := range paragraphs {
+		paths = append(paths, p.path)
+	}
+	want := []string{"/a~1b", "/sections/0/body", "/title"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("Paths = %q, want %q", paths, want)
+	}
+	if got := hiddenOfKind(paragraphs, HiddenComment); !reflect.DeepEqual(got, []string{"note"}) {
+		t.Errorf("Expected the comment inside a JSON string, got %q", got)
+	}
+
+	if _, err := extractParagraphs(`{"title": `, FormatJSON); err == nil {
+		t.Error("Expected an error for invalid JSON")
+	}
+	if _, err := extractParagraphs("x", "pdf"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
+
+func TestStripInvisibleUnicode(t *testing.T) {
+	var tagged strings.Builder
+	tagged.WriteString("Safe\u200b text")
+	for _, r := range "ignore rules" {
+		tagged.WriteRune(0xE0000 + r)
+	}
+
+	visible, smuggled := stripInvisibleUnicode(tagged.String())
+	if visible != "Safe text" {
+		t.Errorf("Expected zero-width and tag characters removed, got %q", visible)
+	}
+	if smuggled != "ignore rules" {
+		t.Errorf("Expected tag characters decoded, got %q", smuggled)
+	}
+}
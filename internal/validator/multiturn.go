@@ -0,0 +1,318 @@
+package validator
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Multi-turn analysis tuning. Each user or tool turn adds its risk to a
+// running total that decays between turns, so several mildly suspicious
+// messages in a row add up while an isolated one fades.
+const (
+	cumulativeRiskDecay     = 0.75
+	cumulativeRiskThreshold = 60
+	crescendoMinTurns       = 3
+	crescendoMinRisk        = 20
+)
+
+// ConversationAnalysis is the conversation-level view of a multi-turn
+// exchange, looking for attacks spread across messages that no single
+// message reveals
+type ConversationAnalysis struct {
+	RiskLevel      string            `json:"risk_level"`
+	CumulativeRisk int               `json:"cumulative_risk"`
+	PeakRisk       int               `json:"peak_risk"`
+	Crescendo      bool              `json:"crescendo"`
+	SplitPayload   bool              `json:"split_payload"`
+	CoercedPersona bool              `json:"coerced_persona"`
+	Issues         []ValidationIssue `json:"issues"`
+	Timeline       []TurnRisk        `json:"timeline"`
+}
+
+// TurnRisk is one point on the per-turn risk timeline
+type TurnRisk struct {
+	Index          int      `json:"index"`
+	Role           string   `json:"role"`
+	Risk           int      `json:"risk"`
+	CumulativeRisk int      `json:"cumulative_risk"`
+	Signals        []string `json:"signals"`
+}
+
+// turnSignals are phrases that raise a turn's risk without being harmful on
+// their own
+var turnSignals = []struct {
+	name    string
+	risk    int
+	pattern *regexp.Regexp
+}{
+	{"escalation", 15, regexp.MustCompile(`(?i)\b(more (detail|specific)|be more specific|go (further|deeper)|now (tell|show|give|explain)|step[- ]by[- ]step|exact (steps|instructions|amounts))\b`)},
+	{"hypothetical framing", 10, regexp.MustCompile(`(?i)\b(hypothetically|in a fictional|for a (story|novel|movie)|pretend|role-?play|imagine you)\b`)},
+	{"reassembly request", 20, regexp.MustCompile(`(?i)\b(combine|concatenate|join|put together|merge|reassemble)\b.{0,40}\b(previous|above|earlier|parts?|pieces|strings|messages|fragments)\b`)},
+}
+
+// personaMarkers detect an assistant reply that has taken on a persona the
+// user forced on it
+var personaMarkers = regexp.MustCompile(`(?i)(\bas DAN\b|\bI am DAN\b|^\s*\[?DAN\]?\s*:|\bjailbroken\b|developer mode (is )?(enabled|activated|on)|\bI (have|am under) no (restrictions|limits|filters|rules)\b|\bfree from (all )?(restrictions|rules|guidelines)\b)`)
+
+// analyzeConversation runs the conversation-level detectors. results holds
+// the per-message validation results from ValidateConversation, and r the
+// run they belong to.
+func analyzeConversation(messages []Message, results []MessageResult, config *Config, r *run) *ConversationAnalysis {
+	analysis := &ConversationAnalysis{
+		RiskLevel: "low",
+		Issues:    []ValidationIssue{},
+		Timeline:  []TurnRisk{},
+	}
+
+	cumulative := 0.0
+	var userTurns []TurnRisk
+	for _, result := range results {
+		content := messages[result.Index].Content
+		turn := TurnRisk{
+			Index:   result.Index,
+			Role:    result.Role,
+			Risk:    100 - result.Score,
+			Signals: issueSignals(result.Issues),
+		}
+
+		switch result.Role {
+		case RoleUser, RoleTool:
+			for _, signal := range turnSignals {
+				if signal.pattern.MatchString(content) {
+					turn.Risk += signal.risk
+					turn.Signals = append(turn.Signals, signal.name)
+				}
+			}
+		case RoleAssistant:
+			if personaMarkers.MatchString(content) {
+				turn.Risk += 50
+				turn.Signals = append(turn.Signals, "coerced persona")
+				analysis.CoercedPersona = true
+				analysis.Issues = append(analysis.Issues, turnIssue(turn, "error",
+					"Assistant reply adopted a persona forced on it by earlier turns",
+					"Reset the conversation and do not forward this reply"))
+			}
+		}
+		turn.Risk = clampRisk(turn.Risk)
+
+		// Only untrusted input builds up risk; system and assistant turns
+		// still appear on the timeline.
+		if result.Role == RoleUser || result.Role == RoleTool {
+			cumulative = cumulative*cumulativeRiskDecay + float64(turn.Risk)
+		} else {
+			cumulative *= cumulativeRiskDecay
+		}
+		turn.CumulativeRisk = clampRisk(int(cumulative + 0.5))
+
+		if turn.Risk > analysis.PeakRisk {
+			analysis.PeakRisk = turn.Risk
+		}
+		if turn.CumulativeRisk >= cumulativeRiskThreshold && analysis.CumulativeRisk < cumulativeRiskThreshold {
+			analysis.Issues = append(analysis.Issues, turnIssue(turn, "warning",
+				fmt.Sprintf("Cumulative risk across turns reached %d", turn.CumulativeRisk),
+				"Review the conversation as a whole, not just the latest message"))
+		}
+		if turn.CumulativeRisk > analysis.CumulativeRisk {
+			analysis.CumulativeRisk = turn.CumulativeRisk
+		}
+
+		analysis.Timeline = append(analysis.Timeline, turn)
+		if result.Role == RoleUser {
+			userTurns = append(userTurns, turn)
+		}
+	}
+
+	if end, ok := detectCrescendo(userTurns); ok {
+		analysis.Crescendo = true
+		markSignal(analysis.Timeline, end.Index, "crescendo")
+		analysis.Issues = append(analysis.Issues, turnIssue(end, "warning",
+			"Requests escalate steadily across turns (crescendo pattern)",
+			"Evaluate the latest request in light of where the conversation is heading"))
+	}
+
+	if index, name, ok := detectSplitPayload(messages, results, config, r); ok {
+		analysis.SplitPayload = true
+		markSignal(analysis.Timeline, index, "split payload")
+		turn := TurnRisk{Index: index, Role: messages[index].Role}
+		analysis.Issues = append(analysis.Issues, turnIssue(turn, "error",
+			fmt.Sprintf("Content split across messages combines into %s", name),
+			"Treat the combined messages as a single prompt"))
+	}
+
+	switch {
+	case analysis.CoercedPersona || analysis.SplitPayload || analysis.CumulativeRisk >= cumulativeRiskThreshold:
+		analysis.RiskLevel = "high"
+	case analysis.Crescendo || analysis.CumulativeRisk >= cumulativeRiskThreshold/2:
+		analysis.RiskLevel = "medium"
+	}
+
+	return analysis
+}
+
+// detectCrescendo looks for a run of user turns whose risk never drops,
+// rises at least twice, and ends at a meaningful level. It returns the last
+// turn of the first such run.
+func detectCrescendo(turns []TurnRisk) (TurnRisk, bool) {
+	start := 0
+	for i := 1; i <= len(turns); i++ {
+		if i < len(turns) && turns[i].Risk >= turns[i-1].Risk {
+			continue
+		}
+
+		run := turns[start:i]
+		increases := 0
+		for j := 1; j < len(run); j++ {
+			if run[j].Risk > run[j-1].Risk {
+				increases++
+			}
+		}
+		if len(run) >= crescendoMinTurns && increases >= 2 && run[len(run)-1].Risk >= crescendoMinRisk {
+			return run[len(run)-1], true
+		}
+		start = i
+	}
+	return TurnRisk{}, false
+}
+
+// detectSplitPayload joins consecutive user and tool turns, one per line,
+// and reports the first injection marker or blocked pattern that no single
+// turn matches but that matches across a turn boundary once they are
+// combined, along with the turn that completed it. Turns are joined with a
+// newline rather than run together, so the end of one word and the start of
+// the next never form a match such as "kill" by accident; across the join,
+// "." also matches the newline. Each detector scans the joined text once,
+// and the scan stops early when r's context is done.
+func detectSplitPayload(messages []Message, results []MessageResult, config *Config, r *run) (int, string, bool) {
+	var untrusted []MessageResult
+	for _, result := range results {
+		if result.Role == RoleUser || result.Role == RoleTool {
+			untrusted = append(untrusted, result)
+		}
+	}
+	if len(untrusted) < 2 {
+		return 0, "", false
+	}
+
+	// boundaries[i] is the offset of the newline before untrusted[i+1].
+	var text strings.Builder
+	boundaries := make([]int, 0, len(untrusted)-1)
+	for i, result := range untrusted {
+		if i > 0 {
+			boundaries = append(boundaries, text.Len())
+			text.WriteByte('\n')
+		}
+		text.WriteString(strings.TrimSpace(messages[result.Index].Content))
+	}
+	joinedText := text.String()
+
+	type detector struct {
+		name            string
+		pattern, joined *regexp.Regexp
+	}
+	detectors := make([]detector, 0, len(injectionMarkers)+len(config.BlockedPatterns))
+	for _, marker := range injectionMarkers {
+		detectors = append(detectors, detector{"a prompt injection (" + marker.name + ")", marker.pattern, marker.joined})
+	}
+	for _, pattern := range config.BlockedPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			continue // Skip invalid patterns
+		}
+		joined, err := regexp.Compile("(?s)" + pattern)
+		if err != nil {
+			joined = re
+		}
+		detectors = append(detectors, detector{"blocked pattern " + pattern, re, joined})
+	}
+
+	for _, d := range detectors {
+		if r.stopped() {
+			r.skip(StageConversationAnalysis)
+			return 0, "", false
+		}
+
+		matchedAlone := false
+		for _, result := range untrusted {
+			if d.pattern.MatchString(messages[result.Index].Content) {
+				matchedAlone = true
+				break
+			}
+		}
+		if matchedAlone {
+			continue
+		}
+
+		if turn, ok := completingTurn(d.joined.FindAllStringIndex(joinedText, -1), boundaries); ok {
+			return untrusted[turn].Index, d.name, true
+		}
+	}
+	return 0, "", false
+}
+
+// completingTurn returns the position, among the joined turns, of the
+// earliest turn that completes a match with text on both sides of one of
+// the separators at boundaries: the turn holding the match's last byte.
+func completingTurn(matches [][]int, boundaries []int) (int, bool) {
+	best, found := 0, false
+	for _, match := range matches {
+		// The first separator after the match starts must end before the
+		// match does, with text after it.
+		first := sort.SearchInts(boundaries, match[0]+1)
+		if first == len(boundaries) || boundaries[first]+1 >= match[1] {
+			continue
+		}
+		// The match ends in the turn after the last separator before its
+		// last byte.
+		turn := sort.SearchInts(boundaries, match[1]-1)
+		if !found || turn < best {
+			best, found = turn, true
+		}
+	}
+	return best, found
+}
+
+// issueSignals lists the distinct issue types of a message as timeline
+// signals
+func issueSignals(issues []ValidationIssue) []string {
+	signals := []string{}
+	seen := make(map[string]bool)
+	for _, issue := range issues {
+		if !seen[issue.Type] {
+			seen[issue.Type] = true
+			signals = append(signals, issue.Type)
+		}
+	}
+	return signals
+}
+
+func markSignal(timeline []TurnRisk, index int, signal string) {
+	for i := range timeline {
+		if timeline[i].Index == index {
+			timeline[i].Signals = append(timeline[i].Signals, signal)
+		}
+	}
+}
+
+func turnIssue(turn TurnRisk, severity, message, suggestion string) ValidationIssue {
+	index := turn.Index
+	return ValidationIssue{
+		Type:         "multi_turn",
+		Severity:     severity,
+		Message:      message,
+		Suggestion:   suggestion,
+		MessageIndex: &index,
+		Role:         turn.Role,
+	}
+}
+
+func clampRisk(risk int) int {
+	if risk < 0 {
+		return 0
+	}
+	if risk > 100 {
+		return 100
+	}
+	return risk
+}
@@ -0,0 +1,366 @@
+package validator
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// System prompt leakage thresholds. Overlap is the share of the system
+// prompt's word n-grams found in the output; runs are counted in words.
+const (
+	leakageNGramSize       = 5
+	leakagePartialOverlap  = 0.2
+	leakageVerbatimOverlap = 0.5
+	leakagePartialRun      = 8
+	leakageVerbatimRun     = 20
+)
+
+// OutputOptions supplies context for ValidateOutput
+type OutputOptions struct {
+	// SystemPrompt, when set, is checked for leakage into the output.
+	SystemPrompt string
+	// Canaries are the registered canary tokens to look for.
+	Canaries []CanaryToken
+}
+
+// OutputValidationResult is the result of validating a model response
+type OutputValidationResult struct {
+	ValidationResult
+	SystemPromptLeakage *LeakageAnalysis `json:"system_prompt_leakage,omitempty"`
+	SecurityAnalysis    SecurityAnalysis `json:"security_analysis"`
+	ComplianceCheck     ComplianceCheck  `json:"compliance_check"`
+	CanaryLeaks         []CanaryToken    `json:"canary_leaks,omitempty"`
+}
+
+// LeakageAnalysis measures how much of the system prompt appears in the
+// output
+type LeakageAnalysis struct {
+	Overlap       float64 `json:"overlap"`
+	MatchedNGrams int     `json:"matched_ngrams"`
+	TotalNGrams   int     `json:"total_ngrams"`
+	LongestRun    int     `json:"longest_run_words"`
+	Leaked        bool    `json:"leaked"`
+	Verbatim      bool    `json:"verbatim"`
+}
+
+// outputSecretPatterns match credentials that should never appear in model
+// output
+var outputSecretPatterns = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"model provider API key", regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{20,}`)},
+	{"AWS access key", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"GitHub token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`)},
+	{"Slack token", regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`)},
+	{"PromptSentinel API key", regexp.MustCompile(`\bps_[A-Za-z0-9_-]{40,}`)},
+	{"private key", regexp.MustCompile(`-----BEGIN ([A-Z]+ )?PRIVATE KEY-----`)},
+	{"JSON web token", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+	{"credential assignment", regexp.MustCompile(`(?i)\b(password|passwd|secret|api[_-]?key|access[_-]?token)\s*[:=]\s*["']?[^\s"']{6,}`)},
+}
+
+// outputPIIPatterns match personal data. Card numbers are additionally
+// checked with the Luhn algorithm.
+var outputPIIPatterns = []struct {
+	name     string
+	severity string
+	pattern  *regexp.Regexp
+}{
+	{"email address", "warning", regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`)},
+	{"phone number", "warning", regexp.MustCompile(`(?:\+?1[ .-]?)?\(?\b\d{3}\)?[ .-]\d{3}[ .-]\d{4}\b`)},
+	{"social security number", "error", regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)},
+	{"payment card number", "error", regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)},
+}
+
+var (
+	markdownImagePattern = regexp.MustCompile(`!\[[^\]]*\]\(\s*<?([^)\s>]+)>?[^)]*\)`)
+	markdownLinkPattern  = regexp.MustCompile(`(?:^|[^!])\[[^\]]*\]\(\s*<?([^)\s>]+)>?[^)]*\)`)
+	htmlImagePattern     = regexp.MustCompile(`(?i)<img[^>]+src\s*=\s*["']?([^"'\s>]+)`)
+)
+
+// ValidateOutput checks a model response before it reaches the user. It
+// looks for system prompt leakage, registered canary tokens, secrets,
+// personal data, and markdown images or links that could carry data to a
+// third party, and applies the blocked patterns, custom rules, use case
+// checks, and security and compliance analysis used for prompts.
+func ValidateOutput(output string, opts OutputOptions, config *Config) (*OutputValidationResult, error) {
+	return ValidateOutputContext(context.Background(), output, opts, config)
+}
+
+// ValidateOutputContext is ValidateOutput with a context. When the context
+// is done, the remaining checks are skipped and the result is marked
+// incomplete.
+func ValidateOutputContext(ctx context.Context, output string, opts OutputOptions, config *Config) (*OutputValidationResult, error) {
+	startTime := time.Now()
+	r := newRun(ctx, nil)
+
+	// Length limits and injection markers describe what may be sent to a
+	// model, not what it may say, so they do not apply here.
+	base, err := validateText(output, config, func(issueType string) bool {
+		return issueType != "length" && issueType != "injection"
+	}, r)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &OutputValidationResult{ValidationResult: *base}
+	r.detect("security_analysis", "", func() {
+		result.SecurityAnalysis = performSecurityAnalysis(output)
+	})
+	r.detect("compliance_check", "", func() {
+		result.ComplianceCheck = performComplianceCheck(output, config)
+	})
+
+	if strings.TrimSpace(opts.SystemPrompt) != "" {
+		r.detect("leakage", "", func() {
+			leakage := analyzeLeakage(output, opts.SystemPrompt)
+			result.SystemPromptLeakage = &leakage
+			switch {
+			case leakage.Verbatim:
+				addOutputIssue(&result.ValidationResult, "leakage", "error",
+					fmt.Sprintf("Output reveals the system prompt verbatim (%.0f%% overlap, %d-word run)", leakage.Overlap*100, leakage.LongestRun),
+					"Do not return this response; the model disclosed its instructions")
+			case leakage.Leaked:
+				addOutputIssue(&result.ValidationResult, "leakage", "warning",
+					fmt.Sprintf("Output repeats part of the system prompt (%.0f%% overlap, %d-word run)", leakage.Overlap*100, leakage.LongestRun),
+					"Check whether the response paraphrases confidential instructions")
+			}
+		})
+	}
+
+	r.detect("canary", "", func() {
+		for _, canary := range FindCanaries(output, opts.Canaries) {
+			result.CanaryLeaks = append(result.CanaryLeaks, canary)
+			issue := CanaryIssue(canary, "Output")
+			addOutputIssue(&result.ValidationResult, issue.Type, issue.Severity, issue.Message, issue.Suggestion)
+		}
+	})
+
+	r.detect("secret", "", func() {
+		validateOutputSecrets(output, &result.ValidationResult)
+	})
+	r.detect("pii", "", func() {
+		validateOutputPII(output, &result.ValidationResult)
+	})
+	r.detect("exfiltration", "", func() {
+		validateOutputExfiltration(output, config, &result.ValidationResult)
+	})
+
+	r.finish(&result.ValidationResult, config)
+
+	result.Metadata["processing_time_ms"] = elapsedMillis(startTime)
+	result.Metadata["output_length"] = len(output)
+	result.Metadata["use_case"] = config.UseCase
+
+	return result, nil
+}
+
+// analyzeLeakage compares word n-grams of the system prompt with those of
+// the output. The longest run is the longest stretch of consecutive system
+// prompt n-grams that all occur in the output.
+func analyzeLeakage(output, systemPrompt string) LeakageAnalysis {
+	promptWords := normalizedWords(systemPrompt)
+	outputWords := normalizedWords(output)
+
+	n := leakageNGramSize
+	if len(promptWords) < n {
+		n = len(promptWords)
+	}
+	if n == 0 || len(outputWords) < n {
+		return LeakageAnalysis{}
+	}
+
+	outputGrams := make(map[string]bool)
+	for i := 0; i+n <= len(outputWords); i++ {
+		outputGrams[strings.Join(outputWords[i:i+n], " ")] = true
+	}
+
+	analysis := LeakageAnalysis{}
+	seen := make(map[string]bool)
+	run, longest := 0, 0
+	for i := 0; i+n <= len(promptWords); i++ {
+		gram := strings.Join(promptWords[i:i+n], " ")
+		matched := outputGrams[gram]
+
+		if !seen[gram] {
+			seen[gram] = true
+			analysis.TotalNGrams++
+			if matched {
+				analysis.MatchedNGrams++
+			}
+		}
+
+		if matched {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+
+	if longest > 0 {
+		analysis.LongestRun = longest + n - 1
+	}
+	analysis.Overlap = float64(analysis.MatchedNGrams) / float64(analysis.TotalNGrams)
+
+	// Short system prompts cannot reach the run thresholds, so leaking all
+	// of one counts as verbatim.
+	verbatimRun, partialRun := leakageVerbatimRun, leakagePartialRun
+	if len(promptWords) < verbatimRun {
+		verbatimRun = len(promptWords)
+	}
+	if len(promptWords) < partialRun {
+		partialRun = len(promptWords)
+	}
+
+	analysis.Verbatim = analysis.Overlap >= leakageVerbatimOverlap || analysis.LongestRun >= verbatimRun
+	analysis.Leaked = analysis.Verbatim || analysis.Overlap >= leakagePartialOverlap || analysis.LongestRun >= partialRun
+	return analysis
+}
+
+// normalizedWords lower-cases text and splits it into words, ignoring
+// punctuation and formatting so that light rewording still matches
+func normalizedWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// validateOutputSecrets flags credentials in the output
+func validateOutputSecrets(output string, result *ValidationResult) {
+	for _, secret := range outputSecretPatterns {
+		if secret.pattern.MatchString(output) {
+			addOutputIssue(result, "secret", "error",
+				fmt.Sprintf("Output contains a credential (%s)", secret.name),
+				"Redact the credential and rotate it if it is real")
+		}
+	}
+}
+
+// validateOutputPII flags personal data in the output
+func validateOutputPII(output string, result *ValidationResult) {
+	for _, pii := range outputPIIPatterns {
+		matches := pii.pattern.FindAllString(output, -1)
+		if pii.name == "payment card number" {
+			matches = filterLuhn(matches)
+		}
+		if len(matches) == 0 {
+			continue
+		}
+
+		addOutputIssue(result, "pii", pii.severity,
+			fmt.Sprintf("Output contains personal data (%s)", pii.name),
+			"Remove personal data unless the user is entitled to it")
+	}
+}
+
+// validateOutputExfiltration flags markdown and HTML images and links that
+// would send data to a host outside config.AllowedDomains when rendered.
+// Images load without a click, so any image with a query string is treated
+// as an exfiltration attempt.
+func validateOutputExfiltration(output string, config *Config, result *ValidationResult) {
+	check := func(rawURL string, image bool) {
+		parsed, err := url.Parse(rawURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			return
+		}
+		if domainAllowed(parsed.Hostname(), config.AllowedDomains) {
+			return
+		}
+
+		carriesData := parsed.RawQuery != "" || len(parsed.Path) > 100
+		switch {
+		case image && carriesData:
+			addOutputIssue(result, "exfiltration", "error",
+				fmt.Sprintf("Output embeds an image that sends data to %s", parsed.Hostname()),
+				"Strip external images from responses or add the host to allowed_domains")
+		case image:
+			addOutputIssue(result, "exfiltration", "warning",
+				fmt.Sprintf("Output embeds an image from untrusted host %s", parsed.Hostname()),
+				"Strip external images from responses or add the host to allowed_domains")
+		case carriesData:
+			addOutputIssue(result, "exfiltration", "warning",
+				fmt.Sprintf("Output links to %s with data in the URL", parsed.Hostname()),
+				"Review links in responses before rendering them")
+		}
+	}
+
+	for _, match := range markdownImagePattern.FindAllStringSubmatch(output, -1) {
+		check(match[1], true)
+	}
+	for _, match := range htmlImagePattern.FindAllStringSubmatch(output, -1) {
+		check(match[1], true)
+	}
+	for _, match := range markdownLinkPattern.FindAllStringSubmatch(output, -1) {
+		check(match[1], false)
+	}
+}
+
+// domainAllowed reports whether host is one of domains or a subdomain of one
+func domainAllowed(host string, domains []string) bool {
+	host = strings.ToLower(host)
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterLuhn keeps the candidate card numbers that pass the Luhn checksum
+func filterLuhn(candidates []string) []string {
+	var valid []string
+	for _, candidate := range candidates {
+		var digits []int
+		for _, r := range candidate {
+			if r >= '0' && r <= '9' {
+				digits = append(digits, int(r-'0'))
+			}
+		}
+		if len(digits) < 13 || len(digits) > 19 {
+			continue
+		}
+
+		sum := 0
+		for i := len(digits) - 1; i >= 0; i-- {
+			digit := digits[i]
+			if (len(digits)-1-i)%2 == 1 {
+				digit *= 2
+				if digit > 9 {
+					digit -= 9
+				}
+			}
+			sum += digit
+		}
+		if sum%10 == 0 {
+			valid = append(valid, candidate)
+		}
+	}
+	return valid
+}
+
+func addOutputIssue(result *ValidationResult, issueType, severity, message, suggestion string) {
+	result.Issues = append(result.Issues, ValidationIssue{
+		Type:       issueType,
+		Severity:   severity,
+		Message:    message,
+		Suggestion: suggestion,
+	})
+	if severity == "error" {
+		result.IsValid = false
+		result.Score -= 25
+	} else {
+		result.Score -= 10
+	}
+	if result.Score < 0 {
+		result.Score = 0
+	}
+}
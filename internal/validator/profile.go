@@ -0,0 +1,100 @@
+package validator
+
+import (
+	"time"
+)
+
+// Timing is how long a stage of validation, or a detector within it, took.
+// Durations are in microseconds.
+type Timing struct {
+	Name string `json:"name"`
+	// Detail identifies the rule a detector ran, such as a blocked pattern
+	// or custom rule name.
+	Detail string `json:"detail,omitempty"`
+	// Stage is the stage a detector ran in.
+	Stage string `json:"stage,omitempty"`
+	// Calls counts how often a detector ran, once per message for a
+	// conversation.
+	Calls          int     `json:"calls"`
+	DurationMicros float64 `json:"duration_us"`
+}
+
+// Stages of comprehensive validation, in the order they run
+const (
+	StageValidation           = "validation"
+	StageConversationAnalysis = "conversation_analysis"
+	StageSecurityAnalysis     = "security_analysis"
+	StageComplianceCheck      = "compliance_check"
+	StagePerformanceMetrics   = "performance_metrics"
+)
+
+// profiler collects stage and detector timings. A nil profiler runs
+// everything untimed, so the checks can be shared by callers that do not
+// report timings.
+type profiler struct {
+	start     time.Time
+	stage     string
+	stages    []Timing
+	detectors []Timing
+	index     map[[3]string]int
+}
+
+func newProfiler() *profiler {
+	return &profiler{start: time.Now(), index: make(map[[3]string]int)}
+}
+
+// runStage times fn as a stage; detectors run within it are attributed to
+// the stage
+func (p *profiler) runStage(name string, fn func() error) error {
+	if p == nil {
+		return fn()
+	}
+
+	outer := p.stage
+	p.stage = name
+	start := time.Now()
+	err := fn()
+	p.stages = append(p.stages, Timing{Name: name, Calls: 1, DurationMicros: micros(time.Since(start))})
+	p.stage = outer
+	return err
+}
+
+// detect times fn as a detector, adding to earlier runs of the same one
+func (p *profiler) detect(name, detail string, fn func()) {
+	if p == nil {
+		fn()
+		return
+	}
+
+	start := time.Now()
+	fn()
+	elapsed := micros(time.Since(start))
+
+	key := [3]string{p.stage, name, detail}
+	if i, ok := p.index[key]; ok {
+		p.detectors[i].Calls++
+		p.detectors[i].DurationMicros += elapsed
+		return
+	}
+	p.index[key] = len(p.detectors)
+	p.detectors = append(p.detectors, Timing{Name: name, Detail: detail, Stage: p.stage, Calls: 1, DurationMicros: elapsed})
+}
+
+// finish records the total time and the collected timings in metrics
+func (p *profiler) finish(metrics *PerformanceMetrics) {
+	total := micros(time.Since(p.start))
+	metrics.ProcessingTimeMicros = total
+	metrics.ProcessingTime = total / 1000
+	metrics.Stages = p.stages
+	metrics.Detectors = p.detectors
+}
+
+func micros(d time.Duration) float64 {
+	return float64(d.Nanoseconds()) / 1000
+}
+
+// elapsedMillis returns the time since start in milliseconds, keeping
+// microsecond precision so that fast validations do not read as 0
+func elapsedMillis(start time.Time) float64 {
+	return float64(time.Since(start).Microseconds()) / 1000
+}
@@ -0,0 +1,113 @@
+package validator
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestValidatePromptComprehensive_Timings(t *testing.T) {
+	config := DefaultConfig()
+	config.CustomRules = map[string]string{"no_ssn": `\d{3}-\d{2}-\d{4}`}
+
+	result, err := ValidatePromptComprehensive("Write a story about a cat", config)
+	if err != nil {
+		t.Fatalf("ValidatePromptComprehensive returned error: %v", err)
+	}
+	metrics := result.PerformanceMetrics
+
+	// The old value was the Unix time in milliseconds.
+	if metrics.ProcessingTime <= 0 || metrics.ProcessingTime > 60000 {
+		t.Errorf("Expected processing time to be a duration in milliseconds, got %v", metrics.ProcessingTime)
+	}
+	if math.Abs(metrics.ProcessingTimeMicros-metrics.ProcessingTime*1000) > 1e-6 {
+		t.Errorf("Expected %v µs to match %v ms", metrics.ProcessingTimeMicros, metrics.ProcessingTime)
+	}
+
+	var stages []string
+	var stageTotal float64
+	for _, stage := range metrics.Stages {
+		stages = append(stages, stage.Name)
+		stageTotal += stage.DurationMicros
+	}
+	want := []string{StageValidation, StageSecurityAnalysis, StageComplianceCheck, StagePerformanceMetrics}
+	if !reflect.DeepEqual(stages, want) {
+		t.Errorf("Stages = %v, want %v", stages, want)
+	}
+	if stageTotal > metrics.ProcessingTimeMicros {
+		t.Errorf("Stages took %v µs, more than the %v µs total", stageTotal, metrics.ProcessingTimeMicros)
+	}
+
+	detectors := make(map[string]Timing)
+	for _, detector := range metrics.Detectors {
+		detectors[detector.Name+" "+detector.Detail] = detector
+		if detector.Stage != StageValidation || detector.Calls != 1 {
+			t.Errorf("Expected detector to run once in the validation stage, got %#v", detector)
+		}
+	}
+	expected := []string{"length ", "use_case general", "custom_rule no_ssn"}
+	for _, pattern := range config.BlockedPatterns {
+		expected = append(expected, "pattern "+pattern)
+	}
+	for _, name := range expected {
+		if _, ok := detectors[name]; !ok {
+			t.Errorf("Expected a timing for detector %q, got %#v", name, metrics.Detectors)
+		}
+	}
+	if _, ok := detectors["injection "]; ok {
+		t.Error("Expected no timing for injection markers, which single prompts skip")
+	}
+
+	if _, ok := result.Metadata["processing_time_ms"].(float64); !ok {
+		t.Errorf("Expected processing_time_ms metadata with sub-millisecond precision, got %T", result.Metadata["processing_time_ms"])
+	}
+}
+
+func TestValidateConversationComprehensive_Timings(t *testing.T) {
+	messages := []Message{
+		{Role: RoleSystem, Content: "You are a helpful assistant."},
+		{Role: RoleUser, Content: "Tell me about cats."},
+		{Role: RoleUser, Content: "And dogs?"},
+	}
+
+	result, err := ValidateConversationComprehensive(messages, DefaultConfig())
+	if err != nil {
+		t.Fatalf("ValidateConversationComprehensive returned error: %v", err)
+	}
+
+	var stages []string
+	for _, stage := range result.PerformanceMetrics.Stages {
+		stages = append(stages, stage.Name)
+	}
+	// The conversation analysis runs inside validation and finishes first.
+	want := []string{StageConversationAnalysis, StageValidation, StageSecurityAnalysis, StageComplianceCheck, StagePerformanceMetrics}
+	if !reflect.DeepEqual(stages, want) {
+		t.Errorf("Stages = %v, want %v", stages, want)
+	}
+
+	for _, detector := range result.PerformanceMetrics.Detectors {
+		switch detector.Name {
+		case "length":
+			if detector.Calls != len(messages) {
+				t.Errorf("Expected length to run once per message, got %d calls", detector.Calls)
+			}
+		case "injection":
+			// Skipped for the system message by the default role policy.
+			if detector.Calls != 2 {
+				t.Errorf("Expected injection markers to run for the two user messages, got %d calls", detector.Calls)
+			}
+		}
+	}
+}
+
+func TestProfilerNil(t *testing.T) {
+	var p *profiler
+	ran := 0
+	p.detect("length", "", func() { ran++ })
+	if err := p.runStage(StageValidation, func() error { ran++; return nil }); err != nil {
+		t.Fatalf("runStage returned error: %v", err)
+	}
+	if ran != 2 {
+		t.Errorf("Expected a nil profiler to run both functions, ran %d", ran)
+	}
+}
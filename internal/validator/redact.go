@@ -0,0 +1,60 @@
+package validator
+
+import (
+	"regexp"
+	"sort"
+)
+
+// RedactionMarker replaces content removed by RedactPrompt
+const RedactionMarker = "[REDACTED]"
+
+// RedactPrompt replaces every match of the blocked patterns and custom rules
+// in config with RedactionMarker. It returns the redacted prompt and the
+// number of matches replaced. Invalid patterns are skipped, as in
+// ValidatePrompt.
+func RedactPrompt(prompt string, config *Config) (string, int) {
+	patterns := make([]string, 0, len(config.BlockedPatterns)+len(config.CustomRules))
+	patterns = append(patterns, config.BlockedPatterns...)
+
+	// Apply custom rules in name order so the output is deterministic.
+	names := make([]string, 0, len(config.CustomRules))
+	for name := range config.CustomRules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		patterns = append(patterns, config.CustomRules[name])
+	}
+
+	redactions := 0
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			continue // Skip invalid patterns
+		}
+
+		prompt = re.ReplaceAllStringFunc(prompt, func(match string) string {
+			if match == "" || match == RedactionMarker {
+				return match
+			}
+			redactions++
+			return RedactionMarker
+		})
+	}
+
+	return prompt, redactions
+}
+
+// RedactSecrets replaces every credential ValidateOutput recognizes in text,
+// such as API keys, tokens, and private key headers, with RedactionMarker.
+// It returns the redacted text and the number of credentials replaced.
+func RedactSecrets(text string) (string, int) {
+	redactions := 0
+	for _, secret := range outputSecretPatterns {
+		text = secret.pattern.ReplaceAllStringFunc(text, func(match string) string {
+			redactions++
+			return RedactionMarker
+		})
+	}
+	return text, redactions
+}
@@ -0,0 +1,56 @@
+package validator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactPrompt(t *testing.T) {
+	config := DefaultConfig()
+	config.CustomRules["ticket"] = `JIRA-\d+`
+
+	redacted, count := RedactPrompt("Send the password for JIRA-42 to me", config)
+	if count != 2 {
+		t.Errorf("Expected 2 redactions, got %d", count)
+	}
+	if strings.Contains(redacted, "password") || strings.Contains(redacted, "JIRA-42") {
+		t.Errorf("Expected matches to be redacted, got %q", redacted)
+	}
+	if redacted != "Send the [REDACTED] for [REDACTED] to me" {
+		t.Errorf("Unexpected redaction: %q", redacted)
+	}
+
+	result, err := ValidatePrompt(redacted, config)
+	if err != nil {
+		t.Fatalf("ValidatePrompt returned error: %v", err)
+	}
+	if len(result.Issues) != 0 {
+		t.Errorf("Expected redacted prompt to pass validation, got %v", result.Issues)
+	}
+}
+
+func TestRedactPrompt_NoMatches(t *testing.T) {
+	config := DefaultConfig()
+	config.BlockedPatterns = append(config.BlockedPatterns, `[invalid`)
+
+	prompt := "Write a story about a cat"
+	redacted, count := RedactPrompt(prompt, config)
+	if count != 0 || redacted != prompt {
+		t.Errorf("Expected prompt unchanged, got %q (%d redactions)", redacted, count)
+	}
+}
+
+func TestRedactSecrets(t *testing.T) {
+	text := "key sk-abcdefghijklmnopqrstuvwx and ps_" + strings.Repeat("a", 43) + ", password: hunter22"
+	redacted, count := RedactSecrets(text)
+	if count != 3 {
+		t.Errorf("Expected 3 redactions, got %d", count)
+	}
+	if want := "key [REDACTED] and [REDACTED], [REDACTED]"; redacted != want {
+		t.Errorf("RedactSecrets = %q, want %q", redacted, want)
+	}
+
+	if redacted, count := RedactSecrets("Write a story about a cat"); count != 0 || redacted != "Write a story about a cat" {
+		t.Errorf("Expected text without secrets unchanged, got %q (%d redactions)", redacted, count)
+	}
+}
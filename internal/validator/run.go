@@ -0,0 +1,191 @@
+package validator
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Fail modes for Config.FailMode
+const (
+	// FailClosed blocks content whose validation did not finish.
+	FailClosed = "closed"
+	// FailOpen judges content whose validation did not finish by the
+	// checks that ran.
+	FailOpen = "open"
+)
+
+// tracerName names the tracer that records stage and detector spans.
+const tracerName = "promptsentinel/internal/validator"
+
+// Span attributes. Detector spans name the detector and the rule it ran,
+// never the text being checked.
+const (
+	attrStage          = attribute.Key("promptsentinel.stage")
+	attrDetector       = attribute.Key("promptsentinel.detector")
+	attrDetectorDetail = attribute.Key("promptsentinel.detector.detail")
+)
+
+type loggerKey struct{}
+
+// WithLogger returns a context whose validations log to logger. Without
+// one, they log to slog.Default. The validator logs rule names, checks it
+// skipped, and why, never the text it checks.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+func loggerFrom(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok && logger != nil {
+		return logger
+	}
+	return slog.Default()
+}
+
+// reportedPatterns holds the invalid patterns already logged, so a policy
+// with a bad rule warns once rather than on every validation.
+var reportedPatterns sync.Map
+
+// run carries the context of one validation, and its profiler when timings
+// are reported. Once the context is done, the remaining checks are skipped
+// and the result is marked incomplete.
+//
+// When the context carries a recording span, stages and detectors are
+// traced as its children, with the tracer provider that span came from.
+// Otherwise nothing is traced and no spans are started.
+type run struct {
+	ctx     context.Context
+	profile *profiler
+	tracer  trace.Tracer
+	// err is why checks were skipped, and skipped names them in the order
+	// they would have run.
+	err     error
+	skipped []string
+}
+
+func newRun(ctx context.Context, profile *profiler) *run {
+	r := &run{ctx: ctx, profile: profile}
+	if span := trace.SpanFromContext(ctx); span.IsRecording() {
+		r.tracer = span.TracerProvider().Tracer(tracerName)
+	}
+	return r
+}
+
+// stopped reports whether the context is done. It stays true once it has
+// been, so a result never mixes checks run before and after a deadline.
+func (r *run) stopped() bool {
+	if r.err == nil {
+		r.err = r.ctx.Err()
+	}
+	return r.err != nil
+}
+
+func (r *run) skip(name string) {
+	for _, skipped := range r.skipped {
+		if skipped == name {
+			return
+		}
+	}
+	r.skipped = append(r.skipped, name)
+}
+
+// invalidPattern logs, once per process, that a rule's pattern does not
+// compile and is skipped
+func (r *run) invalidPattern(detector, pattern string, err error) {
+	if _, reported := reportedPatterns.LoadOrStore(pattern, true); reported {
+		return
+	}
+	loggerFrom(r.ctx).WarnContext(r.ctx, "skipping invalid pattern",
+		"detector", detector, "pattern", pattern, "error", err)
+}
+
+// detect runs fn as a detector unless the context is done, and reports
+// whether it ran
+func (r *run) detect(name, detail string, fn func()) bool {
+	if r.stopped() {
+		r.skip(name)
+		return false
+	}
+	if r.tracer != nil {
+		attrs := []attribute.KeyValue{attrDetector.String(name)}
+		if detail != "" {
+			attrs = append(attrs, attrDetectorDetail.String(detail))
+		}
+		_, span := r.tracer.Start(r.ctx, "detect "+name, trace.WithAttributes(attrs...))
+		defer span.End()
+	}
+	r.profile.detect(name, detail, fn)
+	return true
+}
+
+// runStage runs fn as a stage unless the context is done
+func (r *run) runStage(name string, fn func() error) error {
+	if r.stopped() {
+		r.skip(name)
+		return nil
+	}
+	return r.stage(name, fn)
+}
+
+// stage runs fn as a stage even if the context is done, for stages that
+// only gather the results of others. Detectors run within it are traced as
+// children of its span.
+func (r *run) stage(name string, fn func() error) error {
+	if r.tracer == nil {
+		return r.profile.runStage(name, fn)
+	}
+
+	ctx, span := r.tracer.Start(r.ctx, "stage "+name, trace.WithAttributes(attrStage.String(name)))
+	defer span.End()
+
+	outer := r.ctx
+	r.ctx = ctx
+	err := r.profile.runStage(name, fn)
+	r.ctx = outer
+
+	if err != nil {
+		// Errors can quote what was checked, so only the failure is
+		// recorded.
+		span.SetStatus(codes.Error, "stage failed")
+	}
+	return err
+}
+
+// finish marks result incomplete when checks were skipped and applies the
+// fail mode, then fills in the recommendations and outcome. It can be called
+// again once later stages have run.
+func (r *run) finish(result *ValidationResult, config *Config) {
+	if r.err != nil && !result.Incomplete {
+		result.Incomplete = true
+		loggerFrom(r.ctx).WarnContext(r.ctx, "validation stopped before all checks ran",
+			"skipped", r.skipped, "fail_mode", config.FailMode, "error", r.err)
+
+		issue := ValidationIssue{
+			Type:    "incomplete",
+			Message: fmt.Sprintf("Validation stopped before all checks ran: %v", r.err),
+		}
+		if config.FailMode == FailOpen {
+			issue.Severity = "info"
+			issue.Suggestion = "Only the checks that ran were applied; allow more time for a full result"
+		} else {
+			issue.Severity = "error"
+			issue.Suggestion = "Allow more time, or set fail_mode to open to judge by the checks that ran"
+			result.IsValid = false
+			result.Score -= 25
+			if result.Score < 0 {
+				result.Score = 0
+			}
+		}
+		result.Issues = append(result.Issues, issue)
+	}
+	if len(r.skipped) > 0 {
+		result.Metadata["skipped_checks"] = append([]string(nil), r.skipped...)
+	}
+
+	finalizeResult(result)
+}
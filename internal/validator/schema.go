@@ -0,0 +1,262 @@
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Schema is the subset of JSON Schema used to describe tool arguments: type,
+// properties, required, additionalProperties, items, enum, const, string
+// and array lengths, pattern, and numeric bounds. Other keywords are
+// ignored.
+type Schema struct {
+	Type                 schemaTypes        `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *additionalSchema  `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Const                any                `json:"const,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+}
+
+// schemaTypes accepts "type" as a single name or a list of names
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = schemaTypes{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("schema type must be a string or a list of strings")
+	}
+	*t = list
+	return nil
+}
+
+func (t schemaTypes) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// additionalSchema accepts additionalProperties as a boolean or a schema
+type additionalSchema struct {
+	Allowed bool
+	Schema  *Schema
+}
+
+func (a *additionalSchema) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &a.Allowed); err == nil {
+		return nil
+	}
+	a.Allowed = true
+	return json.Unmarshal(data, &a.Schema)
+}
+
+func (a additionalSchema) MarshalJSON() ([]byte, error) {
+	if a.Schema != nil {
+		return json.Marshal(a.Schema)
+	}
+	return json.Marshal(a.Allowed)
+}
+
+// schemaViolation is a value that does not match its schema, located by a
+// JSON pointer
+type schemaViolation struct {
+	path    string
+	message string
+}
+
+// validateSchema checks value, as decoded by encoding/json with UseNumber,
+// against schema and returns every violation found
+func validateSchema(schema *Schema, value any, path string) []schemaViolation {
+	if schema == nil {
+		return nil
+	}
+
+	var violations []schemaViolation
+	fail := func(format string, args ...any) {
+		violations = append(violations, schemaViolation{path: path, message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(schema.Type) > 0 && !schemaTypeMatches(schema.Type, value) {
+		fail("expected %s, got %s", strings.Join(schema.Type, " or "), jsonTypeName(value))
+		return violations
+	}
+	if len(schema.Enum) > 0 {
+		found := false
+		for _, allowed := range schema.Enum {
+			if jsonEqual(allowed, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("value is not one of the allowed values")
+		}
+	}
+	if schema.Const != nil && !jsonEqual(schema.Const, value) {
+		fail("value does not match the required constant")
+	}
+
+	switch v := value.(type) {
+	case string:
+		length := len([]rune(v))
+		if schema.MinLength != nil && length < *schema.MinLength {
+			fail("string shorter than %d characters", *schema.MinLength)
+		}
+		if schema.MaxLength != nil && length > *schema.MaxLength {
+			fail("string longer than %d characters", *schema.MaxLength)
+		}
+		if schema.Pattern != "" {
+			if re, err := regexp.Compile(schema.Pattern); err == nil && !re.MatchString(v) {
+				fail("string does not match pattern %s", schema.Pattern)
+			}
+		}
+	case json.Number:
+		n, _ := v.Float64()
+		if schema.Minimum != nil && n < *schema.Minimum {
+			fail("number below minimum %v", *schema.Minimum)
+		}
+		if schema.Maximum != nil && n > *schema.Maximum {
+			fail("number above maximum %v", *schema.Maximum)
+		}
+	case []any:
+		if schema.MinItems != nil && len(v) < *schema.MinItems {
+			fail("array has fewer than %d items", *schema.MinItems)
+		}
+		if schema.MaxItems != nil && len(v) > *schema.MaxItems {
+			fail("array has more than %d items", *schema.MaxItems)
+		}
+		for i, item := range v {
+			violations = append(violations, validateSchema(schema.Items, item, path+"/"+strconv.Itoa(i))...)
+		}
+	case map[string]any:
+		for _, name := range schema.Required {
+			if _, ok := v[name]; !ok {
+				fail("missing required property %q", name)
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			child := path + "/" + escapePointerToken(key)
+			if property, ok := schema.Properties[key]; ok {
+				violations = append(violations, validateSchema(property, v[key], child)...)
+				continue
+			}
+			if extra := schema.AdditionalProperties; extra != nil {
+				if !extra.Allowed {
+					violations = append(violations, schemaViolation{path: child, message: "property is not allowed"})
+				} else {
+					violations = append(violations, validateSchema(extra.Schema, v[key], child)...)
+				}
+			}
+		}
+	}
+
+	return violations
+}
+
+func schemaTypeMatches(types []string, value any) bool {
+	for _, name := range types {
+		switch name {
+		case "integer":
+			if n, ok := value.(json.Number); ok {
+				if f, err := n.Float64(); err == nil && f == math.Trunc(f) {
+					return true
+				}
+			}
+		case "number":
+			if _, ok := value.(json.Number); ok {
+				return true
+			}
+		default:
+			if jsonTypeName(value) == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number, float64:
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return "unknown"
+}
+
+// jsonEqual compares two decoded JSON values, treating numbers by value
+// whether they were decoded as json.Number or float64
+func jsonEqual(a, b any) bool {
+	normalize := func(value any) any {
+		if n, ok := value.(json.Number); ok {
+			f, _ := n.Float64()
+			return f
+		}
+		return value
+	}
+	a, b = normalize(a), normalize(b)
+
+	switch av := a.(type) {
+	case []any:
+		bv, ok := b.([]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !jsonEqual(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for key, value := range av {
+			if !jsonEqual(value, bv[key]) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+// escapePointerToken escapes a property name for use in a JSON pointer
+func escapePointerToken(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,78 @@
+package validator
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateSchema(t *testing.T) {
+	schema := `{
+		"type": "object",
+		"required": ["query", "limit"],
+		"properties": {
+			"query": {"type": "string", "minLength": 2, "maxLength": 20, "pattern": "^[a-z ]+$"},
+			"limit": {"type": "integer", "minimum": 1, "maximum": 50},
+			"mode": {"enum": ["fast", "exact"]},
+			"tags": {"type": "array", "maxItems": 2, "items": {"type": "string"}},
+			"filter": {"type": ["object", "null"], "additionalProperties": {"type": "string"}}
+		},
+		"additionalProperties": false
+	}`
+	var s Schema
+	if err := json.Unmarshal([]byte(schema), &s); err != nil {
+		t.Fatalf("Unmarshal schema: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		value string
+		paths []string
+	}{
+		{"valid", `{"query": "red shoes", "limit": 10, "mode": "fast", "tags": ["a"], "filter": null}`, nil},
+		{"missing required", `{"query": "red shoes"}`, []string{""}},
+		{"not an integer", `{"query": "red shoes", "limit": 1.5}`, []string{"/limit"}},
+		{"out of range", `{"query": "red shoes", "limit": 51}`, []string{"/limit"}},
+		{"string constraints", `{"query": "R", "limit": 1}`, []string{"/query", "/query"}},
+		{"enum", `{"query": "ab", "limit": 1, "mode": "slow"}`, []string{"/mode"}},
+		{"array items", `{"query": "ab", "limit": 1, "tags": ["a", 2, "c"]}`, []string{"/tags", "/tags/1"}},
+		{"additional schema", `{"query": "ab", "limit": 1, "filter": {"a/b": 3}}`, []string{"/filter/a~1b"}},
+		{"additional forbidden", `{"query": "ab", "limit": 1, "extra": true}`, []string{"/extra"}},
+		{"wrong root type", `[]`, []string{""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := json.NewDecoder(strings.NewReader(tt.value))
+			decoder.UseNumber()
+			var value any
+			if err := decoder.Decode(&value); err != nil {
+				t.Fatalf("Decode value: %v", err)
+			}
+
+			var paths []string
+			for _, violation := range validateSchema(&s, value, "") {
+				paths = append(paths, violation.path)
+			}
+			if !reflect.DeepEqual(paths, tt.paths) {
+				t.Errorf("Violation paths = %q, want %q", paths, tt.paths)
+			}
+		})
+	}
+}
+
+func TestSchemaRoundTrip(t *testing.T) {
+	input := `{"type":["string","null"],"additionalProperties":false}`
+	var s Schema
+	if err := json.Unmarshal([]byte(input), &s); err != nil {
+		t.Fatalf("Unmarshal schema: %v", err)
+	}
+	data, err := json.Marshal(&s)
+	if err != nil {
+		t.Fatalf("Marshal schema: %v", err)
+	}
+	if string(data) != input {
+		t.Errorf("Round trip = %s, want %s", data, input)
+	}
+}
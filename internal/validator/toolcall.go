@@ -0,0 +1,244 @@
+package validator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ToolCall is a call an agent wants to make: a tool name and its JSON
+// arguments. It decodes from the generic {"name", "arguments"} form, an
+// OpenAI tool call, whose arguments are a JSON-encoded string, and an
+// Anthropic tool_use block.
+type ToolCall struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// ToolPolicy constrains the calls an agent may make to one tool
+type ToolPolicy struct {
+	// Schema describes the tool's arguments.
+	Schema *Schema `json:"schema,omitempty"`
+	// AllowedDomains replaces Config.AllowedDomains for URLs in this tool's
+	// arguments.
+	AllowedDomains []string `json:"allowed_domains,omitempty"`
+	// ForbiddenShellPatterns are matched against every string argument.
+	// When nil, DefaultForbiddenShellPatterns apply; an empty list turns the
+	// check off.
+	ForbiddenShellPatterns []string `json:"forbidden_shell_patterns"`
+}
+
+// ToolCallResult is the result of validating a tool call. Issues about an
+// argument carry a JSON pointer into the arguments in Path.
+type ToolCallResult struct {
+	ValidationResult
+	Tool string `json:"tool"`
+}
+
+// DefaultForbiddenShellPatterns returns the shell patterns checked in tool
+// arguments when a tool's policy does not list its own
+func DefaultForbiddenShellPatterns() []string {
+	return []string{
+		`(?i)\brm\s+-[a-z]*(rf|fr)[a-z]*\s+(/|~|\*|\$home)`,
+		`(?i)\b(curl|wget)\b[^|;\n]*\|\s*(sudo\s+)?(ba|z|da|k)?sh\b`,
+		`(?i)base64\s+(-d|--decode)\b[^|\n]*\|\s*(ba|z)?sh\b`,
+		`/dev/(tcp|udp)/`,
+		`(?i)\b(nc|ncat|netcat)\b[^\n]*\s-[a-z]*e\s`,
+		`(?i)\bchmod\s+(-r\s+)?777\b`,
+		`(?i)\bmkfs(\.\w+)?\b|\bdd\s+if=`,
+		`:\(\)\s*\{\s*:\|:&\s*\};\s*:`,
+		`(?i)/etc/(shadow|sudoers)|~/\.ssh/|\.aws/credentials`,
+		`\$\([^)]*\)`,
+	}
+}
+
+// UnmarshalJSON accepts the tool call formats of common agent frameworks
+func (c *ToolCall) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+		Input     json.RawMessage `json:"input"`
+		Function  *struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		} `json:"function"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	c.Name, c.Arguments = raw.Name, raw.Arguments
+	if raw.Function != nil {
+		c.Name, c.Arguments = raw.Function.Name, raw.Function.Arguments
+	} else if len(raw.Input) > 0 {
+		c.Arguments = raw.Input
+	}
+
+	// OpenAI sends arguments as a string of JSON.
+	var encoded string
+	if len(c.Arguments) > 0 && c.Arguments[0] == '"' {
+		if err := json.Unmarshal(c.Arguments, &encoded); err != nil {
+			return err
+		}
+		c.Arguments = json.RawMessage(encoded)
+	}
+
+	if c.Name == "" {
+		return fmt.Errorf("tool call has no name")
+	}
+	return nil
+}
+
+var toolURLPattern = regexp.MustCompile(`(?i)\b(?:https?|ftp|wss?)://[^\s"'<>()\[\]{}]+`)
+
+// ValidateToolCall checks a tool call against the tool policies in config.
+// When config lists tool policies, only those tools may be called. The
+// arguments are checked against the tool's schema, every URL in them must
+// point to an allowed domain when an allowlist is configured, and every
+// string is checked for forbidden shell patterns and for instructions
+// injected for the model.
+func ValidateToolCall(call ToolCall, config *Config) (*ToolCallResult, error) {
+	return ValidateToolCallContext(context.Background(), call, config)
+}
+
+// ValidateToolCallContext is ValidateToolCall with a context. When the
+// context is done, the remaining checks are skipped and the result is marked
+// incomplete.
+func ValidateToolCallContext(ctx context.Context, call ToolCall, config *Config) (*ToolCallResult, error) {
+	start := time.Now()
+	r := newRun(ctx, nil)
+
+	result := &ToolCallResult{
+		ValidationResult: ValidationResult{
+			IsValid:   true,
+			Score:     100,
+			Issues:    []ValidationIssue{},
+			Metadata:  make(map[string]interface{}),
+			Timestamp: time.Now(),
+		},
+		Tool: call.Name,
+	}
+	fail := func(issueType, path, message, suggestion string) {
+		result.Issues = append(result.Issues, ValidationIssue{
+			Type:       issueType,
+			Severity:   "error",
+			Message:    message,
+			Suggestion: suggestion,
+			Path:       path,
+		})
+		result.IsValid = false
+		result.Score -= 25
+	}
+
+	policy, listed := config.ToolPolicies[call.Name]
+	if len(config.ToolPolicies) > 0 && !listed {
+		fail("tool", "", fmt.Sprintf("Tool %q is not allowed", call.Name), "Add the tool to tool_policies or stop the agent from calling it")
+	}
+
+	var arguments any = map[string]any{}
+	if len(bytes.TrimSpace(call.Arguments)) > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(call.Arguments))
+		decoder.UseNumber()
+		if err := decoder.Decode(&arguments); err != nil {
+			fail("schema", "", fmt.Sprintf("Tool arguments are not valid JSON: %v", err), "Reject the call and ask the agent to retry")
+			return finishToolCall(result, config, r, start), nil
+		}
+	}
+	if _, ok := arguments.(map[string]any); !ok {
+		fail("schema", "", fmt.Sprintf("Tool arguments must be an object, got %s", jsonTypeName(arguments)), "Reject the call and ask the agent to retry")
+		return finishToolCall(result, config, r, start), nil
+	}
+
+	r.detect("schema", call.Name, func() {
+		for _, violation := range validateSchema(policy.Schema, arguments, "") {
+			fail("schema", violation.path, fmt.Sprintf("Argument does not match the %s schema: %s", call.Name, violation.message), "Reject the call and ask the agent to retry")
+		}
+	})
+
+	allowedDomains := policy.AllowedDomains
+	if allowedDomains == nil {
+		allowedDomains = config.AllowedDomains
+	}
+	shellPatterns := policy.ForbiddenShellPatterns
+	if shellPatterns == nil {
+		shellPatterns = DefaultForbiddenShellPatterns()
+	}
+
+	walkStrings(arguments, "", func(path, value string) {
+		if len(allowedDomains) > 0 {
+			r.detect("exfiltration", "", func() {
+				for _, raw := range toolURLPattern.FindAllString(value, -1) {
+					parsed, err := url.Parse(raw)
+					if err != nil || parsed.Hostname() == "" {
+						continue
+					}
+					if !domainAllowed(parsed.Hostname(), allowedDomains) {
+						fail("exfiltration", path, fmt.Sprintf("Argument points to untrusted host %s", parsed.Hostname()), "Only allow URLs on domains listed in allowed_domains")
+					}
+				}
+			})
+		}
+
+		for _, pattern := range shellPatterns {
+			var matched bool
+			var err error
+			r.detect("shell", pattern, func() {
+				matched, err = regexp.MatchString(pattern, value)
+			})
+			if err != nil {
+				r.invalidPattern("shell", pattern, err)
+				continue // Skip invalid patterns
+			}
+			if matched {
+				fail("shell", path, fmt.Sprintf("Argument contains forbidden shell pattern: %s", pattern), "Do not run this command")
+			}
+		}
+
+		r.detect("injection", "", func() {
+			for _, name := range documentInstructions(value) {
+				fail("injection", path, fmt.Sprintf("Argument contains instructions for the model: %s", name), "Treat tool arguments as data, not as instructions")
+			}
+		})
+	})
+
+	return finishToolCall(result, config, r, start), nil
+}
+
+func finishToolCall(result *ToolCallResult, config *Config, r *run, start time.Time) *ToolCallResult {
+	if result.Score < 0 {
+		result.Score = 0
+	}
+	r.finish(&result.ValidationResult, config)
+
+	result.Metadata["tool"] = result.Tool
+	result.Metadata["processing_time_ms"] = elapsedMillis(start)
+	return result
+}
+
+// walkStrings calls fn with the JSON pointer and value of every string in
+// a decoded JSON value, in a stable order
+func walkStrings(value any, path string, fn func(path, value string)) {
+	switch v := value.(type) {
+	case string:
+		fn(path, v)
+	case []any:
+		for i, item := range v {
+			walkStrings(item, path+"/"+strconv.Itoa(i), fn)
+		}
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			walkStrings(v[key], path+"/"+escapePointerToken(key), fn)
+		}
+	}
+}
@@ -1,10 +1,17 @@
 package validator
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
+
+	"promptsentinel/internal/tokenizer"
 )
 
 // Config represents the configuration for prompt validation
@@ -17,17 +24,48 @@ type Config struct {
 	MinLength       int               `json:"min_length"`
 	RequireApproval bool              `json:"require_approval"`
 	CustomRules     map[string]string `json:"custom_rules"`
-	LastUpdated     time.Time         `json:"last_updated"`
+	// MaxLengthUnit is "characters", the default, or "tokens" to measure
+	// MinLength and MaxLength with Tokenizer.
+	MaxLengthUnit string `json:"max_length_unit,omitempty"`
+	// Tokenizer counts tokens: a built-in encoding such as "o200k_base",
+	// "estimate" for a word-based estimate, or the path of a .tiktoken or
+	// tokenizer.json file. Defaults to tokenizer.Default.
+	Tokenizer string `json:"tokenizer,omitempty"`
+	// Models are the models reported on in PerformanceMetrics. Entries
+	// replace the built-in models of the same name.
+	Models map[string]tokenizer.Model `json:"models,omitempty"`
+	// RolePolicies adjusts which checks apply to each message role in a
+	// conversation. Roles not listed use DefaultRolePolicies.
+	RolePolicies map[string]RolePolicy `json:"role_policies,omitempty"`
+	// ToolPolicies lists the tools an agent may call, keyed by name. When
+	// empty, any tool may be called under the default policy.
+	ToolPolicies map[string]ToolPolicy `json:"tool_policies,omitempty"`
+	// FailMode decides the verdict when the context of a validation is done
+	// before all checks have run: FailClosed, the default, or FailOpen.
+	FailMode    string    `json:"fail_mode,omitempty"`
+	LastUpdated time.Time `json:"last_updated"`
 }
 
+// Outcome values reported in ValidationResult.Outcome
+const (
+	OutcomeAllowed         = "allowed"
+	OutcomeBlocked         = "blocked"
+	OutcomePendingApproval = "pending_approval"
+)
+
 // ValidationResult represents the result of prompt validation
 type ValidationResult struct {
 	IsValid         bool                   `json:"is_valid"`
+	Outcome         string                 `json:"outcome"`
+	ApprovalID      string                 `json:"approval_id,omitempty"`
 	Score           int                    `json:"score"`
 	Issues          []ValidationIssue      `json:"issues"`
 	Recommendations []string               `json:"recommendations"`
 	Metadata        map[string]interface{} `json:"metadata"`
 	Timestamp       time.Time              `json:"timestamp"`
+	// Incomplete is set when the context was done before all checks ran.
+	// Config.FailMode decides whether the result is then blocked.
+	Incomplete bool `json:"incomplete,omitempty"`
 }
 
 // ValidationIssue represents a specific validation issue
@@ -38,6 +76,15 @@ type ValidationIssue struct {
 	Suggestion string `json:"suggestion,omitempty"`
 	Line       int    `json:"line,omitempty"`
 	Column     int    `json:"column,omitempty"`
+	// MessageIndex and Role attribute the issue to a message when a
+	// conversation is validated.
+	MessageIndex *int   `json:"message_index,omitempty"`
+	Role         string `json:"role,omitempty"`
+	// ChunkIndex attributes the issue to a chunk when a document is
+	// scanned.
+	ChunkIndex *int `json:"chunk_index,omitempty"`
+	// Path is a JSON pointer to the tool call argument the issue is about.
+	Path string `json:"path,omitempty"`
 }
 
 // ComprehensiveValidationResult extends ValidationResult with additional analysis
@@ -46,6 +93,9 @@ type ComprehensiveValidationResult struct {
 	SecurityAnalysis   SecurityAnalysis   `json:"security_analysis"`
 	ComplianceCheck    ComplianceCheck    `json:"compliance_check"`
 	PerformanceMetrics PerformanceMetrics `json:"performance_metrics"`
+	// ConversationAnalysis is set when a conversation rather than a single
+	// prompt was validated.
+	ConversationAnalysis *ConversationAnalysis `json:"conversation_analysis,omitempty"`
 }
 
 // SecurityAnalysis contains security-related validation results
@@ -66,10 +116,33 @@ type ComplianceCheck struct {
 
 // PerformanceMetrics contains performance-related analysis
 type PerformanceMetrics struct {
-	EstimatedTokens   int     `json:"estimated_tokens"`
-	ComplexityScore   float64 `json:"complexity_score"`
-	ProcessingTime    int64   `json:"processing_time_ms"`
-	ResourceIntensive bool    `json:"resource_intensive"`
+	// EstimatedTokens is counted with Tokenizer.
+	EstimatedTokens int     `json:"estimated_tokens"`
+	Tokenizer       string  `json:"tokenizer"`
+	ComplexityScore float64 `json:"complexity_score"`
+	// ProcessingTime is how long the whole validation took, in
+	// milliseconds; ProcessingTimeMicros is the same in microseconds.
+	ProcessingTime       float64    `json:"processing_time_ms"`
+	ProcessingTimeMicros float64    `json:"processing_time_us"`
+	ResourceIntensive    bool       `json:"resource_intensive"`
+	ModelFit             []ModelFit `json:"model_fit,omitempty"`
+	// Stages and Detectors break the processing time down, in the order
+	// they ran.
+	Stages    []Timing `json:"stages"`
+	Detectors []Timing `json:"detectors"`
+}
+
+// ModelFit reports whether a prompt fits a model's context window and what
+// sending it would cost
+type ModelFit struct {
+	Model         string `json:"model"`
+	Tokens        int    `json:"tokens"`
+	ContextWindow int    `json:"context_window"`
+	Fits          bool   `json:"fits"`
+	// ContextUsed is the share of the context window the prompt takes, from
+	// 0 to 1 and beyond when it does not fit.
+	ContextUsed      float64 `json:"context_used"`
+	EstimatedCostUSD float64 `json:"estimated_cost_usd"`
 }
 
 // DefaultConfig returns a default configuration
@@ -92,9 +165,60 @@ func DefaultConfig() *Config {
 	}
 }
 
+// PolicyVersion returns a short hash identifying the rules in config. Two
+// configurations that judge prompts identically share a version, so the value
+// can be stored alongside results to show which policy produced them.
+// LastUpdated is excluded because it does not affect validation.
+func PolicyVersion(config *Config) string {
+	policy := *config
+	policy.LastUpdated = time.Time{}
+
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
 // ValidatePrompt performs basic prompt validation
 func ValidatePrompt(prompt string, config *Config) (*ValidationResult, error) {
+	return ValidatePromptContext(context.Background(), prompt, config)
+}
+
+// ValidatePromptContext is ValidatePrompt with a context. When the context
+// is done, the remaining checks are skipped and the result is marked
+// incomplete; it is not an error.
+func ValidatePromptContext(ctx context.Context, prompt string, config *Config) (*ValidationResult, error) {
+	return validatePrompt(prompt, config, newRun(ctx, nil))
+}
+
+func validatePrompt(prompt string, config *Config, r *run) (*ValidationResult, error) {
 	startTime := time.Now()
+
+	// Injection markers are only checked for conversations, where the role
+	// tells us whether the text came from the operator or from a user.
+	result, err := validateText(prompt, config, func(issueType string) bool {
+		return issueType != "injection"
+	}, r)
+	if err != nil {
+		return nil, err
+	}
+
+	r.finish(result, config)
+
+	// Add metadata
+	result.Metadata["processing_time_ms"] = elapsedMillis(startTime)
+	result.Metadata["prompt_length"] = len(prompt)
+	result.Metadata["use_case"] = config.UseCase
+
+	return result, nil
+}
+
+// validateText runs the checks for which enabled returns true, keyed by the
+// issue type they report, until the context of r is done.
+func validateText(prompt string, config *Config, enabled func(issueType string) bool, r *run) (*ValidationResult, error) {
 	result := &ValidationResult{
 		IsValid:   true,
 		Score:     100,
@@ -104,84 +228,175 @@ func ValidatePrompt(prompt string, config *Config) (*ValidationResult, error) {
 	}
 
 	// Length validation
-	if len(prompt) < config.MinLength {
-		result.Issues = append(result.Issues, ValidationIssue{
-			Type:       "length",
-			Severity:   "error",
-			Message:    fmt.Sprintf("Prompt too short (minimum %d characters)", config.MinLength),
-			Suggestion: "Add more content to your prompt",
+	if enabled("length") {
+		var length int
+		var unit string
+		var err error
+		ran := r.detect("length", "", func() {
+			length, unit, err = promptLength(prompt, config)
 		})
-		result.IsValid = false
-		result.Score -= 20
-	}
+		if err != nil {
+			return nil, err
+		}
 
-	if len(prompt) > config.MaxLength {
-		result.Issues = append(result.Issues, ValidationIssue{
-			Type:       "length",
-			Severity:   "error",
-			Message:    fmt.Sprintf("Prompt too long (maximum %d characters)", config.MaxLength),
-			Suggestion: "Shorten your prompt",
-		})
-		result.IsValid = false
-		result.Score -= 20
+		if ran && length < config.MinLength {
+			result.Issues = append(result.Issues, ValidationIssue{
+				Type:       "length",
+				Severity:   "error",
+				Message:    fmt.Sprintf("Prompt too short (minimum %d %s)", config.MinLength, unit),
+				Suggestion: "Add more content to your prompt",
+			})
+			result.IsValid = false
+			result.Score -= 20
+		}
+
+		if ran && length > config.MaxLength {
+			result.Issues = append(result.Issues, ValidationIssue{
+				Type:       "length",
+				Severity:   "error",
+				Message:    fmt.Sprintf("Prompt too long (maximum %d %s)", config.MaxLength, unit),
+				Suggestion: "Shorten your prompt",
+			})
+			result.IsValid = false
+			result.Score -= 20
+		}
 	}
 
 	// Pattern validation
-	for _, pattern := range config.BlockedPatterns {
-		matched, err := regexp.MatchString(pattern, prompt)
-		if err != nil {
-			continue // Skip invalid patterns
-		}
-		if matched {
-			result.Issues = append(result.Issues, ValidationIssue{
-				Type:       "pattern",
-				Severity:   "warning",
-				Message:    fmt.Sprintf("Prompt contains blocked pattern: %s", pattern),
-				Suggestion: "Review and modify the flagged content",
+	if enabled("pattern") {
+		for _, pattern := range config.BlockedPatterns {
+			var matched bool
+			var err error
+			r.detect("pattern", pattern, func() {
+				matched, err = regexp.MatchString(pattern, prompt)
 			})
-			result.Score -= 10
+			if err != nil {
+				r.invalidPattern("pattern", pattern, err)
+				continue // Skip invalid patterns
+			}
+			if matched {
+				result.Issues = append(result.Issues, ValidationIssue{
+					Type:       "pattern",
+					Severity:   "warning",
+					Message:    fmt.Sprintf("Prompt contains blocked pattern: %s", pattern),
+					Suggestion: "Review and modify the flagged content",
+				})
+				result.Score -= 10
+			}
 		}
 	}
 
+	// Prompt injection markers
+	if enabled("injection") {
+		r.detect("injection", "", func() {
+			validateInjectionMarkers(prompt, result)
+		})
+	}
+
 	// Use case specific validation
-	if err := validateUseCase(prompt, config.UseCase, result); err != nil {
-		return nil, err
+	if enabled("use_case") {
+		var err error
+		r.detect("use_case", config.UseCase, func() {
+			err = validateUseCase(prompt, config.UseCase, result)
+		})
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Custom rules validation
-	if err := validateCustomRules(prompt, config.CustomRules, result); err != nil {
-		return nil, err
+	if enabled("custom_rule") {
+		if err := validateCustomRules(prompt, config.CustomRules, result, r); err != nil {
+			return nil, err
+		}
 	}
 
-	// Generate recommendations
-	result.Recommendations = generateRecommendations(result)
+	return result, nil
+}
 
-	// Add metadata
-	result.Metadata["processing_time_ms"] = time.Since(startTime).Milliseconds()
-	result.Metadata["prompt_length"] = len(prompt)
-	result.Metadata["use_case"] = config.UseCase
+// finalizeResult fills in the recommendations and outcome once all issues
+// have been collected
+func finalizeResult(result *ValidationResult) {
+	result.Recommendations = generateRecommendations(result)
 
-	return result, nil
+	result.Outcome = OutcomeAllowed
+	if !result.IsValid {
+		result.Outcome = OutcomeBlocked
+	}
 }
 
 // ValidatePromptComprehensive performs comprehensive prompt validation
 func ValidatePromptComprehensive(prompt string, config *Config) (*ComprehensiveValidationResult, error) {
+	return ValidatePromptComprehensiveContext(context.Background(), prompt, config)
+}
+
+// ValidatePromptComprehensiveContext is ValidatePromptComprehensive with a
+// context. When the context is done, the remaining checks and stages are
+// skipped and the result is marked incomplete.
+func ValidatePromptComprehensiveContext(ctx context.Context, prompt string, config *Config) (*ComprehensiveValidationResult, error) {
+	r := newRun(ctx, newProfiler())
+
 	// Perform basic validation first
-	basicResult, err := ValidatePrompt(prompt, config)
+	var basicResult *ValidationResult
+	err := r.stage(StageValidation, func() (err error) {
+		basicResult, err = validatePrompt(prompt, config, r)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	comprehensiveResult := &ComprehensiveValidationResult{
-		ValidationResult:   *basicResult,
-		SecurityAnalysis:   performSecurityAnalysis(prompt),
-		ComplianceCheck:    performComplianceCheck(prompt, config),
-		PerformanceMetrics: calculatePerformanceMetrics(prompt),
+	comprehensiveResult := &ComprehensiveValidationResult{ValidationResult: *basicResult}
+	if err := analyzeText(comprehensiveResult, prompt, config, r); err != nil {
+		return nil, err
 	}
 
 	return comprehensiveResult, nil
 }
 
+// analyzeText runs the security, compliance, and performance stages of
+// comprehensive validation over text and records the timings
+func analyzeText(result *ComprehensiveValidationResult, text string, config *Config, r *run) error {
+	r.runStage(StageSecurityAnalysis, func() error {
+		result.SecurityAnalysis = performSecurityAnalysis(text)
+		return nil
+	})
+	r.runStage(StageComplianceCheck, func() error {
+		result.ComplianceCheck = performComplianceCheck(text, config)
+		return nil
+	})
+	err := r.runStage(StagePerformanceMetrics, func() (err error) {
+		result.PerformanceMetrics, err = calculatePerformanceMetrics(text, config)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// A stage skipped here can make a result incomplete that was complete
+	// after basic validation.
+	r.finish(&result.ValidationResult, config)
+	r.profile.finish(&result.PerformanceMetrics)
+	return nil
+}
+
+// RequiresApproval reports whether a result must be reviewed by a human
+// before the prompt is used. When config.RequireApproval is set, any prompt
+// flagged with a warning or error goes to the approval queue; clean prompts
+// and those with only informational notes pass straight through.
+func RequiresApproval(config *Config, result *ValidationResult) bool {
+	if !config.RequireApproval {
+		return false
+	}
+
+	for _, issue := range result.Issues {
+		if issue.Severity == "warning" || issue.Severity == "error" {
+			return true
+		}
+	}
+	return false
+}
+
 // validateUseCase performs use case specific validation
 func validateUseCase(prompt string, useCase string, result *ValidationResult) error {
 	switch useCase {
@@ -214,10 +429,15 @@ func validateUseCase(prompt string, useCase string, result *ValidationResult) er
 }
 
 // validateCustomRules validates against custom rules
-func validateCustomRules(prompt string, customRules map[string]string, result *ValidationResult) error {
+func validateCustomRules(prompt string, customRules map[string]string, result *ValidationResult, r *run) error {
 	for ruleName, pattern := range customRules {
-		matched, err := regexp.MatchString(pattern, prompt)
+		var matched bool
+		var err error
+		r.detect("custom_rule", ruleName, func() {
+			matched, err = regexp.MatchString(pattern, prompt)
+		})
 		if err != nil {
+			r.invalidPattern("custom_rule", pattern, err)
 			continue // Skip invalid patterns
 		}
 		if matched {
@@ -317,10 +537,12 @@ func performComplianceCheck(prompt string, config *Config) ComplianceCheck {
 }
 
 // calculatePerformanceMetrics calculates performance-related metrics
-func calculatePerformanceMetrics(prompt string) PerformanceMetrics {
-	// Simple token estimation (rough approximation)
-	words := strings.Fields(prompt)
-	estimatedTokens := int(float64(len(words)) * 1.3) // Rough approximation
+func calculatePerformanceMetrics(prompt string, config *Config) (PerformanceMetrics, error) {
+	tok, err := tokenizer.Get(config.Tokenizer)
+	if err != nil {
+		return PerformanceMetrics{}, err
+	}
+	estimatedTokens := tok.Count(prompt)
 
 	// Calculate complexity score based on various factors
 	complexityScore := 0.0
@@ -331,12 +553,77 @@ func calculatePerformanceMetrics(prompt string) PerformanceMetrics {
 	// Check if resource intensive
 	resourceIntensive := len(prompt) > 5000 || estimatedTokens > 2000
 
+	modelFit, err := calculateModelFit(prompt, config)
+	if err != nil {
+		return PerformanceMetrics{}, err
+	}
+
 	return PerformanceMetrics{
-		EstimatedTokens:   int(estimatedTokens),
+		EstimatedTokens:   estimatedTokens,
+		Tokenizer:         tok.Name(),
 		ComplexityScore:   complexityScore,
-		ProcessingTime:    time.Now().UnixNano() / 1000000, // Mock processing time
 		ResourceIntensive: resourceIntensive,
+		ModelFit:          modelFit,
+	}, nil
+}
+
+// calculateModelFit counts the prompt with each model's own tokenizer and
+// compares it to the model's context window
+func calculateModelFit(prompt string, config *Config) ([]ModelFit, error) {
+	models := tokenizer.DefaultModels()
+	for name, model := range config.Models {
+		models[name] = model
+	}
+
+	names := make([]string, 0, len(models))
+	for name := range models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	counts := make(map[string]int)
+	fits := make([]ModelFit, 0, len(names))
+	for _, name := range names {
+		model := models[name]
+		tokens, counted := counts[model.Encoding]
+		if !counted {
+			tok, err := tokenizer.Get(model.Encoding)
+			if err != nil {
+				return nil, fmt.Errorf("model %s: %w", name, err)
+			}
+			tokens = tok.Count(prompt)
+			counts[model.Encoding] = tokens
+		}
+
+		fit := ModelFit{
+			Model:            name,
+			Tokens:           tokens,
+			ContextWindow:    model.ContextWindow,
+			Fits:             tokens <= model.ContextWindow,
+			EstimatedCostUSD: model.Cost(tokens),
+		}
+		if model.ContextWindow > 0 {
+			fit.ContextUsed = float64(tokens) / float64(model.ContextWindow)
+		}
+		fits = append(fits, fit)
+	}
+	return fits, nil
+}
+
+// promptLength measures prompt in the unit MinLength and MaxLength are
+// expressed in
+func promptLength(prompt string, config *Config) (int, string, error) {
+	switch config.MaxLengthUnit {
+	case "", "characters":
+		return len(prompt), "characters", nil
+	case "tokens":
+		tok, err := tokenizer.Get(config.Tokenizer)
+		if err != nil {
+			return 0, "", err
+		}
+		return tok.Count(prompt), "tokens", nil
 	}
+	return 0, "", fmt.Errorf("unknown max_length_unit %q (expected characters or tokens)", config.MaxLengthUnit)
 }
 
 // generateRecommendations generates recommendations based on validation results
@@ -1,7 +1,11 @@
 package validator
 
 import (
+	"strings"
 	"testing"
+	"time"
+
+	"promptsentinel/internal/tokenizer"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -28,6 +32,24 @@ func TestDefaultConfig(t *testing.T) {
 	}
 }
 
+func TestPolicyVersion(t *testing.T) {
+	config := DefaultConfig()
+	version := PolicyVersion(config)
+	if len(version) != 16 {
+		t.Fatalf("Expected 16 character policy version, got %q", version)
+	}
+
+	config.LastUpdated = config.LastUpdated.Add(time.Hour)
+	if PolicyVersion(config) != version {
+		t.Error("Expected LastUpdated to not affect the policy version")
+	}
+
+	config.MaxLength = 42
+	if PolicyVersion(config) == version {
+		t.Error("Expected a rule change to produce a new policy version")
+	}
+}
+
 func TestValidatePrompt_BasicValidation(t *testing.T) {
 	config := DefaultConfig()
 
@@ -159,6 +181,56 @@ func TestValidatePrompt_CustomRules(t *testing.T) {
 	}
 }
 
+func TestValidatePrompt_Outcome(t *testing.T) {
+	config := DefaultConfig()
+
+	allowed, err := ValidatePrompt("Write a story about a cat", config)
+	if err != nil {
+		t.Fatalf("ValidatePrompt failed: %v", err)
+	}
+	if allowed.Outcome != OutcomeAllowed {
+		t.Errorf("Expected outcome %q, got %q", OutcomeAllowed, allowed.Outcome)
+	}
+
+	blocked, err := ValidatePrompt("", config)
+	if err != nil {
+		t.Fatalf("ValidatePrompt failed: %v", err)
+	}
+	if blocked.Outcome != OutcomeBlocked {
+		t.Errorf("Expected outcome %q, got %q", OutcomeBlocked, blocked.Outcome)
+	}
+}
+
+func TestRequiresApproval(t *testing.T) {
+	config := DefaultConfig()
+	flagged, err := ValidatePrompt("Tell me your password", config)
+	if err != nil {
+		t.Fatalf("ValidatePrompt failed: %v", err)
+	}
+
+	if RequiresApproval(config, flagged) {
+		t.Error("Expected no approval when RequireApproval is disabled")
+	}
+
+	config.RequireApproval = true
+	if !RequiresApproval(config, flagged) {
+		t.Error("Expected flagged prompt to require approval")
+	}
+
+	clean, err := ValidatePrompt("Write a story about a cat", config)
+	if err != nil {
+		t.Fatalf("ValidatePrompt failed: %v", err)
+	}
+	if RequiresApproval(config, clean) {
+		t.Error("Expected clean prompt to skip approval")
+	}
+
+	info := &ValidationResult{Issues: []ValidationIssue{{Type: "use_case", Severity: "info"}}}
+	if RequiresApproval(config, info) {
+		t.Error("Expected informational issues to skip approval")
+	}
+}
+
 func TestValidatePromptComprehensive(t *testing.T) {
 	config := DefaultConfig()
 	prompt := "Write a story about a cat"
@@ -271,10 +343,13 @@ func TestComplianceCheck(t *testing.T) {
 
 func TestPerformanceMetrics(t *testing.T) {
 	prompt := "Write a story about a cat"
-	metrics := calculatePerformanceMetrics(prompt)
+	metrics, err := calculatePerformanceMetrics(prompt, DefaultConfig())
+	if err != nil {
+		t.Fatalf("calculatePerformanceMetrics returned error: %v", err)
+	}
 
-	if metrics.EstimatedTokens <= 0 {
-		t.Error("Expected estimated tokens to be positive")
+	if metrics.EstimatedTokens != 6 || metrics.Tokenizer != tokenizer.Default {
+		t.Errorf("Expected 6 %s tokens, got %d %s", tokenizer.Default, metrics.EstimatedTokens, metrics.Tokenizer)
 	}
 
 	if metrics.ComplexityScore < 0 {
@@ -282,6 +357,69 @@ func TestPerformanceMetrics(t *testing.T) {
 	}
 }
 
+func TestPerformanceMetrics_ModelFit(t *testing.T) {
+	config := DefaultConfig()
+	config.Models = map[string]tokenizer.Model{
+		"tiny":   {Encoding: tokenizer.CL100KBase, ContextWindow: 4, InputPricePerMillion: 1000},
+		"gpt-4o": {Encoding: tokenizer.O200KBase, ContextWindow: 100, InputPricePerMillion: 5},
+	}
+
+	metrics, err := calculatePerformanceMetrics("hello world, hello world", config)
+	if err != nil {
+		t.Fatalf("calculatePerformanceMetrics returned error: %v", err)
+	}
+
+	fits := make(map[string]ModelFit)
+	for _, fit := range metrics.ModelFit {
+		fits[fit.Model] = fit
+	}
+	if len(fits) != len(tokenizer.DefaultModels())+1 {
+		t.Errorf("Expected the built-in models plus the configured one, got %d", len(fits))
+	}
+	tiny := fits["tiny"]
+	if tiny.Tokens != 5 || tiny.Fits || tiny.ContextUsed != 1.25 || tiny.EstimatedCostUSD != 0.005 {
+		t.Errorf("Unexpected fit for tiny model: %#v", tiny)
+	}
+	if override := fits["gpt-4o"]; override.ContextWindow != 100 || !override.Fits {
+		t.Errorf("Expected configured gpt-4o to replace the built-in one, got %#v", override)
+	}
+
+	config.Models = map[string]tokenizer.Model{"broken": {Encoding: "missing.tiktoken"}}
+	if _, err := calculatePerformanceMetrics("hello", config); err == nil {
+		t.Error("Expected an error for a model with an unknown encoding")
+	}
+}
+
+func TestValidatePrompt_MaxLengthInTokens(t *testing.T) {
+	config := DefaultConfig()
+	config.BlockedPatterns = nil
+	config.MaxLengthUnit = "tokens"
+	config.Tokenizer = tokenizer.CL100KBase
+	config.MaxLength = 4
+
+	// 23 characters but only 4 tokens
+	result, err := ValidatePrompt("hello world hello world", config)
+	if err != nil {
+		t.Fatalf("ValidatePrompt returned error: %v", err)
+	}
+	if !result.IsValid {
+		t.Errorf("Expected 4 tokens to fit a 4 token limit, got %#v", result.Issues)
+	}
+
+	result, err = ValidatePrompt("hello world hello world hello", config)
+	if err != nil {
+		t.Fatalf("ValidatePrompt returned error: %v", err)
+	}
+	if result.IsValid || !strings.Contains(result.Issues[0].Message, "maximum 4 tokens") {
+		t.Errorf("Expected a token length issue, got %#v", result.Issues)
+	}
+
+	config.MaxLengthUnit = "words"
+	if _, err := ValidatePrompt("hello", config); err == nil {
+		t.Error("Expected an error for an unknown length unit")
+	}
+}
+
 func TestGenerateRecommendations(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -0,0 +1,184 @@
+package sentinel
+
+import (
+	"flag"
+	"fmt"
+	"go/importer"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var updateAPI = flag.Bool("update-api", false, "rewrite testdata/api.golden with the current API")
+
+// TestAPICompatibility lists the exported API, including the fields and
+// methods of the internal types it aliases or returns, and compares it with
+// testdata/api.golden. Most of the API is defined in internal/validator, so
+// this is what stops a change there from breaking the compatibility
+// promise unnoticed. After an intended, compatible addition, run
+//
+//	go test ./sentinel -run TestAPICompatibility -update-api
+//
+// and review the diff; a removed or changed line breaks the promise.
+func TestAPICompatibility(t *testing.T) {
+	pkg, err := importer.ForCompiler(token.NewFileSet(), "source", nil).Import("promptsentinel/sentinel")
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	api, unnamed := describeAPI(pkg)
+	for _, name := range unnamed {
+		t.Errorf("%s is part of the API but has no exported alias, so callers cannot name it", name)
+	}
+
+	golden := filepath.Join("testdata", "api.golden")
+	if *updateAPI {
+		if err := os.WriteFile(golden, []byte(api), 0o644); err != nil {
+			t.Fatalf("write golden: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("read golden: %v", err)
+	}
+	if api == string(want) {
+		return
+	}
+
+	current := lineSet(api)
+	expected := lineSet(string(want))
+	for _, line := range strings.Split(string(want), "\n") {
+		if line != "" && !current[line] {
+			t.Errorf("removed or changed: %s", line)
+		}
+	}
+	for _, line := range strings.Split(api, "\n") {
+		if line != "" && !expected[line] {
+			t.Errorf("added: %s", line)
+		}
+	}
+	t.Log("run with -update-api to accept additions")
+}
+
+// describeAPI returns one line per exported declaration of pkg, and per
+// exported field and method of every named type reachable from them. It
+// also returns the reachable internal types that pkg does not alias.
+func describeAPI(pkg *types.Package) (string, []string) {
+	qualifier := func(other *types.Package) string { return other.Name() }
+	var lines []string
+	var queue []*types.Named
+	seen := make(map[*types.Named]bool)
+
+	// visit queues the named types in t that outside code can reach into.
+	var visit func(t types.Type)
+	visit = func(t types.Type) {
+		switch t := types.Unalias(t).(type) {
+		case *types.Named:
+			if t.Obj().Pkg() != nil && t.Obj().Exported() && !seen[t] {
+				seen[t] = true
+				queue = append(queue, t)
+			}
+		case *types.Pointer:
+			visit(t.Elem())
+		case *types.Slice:
+			visit(t.Elem())
+		case *types.Array:
+			visit(t.Elem())
+		case *types.Map:
+			visit(t.Key())
+			visit(t.Elem())
+		case *types.Signature:
+			for _, tuple := range []*types.Tuple{t.Params(), t.Results()} {
+				for i := 0; i < tuple.Len(); i++ {
+					visit(tuple.At(i).Type())
+				}
+			}
+		}
+	}
+
+	aliased := make(map[types.Type]bool)
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		obj := scope.Lookup(name)
+		if !obj.Exported() {
+			continue
+		}
+		switch obj := obj.(type) {
+		case *types.Const:
+			lines = append(lines, fmt.Sprintf("const %s %s = %s", name, types.TypeString(obj.Type(), qualifier), obj.Val()))
+		case *types.Var:
+			lines = append(lines, fmt.Sprintf("var %s %s", name, types.TypeString(obj.Type(), qualifier)))
+		case *types.Func:
+			lines = append(lines, "func "+name+strings.TrimPrefix(types.TypeString(obj.Type(), qualifier), "func"))
+		case *types.TypeName:
+			if obj.IsAlias() {
+				aliased[types.Unalias(obj.Type())] = true
+				lines = append(lines, fmt.Sprintf("type %s = %s", name, types.TypeString(types.Unalias(obj.Type()), qualifier)))
+			} else {
+				lines = append(lines, fmt.Sprintf("type %s", name))
+			}
+		}
+		visit(obj.Type())
+	}
+
+	var unnamed []string
+	for len(queue) > 0 {
+		named := queue[0]
+		queue = queue[1:]
+		if named.Obj().Pkg().Path() != pkg.Path() && !strings.HasPrefix(named.Obj().Pkg().Path(), "promptsentinel/") {
+			continue // Standard library and third-party types keep their own promises.
+		}
+		typeName := types.TypeString(named, qualifier)
+		if named.Obj().Pkg() != pkg && !aliased[named] {
+			unnamed = append(unnamed, typeName)
+		}
+
+		switch underlying := named.Underlying().(type) {
+		case *types.Struct:
+			lines = append(lines, fmt.Sprintf("type %s struct", typeName))
+			for i := 0; i < underlying.NumFields(); i++ {
+				field := underlying.Field(i)
+				if !field.Exported() {
+					continue
+				}
+				line := fmt.Sprintf("field %s.%s %s", typeName, field.Name(), types.TypeString(field.Type(), qualifier))
+				if tag := underlying.Tag(i); tag != "" {
+					line += " `" + tag + "`"
+				}
+				lines = append(lines, line)
+				visit(field.Type())
+			}
+		default:
+			lines = append(lines, fmt.Sprintf("type %s %s", typeName, types.TypeString(underlying, qualifier)))
+			visit(underlying)
+		}
+
+		methods := types.NewMethodSet(types.NewPointer(named))
+		for i := 0; i < methods.Len(); i++ {
+			method := methods.At(i).Obj()
+			if !method.Exported() {
+				continue
+			}
+			signature := strings.TrimPrefix(types.TypeString(method.Type(), qualifier), "func")
+			lines = append(lines, fmt.Sprintf("method %s.%s%s", typeName, method.Name(), signature))
+			visit(method.Type())
+		}
+	}
+
+	sort.Strings(lines)
+	sort.Strings(unnamed)
+	return strings.Join(lines, "\n") + "\n", unnamed
+}
+
+func lineSet(text string) map[string]bool {
+	set := make(map[string]bool)
+	for _, line := range strings.Split(text, "\n") {
+		set[line] = true
+	}
+	return set
+}
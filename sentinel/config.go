@@ -0,0 +1,55 @@
+package sentinel
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"promptsentinel/internal/validator"
+)
+
+// DefaultConfig returns the policy an Engine starts from.
+func DefaultConfig() *Config {
+	return validator.DefaultConfig()
+}
+
+// LoadConfigFile reads a policy from a JSON configuration file. A missing
+// file yields DefaultConfig, so that a fresh install works without one.
+func LoadConfigFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return DefaultConfig(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	return &config, nil
+}
+
+// PolicyVersion returns a short hash identifying the rules in config, for
+// storing alongside results.
+func PolicyVersion(config *Config) string {
+	return validator.PolicyVersion(config)
+}
+
+// ParseMessages decodes a conversation in OpenAI or Anthropic chat format.
+func ParseMessages(data []byte) ([]Message, error) {
+	return validator.ParseMessages(data)
+}
+
+// DetectDocumentFormat guesses the format of a document from its file name
+// and content.
+func DetectDocumentFormat(name, content string) string {
+	return validator.DetectDocumentFormat(name, content)
+}
+
+// ConversationText joins the messages of a conversation into one transcript.
+func ConversationText(messages []Message) string {
+	return validator.ConversationText(messages)
+}
@@ -0,0 +1,46 @@
+package sentinel
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigFile(t *testing.T) {
+	dir := t.TempDir()
+
+	config, err := LoadConfigFile(filepath.Join(dir, "missing.json"))
+	if err != nil {
+		t.Fatalf("LoadConfigFile returned error for a missing file: %v", err)
+	}
+	if config.UseCase != DefaultConfig().UseCase {
+		t.Errorf("Expected the default policy for a missing file, got %#v", config)
+	}
+
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(`{"use_case": "business", "fail_mode": "open"}`), 0644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	config, err = LoadConfigFile(path)
+	if err != nil {
+		t.Fatalf("LoadConfigFile returned error: %v", err)
+	}
+	if config.UseCase != "business" || config.FailMode != FailOpen {
+		t.Errorf("Expected the file's settings, got %#v", config)
+	}
+
+	engine, err := New(WithConfigFile(path), WithUseCase("creative"))
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	if got := engine.Config(); got.UseCase != "creative" || got.FailMode != FailOpen {
+		t.Errorf("Expected options to apply on top of the file, got %#v", got)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"use_case": `), 0644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if _, err := LoadConfigFile(path); err == nil {
+		t.Error("Expected an error for invalid JSON")
+	}
+}
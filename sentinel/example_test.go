@@ -0,0 +1,33 @@
+package sentinel_test
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"promptsentinel/sentinel"
+)
+
+func ExampleEngine_Check() {
+	engine, err := sentinel.New(
+		sentinel.WithUseCase("business"),
+		sentinel.WithCustomRule("ticket_id", `TICKET-\d+`),
+		sentinel.WithTimeout(100*time.Millisecond),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	result, err := engine.Check(context.Background(), "Summarize TICKET-1234 for the weekly report")
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(result.Outcome, result.Score)
+	for _, issue := range result.Issues {
+		fmt.Println(issue.Type, issue.Severity)
+	}
+	// Output:
+	// allowed 95
+	// custom_rule warning
+}
@@ -0,0 +1,149 @@
+package sentinel
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Option configures an Engine. Options apply in order, so WithConfig and
+// WithConfigFile, which replace the whole policy, go before the options
+// that adjust it.
+type Option func(*Engine) error
+
+// WithConfig validates against a copy of config. Later changes to config do
+// not affect the engine.
+func WithConfig(config *Config) Option {
+	return func(e *Engine) error {
+		if config == nil {
+			return fmt.Errorf("config cannot be nil")
+		}
+		copied, err := cloneConfig(config)
+		if err != nil {
+			return err
+		}
+		e.config = copied
+		return nil
+	}
+}
+
+// WithConfigFile validates against the policy in a configuration file, as
+// read by LoadConfigFile.
+func WithConfigFile(path string) Option {
+	return func(e *Engine) error {
+		config, err := LoadConfigFile(path)
+		if err != nil {
+			return err
+		}
+		e.config = config
+		return nil
+	}
+}
+
+// WithUseCase sets the use case prompts are checked for, such as
+// "general", "educational", "business", or "creative".
+func WithUseCase(useCase string) Option {
+	return func(e *Engine) error {
+		e.config.UseCase = useCase
+		return nil
+	}
+}
+
+// WithBlockedPatterns replaces the blocked patterns, which are regular
+// expressions.
+func WithBlockedPatterns(patterns ...string) Option {
+	return func(e *Engine) error {
+		e.config.BlockedPatterns = append([]string(nil), patterns...)
+		return nil
+	}
+}
+
+// WithCustomRule adds a named rule that flags text matching a regular
+// expression.
+func WithCustomRule(name, pattern string) Option {
+	return func(e *Engine) error {
+		if e.config.CustomRules == nil {
+			e.config.CustomRules = make(map[string]string)
+		}
+		e.config.CustomRules[name] = pattern
+		return nil
+	}
+}
+
+// WithLengthLimits sets the shortest and longest prompt allowed, in
+// characters or, when unit is "tokens", in tokens.
+func WithLengthLimits(minLength, maxLength int, unit string) Option {
+	return func(e *Engine) error {
+		e.config.MinLength = minLength
+		e.config.MaxLength = maxLength
+		e.config.MaxLengthUnit = unit
+		return nil
+	}
+}
+
+// WithTokenizer sets how tokens are counted: a built-in encoding such as
+// "o200k_base", "estimate", or the path of a .tiktoken or tokenizer.json
+// file.
+func WithTokenizer(name string) Option {
+	return func(e *Engine) error {
+		e.config.Tokenizer = name
+		return nil
+	}
+}
+
+// WithAllowedDomains sets the domains that links and tool call URLs may
+// point to.
+func WithAllowedDomains(domains ...string) Option {
+	return func(e *Engine) error {
+		e.config.AllowedDomains = append([]string(nil), domains...)
+		return nil
+	}
+}
+
+// WithToolPolicy adds the policy for one tool. Once any tool has a policy,
+// only tools with one may be called.
+func WithToolPolicy(name string, policy ToolPolicy) Option {
+	return func(e *Engine) error {
+		if e.config.ToolPolicies == nil {
+			e.config.ToolPolicies = make(map[string]ToolPolicy)
+		}
+		e.config.ToolPolicies[name] = policy
+		return nil
+	}
+}
+
+// WithFailMode decides the result when a validation runs out of time:
+// FailClosed, the default, blocks, and FailOpen judges by the checks that
+// ran.
+func WithFailMode(mode string) Option {
+	return func(e *Engine) error {
+		e.config.FailMode = mode
+		return nil
+	}
+}
+
+// WithTimeout bounds every validation the engine runs, in addition to any
+// deadline on the context passed in. Zero means no limit.
+func WithTimeout(timeout time.Duration) Option {
+	return func(e *Engine) error {
+		if timeout < 0 {
+			return fmt.Errorf("timeout cannot be negative")
+		}
+		e.timeout = timeout
+		return nil
+	}
+}
+
+// cloneConfig deep-copies config through its JSON form, which is also how
+// it is stored
+func cloneConfig(config *Config) (*Config, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy config: %w", err)
+	}
+	var copied Config
+	if err := json.Unmarshal(data, &copied); err != nil {
+		return nil, fmt.Errorf("failed to copy config: %w", err)
+	}
+	return &copied, nil
+}
@@ -0,0 +1,133 @@
+package sentinel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ExtractMessages collects the text to check from a JSON document. Each
+// path is a dot-separated list of object keys and array indexes in which *
+// matches every element, such as "prompt" or "input.*.text". A path that
+// selects a string adds it as a user message; one that selects a chat
+// message array, or an object with a "messages" field, adds those messages
+// with their roles, as ParseMessages reads them. Object keys match without
+// regard to case, as encoding/json matches struct fields, and every key that
+// matches is selected, so a handler decoding the document cannot be given
+// text that was not checked. Paths that select nothing are skipped, and an
+// empty result means there is nothing to check.
+func ExtractMessages(data []byte, paths []string) ([]Message, error) {
+	var document any
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	var messages []Message
+	for _, path := range paths {
+		var segments []string
+		if path != "" {
+			segments = strings.Split(path, ".")
+		}
+
+		for _, value := range selectPath(document, segments) {
+			switch v := value.(type) {
+			case nil:
+			case string:
+				if strings.TrimSpace(v) != "" {
+					messages = append(messages, Message{Role: RoleUser, Content: v})
+				}
+			case []any, map[string]any:
+				raw, err := json.Marshal(v)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", path, err)
+				}
+				conversation, err := ParseMessages(raw)
+				if err != nil {
+					return nil, fmt.Errorf("%s: expected text or chat messages: %w", path, err)
+				}
+				messages = append(messages, conversation...)
+			default:
+				return nil, fmt.Errorf("%s: expected text or chat messages, got %T", path, v)
+			}
+		}
+	}
+	return messages, nil
+}
+
+// selectPath returns the values at segments below value
+func selectPath(value any, segments []string) []any {
+	if len(segments) == 0 {
+		return []any{value}
+	}
+	segment, rest := segments[0], segments[1:]
+
+	var values []any
+	switch v := value.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			if segment == "*" || strings.EqualFold(key, segment) {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			values = append(values, selectPath(v[key], rest)...)
+		}
+	case []any:
+		if segment == "*" {
+			for _, child := range v {
+				values = append(values, selectPath(child, rest)...)
+			}
+		} else if i, err := strconv.Atoi(segment); err == nil && i >= 0 && i < len(v) {
+			values = selectPath(v[i], rest)
+		}
+	}
+	return values
+}
+
+// CheckJSON checks the text that paths select in a JSON request body as a
+// conversation, as ExtractMessages describes. It returns a nil result when
+// the paths select no text. This is what the sentinelhttp and sentinelgrpc
+// middleware run for each request.
+func (e *Engine) CheckJSON(ctx context.Context, data []byte, paths []string) (*ConversationResult, error) {
+	messages, err := ExtractMessages(data, paths)
+	if err != nil || len(messages) == 0 {
+		return nil, err
+	}
+	return e.CheckConversation(ctx, messages)
+}
+
+type resultKey struct{}
+
+// ContextWithResult returns a copy of ctx carrying the result of screening
+// a request, for handlers further down to read with ResultFromContext.
+func ContextWithResult(ctx context.Context, result *ConversationResult) context.Context {
+	return context.WithValue(ctx, resultKey{}, result)
+}
+
+// ResultFromContext returns the result that screening middleware stored in
+// ctx. It reports false when the request was not screened or had no text to
+// check.
+func ResultFromContext(ctx context.Context) (*ConversationResult, bool) {
+	result, ok := ctx.Value(resultKey{}).(*ConversationResult)
+	return result, ok && result != nil
+}
+
+// IssueTypes returns the distinct, sorted types of the issues in result,
+// for reporting a decision without its details.
+func IssueTypes(result *Result) []string {
+	types := []string{}
+	seen := make(map[string]bool)
+	for _, issue := range result.Issues {
+		if !seen[issue.Type] {
+			seen[issue.Type] = true
+			types = append(types, issue.Type)
+		}
+	}
+	sort.Strings(types)
+	return types
+}
@@ -0,0 +1,170 @@
+// Package sentinel embeds PromptSentinel's validation in Go programs. An
+// Engine checks prompts, conversations, model responses, RAG documents, and
+// agent tool calls against a policy, the same way the promptsentinel CLI
+// and HTTP API do:
+//
+//	engine, err := sentinel.New(sentinel.WithUseCase("business"), sentinel.WithTimeout(50*time.Millisecond))
+//	if err != nil {
+//		return err
+//	}
+//	result, err := engine.Check(ctx, prompt)
+//	if err != nil {
+//		return err
+//	}
+//	if !result.IsValid {
+//		// reject the prompt
+//	}
+//
+// # Compatibility
+//
+// The package follows semantic versioning, reported in Version. Within a
+// major version, exported names are not removed or renamed, function
+// signatures do not change, and result fields keep their meaning and JSON
+// names. New options, methods, result fields, issue types, and checks may be
+// added in minor versions, so a prompt that passes today may be flagged by
+// a later release; compare results with the policy's PolicyVersion rather
+// than assuming they are stable.
+package sentinel
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"promptsentinel/internal/tokenizer"
+	"promptsentinel/internal/validator"
+)
+
+// Version is the version of the sentinel API.
+const Version = "1.0.0"
+
+// Engine validates content against one policy. It is safe for concurrent
+// use, and its policy does not change once it is created.
+type Engine struct {
+	config  *Config
+	timeout time.Duration
+}
+
+// New creates an Engine. It starts from DefaultConfig and applies opts in
+// order.
+func New(opts ...Option) (*Engine, error) {
+	e := &Engine{config: DefaultConfig()}
+	for _, opt := range opts {
+		if err := opt(e); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := checkConfig(e.config); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// checkConfig rejects settings that would make every validation fail
+func checkConfig(config *Config) error {
+	switch config.FailMode {
+	case "", FailClosed, FailOpen:
+	default:
+		return fmt.Errorf("invalid fail mode %q: expected closed or open", config.FailMode)
+	}
+	switch config.MaxLengthUnit {
+	case "", "characters", "tokens":
+	default:
+		return fmt.Errorf("invalid max length unit %q: expected characters or tokens", config.MaxLengthUnit)
+	}
+	if config.Tokenizer != "" {
+		if _, err := tokenizer.Get(config.Tokenizer); err != nil {
+			return fmt.Errorf("invalid tokenizer: %w", err)
+		}
+	}
+	return nil
+}
+
+// Config returns a copy of the engine's policy.
+func (e *Engine) Config() *Config {
+	// The policy was copied in through JSON, so it copies back out.
+	copied, _ := cloneConfig(e.config)
+	return copied
+}
+
+// PolicyVersion identifies the engine's policy.
+func (e *Engine) PolicyVersion() string {
+	return validator.PolicyVersion(e.config)
+}
+
+// withTimeout applies the engine's timeout to ctx
+func (e *Engine) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if e.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, e.timeout)
+}
+
+// Check runs the basic checks on a prompt: length, blocked patterns, the
+// use case, and custom rules. When ctx is done or the engine's timeout
+// passes first, the remaining checks are skipped and the result is marked
+// Incomplete; the fail mode decides whether it is valid.
+func (e *Engine) Check(ctx context.Context, prompt string) (*Result, error) {
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
+	return validator.ValidatePromptContext(ctx, prompt, e.config)
+}
+
+// CheckConversation checks each message under its role's policy and looks
+// for attacks spread across turns.
+func (e *Engine) CheckConversation(ctx context.Context, messages []Message) (*ConversationResult, error) {
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
+	return validator.ValidateConversationContext(ctx, messages, e.config)
+}
+
+// Validate checks a prompt and adds security, compliance, and performance
+// analysis with per-stage timings.
+func (e *Engine) Validate(ctx context.Context, prompt string) (*ComprehensiveResult, error) {
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
+	return validator.ValidatePromptComprehensiveContext(ctx, prompt, e.config)
+}
+
+// ValidateConversation is Validate for a conversation.
+func (e *Engine) ValidateConversation(ctx context.Context, messages []Message) (*ComprehensiveResult, error) {
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
+	return validator.ValidateConversationComprehensiveContext(ctx, messages, e.config)
+}
+
+// ValidateOutput checks a model response for system prompt leakage, canary
+// tokens, secrets, personal data, and exfiltration links.
+func (e *Engine) ValidateOutput(ctx context.Context, output string, opts OutputOptions) (*OutputResult, error) {
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
+	return validator.ValidateOutputContext(ctx, output, opts, e.config)
+}
+
+// ScanDocument checks a document retrieved for RAG for instructions aimed
+// at the model, scoring each chunk.
+func (e *Engine) ScanDocument(ctx context.Context, content string, opts DocumentOptions) (*DocumentResult, error) {
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
+	return validator.ScanDocumentContext(ctx, content, opts, e.config)
+}
+
+// ValidateToolCall checks a tool call against the engine's tool policies.
+func (e *Engine) ValidateToolCall(ctx context.Context, call ToolCall) (*ToolCallResult, error) {
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
+	return validator.ValidateToolCallContext(ctx, call, e.config)
+}
+
+// Redact replaces blocked patterns and custom rule matches in prompt and
+// reports how many were replaced.
+func (e *Engine) Redact(prompt string) (string, int) {
+	return validator.RedactPrompt(prompt, e.config)
+}
+
+// RequiresApproval reports whether a result must be reviewed by a human
+// under the engine's policy.
+func (e *Engine) RequiresApproval(result *Result) bool {
+	return validator.RequiresApproval(e.config, result)
+}
@@ -0,0 +1,165 @@
+// Package sentinelgrpc screens the prompts in gRPC requests before they
+// reach a service:
+//
+//	engine, _ := sentinel.New(sentinel.WithConfigFile("config.json"))
+//	paths := []string{"prompt", "messages"}
+//	server := grpc.NewServer(
+//		grpc.ChainUnaryInterceptor(sentinelgrpc.UnaryServerInterceptor(engine, paths)),
+//		grpc.ChainStreamInterceptor(sentinelgrpc.StreamServerInterceptor(engine, paths)),
+//	)
+//
+// Requests are converted to JSON with their proto field names, so paths
+// name fields as the .proto file does. Blocked requests fail with
+// PermissionDenied unless Annotate is given. Either way, handlers read the
+// result with sentinel.ResultFromContext instead of validating again.
+package sentinelgrpc
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+
+	"promptsentinel/sentinel"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Metadata keys added to the incoming metadata of screened requests, for
+// handlers that forward it. Values sent by the client are removed first.
+const (
+	OutcomeKey = "x-promptsentinel-outcome"
+	ScoreKey   = "x-promptsentinel-score"
+	IssuesKey  = "x-promptsentinel-issues"
+)
+
+// Option configures the interceptors.
+type Option func(*options)
+
+type options struct {
+	annotate bool
+}
+
+// Annotate passes blocked requests on to the handler, which decides what to
+// do from the result in the context, instead of failing them.
+func Annotate() Option {
+	return func(o *options) { o.annotate = true }
+}
+
+// screener checks request messages for the interceptors
+type screener struct {
+	engine *sentinel.Engine
+	paths  []string
+	opts   options
+}
+
+func newScreener(engine *sentinel.Engine, paths []string, opts []Option) *screener {
+	s := &screener{engine: engine, paths: paths}
+	for _, opt := range opts {
+		opt(&s.opts)
+	}
+	return s
+}
+
+var marshalOptions = protojson.MarshalOptions{UseProtoNames: true}
+
+// screen checks msg and returns the context handlers should see, or the
+// status error to fail the call with
+func (s *screener) screen(ctx context.Context, msg any) (context.Context, error) {
+	var data []byte
+	var err error
+	if m, ok := msg.(proto.Message); ok {
+		data, err = marshalOptions.Marshal(m)
+	} else {
+		data, err = json.Marshal(msg)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode request for screening: %v", err)
+	}
+
+	result, err := s.engine.CheckJSON(ctx, data, s.paths)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	md = md.Copy()
+	md.Delete(OutcomeKey)
+	md.Delete(ScoreKey)
+	md.Delete(IssuesKey)
+	if result == nil {
+		return metadata.NewIncomingContext(ctx, md), nil
+	}
+
+	issueTypes := sentinel.IssueTypes(&result.ValidationResult)
+	if !result.IsValid && !s.opts.annotate {
+		return nil, status.Errorf(codes.PermissionDenied,
+			"request blocked by PromptSentinel policy (issues: %s)", strings.Join(issueTypes, ", "))
+	}
+
+	md.Set(OutcomeKey, result.Outcome)
+	md.Set(ScoreKey, strconv.Itoa(result.Score))
+	md.Set(IssuesKey, strings.Join(issueTypes, ","))
+	ctx = metadata.NewIncomingContext(ctx, md)
+	return sentinel.ContextWithResult(ctx, result), nil
+}
+
+// UnaryServerInterceptor screens the request of each unary call.
+func UnaryServerInterceptor(engine *sentinel.Engine, paths []string, opts ...Option) grpc.UnaryServerInterceptor {
+	s := newScreener(engine, paths, opts)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := s.screen(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor screens each message a client sends on a stream.
+// A blocked message fails the stream's RecvMsg; the stream's context
+// carries the result of the latest message received.
+func StreamServerInterceptor(engine *sentinel.Engine, paths []string, opts ...Option) grpc.StreamServerInterceptor {
+	s := newScreener(engine, paths, opts)
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &screenedStream{ServerStream: stream, screener: s, ctx: stream.Context()})
+	}
+}
+
+// screenedStream screens messages as the handler receives them
+type screenedStream struct {
+	grpc.ServerStream
+	screener *screener
+
+	// The handler may read the context from another goroutine while it
+	// receives.
+	mu  sync.Mutex
+	ctx context.Context
+}
+
+func (s *screenedStream) Context() context.Context {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ctx
+}
+
+func (s *screenedStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	ctx, err := s.screener.screen(s.ServerStream.Context(), m)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.ctx = ctx
+	s.mu.Unlock()
+	return nil
+}
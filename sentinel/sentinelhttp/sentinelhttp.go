@@ -0,0 +1,172 @@
+// Package sentinelhttp screens the prompts in HTTP request bodies before
+// they reach a handler:
+//
+//	engine, _ := sentinel.New(sentinel.WithConfigFile("config.json"))
+//	handler := sentinelhttp.Middleware(engine, []string{"messages", "prompt"})(mux)
+//
+// Blocked requests are rejected with 403 unless Annotate is given. Either
+// way, handlers read the result with sentinel.ResultFromContext instead of
+// validating again. Form posts, uploads, and other bodies that are not JSON
+// pass through unscreened unless RequireJSON is given.
+package sentinelhttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"promptsentinel/sentinel"
+)
+
+// Headers set on screened requests, for handlers that forward them. Values
+// sent by the client are removed first.
+const (
+	OutcomeHeader = "X-PromptSentinel-Outcome"
+	ScoreHeader   = "X-PromptSentinel-Score"
+	IssuesHeader  = "X-PromptSentinel-Issues"
+)
+
+// DefaultMaxBodyBytes is the largest request body read when MaxBodyBytes is
+// not given.
+const DefaultMaxBodyBytes = 1 << 20
+
+// Option configures the middleware.
+type Option func(*options)
+
+type options struct {
+	annotate     bool
+	requireJSON  bool
+	maxBodyBytes int64
+}
+
+// Annotate passes blocked requests on to the handler, which decides what to
+// do from the result in the request context, instead of rejecting them.
+func Annotate() Option {
+	return func(o *options) { o.annotate = true }
+}
+
+// RequireJSON rejects requests with a body that is not JSON, such as form
+// posts and uploads, with 415 instead of passing them on unscreened. Use it
+// when the handler reads JSON whatever the Content-Type says.
+func RequireJSON() Option {
+	return func(o *options) { o.requireJSON = true }
+}
+
+// MaxBodyBytes sets the largest request body the middleware reads. Larger
+// requests are rejected with 413.
+func MaxBodyBytes(n int64) Option {
+	return func(o *options) { o.maxBodyBytes = n }
+}
+
+// ErrorResponse is the body of a rejected request.
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// ErrorBody describes why a request was rejected. Code is stable and
+// machine readable; Message is meant for humans.
+type ErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Middleware returns middleware that checks the text paths select in each
+// JSON request body, as sentinel.ExtractMessages describes. A body is
+// screened when it parses as JSON, whatever its Content-Type. One that does
+// not is rejected with 400 when its Content-Type is JSON or missing, and
+// otherwise passed through unscreened, or rejected with 415 under
+// RequireJSON. Requests without a body, or with no text at those paths,
+// also pass through unscreened, with no result in their context.
+func Middleware(engine *sentinel.Engine, paths []string, opts ...Option) func(http.Handler) http.Handler {
+	o := options{maxBodyBytes: DefaultMaxBodyBytes}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.Header.Del(OutcomeHeader)
+			r.Header.Del(ScoreHeader)
+			r.Header.Del(IssuesHeader)
+
+			if r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, o.maxBodyBytes))
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					writeError(w, http.StatusRequestEntityTooLarge, "request_too_large", fmt.Sprintf("request body exceeds %d bytes", o.maxBodyBytes))
+					return
+				}
+				writeError(w, http.StatusBadRequest, "invalid_request", "failed to read request body")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			// JSON is screened whatever the Content-Type claims, since
+			// handlers often decode it regardless. A body declared as
+			// JSON that does not parse fails closed.
+			if !json.Valid(body) {
+				switch {
+				case isJSON(r.Header.Get("Content-Type")):
+					writeError(w, http.StatusBadRequest, "invalid_request", "request body must be a single JSON value")
+				case o.requireJSON:
+					writeError(w, http.StatusUnsupportedMediaType, "unsupported_media_type", "request body must be JSON")
+				default:
+					next.ServeHTTP(w, r)
+				}
+				return
+			}
+
+			result, err := engine.CheckJSON(r.Context(), body, paths)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+				return
+			}
+			if result == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			issueTypes := sentinel.IssueTypes(&result.ValidationResult)
+			if !result.IsValid && !o.annotate {
+				writeError(w, http.StatusForbidden, "prompt_blocked",
+					fmt.Sprintf("request blocked by PromptSentinel policy (issues: %s)", strings.Join(issueTypes, ", ")))
+				return
+			}
+
+			r.Header.Set(OutcomeHeader, result.Outcome)
+			r.Header.Set(ScoreHeader, strconv.Itoa(result.Score))
+			r.Header.Set(IssuesHeader, strings.Join(issueTypes, ","))
+			next.ServeHTTP(w, r.WithContext(sentinel.ContextWithResult(r.Context(), result)))
+		})
+	}
+}
+
+// isJSON reports whether contentType is JSON or missing, as clients often
+// send JSON without saying so
+func isJSON(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return true
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{Error: ErrorBody{Code: code, Message: message}})
+}
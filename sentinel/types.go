@@ -0,0 +1,119 @@
+package sentinel
+
+import (
+	"promptsentinel/internal/tokenizer"
+	"promptsentinel/internal/validator"
+)
+
+// The types below are the validator's own, so results can be passed to code
+// written against either package. They follow the compatibility promise in
+// the package documentation: fields may be added, but none are removed or
+// change meaning within a major version.
+
+// Config is a validation policy. Its JSON form is the configuration file
+// format.
+type Config = validator.Config
+
+// Model describes a model in Config.Models: its tokenizer encoding, context
+// window, and price, used to estimate fit and cost.
+type Model = tokenizer.Model
+
+// Result is the result of a Check.
+type Result = validator.ValidationResult
+
+// Issue is one finding in a result.
+type Issue = validator.ValidationIssue
+
+// ComprehensiveResult is the result of a Validate, with security,
+// compliance, and performance analysis.
+type ComprehensiveResult = validator.ComprehensiveValidationResult
+
+// SecurityAnalysis, ComplianceCheck, PerformanceMetrics, ModelFit, and
+// Timing are sections of a ComprehensiveResult.
+type (
+	SecurityAnalysis   = validator.SecurityAnalysis
+	ComplianceCheck    = validator.ComplianceCheck
+	PerformanceMetrics = validator.PerformanceMetrics
+	ModelFit           = validator.ModelFit
+	Timing             = validator.Timing
+)
+
+// Message is one message of a conversation.
+type Message = validator.Message
+
+// ConversationResult is the result of a CheckConversation, with a result
+// per message.
+type ConversationResult = validator.ConversationResult
+
+// MessageResult, ConversationAnalysis, and TurnRisk are sections of a
+// ConversationResult.
+type (
+	MessageResult        = validator.MessageResult
+	ConversationAnalysis = validator.ConversationAnalysis
+	TurnRisk             = validator.TurnRisk
+)
+
+// RolePolicy adjusts which checks apply to messages of one role.
+type RolePolicy = validator.RolePolicy
+
+// OutputOptions, OutputResult, and LeakageAnalysis describe the validation
+// of a model response.
+type (
+	OutputOptions   = validator.OutputOptions
+	OutputResult    = validator.OutputValidationResult
+	LeakageAnalysis = validator.LeakageAnalysis
+)
+
+// CanaryToken is a marker embedded in a system prompt to detect leaks.
+type CanaryToken = validator.CanaryToken
+
+// DocumentOptions, DocumentResult, DocumentChunk, and HiddenContent
+// describe the scan of a document retrieved for RAG.
+type (
+	DocumentOptions = validator.DocumentOptions
+	DocumentResult  = validator.DocumentScanResult
+	DocumentChunk   = validator.DocumentChunk
+	HiddenContent   = validator.HiddenContent
+)
+
+// ToolCall, ToolPolicy, ToolCallResult, and Schema describe the validation
+// of a call an agent wants to make.
+type (
+	ToolCall       = validator.ToolCall
+	ToolPolicy     = validator.ToolPolicy
+	ToolCallResult = validator.ToolCallResult
+	Schema         = validator.Schema
+)
+
+// Outcomes reported in Result.Outcome
+const (
+	OutcomeAllowed         = validator.OutcomeAllowed
+	OutcomeBlocked         = validator.OutcomeBlocked
+	OutcomePendingApproval = validator.OutcomePendingApproval
+)
+
+// Fail modes for WithFailMode and Config.FailMode
+const (
+	FailClosed = validator.FailClosed
+	FailOpen   = validator.FailOpen
+)
+
+// Message roles
+const (
+	RoleSystem    = validator.RoleSystem
+	RoleUser      = validator.RoleUser
+	RoleAssistant = validator.RoleAssistant
+	RoleTool      = validator.RoleTool
+)
+
+// DefaultChunkSize is the chunk size used when DocumentOptions does not set
+// one.
+const DefaultChunkSize = validator.DefaultChunkSize
+
+// Document formats for DocumentOptions.Format
+const (
+	FormatText     = validator.FormatText
+	FormatMarkdown = validator.FormatMarkdown
+	FormatHTML     = validator.FormatHTML
+	FormatJSON     = validator.FormatJSON
+)